@@ -0,0 +1,34 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+// Aborted return the error that aborted parsing with scanner `s`, or any
+// of its clones, like backtracking beyond the limit set by
+// WithMaxBacktrack or Foreign's consumer returning error, nil if the
+// parse is not aborted. Once aborted, all matches on the scanner and its
+// clones fail, hence parsers fail. Run reports the error, parsers
+// applied directly shall check it using Aborted. Supported by scanners
+// created with NewScanner, NewScannerString and NewScannerAt, with other
+// scanners the parse simply fails.
+func Aborted(s Scanner) error {
+	if as, ok := s.(abortScanner); ok {
+		if perr := as.aborted(); perr != nil {
+			return perr
+		}
+	}
+	return nil
+}
+
+// abortScanner is implemented by scanners that can abort the parse.
+type abortScanner interface {
+	aborted() *ParseError
+	abortParse(perr *ParseError)
+}
+
+// abortParse abort parsing with scanner `s`, if supported, with `perr`.
+func abortParse(s Scanner, perr *ParseError) {
+	if as, ok := s.(abortScanner); ok {
+		as.abortParse(perr)
+	}
+}
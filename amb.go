@@ -0,0 +1,113 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "reflect"
+
+// Bounds on the work done by AllParses.
+const (
+	MaxAllParses     = 64   // maximum number of parses returned.
+	MaxAmbiguousRuns = 4096 // maximum number of times the parser is applied.
+)
+
+// ambPath is the sequence of choices made by AmbChoice combinators
+// while applying a parser with AllParses, shared by a scanner and all
+// its clones.
+type ambPath struct {
+	choices []int // alternative chosen at every choice point, in order.
+	counts  []int // number of alternatives at every choice point.
+	next    int   // next choice point.
+	dead    bool  // the run duplicates, or contradicts, another run.
+}
+
+// choose return the alternative, out of `n`, to be tried at the next
+// choice point, the first one for choice points not visited before.
+func (ap *ambPath) choose(n int) int {
+	if ap.next == len(ap.choices) {
+		ap.choices, ap.counts = append(ap.choices, 0), append(ap.counts, n)
+	}
+	ap.next++
+	return ap.choices[ap.next-1]
+}
+
+// advance to the next sequence of choices, depth first. Return false
+// if all the sequences are tried.
+func (ap *ambPath) advance() bool {
+	for i := ap.next - 1; i >= 0; i-- {
+		if ap.choices[i]+1 < ap.counts[i] {
+			ap.choices[i]++
+			ap.choices, ap.counts = ap.choices[:i+1], ap.counts[:i+1]
+			ap.next, ap.dead = 0, false
+			return true
+		}
+	}
+	return false
+}
+
+// AmbChoice combinator is same as OrdChoice, but when applied by
+// AllParses it branches into every alternative that matches the input,
+// so that AllParses can collect the parses of ambiguous grammars.
+func AmbChoice(callb Nodify, parsers ...interface{}) Parser {
+	ordchoice := OrdChoice(callb, parsers...)
+	return func(s Scanner) (ParsecNode, Scanner) {
+		ss, ok := s.(*SimpleScanner)
+		if !ok || ss.amb == nil || len(parsers) == 0 {
+			return ordchoice(s)
+		}
+		ap := ss.amb
+		i := ap.choose(len(parsers))
+		if n, news := doParse(parsers[i], s.Clone()); n != nil {
+			if node := docallback(callb, []ParsecNode{n}); node != nil {
+				return node, news
+			}
+		}
+		// chosen alternative doesn't match, the run is a dead end if
+		// another alternative does, or if it duplicates the first one.
+		if i > 0 {
+			ap.dead = true
+		} else {
+			plain := ss.Clone().(*SimpleScanner)
+			plain.scanState = plain.fork()
+			plain.amb = nil
+			if node, _ := ordchoice(plain); node != nil {
+				ap.dead = true
+			}
+		}
+		return nil, s
+	}
+}
+
+// AllParses apply parser `p` on `text`, with every combination of
+// alternatives at AmbChoice combinators, and return the distinct parses
+// that consume the entire text, except for trailing whitespace. Parses
+// are compared using reflect.DeepEqual. The work is bounded, atmost
+// MaxAllParses parses are returned and `p` is applied atmost
+// MaxAmbiguousRuns times, hence meant for grammars with bounded
+// ambiguity. AmbChoice combinators shall not be memoized.
+func AllParses(p Parser, text []byte) []ParsecNode {
+	ap, parses := &ambPath{}, []ParsecNode{}
+	for runs := 0; runs < MaxAmbiguousRuns; runs++ {
+		s := NewScanner(text).(*SimpleScanner)
+		s.amb = ap
+		node, news := p(s)
+		if node != nil && !ap.dead {
+			if _, news = news.SkipWS(); news.Endof() && !hasParse(parses, node) {
+				parses = append(parses, node)
+			}
+		}
+		if len(parses) == MaxAllParses || !ap.advance() {
+			break
+		}
+	}
+	return parses
+}
+
+func hasParse(parses []ParsecNode, node ParsecNode) bool {
+	for _, parse := range parses {
+		if reflect.DeepEqual(parse, node) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package parsec
+
+import "reflect"
+import "strings"
+import "testing"
+
+func TestAllParses(t *testing.T) {
+	words := func(ns []ParsecNode) ParsecNode {
+		ws := []string{}
+		for _, n := range ns {
+			switch v := n.(type) {
+			case *Terminal:
+				ws = append(ws, v.Value)
+			case string:
+				ws = append(ws, v)
+			}
+		}
+		return "(" + strings.Join(ws, " ") + ")"
+	}
+	a := Atom("a", "A")
+	one := And(words, a)
+	two := And(words, a, a)
+	first := func(ns []ParsecNode) ParsecNode { return ns[0] }
+	w := AmbChoice(first, one, two)
+
+	// S -> W W
+	y := And(words, w, w)
+	parses := AllParses(y, []byte("a a a"))
+	ref := []ParsecNode{"((a) (a a))", "((a a) (a))"}
+	if !reflect.DeepEqual(parses, ref) {
+		t.Errorf("expected %v, got %v", ref, parses)
+	}
+	// OrdChoice semantics, without AllParses.
+	if node, _ := y(NewScanner([]byte("a a a"))); node != "((a) (a))" {
+		t.Errorf("expected %v, got %v", "((a) (a))", node)
+	}
+
+	// S -> W+
+	y = Many(words, w)
+	parses = AllParses(y, []byte("a a a"))
+	ref = []ParsecNode{"((a) (a) (a))", "((a) (a a))", "((a a) (a))"}
+	if !reflect.DeepEqual(parses, ref) {
+		t.Errorf("expected %v, got %v", ref, parses)
+	}
+	if parses = AllParses(y, []byte("a b")); len(parses) != 0 {
+		t.Errorf("unexpected %v", parses)
+	}
+
+	// ambiguity is bounded.
+	parses = AllParses(y, []byte(strings.Repeat("a ", 20)))
+	if len(parses) != MaxAllParses {
+		t.Errorf("expected %v, got %v", MaxAllParses, len(parses))
+	}
+}
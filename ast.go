@@ -118,7 +118,7 @@ func (ast *AST) And(name string, callb ASTNodify, parsers ...interface{}) Parser
 	return func(s Scanner) (ParsecNode, Scanner) {
 		var node ParsecNode
 		var err error
-		nt, news := ast.getnt(name), s.Clone()
+		nt, news := ast.getnt(name, s), s.Clone()
 		for i, parser := range parsers {
 			if node, news, err = ast.doParse(parser, news); err != nil {
 				fmsg := "while parsing %vth in %q: %v"
@@ -178,7 +178,7 @@ func (ast *AST) Kleene(nm string, callb ASTNodify, ps ...interface{}) Parser {
 	return func(s Scanner) (ParsecNode, Scanner) {
 		var node ParsecNode
 		var err error
-		nt, news := ast.getnt(nm), s.Clone()
+		nt, news := ast.getnt(nm, s), s.Clone()
 		for {
 			if node, news, err = ast.doParse(opScan, news); err != nil {
 				panic(fmt.Errorf("while opscan-parsing %q: %v", nm, err))
@@ -216,7 +216,7 @@ func (ast *AST) Many(nm string, callb ASTNodify, parsers ...interface{}) Parser
 	return func(s Scanner) (ParsecNode, Scanner) {
 		var node ParsecNode
 		var err error
-		nt, news := ast.getnt(nm), s.Clone()
+		nt, news := ast.getnt(nm, s), s.Clone()
 		for {
 			if node, news, err = ast.doParse(opScan, news); err != nil {
 				panic(fmt.Errorf("while opscan-parsing %q: %v", nm, err))
@@ -259,7 +259,7 @@ func (ast *AST) ManyUntil(nm string, callb ASTNodify, ps ...interface{}) Parser
 	return func(s Scanner) (ParsecNode, Scanner) {
 		var node ParsecNode
 		var err error
-		nt, news := ast.getnt(nm), s.Clone()
+		nt, news := ast.getnt(nm, s), s.Clone()
 		for {
 			if node, _, err = ast.doParse(untilScan, news.Clone()); err != nil {
 				panic(fmt.Errorf("while untilscan-parsing %q: %v", nm, err))
@@ -311,7 +311,7 @@ func (ast *AST) Maybe(name string, callb ASTNodify, parser interface{}) Parser {
 func (ast *AST) End(name string) Parser {
 	return func(s Scanner) (ParsecNode, Scanner) {
 		if s.Endof() {
-			return NewTerminal(name, "", s.GetCursor()), s
+			return newTerminal(s, name, "", s.GetCursor()), s
 		}
 		return nil, s
 	}
@@ -356,12 +356,9 @@ func (ast *AST) Query(selectors string, ch chan Queryable) {
 func (ast *AST) doParse(
 	parser interface{}, s Scanner) (ParsecNode, Scanner, error) {
 
-	switch p := parser.(type) {
-	case Parser:
-		node, news := p(s)
-		return node, news, nil
-	case *Parser:
-		node, news := (*p)(s)
+	switch parser.(type) {
+	case Parser, *Parser:
+		node, news := doParse(parser, s)
 		return node, news, nil
 	default:
 		return nil, s, errors.New("badtype")
@@ -382,21 +379,25 @@ func (ast *AST) docallback(
 		}
 		return q
 	}
+	if nt, ok := node.(*NonTerminal); ok {
+		dropTokens(s, nt)
+	}
 	return node
 }
 
-func (ast *AST) getnt(name string) (node *NonTerminal) {
+func (ast *AST) getnt(name string, s Scanner) (node *NonTerminal) {
 	select {
 	case node = <-ast.ntpool:
-		node.Name = name
+		node.Name, node.ID = name, nextNodeID(s)
 	default:
-		node = NewNonTerminal(name)
+		node = newNonTerminal(s, name)
 	}
 	return node
 }
 
 func (ast *AST) putnt(node *NonTerminal) {
-	node.Children = node.Children[:0]
+	node.Children, node.Parent, node.ID = node.Children[:0], nil, 0
+	node.span = nil
 	select {
 	case ast.ntpool <- node:
 	default: // node shall be collected by GC.
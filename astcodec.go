@@ -0,0 +1,131 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "encoding/json"
+import "fmt"
+
+// ASTVersion is the format version of documents generated by EncodeAST.
+const ASTVersion = 2
+
+// ASTVersionError is returned by DecodeAST for documents encoded in a
+// format version that this package does not understand.
+type ASTVersionError struct {
+	Version int
+}
+
+func (err *ASTVersionError) Error() string {
+	fmsg := "parsec: unsupported AST format version %v, expected <= %v"
+	return fmt.Sprintf(fmsg, err.Version, ASTVersion)
+}
+
+// astEnvelope wraps the root node with the format version. Version 1
+// documents did not have an envelope and carried the root node as is.
+type astEnvelope struct {
+	Version *int     `json:"parsecAST"`
+	Root    *astNode `json:"root"`
+}
+
+// astNode is the encoded form of a Queryable node. Span and Attributes
+// were added in version 2.
+type astNode struct {
+	Name       string              `json:"name"`
+	Terminal   bool                `json:"terminal,omitempty"`
+	Value      string              `json:"value,omitempty"`
+	Span       []int               `json:"span,omitempty"`
+	Attributes map[string][]string `json:"attributes,omitempty"`
+	Children   []*astNode          `json:"children,omitempty"`
+}
+
+// EncodeAST encode the syntax tree rooted at `root` as JSON document,
+// along with the span of input text covered by each node and its
+// attributes. The document carries its format version in the
+// `parsecAST` field.
+func EncodeAST(root Queryable) ([]byte, error) {
+	version := ASTVersion
+	envelope := astEnvelope{Version: &version, Root: encodeNode(root)}
+	return json.Marshal(envelope)
+}
+
+// DecodeAST decode a document generated by EncodeAST back into a tree of
+// Terminal and NonTerminal nodes. Documents from older format versions
+// are accepted, fields that were not available in those versions are
+// left as zero values. Return *ASTVersionError for documents from a
+// newer format version.
+func DecodeAST(data []byte) (Queryable, error) {
+	var envelope astEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+	var root *astNode
+	if envelope.Version == nil { // version 1, root node without envelope.
+		root = &astNode{}
+		if err := json.Unmarshal(data, root); err != nil {
+			return nil, err
+		}
+		stripv2(root)
+
+	} else if *envelope.Version > ASTVersion {
+		return nil, &ASTVersionError{Version: *envelope.Version}
+
+	} else if root = envelope.Root; root == nil {
+		return nil, fmt.Errorf("parsec: AST document without root node")
+
+	} else if *envelope.Version < 2 {
+		stripv2(root)
+	}
+	return decodeNode(root), nil
+}
+
+func encodeNode(q Queryable) *astNode {
+	node := &astNode{
+		Name:       q.GetName(),
+		Terminal:   q.IsTerminal(),
+		Attributes: q.GetAttributes(),
+	}
+	if node.Terminal {
+		node.Value = q.GetValue()
+		pos := q.GetPosition()
+		node.Span = []int{pos, pos + len(node.Value)}
+		return node
+	}
+	if start, end, ok := Span(q); ok {
+		node.Span = []int{start, end}
+	}
+	for _, child := range q.GetChildren() {
+		node.Children = append(node.Children, encodeNode(child))
+	}
+	return node
+}
+
+func decodeNode(node *astNode) Queryable {
+	attrs := node.Attributes
+	if attrs == nil {
+		attrs = make(map[string][]string)
+	}
+	if node.Terminal {
+		t := &Terminal{Name: node.Name, Value: node.Value, Attributes: attrs}
+		if len(node.Span) > 0 {
+			t.Position = node.Span[0]
+		}
+		return t
+	}
+	nt := &NonTerminal{
+		Name:       node.Name,
+		Children:   make([]Queryable, 0, len(node.Children)),
+		Attributes: attrs,
+	}
+	for _, child := range node.Children {
+		nt.Children = append(nt.Children, decodeNode(child))
+	}
+	return nt
+}
+
+// stripv2 ignore fields that are not part of version 1 format.
+func stripv2(node *astNode) {
+	node.Span, node.Attributes = nil, nil
+	for _, child := range node.Children {
+		stripv2(child)
+	}
+}
@@ -0,0 +1,101 @@
+package parsec
+
+import "io/ioutil"
+import "reflect"
+import "testing"
+
+func makeastcodectree(t *testing.T) Queryable {
+	ast := NewAST("pairs", 100)
+	pair := ast.And("pair", nil,
+		Token(`[a-z]+`, "KEY"), Atom("=", "EQUAL"), Token(`[0-9]+`, "VALUE"))
+	y := ast.Kleene("pairs", nil, pair, Atom(",", "COMMA"))
+	root, s := ast.Parsewith(y, NewScanner([]byte("x = 10, yy = 2")))
+	if root == nil || !s.Endof() {
+		t.Fatalf("unexpected %v %v", root, s.GetCursor())
+	}
+	return root
+}
+
+func TestEncodeAST(t *testing.T) {
+	root := makeastcodectree(t)
+	data, err := EncodeAST(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := ioutil.ReadFile("testdata/ast_v2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data)+"\n" != string(golden) {
+		t.Errorf("expected %s, got %s", golden, data)
+	}
+	q, err := DecodeAST(data)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(q, root) {
+		t.Errorf("expected %v, got %v", root, q)
+	}
+}
+
+func TestDecodeASTVersions(t *testing.T) {
+	root := makeastcodectree(t)
+
+	// version 2 fixture round trips the whole tree.
+	data, err := ioutil.ReadFile("testdata/ast_v2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := DecodeAST(data)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(q, root) {
+		t.Errorf("expected %v, got %v", root, q)
+	}
+
+	// version 1 fixture has no spans and attributes.
+	data, err = ioutil.ReadFile("testdata/ast_v1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err = DecodeAST(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var check func(q, ref Queryable)
+	check = func(q, ref Queryable) {
+		if q.GetName() != ref.GetName() || q.IsTerminal() != ref.IsTerminal() {
+			t.Errorf("expected %v, got %v", ref.GetName(), q.GetName())
+		} else if q.GetValue() != ref.GetValue() {
+			t.Errorf("expected %q, got %q", ref.GetValue(), q.GetValue())
+		} else if q.GetPosition() != 0 || len(q.GetAttributes()) != 0 {
+			t.Errorf("unexpected %v %v", q.GetPosition(), q.GetAttributes())
+		} else if x, y := len(ref.GetChildren()), len(q.GetChildren()); x != y {
+			t.Errorf("expected %v, got %v", x, y)
+		} else {
+			for i, child := range q.GetChildren() {
+				check(child, ref.GetChildren()[i])
+			}
+		}
+	}
+	check(q, root)
+
+	// explicit older versions are accepted.
+	data = []byte(`{"parsecAST": 1, "root": {"name": "x", "span": [1, 2]}}`)
+	if q, err = DecodeAST(data); err != nil {
+		t.Errorf("unexpected %v", err)
+	} else if q.GetName() != "x" || q.GetPosition() != 0 {
+		t.Errorf("unexpected %v %v", q.GetName(), q.GetPosition())
+	}
+
+	// future versions are refused.
+	_, err = DecodeAST([]byte(`{"parsecAST": 3, "root": {"name": "x"}}`))
+	if verr, ok := err.(*ASTVersionError); !ok || verr.Version != 3 {
+		t.Errorf("unexpected %v", err)
+	}
+	if _, err = DecodeAST([]byte(`{"parsecAST": 2}`)); err == nil {
+		t.Errorf("expected error")
+	}
+	if _, err = DecodeAST([]byte(`[`)); err == nil {
+		t.Errorf("expected error")
+	}
+}
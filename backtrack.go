@@ -0,0 +1,69 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "errors"
+import "fmt"
+
+// ErrBacktrackLimit is matched, using errors.Is, by the cause of
+// *ParseError for backtracking beyond the limit set by WithMaxBacktrack.
+var ErrBacktrackLimit = errors.New("backtrack limit exceeded")
+
+// BacktrackError is the cause of *ParseError for backtracking beyond
+// the limit, with the two positions involved.
+type BacktrackError struct {
+	Cursor   int // position a parser attempted to backtrack to.
+	Furthest int // furthest position reached before backtracking.
+	Limit    int
+}
+
+func (err *BacktrackError) Error() string {
+	fmsg := "backtrack from offset %v to %v exceeds limit %v"
+	return fmt.Sprintf(fmsg, err.Furthest, err.Cursor, err.Limit)
+}
+
+// Is match ErrBacktrackLimit.
+func (err *BacktrackError) Is(target error) bool {
+	return target == ErrBacktrackLimit
+}
+
+// backtrack limits the distance, in bytes, a scanner and its clones can
+// rewind from the furthest position reached.
+type backtrack struct {
+	max      int
+	furthest int
+}
+
+func newBacktrack(max int) *backtrack {
+	return &backtrack{max: max}
+}
+
+func (bt *backtrack) update(cursor int) {
+	if bt != nil && cursor > bt.furthest {
+		bt.furthest = cursor
+	}
+}
+
+// check return *ParseError if cursor is farther than the limit behind
+// the furthest position.
+func (bt *backtrack) check(cursor int) *ParseError {
+	if bt == nil || bt.furthest-cursor <= bt.max {
+		return nil
+	}
+	fmsg := "backtrack of %v bytes from offset %v exceeds limit %v"
+	msg := fmt.Sprintf(fmsg, bt.furthest-cursor, bt.furthest, bt.max)
+	cause := &BacktrackError{
+		Cursor: cursor, Furthest: bt.furthest, Limit: bt.max,
+	}
+	return &ParseError{Offset: cursor, Msg: msg, Err: cause}
+}
+
+// low return the offset behind which input is no longer needed, that
+// is, the scanner and its clones can't backtrack to it.
+func (bt *backtrack) low() int {
+	if bt == nil {
+		return 0
+	}
+	return bt.furthest - bt.max
+}
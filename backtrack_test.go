@@ -0,0 +1,114 @@
+package parsec
+
+import "errors"
+import "strings"
+import "testing"
+
+func TestSetMaxBacktrack(t *testing.T) {
+	parse := func(y Parser, s Scanner) (ParsecNode, error) {
+		node, _ := y(s)
+		return node, Aborted(s)
+	}
+
+	// alternatives share a long prefix, hence second alternative must
+	// backtrack to the beginning.
+	as := Many(nil, Atom("a", "A"))
+	ambiguous := OrdChoice(nil,
+		And(nil, as, Atom("b", "B")), And(nil, as, Atom("c", "C")))
+	text := strings.Repeat("a ", 50) + "c"
+
+	newscanners := []func() Scanner{
+		func() Scanner { return NewScanner([]byte(text)) },
+		func() Scanner { return NewScannerString(text) },
+	}
+	for _, newfn := range newscanners {
+		// without limit
+		if node, err := parse(ambiguous, newfn()); err != nil || node == nil {
+			t.Errorf("unexpected %v %v", node, err)
+		}
+		// limit is hit cleanly.
+		s := newfn()
+		switch ss := s.(type) {
+		case *SimpleScanner:
+			ss.SetMaxBacktrack(16)
+		case *StringScanner:
+			ss.SetMaxBacktrack(16)
+		}
+		node, err := parse(ambiguous, s)
+		if node != nil {
+			t.Errorf("unexpected %v", node)
+		}
+		perr, ok := err.(*ParseError)
+		if !ok {
+			t.Fatalf("expected ParseError, got %v", err)
+		} else if perr.Offset != 0 {
+			t.Errorf("expected %v, got %v", 0, perr.Offset)
+		}
+		ref := "backtrack of 100 bytes from offset 100 exceeds limit 16 at offset 0"
+		if perr.Error() != ref {
+			t.Errorf("expected %q, got %q", ref, perr.Error())
+		}
+	}
+
+	// well behaved grammar with a small lookahead is unaffected.
+	wellbehaved := Many(nil, OrdChoice(nil, Atom("ab", "AB"), Atom("a", "A")))
+	s := NewScanner([]byte(strings.Repeat("a ab ", 100))).(*SimpleScanner)
+	node, err := parse(wellbehaved, s.SetMaxBacktrack(4))
+	if err != nil {
+		t.Errorf("unexpected %v", err)
+	} else if len(node.([]ParsecNode)) != 200 {
+		t.Errorf("expected %v, got %v", 200, len(node.([]ParsecNode)))
+	}
+}
+
+func TestBacktrackLimit(t *testing.T) {
+	// grammar within the limit streams a large input, holding only the
+	// blocks covering the limit.
+	wellbehaved := Many(nil, OrdChoice(nil, Atom("ab", "AB"), Atom("a", "A")))
+	text := strings.Repeat("a ab ", 20000)
+	for _, limit := range []int{0, 16} {
+		s := newScannerAt(strings.NewReader(text), 0, int64(len(text)), 64, 1<<20)
+		rs, maxblocks := s.(*ReaderAtScanner), 0
+		rs.OnProgress(1, func(cursor, total int64) {
+			if n := rs.blocks.lru.Len(); n > maxblocks {
+				maxblocks = n
+			}
+		})
+		if limit > 0 {
+			rs.SetMaxBacktrack(limit)
+		}
+		res := Run(wellbehaved, s)
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		} else if len(res.Node.([]ParsecNode)) != 40000 {
+			t.Errorf("expected %v, got %v", 40000, len(res.Node.([]ParsecNode)))
+		}
+		if limit > 0 && maxblocks > 2 {
+			t.Errorf("expected atmost %v blocks, got %v", 2, maxblocks)
+		} else if limit == 0 && maxblocks < len(text)/64 {
+			t.Errorf("expected atleast %v blocks, got %v", len(text)/64, maxblocks)
+		}
+	}
+
+	// grammar exceeding the limit fails with typed error.
+	as := Many(nil, Atom("a", "A"))
+	ambiguous := OrdChoice(nil,
+		And(nil, as, Atom("b", "B")), And(nil, as, Atom("c", "C")))
+	text = strings.Repeat("a ", 50) + "c"
+	s := newScannerAt(strings.NewReader(text), 0, int64(len(text)), 8, 4)
+	res := Run(ambiguous, s.(*ReaderAtScanner).SetMaxBacktrack(16))
+	var berr *BacktrackError
+	if !errors.Is(res.Err, ErrBacktrackLimit) {
+		t.Fatalf("expected ErrBacktrackLimit, got %v", res.Err)
+	} else if !errors.As(res.Err, &berr) {
+		t.Fatalf("expected BacktrackError, got %v", res.Err)
+	} else if berr.Cursor != 0 || berr.Furthest != 100 || berr.Limit != 16 {
+		t.Errorf("unexpected %+v", berr)
+	} else if res.Node != nil {
+		t.Errorf("unexpected %v", res.Node)
+	}
+	ref := "backtrack from offset 100 to 0 exceeds limit 16"
+	if berr.Error() != ref {
+		t.Errorf("expected %q, got %q", ref, berr.Error())
+	}
+}
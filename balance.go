@@ -0,0 +1,67 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+import "strings"
+import "unicode/utf8"
+
+// CheckBalance scan `text` once for unbalanced brackets, as a fast
+// pre-flight check before parsing it with the full grammar. `pairs` map
+// opening brackets to their closing brackets, like '{' to '}', and
+// brackets within quoted regions, delimited by one of the characters in
+// `quotes`, are ignored. Within quoted regions backslash escapes the next
+// character. Return *ParseError, located within text, for the first
+// closing bracket that doesn't match the innermost open bracket, or else
+// the outermost bracket left open, or an unterminated quoted region.
+func CheckBalance(text []byte, pairs map[rune]rune, quotes string) error {
+	type open struct {
+		r      rune
+		offset int
+	}
+	closers := make(map[rune]bool)
+	for _, c := range pairs {
+		closers[c] = true
+	}
+
+	stack := []open{}
+	quote, qoffset := rune(0), 0
+	for off := 0; off < len(text); {
+		r, size := utf8.DecodeRune(text[off:])
+		switch {
+		case quote != 0 && r == '\\':
+			_, esize := utf8.DecodeRune(text[off+size:])
+			size += esize
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case strings.ContainsRune(quotes, r):
+			quote, qoffset = r, off
+		case pairs[r] != 0:
+			stack = append(stack, open{r: r, offset: off})
+		case closers[r]:
+			if len(stack) == 0 {
+				return balanceError(text, off, "unexpected %q", r)
+			} else if top := stack[len(stack)-1]; pairs[top.r] != r {
+				fmsg := "unexpected %q, expected %q"
+				return balanceError(text, off, fmsg, r, pairs[top.r])
+			}
+			stack = stack[:len(stack)-1]
+		}
+		off += size
+	}
+	if quote != 0 {
+		return balanceError(text, qoffset, "unterminated %q", quote)
+	} else if len(stack) > 0 {
+		return balanceError(text, stack[0].offset, "unclosed %q", stack[0].r)
+	}
+	return nil
+}
+
+func balanceError(text []byte, offset int, fmsg string, args ...interface{}) error {
+	perr := &ParseError{Offset: offset, Msg: fmt.Sprintf(fmsg, args...)}
+	perr.locate(text)
+	return perr
+}
@@ -0,0 +1,39 @@
+package parsec
+
+import "testing"
+
+func TestCheckBalance(t *testing.T) {
+	pairs := map[rune]rune{'{': '}', '[': ']', '(': ')'}
+	balanced := []string{
+		``,
+		`{"a": [1, (2), {"b": "}]"}], "c\"{": 'x'}`,
+		"{\n  \"key\": \"[unbalanced ( in string\"\n}",
+		`'don\'t {'`,
+	}
+	for _, text := range balanced {
+		if err := CheckBalance([]byte(text), pairs, `"'`); err != nil {
+			t.Errorf("%q: unexpected %v", text, err)
+		}
+	}
+
+	testcases := []struct {
+		text, err string
+		offset    int
+	}{
+		{`{[}`, `line 1, col 3: unexpected '}', expected ']'`, 2},
+		{"{\n  \"a\": 1 ]", `line 2, col 10: unexpected ']', expected '}'`, 11},
+		{`[1, 2])`, `line 1, col 7: unexpected ')'`, 6},
+		{`{"a": [1, 2}`, `line 1, col 12: unexpected '}', expected ']'`, 11},
+		{`{"a": "open}`, `line 1, col 7: unterminated '"'`, 6},
+		{`{"a": ["x"`, `line 1, col 1: unclosed '{'`, 0},
+		{`é(`, `line 1, col 2: unclosed '('`, 2},
+	}
+	for _, tcase := range testcases {
+		err := CheckBalance([]byte(tcase.text), pairs, `"`)
+		if err == nil || err.Error() != tcase.err {
+			t.Errorf("%q: expected %v, got %v", tcase.text, tcase.err, err)
+		} else if perr := err.(*ParseError); perr.Offset != tcase.offset {
+			t.Errorf("%q: expected %v, got %v", tcase.text, tcase.offset, perr.Offset)
+		}
+	}
+}
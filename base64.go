@@ -0,0 +1,71 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "encoding/base64"
+import "strings"
+
+// Base64Node is returned by Base64 parser, it is a Terminal with the
+// encoded text as its value, along with the decoded bytes as Data.
+type Base64Node struct {
+	*Terminal
+	Data []byte
+}
+
+// Base64Option configures Base64 parser.
+type Base64Option func(*base64Config)
+
+type base64Config struct {
+	urlsafe bool
+}
+
+// URLSafe make Base64 match blobs encoded with the URL and filename
+// safe alphabet, that uses `-` and `_` in place of `+` and `/`.
+func URLSafe() Base64Option {
+	return func(config *base64Config) {
+		config.urlsafe = true
+	}
+}
+
+// Base64 return parser function to match a base64 encoded blob, using
+// the standard alphabet, or the URL safe alphabet with URLSafe option.
+// Padding is optional, but if present it shall be correct. The blob
+// shall be followed by whitespace, end of input or one of the
+// delimiters `"'<>,;:)]}`, so that an invalid character within the blob
+// fails the match. Return *Base64Node named `name`, fails if blob can't be
+// decoded. Skip leading whitespace.
+func Base64(name string, opts ...Base64Option) Parser {
+	var config base64Config
+	for _, opt := range opts {
+		opt(&config)
+	}
+	pattern, padded := `^[A-Za-z0-9+/]+={0,2}`, base64.StdEncoding
+	if config.urlsafe {
+		pattern, padded = `^[A-Za-z0-9_-]+={0,2}`, base64.URLEncoding
+	}
+	raw := padded.WithPadding(base64.NoPadding).Strict()
+	padded = padded.Strict()
+
+	return func(s Scanner) (ParsecNode, Scanner) {
+		news := s.Clone()
+		news.SkipWS()
+		cursor := news.GetCursor()
+		tok, _ := news.Match(pattern)
+		if tok == nil {
+			return nil, s
+		} else if _, ok := news.TryMatch(`^[^\s"'<>,;:)\]}]`); ok {
+			return nil, s
+		}
+		value, enc := string(tok), raw
+		if strings.HasSuffix(value, "=") {
+			enc = padded
+		}
+		data, err := enc.DecodeString(value)
+		if err != nil {
+			return nil, s
+		}
+		t := newTerminal(news, name, value, cursor)
+		return &Base64Node{Terminal: t, Data: data}, news
+	}
+}
@@ -0,0 +1,60 @@
+package parsec
+
+import "testing"
+
+func TestBase64(t *testing.T) {
+	testcases := []struct {
+		text    string
+		data    string
+		urlsafe bool
+		ok      bool
+	}{
+		{"aGVsbG8gd29ybGQ=", "hello world", false, true},
+		{"  aGVsbG8=", "hello", false, true},
+		{"aGVsbG8", "hello", false, true}, // missing padding
+		{"aGVsbA==", "hell", false, true},
+		{"aGVsbA", "hell", false, true},
+		{"+/+/", "\xfb\xff\xbf", false, true},
+		{"-_-_", "\xfb\xff\xbf", true, true},
+		{"aGVsbA===", "", false, false}, // extra padding
+		{"aGVsbG8==", "", false, false}, // wrong padding
+		{"aGVsbA=", "", false, false},
+		{"aGVsb", "", false, false},
+		{"aGVsbG9=", "", false, false}, // non-zero padding bits
+		{"aGVs$bG8", "", false, false},
+		{"aGVsbG8*", "", false, false},
+		{"aGV.sbG8", "", false, false},
+		{"-_-_", "", false, false},
+		{"+/+/", "", true, false},
+		{"", "", false, false},
+	}
+	for _, tcase := range testcases {
+		y := Base64("BLOB")
+		if tcase.urlsafe {
+			y = Base64("BLOB", URLSafe())
+		}
+		node, s := y(NewScanner([]byte(tcase.text)))
+		if !tcase.ok {
+			if node != nil || s.GetCursor() != 0 {
+				t.Errorf("%q: unexpected %v", tcase.text, node)
+			}
+			continue
+		}
+		blob, ok := node.(*Base64Node)
+		if !ok {
+			t.Errorf("%q: unexpected %T", tcase.text, node)
+		} else if string(blob.Data) != tcase.data {
+			t.Errorf("%q: expected %q, got %q", tcase.text, tcase.data, blob.Data)
+		} else if blob.Name != "BLOB" || !s.Endof() {
+			t.Errorf("%q: unexpected %v at %v", tcase.text, blob.Name, s.GetCursor())
+		}
+	}
+
+	// blob followed by delimiter.
+	node, s := Base64("BLOB")(NewScanner([]byte(`aGk=", x`)))
+	if node == nil || string(node.(*Base64Node).Data) != "hi" {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 4 {
+		t.Errorf("expected %v, got %v", 4, s.GetCursor())
+	}
+}
@@ -0,0 +1,149 @@
+package parsec
+
+import "io/ioutil"
+import "strings"
+import "testing"
+
+// Benchmarks comparing grammars with and without memoization, from low
+// backtracking to high backtracking.
+
+func memoize(name string, p Parser) Parser {
+	return Memo(p)
+}
+
+// makejsongrammar has little backtracking, every alternative of value
+// can be decided by the first token.
+func makejsongrammar() *Grammar {
+	return jsongrammar(func(firstSet string, p Parser) Parser { return p })
+}
+
+func jsongrammar(guard func(string, Parser) Parser) *Grammar {
+	g := NewGrammar()
+	g.Define("value", func(g *Grammar) Parser {
+		return OrdChoice(nil,
+			guard(`"`, String()),
+			guard("-0123456789",
+				Token(`-?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?`, "NUM")),
+			guard("tfn", Keywords("true", "false", "null")),
+			guard("[", g.Ref("array")), guard("{", g.Ref("object")),
+		)
+	})
+	g.Define("array", func(g *Grammar) Parser {
+		values := Kleene(nil, g.Ref("value"), Atom(",", "COMMA"))
+		return And(nil, Atom("[", "OPENSQR"), values, Atom("]", "CLOSESQR"))
+	})
+	g.Define("property", func(g *Grammar) Parser {
+		return And(nil, String(), Atom(":", "COLON"), g.Ref("value"))
+	})
+	g.Define("object", func(g *Grammar) Parser {
+		props := Kleene(nil, g.Ref("property"), Atom(",", "COMMA"))
+		return And(nil, Atom("{", "OPENBRACE"), props, Atom("}", "CLOSEBRACE"))
+	})
+	return g
+}
+
+// makeguardedjsongrammar is same as makejsongrammar, with every
+// alternative of value guarded by its first characters.
+func makeguardedjsongrammar() *Grammar {
+	return jsongrammar(Guarded)
+}
+
+// makearithgrammar has deep ordered choices, where every alternative
+// re-parses the same prefix before failing.
+func makearithgrammar() *Grammar {
+	g := NewGrammar()
+	g.Define("expr", func(g *Grammar) Parser {
+		return OrdChoice(nil,
+			And(nil, g.Ref("term"), Atom("+", "ADD"), g.Ref("expr")),
+			And(nil, g.Ref("term"), Atom("-", "SUB"), g.Ref("expr")),
+			g.Ref("term"),
+		)
+	})
+	g.Define("term", func(g *Grammar) Parser {
+		return OrdChoice(nil,
+			And(nil, g.Ref("factor"), Atom("*", "MUL"), g.Ref("term")),
+			And(nil, g.Ref("factor"), Atom("/", "DIV"), g.Ref("term")),
+			g.Ref("factor"),
+		)
+	})
+	g.Define("factor", func(g *Grammar) Parser {
+		group := And(nil, Atom("(", "OPEN"), g.Ref("expr"), Atom(")", "CLOSE"))
+		return OrdChoice(nil, group, Int())
+	})
+	return g
+}
+
+// makeambiguousgrammar backtracks exponentially without memoization,
+// every level tries two alternatives that fail only at the very end.
+func makeambiguousgrammar() *Grammar {
+	g := NewGrammar()
+	g.Define("s", func(g *Grammar) Parser {
+		return OrdChoice(nil,
+			And(nil, Atom("a", "A"), g.Ref("s"), Atom("x", "X")),
+			And(nil, Atom("a", "A"), g.Ref("s"), Atom("y", "Y")),
+			Atom("a", "A"),
+		)
+	})
+	return g
+}
+
+var arithText = []byte(strings.Repeat("(1 + 2 * (3 - 4) / 5) * ", 20) + "6")
+var ambiguousText = []byte(strings.Repeat("a", 12))
+
+func benchGrammar(b *testing.B, g *Grammar, rule string, text []byte) {
+	y := g.Rule(rule)
+	for i := 0; i < b.N; i++ {
+		if node, _ := y(NewScanner(text)); node == nil {
+			b.Fatalf("expected match")
+		}
+	}
+	b.SetBytes(int64(len(text)))
+}
+
+func BenchmarkJSONNoMemo(b *testing.B) {
+	text, _ := ioutil.ReadFile("testdata/medium.json")
+	benchGrammar(b, makejsongrammar(), "value", text)
+}
+
+func BenchmarkJSONMemo(b *testing.B) {
+	text, _ := ioutil.ReadFile("testdata/medium.json")
+	benchGrammar(b, makejsongrammar().ApplyMiddleware(memoize), "value", text)
+}
+
+func BenchmarkJSONGuarded(b *testing.B) {
+	text, _ := ioutil.ReadFile("testdata/medium.json")
+	benchGrammar(b, makeguardedjsongrammar(), "value", text)
+}
+
+func BenchmarkArithNoMemo(b *testing.B) {
+	benchGrammar(b, makearithgrammar(), "expr", arithText)
+}
+
+func BenchmarkArithMemo(b *testing.B) {
+	g := makearithgrammar().ApplyMiddleware(memoize)
+	benchGrammar(b, g, "expr", arithText)
+}
+
+func BenchmarkAmbiguousNoMemo(b *testing.B) {
+	benchGrammar(b, makeambiguousgrammar(), "s", ambiguousText)
+}
+
+func BenchmarkAmbiguousMemo(b *testing.B) {
+	g := makeambiguousgrammar().ApplyMiddleware(memoize)
+	benchGrammar(b, g, "s", ambiguousText)
+}
+
+func BenchmarkNormalizingScanner(b *testing.B) {
+	kw := func(word string) Parser { return AtomExact(word, word) }
+	ident := TokenExact(`[a-z_]+`, "IDENT")
+	columns := List(nil, 1, ident, kw(","))
+	y := Many(nil, And(nil, kw("SELECT"), columns, kw("FROM"), ident, kw(";")))
+	text := []byte(strings.Repeat("SELECT id,\n\tname , email\nFROM users ;\n", 100))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if node, _ := y(NormalizingScanner(NewScanner(text))); node == nil {
+			b.Fatalf("expected match")
+		}
+	}
+	b.SetBytes(int64(len(text)))
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "errors"
+import "fmt"
+import "sort"
+
+// ErrBudgetExceeded is the cause of *ParseError for backtracking beyond
+// the budget set by WithBacktrackBudget.
+var ErrBudgetExceeded = errors.New("backtrack budget exceeded")
+
+// BacktrackReport is returned by BacktrackStats.
+type BacktrackReport struct {
+	Total  int64 // cumulative number of bytes discarded by backtracking.
+	Budget int64
+	Points []BacktrackPoint // positions backtracked to, costliest first.
+}
+
+// BacktrackPoint accounts the backtracking to a position.
+type BacktrackPoint struct {
+	Offset int   // position backtracked to.
+	Cost   int64 // cumulative number of bytes discarded.
+	Count  int   // number of times backtracked to Offset.
+	// Parser is the innermost Grammar rule active at the costliest
+	// backtrack to Offset, empty if no rule was active.
+	Parser string
+}
+
+// BacktrackStats return the cost of backtracking tracked by scanner `s`,
+// and its clones, so far. Return zero BacktrackReport if the scanner is
+// not created with WithBacktrackBudget, or SetBacktrackBudget.
+func BacktrackStats(s Scanner) BacktrackReport {
+	ss, ok := s.(*SimpleScanner)
+	if !ok || ss.budget == nil {
+		return BacktrackReport{}
+	}
+	bb := ss.budget
+	report := BacktrackReport{Total: bb.total, Budget: bb.limit}
+	for _, point := range bb.points {
+		report.Points = append(report.Points, *point)
+	}
+	sort.Slice(report.Points, func(i, j int) bool {
+		x, y := report.Points[i], report.Points[j]
+		if x.Cost != y.Cost {
+			return x.Cost > y.Cost
+		}
+		return x.Offset < y.Offset
+	})
+	return report
+}
+
+// backtrackBudget tracks the cost of backtracking, shared by a scanner
+// and all its clones.
+type backtrackBudget struct {
+	limit  int64
+	total  int64
+	last   int // last position reached.
+	points map[int]*BacktrackPoint
+	costly map[int]int64 // costliest backtrack to a position.
+}
+
+func (bb *backtrackBudget) advance(cursor int) {
+	if bb != nil {
+		bb.last = cursor
+	}
+}
+
+// resume note that parsing resumes from `cursor`, charging the advance
+// beyond cursor if it is behind the last position reached, to the
+// innermost of the active Grammar `rules`. Return *ParseError if the
+// cost exceeds the budget.
+func (bb *backtrackBudget) resume(cursor int, rules []string) *ParseError {
+	if bb == nil || cursor >= bb.last {
+		return nil
+	}
+	cost := int64(bb.last - cursor)
+	bb.total, bb.last = bb.total+cost, cursor
+	point, ok := bb.points[cursor]
+	if !ok {
+		point = &BacktrackPoint{Offset: cursor}
+		bb.points[cursor] = point
+	}
+	point.Count, point.Cost = point.Count+1, point.Cost+cost
+	if cost > bb.costly[cursor] {
+		bb.costly[cursor], point.Parser = cost, ""
+		if n := len(rules); n > 0 {
+			point.Parser = rules[n-1]
+		}
+	}
+	if bb.limit > 0 && bb.total > bb.limit {
+		fmsg := "backtrack cost of %v bytes exceeds budget %v"
+		msg := fmt.Sprintf(fmsg, bb.total, bb.limit)
+		return &ParseError{Offset: cursor, Msg: msg, Err: ErrBudgetExceeded}
+	}
+	return nil
+}
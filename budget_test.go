@@ -0,0 +1,59 @@
+package parsec
+
+import "errors"
+import "testing"
+
+func TestBacktrackBudget(t *testing.T) {
+	g := NewGrammar()
+	g.Define("call", func(g *Grammar) Parser {
+		return And(nil, Ident(), Atom("(", "OPENPARAN"), Atom(")", "CLOSEPARAN"))
+	})
+	g.Define("assign", func(g *Grammar) Parser {
+		return And(nil, Ident(), Atom("=", "EQUAL"), Int())
+	})
+	g.Define("stmt", func(g *Grammar) Parser {
+		return OrdChoice(nil, g.Ref("call"), g.Ref("assign"))
+	})
+	y := Many(nil, g.Rule("stmt"), Atom(";", "SEMICOLON"))
+
+	// without budget.
+	if report := BacktrackStats(NewScanner([]byte("x = 1"))); report.Total != 0 {
+		t.Errorf("unexpected %v", report)
+	}
+
+	s := NewScannerWith([]byte("f(); abc = 1; g()"), WithBacktrackBudget(0))
+	if res := Run(y, s); res.Err != nil {
+		t.Fatalf("unexpected %v", res.Err)
+	}
+	report := BacktrackStats(s)
+	// backtracked from `abc ` to ` abc`.
+	if report.Total != 5 || report.Budget != 0 {
+		t.Errorf("unexpected %v", report)
+	} else if len(report.Points) != 1 {
+		t.Fatalf("unexpected %v", report.Points)
+	}
+	point := report.Points[0]
+	if point.Offset != 4 || point.Cost != 5 || point.Count != 1 {
+		t.Errorf("unexpected %v", point)
+	} else if point.Parser != "stmt" {
+		t.Errorf("expected %v, got %v", "stmt", point.Parser)
+	}
+
+	text := []byte("a = 1; bb = 2; ccc = 3")
+	s = NewScannerWith(text, WithBacktrackBudget(0))
+	Run(y, s)
+	report = BacktrackStats(s)
+	if report.Total != 11 || len(report.Points) != 3 {
+		t.Fatalf("unexpected %v", report)
+	} else if report.Points[0].Offset != 14 || report.Points[2].Offset != 0 {
+		t.Errorf("expected costliest first, got %v", report.Points)
+	}
+
+	res := Run(y, NewScannerWith(text, WithBacktrackBudget(8)))
+	var perr *ParseError
+	if !errors.Is(res.Err, ErrBudgetExceeded) {
+		t.Errorf("expected %v, got %v", ErrBudgetExceeded, res.Err)
+	} else if errors.As(res.Err, &perr); perr.Offset != 14 {
+		t.Errorf("expected %v, got %v", 14, perr.Offset)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+// CallOption configure the syntax matched by Call.
+type CallOption func(*callConfig)
+
+type callConfig struct {
+	trailingComma bool
+}
+
+// AllowTrailingComma option for Call, to accept a comma after the last
+// argument, like `f(1, 2,)`.
+func AllowTrailingComma() CallOption {
+	return func(config *callConfig) {
+		config.trailingComma = true
+	}
+}
+
+var callOpen = Atom("(", "OPENPARAN")
+var callClose = Atom(")", "CLOSEPARAN")
+var callComma = Atom(",", "COMMA")
+
+// Call return parser function to match function-call syntax, like
+// `max(1, min(2, 3))`, where `name` match the function name and `arg`
+// match each of the comma separated arguments, which can be empty.
+// `name` and `arg` can be a Parser or reference to a parser, say to
+// match nested calls. Return a NonTerminal named CALL, with the function
+// name as its first child and a NonTerminal named ARGS, with the
+// arguments in order, as its second child. Nodes that don't implement
+// Queryable are wrapped as NodeValue named NAME and ARG. A comma after
+// the last argument fails the match, unless AllowTrailingComma.
+func Call(name, arg interface{}, opts ...CallOption) Parser {
+	var config callConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return func(s Scanner) (ParsecNode, Scanner) {
+		fn, news := doParse(name, s.Clone())
+		if fn == nil {
+			return nil, s
+		}
+		n, news := callOpen(news)
+		if n == nil {
+			return nil, s
+		}
+		args := newNonTerminal(news, "ARGS")
+		for comma := false; ; {
+			n, news = doParse(arg, news)
+			if n == nil {
+				if comma && !config.trailingComma {
+					return nil, s
+				}
+				break
+			}
+			args.Children = append(args.Children, queryable(n, "ARG"))
+			if n, news = callComma(news); n == nil {
+				break
+			}
+			comma = true
+		}
+		if n, news = callClose(news); n == nil {
+			return nil, s
+		}
+		nt := newNonTerminal(news, "CALL")
+		nt.Children = append(nt.Children, queryable(fn, "NAME"), args)
+		return nt, news
+	}
+}
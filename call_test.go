@@ -0,0 +1,73 @@
+package parsec
+
+import "fmt"
+import "strings"
+import "testing"
+
+func TestCall(t *testing.T) {
+	var call, arg Parser
+	first := func(ns []ParsecNode) ParsecNode { return ns[0] }
+	call = Call(Ident(), &arg)
+	arg = OrdChoice(first, &call, Int(), Ident())
+
+	node, s := call(NewScanner([]byte("max(1, min(2, x) )")))
+	if node == nil || !s.Endof() {
+		t.Fatalf("expected match, got %v", node)
+	}
+	if ref, out := "max(1,min(2,x))", callString(node.(Queryable)); out != ref {
+		t.Errorf("expected %v, got %v", ref, out)
+	}
+	nt := node.(*NonTerminal)
+	if nt.GetName() != "CALL" || nt.Children[1].GetName() != "ARGS" {
+		t.Errorf("unexpected %v %v", nt.GetName(), nt.Children[1].GetName())
+	}
+	inner := nt.Children[1].GetChildren()[1]
+	if inner.GetName() != "CALL" || inner.GetChildren()[0].GetValue() != "min" {
+		t.Errorf("unexpected %v", inner)
+	}
+
+	// empty argument list.
+	node, s = call(NewScanner([]byte("f()")))
+	if node == nil || !s.Endof() {
+		t.Fatalf("expected match, got %v", node)
+	} else if args := node.(Queryable).GetChildren()[1]; len(args.GetChildren()) != 0 {
+		t.Errorf("unexpected %v", args.GetChildren())
+	}
+
+	// trailing comma.
+	trailing := Call(Ident(), &arg, AllowTrailingComma())
+	testcases := []struct {
+		p    Parser
+		text string
+		ref  string
+	}{
+		{call, "f(1, 2,)", ""},
+		{trailing, "f(1, 2,)", "f(1,2)"},
+		{call, "f(,)", ""},
+		{trailing, "f(,)", ""},
+		{call, "f(1 2)", ""},
+		{call, "f(1", ""},
+		{call, "f", ""},
+	}
+	for _, tcase := range testcases {
+		node, s := tcase.p(NewScanner([]byte(tcase.text)))
+		if tcase.ref == "" && (node != nil || s.GetCursor() != 0) {
+			t.Errorf("%q: unexpected %v at %v", tcase.text, node, s.GetCursor())
+		} else if tcase.ref != "" && node == nil {
+			t.Errorf("%q: expected match", tcase.text)
+		} else if tcase.ref != "" && callString(node.(Queryable)) != tcase.ref {
+			t.Errorf("%q: expected %v, got %v", tcase.text, tcase.ref, node)
+		}
+	}
+}
+
+func callString(q Queryable) string {
+	if q.GetName() != "CALL" {
+		return q.GetValue()
+	}
+	args := []string{}
+	for _, arg := range q.GetChildren()[1].GetChildren() {
+		args = append(args, callString(arg))
+	}
+	return fmt.Sprintf("%v(%v)", q.GetChildren()[0].GetValue(), strings.Join(args, ","))
+}
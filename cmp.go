@@ -0,0 +1,116 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "reflect"
+
+import "github.com/google/go-cmp/cmp"
+
+// EqualNodes compare two syntax trees, Terminal nodes are equal if their
+// name, value, position and attributes are equal, and NonTerminal nodes
+// are equal if their name, attributes and children are equal. Node
+// identifiers and parent links are not compared, nor is the difference
+// between nil and empty attributes. []ParsecNode are compared element
+// by element, and other types of nodes are compared with
+// reflect.DeepEqual.
+func EqualNodes(a, b ParsecNode) bool {
+	if la, ok := a.([]ParsecNode); ok {
+		lb, ok := b.([]ParsecNode)
+		if !ok || len(la) != len(lb) {
+			return false
+		}
+		for i := range la {
+			if !EqualNodes(la[i], lb[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	va, oka := viewOf(a)
+	vb, okb := viewOf(b)
+	switch {
+	case oka != okb:
+		return false
+	case !oka:
+		return reflect.DeepEqual(a, b)
+	case va.Terminal != vb.Terminal || va.Name != vb.Name:
+		return false
+	case va.Value != vb.Value:
+		return false
+	case va.Position != vb.Position:
+		return false
+	case !reflect.DeepEqual(va.Attributes, vb.Attributes):
+		return false
+	case len(va.Children) != len(vb.Children):
+		return false
+	}
+	for i := range va.Children {
+		if !EqualNodes(va.Children[i], vb.Children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// CmpOption return an option for github.com/google/go-cmp, to compare
+// Terminal and NonTerminal nodes as done by EqualNodes, reporting
+// differences by field, like:
+//
+//	if diff := cmp.Diff(want, got, parsec.CmpOption()); diff != "" {
+//		t.Errorf("mismatch (-want +got):\n%s", diff)
+//	}
+func CmpOption() cmp.Option {
+	return cmp.Options{
+		cmp.Transformer("parsec.Terminal", func(t *Terminal) nodeView {
+			view, _ := viewOf(t)
+			return view
+		}),
+		cmp.Transformer("parsec.NonTerminal", func(nt *NonTerminal) nodeView {
+			view, _ := viewOf(nt)
+			return view
+		}),
+	}
+}
+
+// nodeView is the part of Terminal and NonTerminal nodes compared by
+// EqualNodes and CmpOption, fields are exported for go-cmp.
+type nodeView struct {
+	Terminal   bool
+	Name       string
+	Value      string
+	Position   int
+	Attributes map[string][]string
+	Children   []ParsecNode
+}
+
+func viewOf(node ParsecNode) (nodeView, bool) {
+	var view nodeView
+	var attrs map[string][]string
+	switch n := node.(type) {
+	case *Terminal:
+		if n == nil {
+			return view, false
+		}
+		view.Terminal, view.Name = true, n.Name
+		view.Value, view.Position, attrs = n.Value, n.Position, n.Attributes
+
+	case *NonTerminal:
+		if n == nil {
+			return view, false
+		}
+		view.Name, attrs = n.Name, n.Attributes
+		view.Children = make([]ParsecNode, 0, len(n.Children))
+		for _, child := range n.Children {
+			view.Children = append(view.Children, child)
+		}
+
+	default:
+		return view, false
+	}
+	if len(attrs) > 0 {
+		view.Attributes = attrs
+	}
+	return view, true
+}
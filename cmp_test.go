@@ -0,0 +1,53 @@
+package parsec
+
+import "strings"
+import "testing"
+
+import "github.com/google/go-cmp/cmp"
+
+func TestEqualNodes(t *testing.T) {
+	text := []byte(`[1, {"a": "x"}, [2]]`)
+	parse := func(s Scanner) ParsecNode {
+		node, _ := makeastjson(NewAST("json", 100))(s)
+		if node == nil {
+			t.Fatalf("expected match")
+		}
+		return node
+	}
+	a := parse(NewScanner(text))
+	b := parse(NewScanner(text).(*SimpleScanner).WithNodeIDs())
+	if !EqualNodes(a, b) {
+		t.Errorf("expected equal nodes")
+	} else if diff := cmp.Diff(a, b, CmpOption()); diff != "" {
+		t.Errorf("unexpected diff %v", diff)
+	}
+
+	c := parse(NewScanner([]byte(`[1, {"a": "y"}, [2]]`)))
+	if EqualNodes(a, c) {
+		t.Errorf("expected different nodes")
+	}
+	diff := cmp.Diff(a, c, CmpOption())
+	if !strings.Contains(diff, "`\"x\"`") || !strings.Contains(diff, "`\"y\"`") {
+		t.Errorf("expected values in diff, got %v", diff)
+	} else if !strings.Contains(diff, "Value") {
+		t.Errorf("expected field name in diff, got %v", diff)
+	}
+
+	// attributes, nil and empty attributes are equal.
+	t1, t2 := NewTerminal("INT", "1", 0), &Terminal{Name: "INT", Value: "1"}
+	if EqualNodes(t1, t2) {
+		t.Errorf("expected different nodes")
+	}
+	delete(t1.Attributes, "class")
+	if !EqualNodes(t1, t2) {
+		t.Errorf("expected equal nodes")
+	} else if diff := cmp.Diff(t1, t2, CmpOption()); diff != "" {
+		t.Errorf("unexpected diff %v", diff)
+	}
+	nt := NewNonTerminal("INT")
+	if EqualNodes(t2, nt) || EqualNodes([]ParsecNode{t2}, []ParsecNode{nt}) {
+		t.Errorf("expected different nodes")
+	} else if !EqualNodes([]ParsecNode{t2, "str"}, []ParsecNode{t1, "str"}) {
+		t.Errorf("expected equal nodes")
+	}
+}
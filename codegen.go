@@ -0,0 +1,113 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "bytes"
+import "fmt"
+import "go/format"
+import "go/token"
+import "os"
+import "strconv"
+import "strings"
+
+// GenerateParserCode write Go source to `outputFile`, in package `pkg`,
+// with the combinator calls equivalent to the rules in grammar `g`, so
+// that the grammar can be compiled once and shipped as generated code.
+// Generated source defines the function:
+//
+//	func NewParsers(terminals map[string]parsec.Parser) map[string]parsec.Parser
+//
+// which return the parsers keyed by rule name, where `terminals` are
+// the parsers for terminal identifiers, same as for GrammarFromEBNF.
+// Only the grammars built by GrammarFromEBNF can be generated, return
+// error if `g` has rules defined by RuleFunc or has middlewares.
+func GenerateParserCode(g *Grammar, pkg, outputFile string) error {
+	src, err := generateParserCode(g, pkg)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputFile, src, 0644)
+}
+
+func generateParserCode(g *Grammar, pkg string) ([]byte, error) {
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("parsec: invalid package name %q", pkg)
+	} else if len(g.mws) > 0 {
+		return nil, fmt.Errorf("parsec: cannot generate code for middlewares")
+	}
+	vars := make(map[string]string)
+	for _, name := range g.names {
+		if _, ok := g.ebnf[name]; !ok {
+			fmsg := "parsec: cannot generate code for rule %q defined by RuleFunc"
+			return nil, fmt.Errorf(fmsg, name)
+		}
+		vars[name] = name
+		if token.IsKeyword(name) || name == "parsec" || name == "terminals" {
+			vars[name] = name + "_"
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by parsec.GenerateParserCode. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %v\n\n", pkg)
+	buf.WriteString("import \"github.com/prataprc/goparsec\"\n\n")
+	buf.WriteString("// NewParsers return the parsers for grammar rules, keyed by rule\n")
+	buf.WriteString("// name, where terminals are the parsers for terminal identifiers.\n")
+	buf.WriteString("func NewParsers(")
+	buf.WriteString("terminals map[string]parsec.Parser) map[string]parsec.Parser {\n")
+	if len(g.names) > 0 {
+		names := make([]string, 0, len(g.names))
+		for _, name := range g.names {
+			names = append(names, vars[name])
+		}
+		fmt.Fprintf(&buf, "var %v parsec.Parser\n\n", strings.Join(names, ", "))
+	}
+	for _, name := range g.names {
+		expr := g.ebnf[name]
+		if expr.op == 'r' && vars[expr.name] != "" {
+			// reference to a rule that may not be constructed yet.
+			fmsg := "%v = func(s parsec.Scanner) (parsec.ParsecNode, parsec.Scanner) {\n"
+			fmt.Fprintf(&buf, fmsg, vars[name])
+			fmt.Fprintf(&buf, "return %v(s)\n}\n", vars[expr.name])
+			continue
+		}
+		fmt.Fprintf(&buf, "%v = %v\n", vars[name], expr.gocode(vars))
+	}
+	buf.WriteString("\nreturn map[string]parsec.Parser{\n")
+	for _, name := range g.names {
+		fmt.Fprintf(&buf, "%v: %v,\n", strconv.Quote(name), vars[name])
+	}
+	buf.WriteString("}\n}\n")
+	return format.Source(buf.Bytes())
+}
+
+// gocode return the Go expression for combinators compiled from `e`,
+// rules are referred by the address of their variables in `vars`.
+func (e *ebnfExpr) gocode(vars map[string]string) string {
+	items := make([]string, 0, len(e.items))
+	for _, item := range e.items {
+		items = append(items, item.gocode(vars))
+	}
+	args := strings.Join(items, ", ")
+	switch e.op {
+	case 'r':
+		if v, ok := vars[e.name]; ok {
+			return "&" + v
+		}
+		return "terminals[" + strconv.Quote(e.name) + "]"
+	case 's':
+		return "parsec.Atom(" + strconv.Quote(e.name) + ", " + strconv.Quote(e.name) + ")"
+	case '.':
+		return "parsec.And(nil, " + args + ")"
+	case '|':
+		return "parsec.OrdChoice(nil, " + args + ")"
+	case '?':
+		return "parsec.Maybe(nil, " + args + ")"
+	case '*':
+		return "parsec.Kleene(nil, " + args + ")"
+	case '+':
+		return "parsec.Many(nil, " + args + ")"
+	}
+	panic(fmt.Errorf("unknown ebnf operator %q", e.op))
+}
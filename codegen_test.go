@@ -0,0 +1,74 @@
+package parsec
+
+import "bytes"
+import "go/format"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "testing"
+
+func TestGenerateParserCode(t *testing.T) {
+	text := []byte(`
+	expr   = term { ("+" | "-") term } ;
+	term   = factor ( ('*' | "/") factor )* ;
+	factor = INT | "(" expr ")" | "-"? IDENT+ ;
+	type   = expr ;
+	`)
+	terminals := map[string]Parser{"INT": Int(), "IDENT": Ident()}
+	g, err := GrammarFromEBNF(text, terminals)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "parsec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	outfile := filepath.Join(dir, "expr.go")
+	if err := GenerateParserCode(g, "expr", outfile); err != nil {
+		t.Fatal(err)
+	}
+	src, err := ioutil.ReadFile(outfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fsrc, err := format.Source(src); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(fsrc, src) {
+		t.Errorf("generated code is not formatted")
+	}
+	refs := []string{
+		"package expr\n",
+		"var expr, term, factor, type_ parsec.Parser\n",
+		`factor = parsec.OrdChoice(nil, terminals["INT"], ` +
+			`parsec.And(nil, parsec.Atom("(", "("), &expr, parsec.Atom(")", ")")), ` +
+			`parsec.And(nil, parsec.Maybe(nil, parsec.Atom("-", "-")), ` +
+			`parsec.Many(nil, terminals["IDENT"])))` + "\n",
+		"\t\treturn expr(s)\n",
+		`"type":   type_,` + "\n",
+	}
+	for _, ref := range refs {
+		if !bytes.Contains(src, []byte(ref)) {
+			t.Errorf("expected %q in generated code", ref)
+		}
+	}
+
+	// errors
+	g.Define("extra", func(g *Grammar) Parser { return Int() })
+	err = GenerateParserCode(g, "expr", outfile)
+	ref := `parsec: cannot generate code for rule "extra" defined by RuleFunc`
+	if err == nil || err.Error() != ref {
+		t.Errorf("expected %q, got %v", ref, err)
+	}
+	g, _ = GrammarFromEBNF(text, terminals)
+	err = GenerateParserCode(g, "expr-parser", outfile)
+	if ref = `parsec: invalid package name "expr-parser"`; err == nil || err.Error() != ref {
+		t.Errorf("expected %q, got %v", ref, err)
+	}
+	g = g.ApplyMiddleware(func(name string, p Parser) Parser { return p })
+	err = GenerateParserCode(g, "expr", outfile)
+	if ref = "parsec: cannot generate code for middlewares"; err == nil || err.Error() != ref {
+		t.Errorf("expected %q, got %v", ref, err)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "context"
+
+// MatchWithContext is same as Match, except that the pattern is matched
+// in a separate goroutine and ctx.Err() is returned, without advancing
+// the cursor, if `ctx` is done before the match completes.
+func (s *SimpleScanner) MatchWithContext(
+	ctx context.Context, pattern string) ([]byte, Scanner, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, s, err
+	}
+	regc, text := s.getPattern(pattern), s.matchtext()
+	locch := make(chan []int, 1)
+	go func() { locch <- regc.FindIndex(text) }()
+	select {
+	case <-ctx.Done():
+		return nil, s, ctx.Err()
+	case loc := <-locch:
+		if loc == nil {
+			return nil, s, nil
+		}
+		return s.matched(loc), s, nil
+	}
+}
+
+// ContextScanner wraps a Scanner and bounds its pattern matching by the
+// deadline of a context. Once the context is done, all matches fail,
+// causing parsers to fail, and Err return the reason. Scanners wrapped
+// by ContextScanner shall not be used directly. Matches on scanners
+// created with NewScanner and NewScannerString are abandoned when the
+// context is done, matches on other scanners are bounded only between
+// matches.
+type ContextScanner struct {
+	Scanner
+	ctx context.Context
+}
+
+// NewContextScanner wrap scanner `s`, bound by `ctx`.
+func NewContextScanner(ctx context.Context, s Scanner) *ContextScanner {
+	return &ContextScanner{Scanner: s, ctx: ctx}
+}
+
+// Err return the context's error, if it is done.
+func (s *ContextScanner) Err() error {
+	return s.ctx.Err()
+}
+
+// MatchWithContext same as SimpleScanner.MatchWithContext, bound by
+// both `ctx` and the scanner's context.
+func (s *ContextScanner) MatchWithContext(
+	ctx context.Context, pattern string) ([]byte, Scanner, error) {
+
+	if err := s.ctx.Err(); err != nil {
+		return nil, s, err
+	}
+	ctx, cancel := mergeContext(ctx, s.ctx)
+	defer cancel()
+
+	var token []byte
+	var err error
+	if ss, ok := s.Scanner.(*SimpleScanner); ok {
+		token, _, err = ss.MatchWithContext(ctx, pattern)
+	} else {
+		err = s.run(ctx, func(inner Scanner) Scanner {
+			token, inner = inner.Match(pattern)
+			return inner
+		}, pattern)
+	}
+	if err != nil && s.ctx.Err() != nil { // report scanner's deadline.
+		err = s.ctx.Err()
+	}
+	return token, s, err
+}
+
+// SetWSPattern implement Scanner{} interface.
+func (s *ContextScanner) SetWSPattern(pattern string) Scanner {
+	s.Scanner = s.Scanner.SetWSPattern(pattern)
+	return s
+}
+
+// TrackLineno implement Scanner{} interface.
+func (s *ContextScanner) TrackLineno() Scanner {
+	s.Scanner = s.Scanner.TrackLineno()
+	return s
+}
+
+// Clone implement Scanner{} interface.
+func (s *ContextScanner) Clone() Scanner {
+	return &ContextScanner{Scanner: s.Scanner.Clone(), ctx: s.ctx}
+}
+
+// Match implement Scanner{} interface.
+func (s *ContextScanner) Match(pattern string) ([]byte, Scanner) {
+	token, _, _ := s.MatchWithContext(s.ctx, pattern)
+	return token, s
+}
+
+// MatchString implement Scanner{} interface.
+func (s *ContextScanner) MatchString(str string) (bool, Scanner) {
+	if s.ctx.Err() != nil {
+		return false, s
+	}
+	ok, news := s.Scanner.MatchString(str)
+	s.Scanner = news
+	return ok, s
+}
+
+// SubmatchAll implement Scanner{} interface.
+func (s *ContextScanner) SubmatchAll(pattern string) (map[string][]byte, Scanner) {
+	var captures map[string][]byte
+	s.run(s.ctx, func(inner Scanner) Scanner {
+		captures, inner = inner.SubmatchAll(pattern)
+		return inner
+	}, pattern)
+	return captures, s
+}
+
+// SkipWS implement Scanner{} interface.
+func (s *ContextScanner) SkipWS() ([]byte, Scanner) {
+	var token []byte
+	s.run(s.ctx, func(inner Scanner) Scanner {
+		token, inner = inner.SkipWS()
+		return inner
+	})
+	return token, s
+}
+
+// SkipAny implement Scanner{} interface.
+func (s *ContextScanner) SkipAny(pattern string) ([]byte, Scanner) {
+	if pattern[0] != '^' {
+		pattern = "^" + pattern
+	}
+	var token []byte
+	s.run(s.ctx, func(inner Scanner) Scanner {
+		token, inner = inner.SkipAny(pattern)
+		return inner
+	}, pattern)
+	return token, s
+}
+
+// TryMatch implement Scanner{} interface.
+func (s *ContextScanner) TryMatch(pattern string) ([]byte, bool) {
+	var token []byte
+	var ok bool
+	s.run(s.ctx, func(inner Scanner) Scanner {
+		token, ok = inner.TryMatch(pattern)
+		return inner
+	}, pattern)
+	return token, ok
+}
+
+// SkipN implement Scanner{} interface.
+func (s *ContextScanner) SkipN(n int) Scanner {
+	s.Scanner = s.Scanner.SkipN(n)
+	return s
+}
+
+// run `fn` on an isolated copy of the wrapped scanner, with `patterns`
+// compiled, in a separate goroutine, and advance the wrapped scanner to
+// the copy's cursor if fn returns before `ctx` is done. Abandoned copies
+// share no mutable state with the wrapped scanner, hence they can keep
+// matching after the wrapped scanner has moved on. Scanners that can't
+// be isolated are matched in the calling goroutine.
+func (s *ContextScanner) run(
+	ctx context.Context, fn func(Scanner) Scanner, patterns ...string) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	is, ok := s.Scanner.(isolatingScanner)
+	if !ok {
+		s.Scanner = fn(s.Scanner)
+		return nil
+	}
+	inner := is.isolate(patterns...)
+	done := make(chan Scanner, 1)
+	go func() { done <- fn(inner) }()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case news := <-done:
+		is.adopt(news)
+		return nil
+	}
+}
+
+// isolatingScanner is implemented by scanners that can be copied for
+// matching in another goroutine.
+type isolatingScanner interface {
+	// isolate return a copy of the scanner sharing no mutable state with
+	// it, with `patterns` and the white space pattern compiled.
+	isolate(patterns ...string) Scanner
+	// adopt advance the scanner to the cursor of its isolated copy.
+	adopt(iso Scanner)
+}
+
+// mergeContext return a context that is done when either `a` or `b` is
+// done.
+func mergeContext(a, b context.Context) (context.Context, context.CancelFunc) {
+	if a == b {
+		return a, func() {}
+	}
+	ctx, cancel := context.WithCancel(a)
+	stop := context.AfterFunc(b, cancel)
+	return ctx, func() { stop(); cancel() }
+}
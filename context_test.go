@@ -0,0 +1,104 @@
+package parsec
+
+import "context"
+import "strings"
+import "testing"
+import "time"
+
+func TestMatchWithContext(t *testing.T) {
+	s := NewScanner([]byte("hello world")).(*SimpleScanner)
+	token, news, err := s.MatchWithContext(context.Background(), `^[a-z]+`)
+	if err != nil || string(token) != "hello" {
+		t.Errorf("unexpected %q %v", token, err)
+	} else if news.GetCursor() != 5 {
+		t.Errorf("expected %v, got %v", 5, news.GetCursor())
+	}
+	token, news, err = s.MatchWithContext(context.Background(), `^[0-9]+`)
+	if err != nil || token != nil || news.GetCursor() != 5 {
+		t.Errorf("unexpected %q %v %v", token, err, news.GetCursor())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	token, news, err = s.MatchWithContext(ctx, `^ [a-z]+`)
+	if err != context.Canceled || token != nil {
+		t.Errorf("unexpected %q %v", token, err)
+	} else if news.GetCursor() != 5 {
+		t.Errorf("expected %v, got %v", 5, news.GetCursor())
+	}
+	// Match is unaffected.
+	if token, _ := s.Match(`^ [a-z]+`); string(token) != " world" {
+		t.Errorf("unexpected %q", token)
+	}
+}
+
+func TestContextScanner(t *testing.T) {
+	y := Kleene(nil, Int(), Atom(",", "COMMA"))
+	text := strings.Repeat("10, ", 100)
+	newscanners := []func() Scanner{
+		func() Scanner { return NewScanner([]byte(text)) },
+		func() Scanner { return NewScannerString(text) },
+	}
+	for _, newfn := range newscanners {
+		// same as the wrapped scanner, without a deadline.
+		cs := NewContextScanner(context.Background(), newfn())
+		node, news := y(cs)
+		if _, ok := news.(*ContextScanner); !ok {
+			t.Errorf("expected ContextScanner, got %T", news)
+		} else if len(node.([]ParsecNode)) != 100 {
+			t.Errorf("expected %v, got %v", 100, len(node.([]ParsecNode)))
+		} else if news.SkipWS(); !news.Endof() || cs.Err() != nil {
+			t.Errorf("unexpected %v %v", news.GetCursor(), cs.Err())
+		}
+
+		// deadline passed, matches fail.
+		ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+		defer cancel()
+		<-ctx.Done()
+		cs = NewContextScanner(ctx, newfn())
+		if node, news := y(cs); len(node.([]ParsecNode)) != 0 {
+			t.Errorf("unexpected %v", node)
+		} else if news.GetCursor() != 0 {
+			t.Errorf("expected %v, got %v", 0, news.GetCursor())
+		} else if cs.Err() != context.DeadlineExceeded {
+			t.Errorf("expected %v, got %v", context.DeadlineExceeded, cs.Err())
+		}
+		_, _, err := cs.MatchWithContext(context.Background(), `^[0-9]+`)
+		if err != context.DeadlineExceeded {
+			t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+		}
+	}
+}
+
+func TestContextScannerIsolate(t *testing.T) {
+	text := "  \n\n  hello"
+	newscanners := []func() Scanner{
+		func() Scanner { return NewScanner([]byte(text)) },
+		func() Scanner { return NewScannerString(text) },
+	}
+	for _, newfn := range newscanners {
+		inner := newfn().TrackLineno()
+		var consumed int64
+		switch s := inner.(type) {
+		case *SimpleScanner:
+			s.OnProgress(1, func(n, _ int64) { consumed = n })
+		case *StringScanner:
+			s.OnProgress(1, func(n, _ int64) { consumed = n })
+		}
+		// isolated copy doesn't report progress of the wrapped scanner.
+		iso := inner.(isolatingScanner).isolate(`^[a-z]+`)
+		if iso.SkipWS(); consumed != 0 || inner.GetCursor() != 0 {
+			t.Errorf("unexpected %v %v", consumed, inner.GetCursor())
+		}
+		// matches run on an isolated copy, adopted by the wrapped scanner.
+		cs := NewContextScanner(context.Background(), inner)
+		cs.SkipWS()
+		if token, _ := cs.TryMatch(`^[a-z]+`); string(token) != "hello" {
+			t.Errorf("unexpected %q", token)
+		} else if cs.GetCursor() != 6 || cs.Lineno() != 3 {
+			t.Errorf("unexpected %v %v", cs.GetCursor(), cs.Lineno())
+		} else if consumed != 6 {
+			t.Errorf("expected %v, got %v", 6, consumed)
+		}
+	}
+}
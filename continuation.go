@@ -0,0 +1,45 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "regexp"
+
+// continuationPattern extend white space pattern `ws` to skip line
+// continuations, `marker` followed by newline, along with white space.
+func continuationPattern(ws string, marker string) string {
+	if marker == "" {
+		return ws
+	}
+	if ws != "" && ws[0] == '^' {
+		ws = ws[1:]
+	}
+	cont := regexp.QuoteMeta(marker) + `\r?\n`
+	return "^(?:(?:" + ws + ")|" + cont + ")+"
+}
+
+// TokenExactContinued is same as TokenExact, but skips line
+// continuations, on scanners set to LineContinuation, before matching
+// the pattern. Continuations are not skipped within the token, hence a
+// token split across a continuation is not joined.
+func TokenExactContinued(pattern string, name string) Parser {
+	exact := TokenExact(pattern, name)
+	return func(s Scanner) (ParsecNode, Scanner) {
+		cs, ok := s.(continuationScanner)
+		if !ok || cs.continuationMarker() == "" {
+			return exact(s)
+		}
+		news := s.Clone()
+		news.SkipAny(`^(?:` + regexp.QuoteMeta(cs.continuationMarker()) + `\r?\n)+`)
+		if node, news := exact(news); node != nil {
+			return node, news
+		}
+		return nil, s
+	}
+}
+
+// continuationScanner is implemented by scanners that can skip line
+// continuations.
+type continuationScanner interface {
+	continuationMarker() string
+}
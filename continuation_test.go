@@ -0,0 +1,68 @@
+package parsec
+
+import "testing"
+
+func TestLineContinuation(t *testing.T) {
+	text := "CC = gcc \\\n    -O2\\\r\n-g \\\nlib\\\nc"
+	word := Token(`[^ \t\r\n\\]+`, "WORD")
+	scanners := []Scanner{
+		NewScanner([]byte(text)).TrackLineno().(*SimpleScanner).LineContinuation('\\'),
+		NewScannerString(text).TrackLineno().(*StringScanner).LineContinuation('\\'),
+	}
+	for _, s := range scanners {
+		refs := []struct {
+			value       string
+			pos, lineno int
+		}{
+			{"CC", 0, 1}, {"=", 3, 1}, {"gcc", 5, 1}, {"-O2", 15, 2},
+			{"-g", 21, 3}, {"lib", 26, 4},
+		}
+		for _, ref := range refs {
+			node, news := word(s)
+			if node == nil {
+				t.Fatalf("%T: expected %q", s, ref.value)
+			}
+			term := node.(*Terminal)
+			if term.Value != ref.value || term.Position != ref.pos {
+				t.Errorf("%T: expected %q at %v, got %q at %v",
+					s, ref.value, ref.pos, term.Value, term.Position)
+			} else if news.Lineno() != ref.lineno {
+				t.Errorf("%T: expected line %v, got %v", s, ref.lineno, news.Lineno())
+			}
+			s = news
+		}
+		// token split across continuation is not joined.
+		if node, _ := TokenExact(`[a-z]+`, "WORD")(s.Clone()); node != nil {
+			t.Errorf("%T: unexpected %v", s, node)
+		}
+		// unless requested, TokenExact doesn't skip continuations.
+		if node, news := TokenExactContinued(`[a-z]+`, "WORD")(s.Clone()); node == nil {
+			t.Errorf("%T: expected match", s)
+		} else if term := node.(*Terminal); term.Value != "c" || term.Position != 31 {
+			t.Errorf("%T: expected %q at %v, got %q at %v", s, "c", 31, term.Value, term.Position)
+		} else if news.Lineno() != 5 {
+			t.Errorf("%T: expected line 5, got %v", s, news.Lineno())
+		}
+		if node, _ := TokenExactContinued(`[a-z]+`, "WORD")(s.Clone().SkipN(1)); node != nil {
+			t.Errorf("%T: unexpected %v", s, node)
+		}
+		node, news := word(s)
+		if node == nil || node.(*Terminal).Value != "c" || !news.Endof() {
+			t.Errorf("%T: unexpected %v", s, node)
+		} else if news.Lineno() != 5 {
+			t.Errorf("%T: expected line 5, got %v", s, news.Lineno())
+		}
+	}
+
+	// marker at end of input is not a continuation.
+	s := NewScanner([]byte("a \\")).(*SimpleScanner).LineContinuation('\\')
+	_, s = Token(`a`, "A")(s)
+	if _, news := s.SkipWS(); news.GetCursor() != 2 {
+		t.Errorf("expected 2, got %v", news.GetCursor())
+	}
+	// white space pattern set after continuation.
+	s = NewScanner([]byte("\\\n;x")).(*SimpleScanner).LineContinuation('\\').SetWSPattern(`;`)
+	if _, news := s.SkipWS(); news.GetCursor() != 3 {
+		t.Errorf("expected 3, got %v", news.GetCursor())
+	}
+}
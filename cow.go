@@ -0,0 +1,99 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+// COWScanner wrap scanner `s`, and its clones, to clone the underlying
+// scanner lazily. Clone only marks the underlying scanner as shared,
+// which is then cloned by the first call that can mutate it, like Match
+// and SkipWS, on either of the scanners sharing it. Combinators clone the
+// scanner before applying a parser, and parsers clone it again, hence
+// clones that are discarded before moving the cursor, or that are cloned
+// again, need not copy scanners with large state.
+func COWScanner(s Scanner) Scanner {
+	return &cowScanner{Scanner: s, refs: &cowRefs{n: 1}}
+}
+
+type cowScanner struct {
+	Scanner
+	refs *cowRefs // number of scanners sharing the underlying scanner.
+}
+
+type cowRefs struct {
+	n int
+}
+
+// own clone the underlying scanner if it is shared with other scanners.
+func (s *cowScanner) own() {
+	if s.refs.n > 1 {
+		s.refs.n--
+		s.Scanner, s.refs = s.Scanner.Clone(), &cowRefs{n: 1}
+	}
+}
+
+// SetWSPattern implement Scanner{} interface.
+func (s *cowScanner) SetWSPattern(pattern string) Scanner {
+	s.own()
+	s.Scanner = s.Scanner.SetWSPattern(pattern)
+	return s
+}
+
+// TrackLineno implement Scanner{} interface.
+func (s *cowScanner) TrackLineno() Scanner {
+	s.own()
+	s.Scanner = s.Scanner.TrackLineno()
+	return s
+}
+
+// Clone implement Scanner{} interface.
+func (s *cowScanner) Clone() Scanner {
+	s.refs.n++
+	return &cowScanner{Scanner: s.Scanner, refs: s.refs}
+}
+
+// Match implement Scanner{} interface.
+func (s *cowScanner) Match(pattern string) ([]byte, Scanner) {
+	s.own()
+	token, news := s.Scanner.Match(pattern)
+	s.Scanner = news
+	return token, s
+}
+
+// MatchString implement Scanner{} interface.
+func (s *cowScanner) MatchString(str string) (bool, Scanner) {
+	s.own()
+	ok, news := s.Scanner.MatchString(str)
+	s.Scanner = news
+	return ok, s
+}
+
+// SubmatchAll implement Scanner{} interface.
+func (s *cowScanner) SubmatchAll(pattern string) (map[string][]byte, Scanner) {
+	s.own()
+	captures, news := s.Scanner.SubmatchAll(pattern)
+	s.Scanner = news
+	return captures, s
+}
+
+// SkipWS implement Scanner{} interface.
+func (s *cowScanner) SkipWS() ([]byte, Scanner) {
+	s.own()
+	token, news := s.Scanner.SkipWS()
+	s.Scanner = news
+	return token, s
+}
+
+// SkipAny implement Scanner{} interface.
+func (s *cowScanner) SkipAny(pattern string) ([]byte, Scanner) {
+	s.own()
+	token, news := s.Scanner.SkipAny(pattern)
+	s.Scanner = news
+	return token, s
+}
+
+// SkipN implement Scanner{} interface.
+func (s *cowScanner) SkipN(n int) Scanner {
+	s.own()
+	s.Scanner = s.Scanner.SkipN(n)
+	return s
+}
@@ -0,0 +1,88 @@
+package parsec
+
+import "reflect"
+import "testing"
+
+// cloneCounter count the clones of the underlying scanner.
+type cloneCounter struct {
+	Scanner
+	n *int
+}
+
+func (s *cloneCounter) Clone() Scanner {
+	*s.n++
+	return &cloneCounter{Scanner: s.Scanner.Clone(), n: s.n}
+}
+
+func (s *cloneCounter) Match(pattern string) ([]byte, Scanner) {
+	token, _ := s.Scanner.Match(pattern)
+	return token, s
+}
+
+func (s *cloneCounter) MatchString(str string) (bool, Scanner) {
+	ok, _ := s.Scanner.MatchString(str)
+	return ok, s
+}
+
+func (s *cloneCounter) SubmatchAll(pattern string) (map[string][]byte, Scanner) {
+	captures, _ := s.Scanner.SubmatchAll(pattern)
+	return captures, s
+}
+
+func (s *cloneCounter) SkipWS() ([]byte, Scanner) {
+	token, _ := s.Scanner.SkipWS()
+	return token, s
+}
+
+func (s *cloneCounter) SkipAny(pattern string) ([]byte, Scanner) {
+	token, _ := s.Scanner.SkipAny(pattern)
+	return token, s
+}
+
+func (s *cloneCounter) SkipN(n int) Scanner {
+	s.Scanner.SkipN(n)
+	return s
+}
+
+func TestCOWScanner(t *testing.T) {
+	testScannerContract(t, func(text []byte) Scanner {
+		return COWScanner(NewScanner(text))
+	})
+
+	// clones share the underlying scanner until it is mutated.
+	var clones int
+	s := COWScanner(&cloneCounter{Scanner: NewScannerString("hello world"), n: &clones})
+	c1, c2 := s.Clone(), s.Clone()
+	if clones != 0 {
+		t.Errorf("expected %v, got %v", 0, clones)
+	}
+	if _, c1 = c1.Match(`^hello`); c1.GetCursor() != 5 || clones != 1 {
+		t.Errorf("unexpected %v %v", c1.GetCursor(), clones)
+	}
+	if _, c2 = c2.SkipAny(`^h`); c2.GetCursor() != 1 || clones != 2 {
+		t.Errorf("unexpected %v %v", c2.GetCursor(), clones)
+	}
+	// last of the scanners sharing it need not clone.
+	if _, s = s.SkipWS(); s.GetCursor() != 0 || clones != 2 {
+		t.Errorf("unexpected %v %v", s.GetCursor(), clones)
+	}
+	c3 := c1.Clone()
+	if c3.SkipN(1); c3.GetCursor() != 6 || c1.GetCursor() != 5 || clones != 3 {
+		t.Errorf("unexpected %v %v %v", c3.GetCursor(), c1.GetCursor(), clones)
+	}
+
+	// parsers see the same result, cloning the underlying scanner fewer
+	// times.
+	text := []byte(`{"a": [1, 2, {"b": null}], "c": "x", "d": [true, false]}`)
+	value := makejsongrammar().Rule("value")
+	var eager, lazy int
+	ref, s1 := value(&cloneCounter{Scanner: NewScanner(text), n: &eager})
+	node, s2 := value(COWScanner(&cloneCounter{Scanner: NewScanner(text), n: &lazy}))
+	if !reflect.DeepEqual(node, ref) {
+		t.Errorf("expected %v, got %v", ref, node)
+	} else if !s1.Endof() || !s2.Endof() {
+		t.Errorf("unexpected %v %v", s1.GetCursor(), s2.GetCursor())
+	} else if lazy >= eager {
+		t.Errorf("expected fewer than %v clones, got %v", eager, lazy)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+// Package csv provide a parser to parse and a writer to generate RFC 4180
+// style comma separated values, based on the following rule.
+//
+//	records -> record (newline record)*
+//	record  -> field (delim field)*
+//	field   -> quoted
+//	        |  unquoted
+package csv
+
+import "fmt"
+import "io"
+import "io/ioutil"
+import "regexp"
+import "strings"
+
+import "github.com/prataprc/goparsec"
+
+// NewParser return the root parser to parse records separated by `delim`.
+// Parsed records are returned as [][]string ParsecNode.
+func NewParser(delim byte) parsec.Parser {
+	d := regexp.QuoteMeta(string(delim))
+	quoted := parsec.TokenExact(`"(?:[^"]|"")*"`, "QUOTED")
+	unquoted := parsec.TokenExact(`[^"\r\n`+d+`]*`, "UNQUOTED")
+	field := parsec.OrdChoice(fieldNode, quoted, unquoted)
+	record := parsec.Many(recordNode, field, parsec.AtomExact(string(delim), "DELIM"))
+	newline := parsec.TokenExact(`\r?\n`, "NEWLINE")
+	return parsec.Kleene(recordsNode, record, newline)
+}
+
+// CSVParse parse text into records, fields in each record are separated by
+// `delim`. Return error if the text is not fully parsed.
+func CSVParse(text []byte, delim byte) ([][]string, error) {
+	node, s := NewParser(delim)(parsec.NewScanner(text))
+	if !s.Endof() {
+		return nil, fmt.Errorf("csv: parse error at offset %v", s.GetCursor())
+	}
+	return node.([][]string), nil
+}
+
+// CSVWrite records to `w`, fields in each record are separated by `delim`
+// and each record is terminated by newline. Fields are quoted only when
+// they cannot be represented otherwise.
+func CSVWrite(w io.Writer, records [][]string, delim byte) error {
+	for _, record := range records {
+		fields := make([]string, 0, len(record))
+		for _, field := range record {
+			if fieldNeedsQuotes(field, delim) {
+				field = `"` + strings.Replace(field, `"`, `""`, -1) + `"`
+			}
+			fields = append(fields, field)
+		}
+		line := strings.Join(fields, string(delim)) + "\n"
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CSVTranscode parse records from `r` separated by `inDelim` and write
+// them back to `w` separated by `outDelim`.
+func CSVTranscode(r io.Reader, w io.Writer, inDelim, outDelim byte) error {
+	text, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	records, err := CSVParse(text, inDelim)
+	if err != nil {
+		return err
+	}
+	return CSVWrite(w, records, outDelim)
+}
+
+//----------
+// Nodifiers
+//----------
+
+func fieldNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	return ns[0]
+}
+
+func recordNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	record := make([]string, 0, len(ns))
+	for _, n := range ns {
+		t := n.(*parsec.Terminal)
+		if t.Name == "QUOTED" {
+			value := t.Value[1 : len(t.Value)-1]
+			record = append(record, strings.Replace(value, `""`, `"`, -1))
+			continue
+		}
+		record = append(record, t.Value)
+	}
+	// blank lines are skipped, only a quoted empty field makes a record.
+	if len(ns) == 1 && ns[0].(*parsec.Terminal).Value == "" {
+		return []string(nil)
+	}
+	return record
+}
+
+func recordsNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	records := make([][]string, 0, len(ns))
+	for _, n := range ns {
+		if record := n.([]string); record != nil {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+//----------------
+// Local functions
+//----------------
+
+// fieldNeedsQuotes follows the same rules as encoding/csv.
+func fieldNeedsQuotes(field string, delim byte) bool {
+	if field == "" {
+		return false
+	} else if field == `\.` {
+		return true
+	} else if strings.IndexByte(field, delim) >= 0 {
+		return true
+	} else if strings.ContainsAny(field, "\"\r\n") {
+		return true
+	}
+	return field[0] == ' ' || field[0] == '\t'
+}
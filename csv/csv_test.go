@@ -0,0 +1,112 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package csv
+
+import "bytes"
+import stdcsv "encoding/csv"
+import "reflect"
+import "strings"
+import "testing"
+
+var trickyRecords = [][]string{
+	{"name", "value", "comment"},
+	{"plain", "10", ""},
+	{"with,comma", "with;semicolon", "with\ttab"},
+	{`with "quotes"`, `"`, `""`},
+	{"multi\nline", "trailing\n", "\nleading"},
+	{" leading space", "\tleading tab", "trailing space "},
+	{"", "", ""},
+	{"unicode 逗号分隔值", "ünïcödé", `\.`},
+}
+
+func TestCSVParse(t *testing.T) {
+	text := "a,b,c\n\"x,y\",\"say \"\"hi\"\"\",\n\n\"multi\nline\",,z\n"
+	ref := [][]string{
+		{"a", "b", "c"},
+		{"x,y", `say "hi"`, ""},
+		{"multi\nline", "", "z"},
+	}
+	records, err := CSVParse([]byte(text), ',')
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(records, ref) {
+		t.Fatalf("expected %q, got %q", ref, records)
+	}
+	// compare with encoding/csv
+	stdrecords, err := stdcsv.NewReader(strings.NewReader(text)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(records, stdrecords) {
+		t.Fatalf("expected %q, got %q", stdrecords, records)
+	}
+
+	// negative cases
+	for _, text := range []string{"a,\"b", "a,b\"c\"", "\"a\"b,c"} {
+		if _, err := CSVParse([]byte(text), ','); err == nil {
+			t.Errorf("expected error for %q", text)
+		}
+	}
+}
+
+func TestCSVWrite(t *testing.T) {
+	for _, delim := range []byte{',', ';', '\t', '|'} {
+		var out, ref bytes.Buffer
+		if err := CSVWrite(&out, trickyRecords, delim); err != nil {
+			t.Fatal(err)
+		}
+		w := stdcsv.NewWriter(&ref)
+		w.Comma = rune(delim)
+		w.WriteAll(trickyRecords)
+		if out.String() != ref.String() {
+			t.Errorf("delim %q expected %q, got %q", delim, ref.String(), out.String())
+		}
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	for _, delim := range []byte{',', ';', '\t', '|'} {
+		var out bytes.Buffer
+		if err := CSVWrite(&out, trickyRecords, delim); err != nil {
+			t.Fatal(err)
+		}
+		records, err := CSVParse(out.Bytes(), delim)
+		if err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(records, trickyRecords) {
+			t.Errorf("delim %q expected %q, got %q", delim, trickyRecords, records)
+		}
+	}
+}
+
+func TestCSVTranscode(t *testing.T) {
+	var in, out bytes.Buffer
+	if err := CSVWrite(&in, trickyRecords, ','); err != nil {
+		t.Fatal(err)
+	}
+	if err := CSVTranscode(&in, &out, ',', ';'); err != nil {
+		t.Fatal(err)
+	}
+	r := stdcsv.NewReader(&out)
+	r.Comma = ';'
+	if records, err := r.ReadAll(); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(records, trickyRecords) {
+		t.Errorf("expected %q, got %q", trickyRecords, records)
+	}
+
+	// parse error
+	if err := CSVTranscode(strings.NewReader(`a,"b`), &out, ',', ';'); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func BenchmarkCSVParse(b *testing.B) {
+	var buf bytes.Buffer
+	CSVWrite(&buf, trickyRecords, ',')
+	text := buf.Bytes()
+	for i := 0; i < b.N; i++ {
+		CSVParse(text, ',')
+	}
+	b.SetBytes(int64(len(text)))
+}
@@ -18,6 +18,70 @@ text:
 	var exprText = []byte(`4 + 123 + 23 + 67 +89 + 87 *78`)
 	s := parsec.NewScanner(exprText)
 
+NewScannerWith accepts options, like WithWS, WithLineno, WithCaseFolding
+and WithLimits, to configure the scanner, in place of the chained
+setters on SimpleScanner:
+	s := parsec.NewScannerWith(exprText, parsec.WithWS(`^[ \t]+`), parsec.WithLineno())
+
+Input text supplied as string can be scanned using NewScannerString,
+and a window of input text from io.ReaderAt, like a memory mapped file,
+can be scanned using NewScannerAt without loading it into memory,
+ReaderOffset positions the cursor within the io.ReaderAt as int64.
+Text pasted from word processors can be matched with NormalizePunctuation,
+which maps smart quotes and dashes to ASCII while positions still refer
+to the original text.
+Scanners set to LineContinuation skip a marker followed by newline, like
+a trailing backslash in shell scripts, as white space, and
+TokenExactContinued skips them before a token without skipping white
+space.
+Scanners set to InternValues share the same string for repeated token
+values, like keys in a large document. Custom scanners can use
+Interner, and Progress to report progress, like the json package.
+CurrentLine on the concrete scanners return the line of input around
+the cursor, and ColumnPosition the column of cursor, honouring TabWidth,
+to show the offending line in error messages. Concrete scanners also
+implement io.Reader, to hand over the rest of the input to libraries
+like encoding/json.
+Tokens from an existing lexer can be parsed using FromTokenFunc.
+Formats that alternate between tokens and variable-length skips can
+be scanned without combinators, using a chain of steps, NewMatcherChain.
+Include directives can be expanded using ExpandIncludes, parsers
+continue transparently across included documents and Sources map the
+positions back to them.
+Building with `parsecdebug` tag asserts that parsers applied by
+combinators leave the cursor within bounds and restore it on failure,
+panicking with the name of the offending parser.
+NormalizingScanner skips white space before every token, for grammars
+written with parsers that don't skip white space, like TokenExact.
+COWScanner defers cloning the scanner it wraps until a clone moves its
+cursor, for scanners that are expensive to clone.
+Patterns matched by parsers can be profiled by wrapping the scanner
+using InstrumentScanner.
+For untrusted input, SetMaxBacktrack bounds the distance parsers can
+backtrack, exceeding it aborts the parse with ErrBacktrackLimit, and
+lets NewScannerAt discard input behind the limit. WithBacktrackBudget
+tracks the cumulative cost of backtracking, reported by BacktrackStats
+by Grammar rule, and aborts the parse with ErrBudgetExceeded beyond the
+budget. Once aborted all matches fail, and Aborted return the error for
+parsers applied without Run. NewContextScanner bounds pattern matching
+by the deadline of a context. Run applies a parser to complete input
+and, WithRecovery, reports errors recovered by the Recover combinator as
+ErrorList. ParseString and ParseFile are shorthands for Run, reporting
+errors by line and column, and ParseWithTokens returns the terminals of
+the parsed tree in source order. RunParser reports *ParseError with the
+line, column, the token found and the names of parsers expected there,
+annotated using WithError and Alternatives. Run, WithFailureSnapshot,
+captures the stack of Grammar rules, the line of input and the last
+tokens at the furthest failure as Snapshot, to be attached to bug
+reports. CheckBalance reports unbalanced brackets and unterminated
+quotes in the text, as a pre-flight check before parsing.
+
+Parsers are typically declared as package-level variables and applied
+to many documents, back-to-back or concurrently. Hence parsers shall not
+hold per-parse state in their closures, state like memo tables, errors,
+expectations and backtrack limits is held by the scanner and shared by
+its clones.
+
 Nodify, callback function is supplied while combining parser
 functions. If the underlying parsing logic matches with i/p text,
 then callback will be dispatched with list of matching ParsecNode.
@@ -51,10 +115,42 @@ parsers. They are,
 
  * And, to combine a sequence of terminals and non-terminal parsers.
  * OrdChoice, to choose between specified list of parsers.
+ * OrdChoiceOrDefault, same as OrdChoice, returning a default node on failure.
+ * ExclusiveChoice, same as OrdChoice, detects ambiguity with DebugAmbiguity.
+ * Alternatives, same as OrdChoice, naming the alternatives for RunParser.
+ * AmbChoice, same as OrdChoice, branching into every match for AllParses.
  * Kleene, to repeat the parser zero or more times.
  * Many, to repeat the parser one or more times.
+ * ManyReuse, same as Many, collecting nodes in a reusable buffer.
+ * Take, match upto N items, leaving the rest of the list unconsumed.
  * ManyUntil, to repeat the parser until a specified end matcher.
+ * List, to repeat the parser with separators and a minimum count.
  * Maybe, to apply the parser once or none.
+ * AndOpt, to combine a sequence where some of the parsers are optional.
+ * AndStruct, to populate a sequence of matches into the fields of a struct.
+ * Compose2, Compose3 and Compose4, same as And for two to four parsers,
+   passing the matching nodes as arguments to callback.
+ * Fields, to match a sequence of required and optional fields, telling
+   which of them were present.
+ * WithMaxMatchLength, to limit the number of bytes consumed by a parser.
+ * Region, to capture bracketed text verbatim for parsing it later.
+ * DocComment, to capture a block comment, optionally nested.
+ * Heredoc, to capture lines of a here document until its marker.
+ * Call, to match function-call syntax with a name and list of arguments.
+ * AttrList, to parse markup attributes with quoted, unquoted or no values.
+ * WithOrWithoutSpaces, to retry a parser with whitespace skipping toggled.
+ * AtBoundary, to match a parser only if it ends at a boundary.
+ * Predicate, to match without consuming input if a condition holds.
+ * Lookahead and Not, to match if a parser matches or not, without consuming
+   input.
+ * Recover, to skip past a synchronising pattern when the parser fails.
+ * Memo, to re-use the result of a parser when backtracking.
+ * ByteDispatch, to select a parser by the next byte in input.
+ * Guarded, to fail fast if the next byte is not a first character of
+   the parser.
+ * HeaderDispatch, to select the parser for a body by its parsed header.
+ * Foreign, to delegate input to an external parser, embedding its result.
+ * TypedSettings, to parse key-value pairs with a value parser per key.
 
 All the above mentioned combinators accept one or more parser function
 as arguments, either by value or by reference. The reason for allowing
@@ -73,6 +169,12 @@ parsing logic, like parsing nested arrays:
 		Y = parsec.OrdChoice(nil, value)
 	}
 
+Recursive rules can also be collected as a Grammar, either defined in
+Go or built from EBNF text using GrammarFromEBNF. Grammars built from
+EBNF can be compiled to Go source using GenerateParserCode.
+Users familiar with parsing expression grammars can use PEGParser, which
+names the combinators after PEG operators.
+
 
 Terminal parsers
 
@@ -81,16 +183,35 @@ Most of these parsers return Terminal type as ParseNode.
 
  * Char, match a single character skipping leading whitespace.
  * Float, match a float literal skipping leading whitespace.
+ * SpecialFloat, match NaN and Infinity literals skipping leading whitespace.
  * Hex, match a hexadecimal literal skipping leading whitespace.
+ * ConfigurableNumber, match a number literal as per NumberSpec, along with
+   its typed value, skipping leading whitespace.
+ * FlexibleNumber, match a number literal, bare or double quoted, along
+   with its typed value, skipping leading whitespace.
  * Int, match a decimal number literal skipping leading whitespace.
  * Oct, match a octal number literal skipping leading whitespace.
- * String, match a string literal skipping leading whitespace.
+ * String, match a string literal skipping leading whitespace, refer
+   ScanString to fold a quoted string from a custom scanner.
  * Ident, match a identifier token skipping leading whitespace.
+ * UUID, match a UUID, validating its groups, skipping leading whitespace.
+ * Entity, match a named or numeric character entity, like `&amp;`.
+ * Base64, match a base64 encoded blob, along with its decoded bytes.
+ * Glob, match a shell style glob pattern, along with its compiled matcher.
+ * InterpolatedString, match a template string with embedded expressions.
+ * QueryString, match a URL query string as ordered, percent decoded pairs.
  * Atom, match a single atom skipping leading whitespace.
  * AtomExact, match a single atom without skipping leading whitespace.
  * Token, match a single token skipping leading whitespace.
  * TokenExact, match a single token without skipping leading whitespace.
+ * TokenNamed, match a single token and its capture groups as children.
+ * Gap, match whitespace and comments at the cursor, without skipping them.
+ * Keywords, match one of the words followed by a word boundary.
+ * Literals, match one of the literals, naming the Terminal after it.
  * OrdToken, match a single token with specified list of alternatives.
+ * PrioritizedTokens, match a single token by the rule of highest priority,
+   like keywords over identifiers.
+ * SkipToNextLine, skip the rest of the line, say an invalid line.
  * End, match end of text.
  * NoEnd, match not an end of text.
 
@@ -114,6 +235,18 @@ Additionally, following points are worth noting while using AST,
    as the Queryable type.
  * ASTNodify function can interpret its Queryable argument and return
    a different type implementing Queryable interface.
+ * MergeTerminals concatenates terminals, like characters, as one Terminal.
+ * ApplyNodify can shape a tree, parsed with nil callbacks, after parsing.
+ * Run, with DropTokens, omits punctuation terminals from NonTerminal
+   nodes constructed without callbacks.
+ * BuildWithParents sets Parent of NonTerminal nodes for bottom-up traversal.
+ * AstToMap converts a syntax tree to maps keyed by the name of nodes.
+ * ToDotGraph renders any ParsecNode tree in Graphviz DOT format.
+ * Scanners set WithNodeIDs assign identifiers to nodes, refer NodeID.
+ * EncodeAST and DecodeAST can save and load a syntax tree as versioned
+   JSON document, documents from older versions can still be decoded.
+ * EqualNodes compares syntax trees, and CmpOption does the same for
+   github.com/google/go-cmp with a readable diff.
 
 */
 package parsec
@@ -0,0 +1,65 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+import "strings"
+
+// dotEscape escapes label text for Graphviz quoted strings.
+var dotEscape = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// ToDotGraph return the tree rooted at `node` in Graphviz DOT format,
+// every node is a labelled vertex with edges from parent to children.
+// Terminal nodes are drawn as boxes labelled with their name and value,
+// and NonTerminal nodes as ovals labelled with their name. Unlike
+// AST.Dotstring, `node` can be any ParsecNode, like the output of
+// combinators with nil callbacks, where []ParsecNode is drawn as an oval
+// labelled `[]` and other values, like the output of Nodify callbacks,
+// as boxes labelled with their value. Missing nodes, nil and MaybeNone,
+// are skipped.
+func ToDotGraph(node ParsecNode) string {
+	var sb strings.Builder
+	sb.WriteString("digraph parsec {\n")
+	sb.WriteString("  edge [arrowsize=0.8];\n")
+	nextid := 0
+	dotvertex(&sb, 0, &nextid, node)
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// dotvertex write vertex for `node`, and its sub-tree, with an edge from
+// vertex `parid`, if not zero.
+func dotvertex(sb *strings.Builder, parid int, nextid *int, node ParsecNode) {
+	var shape, label string
+	var children []ParsecNode
+	switch n := node.(type) {
+	case nil, MaybeNone:
+		return
+	case []ParsecNode:
+		shape, label, children = "ellipse", "[]", n
+	case Queryable:
+		if n.IsTerminal() {
+			shape = "box"
+			label = fmt.Sprintf("%v: %q", n.GetName(), n.GetValue())
+			break
+		}
+		shape, label = "ellipse", n.GetName()
+		for _, child := range n.GetChildren() {
+			children = append(children, child)
+		}
+	default:
+		shape, label = "box", fmt.Sprintf("%v", n)
+	}
+
+	*nextid++
+	id := *nextid
+	fmsg := "  n%v [shape=%v,label=\"%v\"];\n"
+	fmt.Fprintf(sb, fmsg, id, shape, dotEscape.Replace(label))
+	if parid > 0 {
+		fmt.Fprintf(sb, "  n%v -> n%v;\n", parid, id)
+	}
+	for _, child := range children {
+		dotvertex(sb, id, nextid, child)
+	}
+}
@@ -0,0 +1,53 @@
+package parsec
+
+import "testing"
+
+func TestToDotGraph(t *testing.T) {
+	ast := NewAST("pair", 100)
+	str := Token(`"(?:\\.|[^"])*"`, "STRING")
+	y := ast.And("PAIR", nil,
+		Ident(), Atom("=", "EQUAL"), ast.Maybe("VALUE", nil, str))
+	node, _ := y(NewScanner([]byte(`key = "a\"b"`)))
+	ref := `digraph parsec {
+  edge [arrowsize=0.8];
+  n1 [shape=ellipse,label="PAIR"];
+  n2 [shape=box,label="IDENT: \"key\""];
+  n1 -> n2;
+  n3 [shape=box,label="EQUAL: \"=\""];
+  n1 -> n3;
+  n4 [shape=box,label="STRING: \"\\\"a\\\\\\\"b\\\"\""];
+  n1 -> n4;
+}
+`
+	if out := ToDotGraph(node); out != ref {
+		t.Errorf("expected %v", ref)
+		t.Errorf("got %v", out)
+	}
+
+	// nodes from combinators with nil callbacks, missing values skipped.
+	y = And(nil, Int(), Maybe(nil, Atom("+", "ADD")), Many(nil, Int()))
+	node, _ = y(NewScanner([]byte("1 2 3")))
+	ref = `digraph parsec {
+  edge [arrowsize=0.8];
+  n1 [shape=ellipse,label="[]"];
+  n2 [shape=box,label="INT: \"1\""];
+  n1 -> n2;
+  n3 [shape=ellipse,label="[]"];
+  n1 -> n3;
+  n4 [shape=box,label="INT: \"2\""];
+  n3 -> n4;
+  n5 [shape=box,label="INT: \"3\""];
+  n3 -> n5;
+}
+`
+	if out := ToDotGraph(node); out != ref {
+		t.Errorf("expected %v", ref)
+		t.Errorf("got %v", out)
+	}
+
+	ref = "digraph parsec {\n  edge [arrowsize=0.8];\n" +
+		"  n1 [shape=box,label=\"42\"];\n}\n"
+	if out := ToDotGraph(42); out != ref {
+		t.Errorf("expected %q, got %q", ref, out)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+// dropScanner is implemented by scanners that can carry the names of
+// terminals to be omitted from NonTerminal nodes.
+type dropScanner interface {
+	droptokens() map[string]bool
+	setDropTokens(drops map[string]bool)
+}
+
+// DropTokens make AST combinators, called without ASTNodify callback,
+// omit Terminal children named in `names`, like punctuation tokens
+// COMMA, COLON and OPENSQR, from the NonTerminal nodes they construct.
+// Explicit callbacks still receive all the children. Span of NonTerminal
+// nodes still cover the input text of dropped tokens. DropTokens is
+// supported by scanners created with NewScanner, NewScannerString and
+// NewScannerAt, and panics with other scanners.
+func DropTokens(names ...string) RunOption {
+	drops := make(map[string]bool)
+	for _, name := range names {
+		drops[name] = true
+	}
+	return func(config *runConfig) {
+		config.drops = drops
+	}
+}
+
+// dropTokens omit children of `nt` that are Terminals named in the drop
+// set of scanner `s`, remembering the span of children before dropping.
+func dropTokens(s Scanner, nt *NonTerminal) {
+	ds, ok := s.(dropScanner)
+	if !ok || len(ds.droptokens()) == 0 {
+		return
+	}
+	drops := ds.droptokens()
+	start, end, ok := Span(nt)
+	children := make([]Queryable, 0, len(nt.Children))
+	for _, child := range nt.Children {
+		if t, ok := child.(*Terminal); ok && drops[t.Name] {
+			continue
+		}
+		children = append(children, child)
+	}
+	if len(children) == len(nt.Children) {
+		return
+	}
+	nt.Children = children
+	if ok {
+		nt.span = []int{start, end}
+	}
+}
@@ -0,0 +1,99 @@
+package parsec
+
+import "context"
+import "reflect"
+import "testing"
+
+func TestDropTokens(t *testing.T) {
+	makey := func(ast *AST) Parser {
+		var value Parser
+		comma := Atom(",", "COMMA")
+		values := ast.Kleene("VALUES", nil, &value, comma)
+		array := ast.And("ARRAY", nil,
+			Atom("[", "OPENSQR"), values, Atom("]", "CLOSESQR"))
+		property := ast.And("PROPERTY", nil,
+			Token(`"[a-z]*"`, "STRING"), Atom(":", "COLON"), &value)
+		properties := ast.Kleene("PROPERTIES", nil, property, comma)
+		object := ast.And("OBJECT", nil,
+			Atom("{", "OPENBRACE"), properties, Atom("}", "CLOSEBRACE"))
+		value = ast.OrdChoice("VALUE", nil, Int(), array, object)
+		return value
+	}
+	names := func(q Queryable) []string {
+		ns := []string{}
+		for _, child := range q.GetChildren() {
+			ns = append(ns, child.GetName())
+		}
+		return ns
+	}
+	text := []byte(` [1, {"a": 2}] `)
+	drops := DropTokens("OPENSQR", "CLOSESQR", "OPENBRACE", "CLOSEBRACE", "COLON")
+
+	// tokens are kept by default.
+	res := Run(makey(NewAST("json", 100)), NewScanner(text))
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	kept := res.Node.(Queryable)
+	ref := []string{"OPENSQR", "VALUES", "CLOSESQR"}
+	if ns := names(kept); !reflect.DeepEqual(ns, ref) {
+		t.Errorf("expected %v, got %v", ref, ns)
+	}
+
+	res = Run(makey(NewAST("json", 100)), NewScanner(text), drops)
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	root := res.Node.(Queryable)
+	if ns := names(root); !reflect.DeepEqual(ns, []string{"VALUES"}) {
+		t.Errorf("expected %v, got %v", []string{"VALUES"}, ns)
+	}
+	object := root.GetChildren()[0].GetChildren()[1]
+	if ns := names(object); !reflect.DeepEqual(ns, []string{"PROPERTIES"}) {
+		t.Errorf("expected %v, got %v", []string{"PROPERTIES"}, ns)
+	}
+	property := object.GetChildren()[0].GetChildren()[0]
+	ref = []string{"STRING", "INT"}
+	if ns := names(property); !reflect.DeepEqual(ns, ref) {
+		t.Errorf("expected %v, got %v", ref, ns)
+	}
+
+	// spans cover the dropped tokens.
+	for _, q := range []Queryable{root, object} {
+		start, end, _ := Span(q)
+		kstart, kend, _ := Span(kept)
+		if q == object {
+			kobject := kept.GetChildren()[1].GetChildren()[1]
+			kstart, kend, _ = Span(kobject)
+		}
+		if start != kstart || end != kend {
+			t.Errorf("expected [%v,%v), got [%v,%v)", kstart, kend, start, end)
+		}
+	}
+	if start, end, _ := Span(root); start != 1 || end != 14 {
+		t.Errorf("expected [1,14), got [%v,%v)", start, end)
+	} else if pos := object.GetPosition(); pos != 5 {
+		t.Errorf("expected %v, got %v", 5, pos)
+	}
+
+	// explicit callbacks receive all the children.
+	var got []string
+	ast := NewAST("json", 100)
+	y := ast.And("PAIR", func(name string, s Scanner, q Queryable) Queryable {
+		got = names(q)
+		return q
+	}, Atom("[", "OPENSQR"), Int(), Atom("]", "CLOSESQR"))
+	ref = []string{"OPENSQR", "INT", "CLOSESQR"}
+	if res := Run(y, NewScanner([]byte("[1]")), drops); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if !reflect.DeepEqual(got, ref) {
+		t.Errorf("expected %v, got %v", ref, got)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+	Run(y, NewContextScanner(context.Background(), NewScanner(nil)), drops)
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+
+// GrammarFromEBNF build a Grammar from rules specified in EBNF `text`,
+// which is itself parsed with parsec combinators. Supported dialect is:
+//
+//	rule   = IDENT "=" expr ";"
+//	expr   = term { "|" term }
+//	term   = factor { factor }
+//	factor = base [ "?" | "*" | "+" ]
+//	base   = IDENT | STRING | "(" expr ")" | "{" expr "}" | "[" expr "]"
+//
+// STRING is a double or single quoted literal, matched using Atom with
+// the literal as the terminal's name. IDENT refers to a rule, or if no
+// such rule is defined, to a parser in `terminals`, like Int(). Rule
+// definitions are translated to the same combinators that would be
+// written by hand, with nil callbacks: a sequence of factors to And,
+// alternatives to OrdChoice, `?` and `[...]` to Maybe, `*` and `{...}`
+// to Kleene and `+` to Many. Comments are written as `(* ... *)`.
+// Return error if text is not valid EBNF, if a rule is defined more
+// than once, or if an identifier is neither a rule nor a terminal.
+func GrammarFromEBNF(
+	text []byte, terminals map[string]Parser) (*Grammar, error) {
+
+	s := NewScanner(text).SetWSPattern(`^(?:[ \t\r\n]+|\(\*(?s:.*?)\*\))+`)
+	defs, rules := []*ebnfExpr{}, make(map[string]*ebnfExpr)
+	for _, s = s.SkipWS(); !s.Endof(); _, s = s.SkipWS() {
+		node, news := ebnfRule(s)
+		if node == nil {
+			return nil, fmt.Errorf("ebnf: parse error at offset %v", s.GetCursor())
+		}
+		def := node.(*ebnfExpr)
+		if _, ok := rules[def.name]; ok {
+			return nil, fmt.Errorf("ebnf: rule %q already defined", def.name)
+		}
+		defs, rules[def.name], s = append(defs, def), def.items[0], news
+	}
+	for _, def := range defs {
+		if err := def.validate(rules, terminals); err != nil {
+			return nil, err
+		}
+	}
+
+	g := NewGrammar()
+	for _, def := range defs {
+		expr := def.items[0]
+		g.Define(def.name, func(g *Grammar) Parser {
+			return expr.compile(g, rules, terminals)
+		})
+	}
+	g.ebnf = rules
+	return g, nil
+}
+
+// ebnfExpr is a parsed EBNF expression, `op` is one of, 'r' for
+// reference to identifier `name`, 's' for string literal `name`, '.'
+// for sequence, '|' for alternatives, '?', '*', '+' for repetition of
+// items[0], and '=' for rule `name` defined as items[0].
+type ebnfExpr struct {
+	op    byte
+	name  string
+	items []*ebnfExpr
+}
+
+func (e *ebnfExpr) validate(
+	rules map[string]*ebnfExpr, terminals map[string]Parser) error {
+
+	if e.op == 'r' {
+		if _, ok := rules[e.name]; ok {
+			return nil
+		} else if _, ok := terminals[e.name]; ok {
+			return nil
+		}
+		return fmt.Errorf("ebnf: undefined rule or terminal %q", e.name)
+	}
+	for _, item := range e.items {
+		if err := item.validate(rules, terminals); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *ebnfExpr) compile(
+	g *Grammar, rules map[string]*ebnfExpr, terminals map[string]Parser) Parser {
+
+	items := make([]interface{}, 0, len(e.items))
+	for _, item := range e.items {
+		items = append(items, item.compile(g, rules, terminals))
+	}
+	switch e.op {
+	case 'r':
+		if _, ok := rules[e.name]; ok {
+			return g.Ref(e.name)
+		}
+		return terminals[e.name]
+	case 's':
+		return Atom(e.name, e.name)
+	case '.':
+		return And(nil, items...)
+	case '|':
+		return OrdChoice(nil, items...)
+	case '?':
+		return Maybe(nil, items[0])
+	case '*':
+		return Kleene(nil, items[0])
+	case '+':
+		return Many(nil, items[0])
+	}
+	panic(fmt.Errorf("unknown ebnf operator %q", e.op))
+}
+
+// ebnfRule is the parser for a rule in EBNF text.
+var ebnfRule = func() Parser {
+	var expr Parser
+
+	ident := Token(`[A-Za-z_][0-9A-Za-z_]*`, "IDENT")
+	str := Token(`(?:"[^"]*"|'[^']*')`, "STRING")
+	group := func(open, close string, op byte) Parser {
+		return And(func(ns []ParsecNode) ParsecNode {
+			if op == 0 {
+				return ns[1]
+			}
+			return &ebnfExpr{op: op, items: []*ebnfExpr{ns[1].(*ebnfExpr)}}
+		}, Atom(open, open), &expr, Atom(close, close))
+	}
+	base := OrdChoice(func(ns []ParsecNode) ParsecNode {
+		if t, ok := ns[0].(*Terminal); ok && t.Name == "IDENT" {
+			return &ebnfExpr{op: 'r', name: t.Value}
+		} else if ok {
+			return &ebnfExpr{op: 's', name: t.Value[1 : len(t.Value)-1]}
+		}
+		return ns[0]
+	}, ident, str, group("(", ")", 0), group("{", "}", '*'), group("[", "]", '?'))
+
+	repeat := Maybe(
+		func(ns []ParsecNode) ParsecNode { return ns[0] },
+		Token(`[?*+]`, "REPEAT"))
+	factor := And(func(ns []ParsecNode) ParsecNode {
+		if t, ok := ns[1].(*Terminal); ok {
+			return &ebnfExpr{op: t.Value[0], items: []*ebnfExpr{ns[0].(*ebnfExpr)}}
+		}
+		return ns[0]
+	}, base, repeat)
+
+	listof := func(op byte) Nodify {
+		return func(ns []ParsecNode) ParsecNode {
+			if len(ns) == 1 {
+				return ns[0]
+			}
+			e := &ebnfExpr{op: op}
+			for _, n := range ns {
+				e.items = append(e.items, n.(*ebnfExpr))
+			}
+			return e
+		}
+	}
+	term := Many(listof('.'), factor)
+	expr = List(listof('|'), 1, term, Atom("|", "PIPE"))
+
+	rule := And(func(ns []ParsecNode) ParsecNode {
+		name := ns[0].(*Terminal).Value
+		return &ebnfExpr{op: '=', name: name, items: []*ebnfExpr{ns[2].(*ebnfExpr)}}
+	}, ident, Atom("=", "EQUAL"), &expr, Atom(";", "SEMICOLON"))
+	return rule
+}()
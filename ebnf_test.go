@@ -0,0 +1,72 @@
+package parsec
+
+import "testing"
+
+func TestGrammarFromEBNF(t *testing.T) {
+	text := []byte(`
+	(* arithmetic expressions *)
+	expr   = term { ("+" | "-") term } ;
+	term   = factor ( ('*' | "/") factor )* ;
+	factor = INT | "(" expr ")" | "-"? IDENT+ ;
+	`)
+	terminals := map[string]Parser{"INT": Int(), "IDENT": Ident()}
+	g, err := GrammarFromEBNF(text, terminals)
+	if err != nil {
+		t.Fatal(err)
+	} else if names := g.Names(); len(names) != 3 || names[2] != "factor" {
+		t.Errorf("unexpected %v", names)
+	}
+
+	// hand-written form of the same grammar.
+	var expr Parser
+	add := OrdChoice(nil, Atom("+", "+"), Atom("-", "-"))
+	mul := OrdChoice(nil, Atom("*", "*"), Atom("/", "/"))
+	factor := OrdChoice(nil,
+		Int(),
+		And(nil, Atom("(", "("), &expr, Atom(")", ")")),
+		And(nil, Maybe(nil, Atom("-", "-")), Many(nil, Ident())))
+	term := And(nil, factor, Kleene(nil, And(nil, mul, factor)))
+	expr = And(nil, term, Kleene(nil, And(nil, add, term)))
+
+	for _, input := range []string{"1 + 2 * (3 - 4)", "-x y / 2", "((7))"} {
+		ref, rs := expr(NewScanner([]byte(input)))
+		node, s := g.Rule("expr")(NewScanner([]byte(input)))
+		if node == nil || !s.Endof() {
+			t.Errorf("%q: expected match, got %v", input, node)
+		} else if !EqualNodes(ref, node) {
+			t.Errorf("%q: expected %v, got %v", input, ref, node)
+		} else if s.GetCursor() != rs.GetCursor() {
+			t.Errorf("%q: expected %v, got %v", input, rs.GetCursor(), s.GetCursor())
+		}
+	}
+
+	// middlewares apply to rules referred from EBNF.
+	count := 0
+	cg := g.ApplyMiddleware(func(name string, p Parser) Parser {
+		return func(s Scanner) (ParsecNode, Scanner) {
+			count++
+			return p(s)
+		}
+	})
+	if node, _ := cg.Rule("expr")(NewScanner([]byte("(1)"))); node == nil {
+		t.Errorf("expected match")
+	} else if count != 6 {
+		t.Errorf("expected %v, got %v", 6, count)
+	}
+
+	// errors
+	errs := map[string]string{
+		`a = "x" `:                  "ebnf: parse error at offset 0",
+		`a = "x" ; b = "x" | ;`:     "ebnf: parse error at offset 10",
+		`a = "x" ; (* c *) b = ( ;`: "ebnf: parse error at offset 18",
+		`a = b ;`:                   `ebnf: undefined rule or terminal "b"`,
+		`a = "x" ; a = "y" ;`:       `ebnf: rule "a" already defined`,
+	}
+	for text, ref := range errs {
+		if _, err := GrammarFromEBNF([]byte(text), nil); err == nil {
+			t.Errorf("%q: expected error", text)
+		} else if err.Error() != ref {
+			t.Errorf("%q: expected %q, got %q", text, ref, err)
+		}
+	}
+}
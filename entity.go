@@ -0,0 +1,91 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "strconv"
+import "unicode/utf8"
+
+// XMLEntities is the table of predefined named entities in XML.
+var XMLEntities = map[string]rune{
+	"amp": '&', "lt": '<', "gt": '>', "quot": '"', "apos": '\'',
+}
+
+// EntityOption configures Entity parser.
+type EntityOption func(*entityConfig)
+
+type entityConfig struct {
+	lenient bool
+}
+
+// LenientEntities make Entity match named entities that are not in its
+// table, returned without the decoded rune, so that they can be passed
+// through verbatim.
+func LenientEntities() EntityOption {
+	return func(config *entityConfig) {
+		config.lenient = true
+	}
+}
+
+// Entity return parser function to match a character entity, named
+// like `&amp;`, decimal like `&#38;` or hexadecimal like `&#x26;`,
+// at the cursor, without skipping leading whitespace. Named entities
+// are decoded using `table`, like XMLEntities. Return Terminal named
+// `name` with the matched text as its value, and the decoded rune is
+// available using EntityRune. Entity without the terminating semicolon,
+// numeric entity that is not a valid code point, and named entity not
+// in table do not match, unless LenientEntities is supplied for the
+// latter.
+func Entity(name string, table map[string]rune, opts ...EntityOption) Parser {
+	var config entityConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	pattern := `^&(?:#[0-9]+|#[xX][0-9a-fA-F]+|[A-Za-z_][0-9A-Za-z_.-]*);`
+	return func(s Scanner) (ParsecNode, Scanner) {
+		news := s.Clone()
+		cursor := news.GetCursor()
+		tok, _ := news.Match(pattern)
+		if tok == nil {
+			return nil, s
+		}
+		ref := string(tok[1 : len(tok)-1])
+		r, ok := decodeEntity(ref, table)
+		if !ok && (ref[0] == '#' || !config.lenient) {
+			return nil, s
+		}
+		t := newTerminal(news, name, string(tok), cursor)
+		if ok {
+			t.SetAttribute("decoded", string(r))
+		}
+		return t, news
+	}
+}
+
+// EntityRune return the rune decoded from an entity matched by Entity
+// parser. Return false if `t` was not decoded, like an unknown entity
+// matched with LenientEntities.
+func EntityRune(t *Terminal) (rune, bool) {
+	decoded := t.GetAttribute("decoded")
+	if len(decoded) != 1 {
+		return utf8.RuneError, false
+	}
+	r, _ := utf8.DecodeRuneInString(decoded[0])
+	return r, true
+}
+
+func decodeEntity(ref string, table map[string]rune) (rune, bool) {
+	if ref[0] != '#' {
+		r, ok := table[ref]
+		return r, ok
+	}
+	base, digits := 10, ref[1:]
+	if digits[0] == 'x' || digits[0] == 'X' {
+		base, digits = 16, digits[1:]
+	}
+	n, err := strconv.ParseUint(digits, base, 32)
+	if err != nil || n > utf8.MaxRune || (n >= 0xD800 && n <= 0xDFFF) || n == 0 {
+		return utf8.RuneError, false
+	}
+	return rune(n), true
+}
@@ -0,0 +1,64 @@
+package parsec
+
+import "testing"
+
+func TestEntity(t *testing.T) {
+	testcases := []struct {
+		text    string
+		decoded rune
+		ok      bool
+	}{
+		{"&amp;", '&', true},
+		{"&lt;x", '<', true},
+		{"&#38;", '&', true},
+		{"&#x1F600;", '\U0001F600', true},
+		{"&#X1f600;", '\U0001F600', true},
+		{"&amp", 0, false},
+		{"&#38", 0, false},
+		{" &amp;", 0, false},
+		{"&#xD800;", 0, false},
+		{"&#1114112;", 0, false},
+		{"&#x;", 0, false},
+		{"&nbsp;", 0, false},
+	}
+	y := Entity("ENTITY", XMLEntities)
+	for _, tcase := range testcases {
+		node, s := y(NewScanner([]byte(tcase.text)))
+		if !tcase.ok {
+			if node != nil || s.GetCursor() != 0 {
+				t.Errorf("%q: unexpected %v", tcase.text, node)
+			}
+			continue
+		}
+		term, ok := node.(*Terminal)
+		if !ok {
+			t.Errorf("%q: unexpected %T", tcase.text, node)
+			continue
+		}
+		r, ok := EntityRune(term)
+		if !ok || r != tcase.decoded {
+			t.Errorf("%q: expected %q, got %q", tcase.text, tcase.decoded, r)
+		} else if term.Name != "ENTITY" || s.GetCursor() != len(term.Value) {
+			t.Errorf("%q: unexpected %v at %v", tcase.text, term, s.GetCursor())
+		}
+	}
+
+	// unknown entity passed through in lenient mode.
+	y = Entity("ENTITY", XMLEntities, LenientEntities())
+	node, s := y(NewScanner([]byte("&nbsp; x")))
+	if node == nil {
+		t.Fatalf("expected match")
+	} else if term := node.(*Terminal); term.Value != "&nbsp;" {
+		t.Errorf("expected %q, got %q", "&nbsp;", term.Value)
+	} else if _, ok := EntityRune(term); ok {
+		t.Errorf("unexpected decoded rune")
+	} else if s.GetCursor() != 6 {
+		t.Errorf("expected %v, got %v", 6, s.GetCursor())
+	}
+	if node, _ := y(NewScanner([]byte("&#xD800;"))); node != nil {
+		t.Errorf("unexpected %v", node)
+	}
+	if node, _ := y(NewScanner([]byte("&nbsp"))); node != nil {
+		t.Errorf("unexpected %v", node)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+import "strings"
+
+// ParseError describes a failure to parse input text at Offset. Scanners
+// record *ParseError for failures that shall abort the parse, like
+// exceeding the limit set by SetMaxBacktrack, refer Aborted. Line, Col,
+// Expected, Got and Input are populated by RunParser, for handling the
+// error programmatically.
+type ParseError struct {
+	Offset   int // offset in input text where parsing failed.
+	Line     int // line number, starting from 1, zero if not located.
+	Col      int // column in characters, starting from 1.
+	Msg      string
+	Expected []string  // names of parsers expected at Offset.
+	Got      *Terminal // token found at Offset, nil at end of input.
+	Input    []byte    // input text.
+	Err      error     // underlying cause, if any, like *BacktrackError.
+}
+
+// Error format the error as `line 3, col 7: expected one of [INT,
+// STRING, ARRAY], got ","` if it is located, otherwise as `<Msg> at
+// offset <Offset>`.
+func (err *ParseError) Error() string {
+	if err.Line > 0 {
+		return fmt.Sprintf("line %v, col %v: %v", err.Line, err.Col, err.Msg)
+	}
+	return fmt.Sprintf("%v at offset %v", err.Msg, err.Offset)
+}
+
+// Unwrap return the underlying cause.
+func (err *ParseError) Unwrap() error {
+	return err.Err
+}
+
+// ErrorList is a list of errors recovered while parsing, refer Recover.
+type ErrorList []*ParseError
+
+// Error summarise the number of errors and the first few of them.
+func (errs ErrorList) Error() string {
+	switch len(errs) {
+	case 0:
+		return "no errors"
+	case 1:
+		return errs[0].Error()
+	}
+	msgs := []string{}
+	for _, err := range errs {
+		if len(msgs) == 3 {
+			msgs = append(msgs, fmt.Sprintf("and %v more", len(errs)-3))
+			break
+		}
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%v errors: %v", len(errs), strings.Join(msgs, "; "))
+}
@@ -2,6 +2,8 @@ package parsec
 
 import "fmt"
 import "strconv"
+import "strings"
+import "text/scanner"
 
 func ExampleAST_And() {
 	// parse a configuration line from ini file.
@@ -163,6 +165,24 @@ func ExampleMany() {
 	// INT 50
 }
 
+func ExampleKleene() {
+	// parse an argument list, which can be empty
+	args := Kleene(nil, Ident(), Atom(",", "COMMA"))
+	y := And(nil, Ident(), Atom("(", "OPEN"), args, Atom(")", "CLOSE"))
+	for _, text := range []string{"max(a, b, c)", "now()"} {
+		root, _ := y(NewScanner([]byte(text)))
+		nodes := root.([]ParsecNode)
+		fmt.Print(nodes[0].(*Terminal).GetValue(), ":")
+		for _, arg := range nodes[2].([]ParsecNode) {
+			fmt.Print(" ", arg.(*Terminal).GetValue())
+		}
+		fmt.Println()
+	}
+	// Output:
+	// max: a b c
+	// now:
+}
+
 func ExampleManyUntil() {
 	// make sure to parse the entire text
 	text := []byte("10,20,50")
@@ -220,6 +240,17 @@ func ExampleMaybe() {
 	// IDENT b
 }
 
+func ExampleToken() {
+	// parse a semantic version
+	text := []byte(`  v1.12.0-rc1`)
+	y := Token(`v[0-9]+\.[0-9]+\.[0-9]+(-[a-z0-9]+)?`, "VERSION")
+	root, s := y(NewScanner(text))
+	t := root.(*Terminal)
+	fmt.Println(t.GetName(), t.GetValue(), t.GetPosition(), s.Endof())
+	// Output:
+	// VERSION v1.12.0-rc1 2 true
+}
+
 func ExampleNodify() {
 	text := []byte("10 * 20")
 	s := NewScanner(text)
@@ -236,3 +267,40 @@ func ExampleNodify() {
 	// Output:
 	// 200
 }
+
+func ExampleFromTokenFunc() {
+	// reuse a lexer built using text/scanner.
+	var lexer scanner.Scanner
+	lexer.Init(strings.NewReader(`print(x, 10, "hello")`))
+	next := func() (name, value string, pos int, ok bool) {
+		switch tok := lexer.Scan(); tok {
+		case scanner.EOF:
+			return "", "", 0, false
+		case scanner.Ident:
+			name = "IDENT"
+		case scanner.Int:
+			name = "INT"
+		case scanner.String:
+			name = "STRING"
+		default:
+			name = "PUNCT"
+		}
+		return name, lexer.TokenText(), lexer.Position.Offset, true
+	}
+
+	arg := OrdChoice(nil, Token("IDENT", "ID"), Token("INT|STRING", "LITERAL"))
+	args := Kleene(nil, arg, Atom(",", "COMMA"))
+	y := And(nil, Token("IDENT", "FUNC"), Atom("(", "OPEN"), args, Atom(")", "CLOSE"))
+	node, _ := y(FromTokenFunc(next))
+	fn, arguments := node.([]ParsecNode)[0], node.([]ParsecNode)[2]
+	fmt.Println(fn.(*Terminal).Name, fn.(*Terminal).Value)
+	for _, arg := range arguments.([]ParsecNode) {
+		t := arg.([]ParsecNode)[0].(*Terminal)
+		fmt.Println(t.Name, t.Value, t.Position)
+	}
+	// Output:
+	// FUNC print
+	// ID x 6
+	// LITERAL 10 9
+	// LITERAL "hello" 13
+}
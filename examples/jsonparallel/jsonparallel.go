@@ -0,0 +1,174 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+// Package jsonparallel is an example of parsing independent sections of
+// a document concurrently. Elements of a large top-level JSON array are
+// located by tracking brackets and strings, without parsing them, and
+// each element is parsed by its own scanner on a pool of workers.
+package jsonparallel
+
+import "fmt"
+import "sync"
+import "sync/atomic"
+
+import "github.com/prataprc/goparsec"
+
+// NewJSONParser return the root parser for a JSON value, constructed
+// with `ast`. Arrays are parsed as ARRAY NonTerminal, with its elements
+// as children of VALUES NonTerminal, and objects are parsed as OBJECT
+// NonTerminal, with its PROPERTY NonTerminals as children of PROPERTIES
+// NonTerminal.
+func NewJSONParser(ast *parsec.AST) parsec.Parser {
+	var value parsec.Parser
+
+	comma := parsec.Atom(",", "COMMA")
+	str := parsec.Token(`"(?:\\.|[^"\\])*"`, "STRING")
+	num := parsec.Token(
+		`-?(?:0|[1-9][0-9]*)(?:\.[0-9]+)?(?:[eE][+-]?[0-9]+)?`, "NUMBER")
+	values := ast.Kleene("VALUES", nil, &value, comma)
+	array := ast.And("ARRAY", nil,
+		parsec.Atom("[", "OPENSQR"), values, parsec.Atom("]", "CLOSESQR"))
+	property := ast.And("PROPERTY", nil, str, parsec.Atom(":", "COLON"), &value)
+	properties := ast.Kleene("PROPERTIES", nil, property, comma)
+	object := ast.And("OBJECT", nil,
+		parsec.Atom("{", "OPENBRACE"), properties, parsec.Atom("}", "CLOSEBRACE"))
+	keyword := parsec.Keywords("true", "false", "null")
+	value = ast.OrdChoice("VALUE", nil, str, num, keyword, array, object)
+	return value
+}
+
+// JSONParse parse JSON text sequentially. Return error if the text is
+// not fully parsed.
+func JSONParse(text []byte) (parsec.ParsecNode, error) {
+	ast := parsec.NewAST("json", 100)
+	node, s := ast.Parsewith(NewJSONParser(ast), parsec.NewScanner(text))
+	s.SkipWS()
+	if node == nil || !s.Endof() {
+		return nil, fmt.Errorf("json: parse error at offset %v", s.GetCursor())
+	}
+	return node, nil
+}
+
+// JSONParseParallel parse a top-level JSON array using `workers`
+// goroutines, and return its elements as children of a VALUES
+// NonTerminal, in the same order as the input. Positions of parsed nodes
+// are relative to the beginning of `text`, as with JSONParse. Error in
+// any element aborts the parse and return its positioned error. Text
+// that is not an array is parsed sequentially.
+func JSONParseParallel(text []byte, workers int) (parsec.ParsecNode, error) {
+	if workers < 1 {
+		panic(fmt.Errorf("JSONParseParallel expects atleast one worker"))
+	}
+	spans, ok, err := splitElements(text)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return JSONParse(text)
+	}
+
+	nodes := make([]parsec.Queryable, len(spans))
+	errs := make([]error, len(spans))
+	var next, failed int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// scanners are cloned from base, to share the compiled
+			// patterns among elements parsed by this worker.
+			ast, base := parsec.NewAST("json", 100), parsec.NewScanner(text)
+			y := NewJSONParser(ast)
+			for atomic.LoadInt64(&failed) == 0 {
+				i := int(atomic.AddInt64(&next, 1) - 1)
+				if i >= len(spans) {
+					return
+				}
+				nodes[i], errs[i] = parseElement(ast, y, base, spans[i])
+				if errs[i] != nil {
+					atomic.StoreInt64(&failed, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	values := parsec.NewNonTerminal("VALUES")
+	values.Children = nodes
+	return values, nil
+}
+
+// parseElement parse the array element in text[span[0]:span[1]], the
+// scanner starts at the offset of element, so that positions are
+// relative to the beginning of text.
+func parseElement(
+	ast *parsec.AST, y parsec.Parser,
+	base parsec.Scanner, span [2]int) (parsec.Queryable, error) {
+
+	node, s := ast.Parsewith(y, base.Clone().SkipN(span[0]))
+	s.SkipWS()
+	if cursor := s.GetCursor(); node == nil || cursor < span[1] {
+		return nil, fmt.Errorf("json: parse error at offset %v", cursor)
+	} else if cursor > span[1] {
+		return nil, fmt.Errorf("json: parse error at offset %v", span[1])
+	}
+	return node, nil
+}
+
+// splitElements locate the elements of top-level array in text, by
+// tracking nested brackets and strings. Return false if text is not an
+// array and error if the array is not terminated.
+func splitElements(text []byte) ([][2]int, bool, error) {
+	i := skipWS(text, 0)
+	if i >= len(text) || text[i] != '[' {
+		return nil, false, nil
+	}
+
+	spans := [][2]int{}
+	depth, start := 0, i+1
+	for ; i < len(text); i++ {
+		switch text[i] {
+		case '"':
+			for i++; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' {
+					i++
+				}
+			}
+		case '[', '{':
+			depth++
+		case ']', '}':
+			if depth--; depth > 0 {
+				continue
+			} else if text[i] != ']' {
+				return nil, false, fmt.Errorf("json: parse error at offset %v", i)
+			}
+			// empty array and trailing comma are accepted, like Kleene.
+			if skipWS(text, start) < i {
+				spans = append(spans, [2]int{start, i})
+			}
+			if i = skipWS(text, i+1); i < len(text) {
+				return nil, false, fmt.Errorf("json: parse error at offset %v", i)
+			}
+			return spans, true, nil
+		case ',':
+			if depth == 1 {
+				spans = append(spans, [2]int{start, i})
+				start = i + 1
+			}
+		}
+	}
+	return nil, false, fmt.Errorf("json: parse error at offset %v", len(text))
+}
+
+func skipWS(text []byte, i int) int {
+	for ; i < len(text); i++ {
+		if c := text[i]; c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			break
+		}
+	}
+	return i
+}
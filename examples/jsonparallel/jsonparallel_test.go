@@ -0,0 +1,84 @@
+package jsonparallel
+
+import "fmt"
+import "reflect"
+import "strings"
+import "testing"
+
+import "github.com/prataprc/goparsec"
+
+func TestJSONParseParallel(t *testing.T) {
+	texts := []string{
+		`[]`, `[ ]`, `[1]`, ` [1, "two", true, null] `, `[1,]`,
+		`[{"a": [1, 2, {"b": "]}"}]}, [[], {}], "x\"]", -1.5e3]`,
+		makeArray(100),
+	}
+	for _, text := range texts {
+		root, err := JSONParse([]byte(text))
+		if err != nil {
+			t.Fatalf("%q: %v", text, err)
+		}
+		ref := root.(*parsec.NonTerminal).Children[1]
+		for _, workers := range []int{1, 2, 4} {
+			node, err := JSONParseParallel([]byte(text), workers)
+			if err != nil {
+				t.Errorf("%q: %v", text, err)
+			} else if !reflect.DeepEqual(node, ref) {
+				t.Errorf("%q expected %v, got %v", text, ref, node)
+			}
+		}
+	}
+
+	// not an array is parsed sequentially.
+	node, err := JSONParseParallel([]byte(`{"a": 1}`), 2)
+	if err != nil {
+		t.Errorf("unexpected %v", err)
+	} else if name := node.(parsec.Queryable).GetName(); name != "OBJECT" {
+		t.Errorf("expected OBJECT, got %v", name)
+	}
+
+	// errors are positioned in the whole text.
+	errtexts := map[string]string{
+		`[1, 2 3, 4]`: "json: parse error at offset 6",
+		`[1, , 4]`:    "json: parse error at offset 4",
+		`[1, [2}]`:    "json: parse error at offset 4",
+		`[1, 2] 3`:    "json: parse error at offset 7",
+		`[1, 2}`:      "json: parse error at offset 5",
+		`[1, "2]`:     "json: parse error at offset 7",
+	}
+	for text, ref := range errtexts {
+		_, err := JSONParseParallel([]byte(text), 2)
+		if err == nil || err.Error() != ref {
+			t.Errorf("%q expected %v, got %v", text, ref, err)
+		}
+	}
+}
+
+func BenchmarkJSONParse(b *testing.B) {
+	text := []byte(makeArray(2000))
+	b.SetBytes(int64(len(text)))
+	for i := 0; i < b.N; i++ {
+		JSONParse(text)
+	}
+}
+
+func BenchmarkJSONParseParallel(b *testing.B) {
+	text := []byte(makeArray(2000))
+	for _, workers := range []int{1, 2, 4} {
+		b.Run(fmt.Sprintf("workers=%v", workers), func(b *testing.B) {
+			b.SetBytes(int64(len(text)))
+			for i := 0; i < b.N; i++ {
+				JSONParseParallel(text, workers)
+			}
+		})
+	}
+}
+
+func makeArray(n int) string {
+	items := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		item := `{"id": %v, "name": "item %v", "tags": ["a", "b"], "ok": true}`
+		items = append(items, fmt.Sprintf(item, i, i))
+	}
+	return "[" + strings.Join(items, ",\n") + "]"
+}
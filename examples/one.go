@@ -27,7 +27,10 @@ func main() {
 	items := ast.Kleene("items", nil, itemsep, nil)
 	array = ast.And("array", nil, opensqr, items, closesqr)
 
-	s := parsec.NewScanner([]byte(input))
-	node, _ := ast.Parsewith(array, s)
-	fmt.Println(node.GetValue())
+	node, err := parsec.ParseString(array, input)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(node.(parsec.Queryable).GetValue())
 }
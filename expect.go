@@ -0,0 +1,153 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+import "regexp"
+import "strings"
+
+// expectations track the names of parsers, annotated using WithError,
+// that failed at the furthest cursor, shared by a scanner and all its
+// clones.
+type expectations struct {
+	furthest int
+	names    []string
+}
+
+// expectScanner is implemented by scanners that can track expectations.
+type expectScanner interface {
+	expectations() *expectations
+	setExpectations(ex *expectations)
+}
+
+// fail note that parser `name` failed at `cursor`, replacing names
+// noted by its sub-parsers at the same cursor, `mark` being the number
+// of names at cursor before the parser was applied.
+func (ex *expectations) fail(name string, cursor, mark int) {
+	switch {
+	case cursor < ex.furthest:
+		return
+	case cursor > ex.furthest:
+		ex.furthest, ex.names = cursor, nil
+	default:
+		ex.names = ex.names[:mark]
+	}
+	for _, n := range ex.names {
+		if n == name {
+			return
+		}
+	}
+	ex.names = append(ex.names, name)
+}
+
+// mark return the number of names noted at `cursor`.
+func (ex *expectations) mark(cursor int) int {
+	if cursor == ex.furthest {
+		return len(ex.names)
+	}
+	return 0
+}
+
+// WithError annotates parser `p` with `name`, like INT or ARRAY, to be
+// reported as expected by RunParser if `p` fails at the furthest cursor
+// reached while parsing. Names noted by annotated sub-parsers of `p`
+// that failed at the same cursor are replaced by `name`, while names
+// noted by sub-parsers that failed further are retained. Otherwise
+// WithError is same as `p`.
+func WithError(name string, p interface{}) Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		es, ok := s.(expectScanner)
+		if !ok || es.expectations() == nil {
+			return doParse(p, s)
+		}
+		ex := es.expectations()
+		_, ws := s.Clone().SkipWS()
+		cursor := ws.GetCursor()
+		mark := ex.mark(cursor)
+		node, news := doParse(p, s)
+		if node == nil {
+			ex.fail(name, cursor, mark)
+		}
+		return node, news
+	}
+}
+
+// Alternatives combinator is same as OrdChoice, where each parser is
+// annotated, using WithError, with the name at the same index in
+// `names`, so that RunParser reports the alternatives expected when none
+// of them match. Panics if the number of names and parsers differ.
+func Alternatives(callb Nodify, names []string, parsers ...interface{}) Parser {
+	if len(names) != len(parsers) {
+		fmsg := "Alternatives has %v names for %v parsers"
+		panic(fmt.Errorf(fmsg, len(names), len(parsers)))
+	}
+	annotated := make([]interface{}, 0, len(parsers))
+	for i, parser := range parsers {
+		annotated = append(annotated, WithError(names[i], parser))
+	}
+	return OrdChoice(callb, annotated...)
+}
+
+// gotPattern match the token reported by RunParser at the error.
+var gotPattern = regexp.MustCompile(`^(?:\w+|\S)`)
+
+// RunParser applies parser `p` on `input` using Run, tracking the
+// expectations noted by WithError and Alternatives. If parsing fails,
+// return *ParseError positioned by Line and Col, starting from 1, with
+// Expected listing the names of annotated parsers that failed at the
+// furthest cursor and Got the token found there, a word or a single
+// non-space character, nil at the end of input. In recovery mode, every
+// error in ErrorList is positioned.
+func RunParser(
+	p Parser, input []byte, opts ...RunOption) (ParsecNode, error) {
+
+	s := NewScanner(input)
+	ex := &expectations{furthest: -1}
+	s.(expectScanner).setExpectations(ex)
+	res := Run(p, s, opts...)
+	switch err := res.Err.(type) {
+	case nil:
+		return res.Node, nil
+	case ErrorList:
+		for _, perr := range err {
+			perr.locate(input)
+		}
+	case *ParseError:
+		if err.Err == nil && ex.furthest >= err.Offset {
+			err.Offset, err.Expected = ex.furthest, ex.names
+		}
+		err.locate(input)
+		if len(err.Expected) > 0 {
+			err.Msg = err.expected()
+		}
+	}
+	return res.Node, res.Err
+}
+
+// locate the error within `input`.
+func (err *ParseError) locate(input []byte) {
+	err.Input = input
+	err.Line, err.Col = LineCol(input, err.Offset)
+	err.Got = nil
+	if err.Offset < len(input) {
+		rest := input[err.Offset:]
+		if loc := gotPattern.FindIndex(rest); loc != nil {
+			value := truncateValid(rest[:loc[1]], excerptLen)
+			err.Got = NewTerminal("TOKEN", value, err.Offset)
+		}
+	}
+}
+
+// expected describe the expected names and the token found instead.
+func (err *ParseError) expected() string {
+	got := "end of input"
+	if err.Got != nil {
+		got = fmt.Sprintf("%q", err.Got.Value)
+	}
+	if len(err.Expected) == 1 {
+		return fmt.Sprintf("expected %v, got %v", err.Expected[0], got)
+	}
+	expected := strings.Join(err.Expected, ", ")
+	return fmt.Sprintf("expected one of [%v], got %v", expected, got)
+}
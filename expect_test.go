@@ -0,0 +1,84 @@
+package parsec
+
+import "errors"
+import "reflect"
+import "strings"
+import "testing"
+
+func TestRunParser(t *testing.T) {
+	var value Parser
+	comma := Atom(",", "COMMA")
+	array := And(nil, Atom("[", "OPENSQR"), Kleene(nil, &value, comma),
+		Atom("]", "CLOSESQR"))
+	value = Alternatives(nil, []string{"INT", "STRING", "ARRAY"},
+		Int(), String(), array)
+
+	text := []byte("[\n  1,\n  \"a\",, 2]")
+	_, err := RunParser(value, text)
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected *ParseError, got %v", err)
+	}
+	ref := `line 3, col 7: expected one of [INT, STRING, ARRAY], got ","`
+	if err.Error() != ref {
+		t.Errorf("expected %q, got %q", ref, err.Error())
+	} else if perr.Offset != 13 || perr.Line != 3 || perr.Col != 7 {
+		t.Errorf("unexpected %v,%v,%v", perr.Offset, perr.Line, perr.Col)
+	} else if perr.Got == nil || perr.Got.Value != "," {
+		t.Errorf("unexpected %v", perr.Got)
+	} else if perr.Got.Position != 13 {
+		t.Errorf("expected %v, got %v", 13, perr.Got.Position)
+	} else if &perr.Input[0] != &text[0] {
+		t.Errorf("expected input text")
+	}
+	expected := []string{"INT", "STRING", "ARRAY"}
+	if !reflect.DeepEqual(perr.Expected, expected) {
+		t.Errorf("expected %v, got %v", expected, perr.Expected)
+	}
+
+	// annotation replaces the names of sub-parsers failing at its cursor.
+	y := And(nil, Atom("[", "OPENSQR"), WithError("VALUE", value))
+	_, err = RunParser(y, []byte("[ ,"))
+	if ref := `line 1, col 3: expected VALUE, got ","`; err.Error() != ref {
+		t.Errorf("expected %q, got %q", ref, err.Error())
+	}
+	// but not the names of sub-parsers failing further.
+	_, err = RunParser(y, []byte("[[1, x"))
+	ref = `line 1, col 6: expected one of [INT, STRING, ARRAY], got "x"`
+	if err.Error() != ref {
+		t.Errorf("expected %q, got %q", ref, err.Error())
+	}
+
+	_, err = RunParser(And(nil, Int(), WithError("INT", Int())), []byte("1"))
+	if ref := `line 1, col 2: expected INT, got end of input`; err.Error() != ref {
+		t.Errorf("expected %q, got %q", ref, err.Error())
+	} else if errors.As(err, &perr); perr.Got != nil {
+		t.Errorf("unexpected %v", perr.Got)
+	}
+
+	// long tokens are truncated.
+	_, err = RunParser(y, []byte("[ "+strings.Repeat("ab", 30)))
+	ref = `line 1, col 3: expected VALUE, got "` + strings.Repeat("ab", 20) + `..."`
+	if err.Error() != ref {
+		t.Errorf("expected %q, got %q", ref, err.Error())
+	}
+
+	// without annotations.
+	_, err = RunParser(Int(), []byte("1\n x"))
+	if ref := `line 2, col 2: parse error`; err.Error() != ref {
+		t.Errorf("expected %q, got %q", ref, err.Error())
+	} else if errors.As(err, &perr); perr.Expected != nil {
+		t.Errorf("unexpected %v", perr.Expected)
+	}
+
+	if node, err := RunParser(value, []byte(`[1, "a", []]`)); err != nil {
+		t.Errorf("unexpected %v", err)
+	} else if node == nil {
+		t.Errorf("expected node")
+	}
+
+	// annotations are ignored without RunParser.
+	if node, _ := WithError("INT", Int())(NewScanner([]byte("1"))); node == nil {
+		t.Errorf("expected node")
+	}
+}
@@ -13,6 +13,11 @@
 //           |  "-"
 //     value -> num
 //           | "(" expr ")"
+//
+// Y evaluates the expression while parsing, while Tree returns it as
+// BinaryExpr and UnaryExpr nodes to be evaluated using Eval. Program
+// parses a sequence of assignments followed by an expression, to be
+// evaluated using ProgramEval.
 
 package expr
 
@@ -63,11 +68,15 @@ func init() {
 	Y = parsec.OrdChoice(one2one, sum)
 }
 
+// intWS skip white space, including newlines, before an integer,
+// leaving the scanner untouched if there is none.
 func intWS() parsec.Parser {
 	return func(s parsec.Scanner) (parsec.ParsecNode, parsec.Scanner) {
-		_, s = s.SkipAny(`^[  \n\t]+`)
-		p := parsec.Int()
-		return p(s)
+		_, news := s.Clone().SkipAny(`^[  \n\t]+`)
+		if node, news := parsec.Int()(news); node != nil {
+			return node, news
+		}
+		return nil, s
 	}
 }
 
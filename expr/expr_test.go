@@ -3,6 +3,9 @@
 
 package expr
 
+import "fmt"
+import "strings"
+import "sync"
 import "testing"
 
 import "github.com/prataprc/goparsec"
@@ -48,3 +51,122 @@ func BenchmarkExpr(b *testing.B) {
 	}
 	b.SetBytes(int64(len(exprText)))
 }
+
+func TestTree(t *testing.T) {
+	testcases := []struct {
+		text, tree string
+		value      int
+	}{
+		{"1 + 2 * 3", "(1 + (2 * 3))", 7},
+		{"10 - 4 - 3", "((10 - 4) - 3)", 3},
+		{"-(1 + 2) * 4", "((-(1 + 2)) * 4)", -12},
+		{exprText, "", 110},
+	}
+	for _, tcase := range testcases {
+		node, err := parsec.ParseString(Tree, tcase.text)
+		if err != nil {
+			t.Fatalf("%q: %v", tcase.text, err)
+		}
+		if s := fmt.Sprint(node); tcase.tree != "" && s != tcase.tree {
+			t.Errorf("%q: expected %v, got %v", tcase.text, tcase.tree, s)
+		}
+		if v, err := Eval(node); err != nil || v != tcase.value {
+			t.Errorf("%q: expected %v, got %v %v", tcase.text, tcase.value, v, err)
+		}
+	}
+
+	// operator retains its position.
+	node, _ := parsec.ParseString(Tree, "12 / (3 - 3)")
+	if op := node.(*BinaryExpr).Op; op.Name != "DIV" || op.Position != 3 {
+		t.Errorf("unexpected %v", op)
+	}
+	_, err := Eval(node)
+	if ref := "division by zero at offset 3"; err == nil || err.Error() != ref {
+		t.Errorf("expected %q, got %v", ref, err)
+	}
+	_, err = EvalText("1 +\n  2 * 4 / (2 - 2)")
+	if ref := "division by zero at line 2, col 9"; err == nil || err.Error() != ref {
+		t.Errorf("expected %q, got %v", ref, err)
+	} else if everr := err.(*EvalError); everr.Op.Position != 12 {
+		t.Errorf("expected %v, got %v", 12, everr.Op.Position)
+	}
+}
+
+func TestTreeQueryable(t *testing.T) {
+	node, _ := parsec.ParseString(Tree, "-(1 + 2) * (3 + 4)")
+	q := node.(parsec.Queryable)
+	if q.GetName() != "BINARY" || q.GetValue() != "-1+2*3+4" {
+		t.Errorf("unexpected %v %q", q.GetName(), q.GetValue())
+	} else if q.GetPosition() != 0 {
+		t.Errorf("expected %v, got %v", 0, q.GetPosition())
+	}
+	names := []string{}
+	for _, child := range q.GetChildren() {
+		names = append(names, child.GetName())
+	}
+	if ref := "UNARY MULT BINARY"; strings.Join(names, " ") != ref {
+		t.Errorf("expected %v, got %v", ref, names)
+	}
+	q.SetAttribute("class", "expr")
+	if x := q.GetAttribute("class"); len(x) != 1 || x[0] != "expr" {
+		t.Errorf("unexpected %v", x)
+	}
+}
+
+// parsers are package-level variables, per-parse state shall live in the
+// scanner so that documents parsed back-to-back, or concurrently, see
+// the same results as a fresh process.
+func TestSharedParsers(t *testing.T) {
+	docs := []struct {
+		text string
+		val  int
+		ok   bool
+	}{
+		{"1 + 2*3", 7, true},
+		{"1 + * 2", 0, false},
+		{"(4+6) / 5 - 1", 1, true},
+	}
+	check := func(text string, val int, ok bool) error {
+		v, s := Y(parsec.NewScanner([]byte(text)))
+		if _, s = s.SkipWS(); ok != (v != nil && s.Endof()) {
+			return fmt.Errorf("%q: unexpected %v at %v", text, v, s.GetCursor())
+		} else if ok && v.(int) != val {
+			return fmt.Errorf("%q: expected %v, got %v", text, val, v)
+		}
+		if v, err := EvalText(text); ok != (err == nil) {
+			return fmt.Errorf("%q: unexpected %v", text, err)
+		} else if ok && v != val {
+			return fmt.Errorf("%q: expected %v, got %v", text, val, v)
+		}
+		return nil
+	}
+
+	for i := 0; i < 3; i++ {
+		for _, doc := range docs {
+			if err := check(doc.text, doc.val, doc.ok); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	errch := make(chan error, 8*len(docs)*10)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				for _, doc := range docs {
+					if err := check(doc.text, doc.val, doc.ok); err != nil {
+						errch <- err
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errch)
+	for err := range errch {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package expr
+
+import "errors"
+import "fmt"
+import "regexp"
+
+import "github.com/prataprc/goparsec"
+
+// Program is root Parser for a sequence of assignments like `x = 1 + 2`,
+// separated by newlines or semicolons, followed by an expression as the
+// program's result. Expressions can refer to variables assigned by
+// earlier statements. Program shall be applied on a scanner skipping only
+// spaces and tabs as white space, refer ProgramParse.
+//
+//	program -> sep* (ident "=" expr sep+)* expr sep*
+//	value   -> num
+//	        |  ident
+//	        |  "(" expr ")"
+//	        |  "-" value
+//	sep     -> "\n"
+//	        |  ";"
+var Program parsec.Parser
+var progSum, progProd, progValue parsec.Parser // circular rats
+
+// ErrEmptyProgram is returned by ProgramParse for text without
+// statements.
+var ErrEmptyProgram = errors.New("empty program")
+
+// ProgramNode is returned by ProgramParse, a sequence of assignments
+// followed by the expression evaluated as program's result.
+type ProgramNode struct {
+	Assigns []*Assign
+	Result  parsec.ParsecNode
+	text    []byte
+}
+
+// Assign is an assignment statement, retaining the identifier Terminal.
+type Assign struct {
+	Name  *parsec.Terminal
+	Value parsec.ParsecNode
+}
+
+var assignop = parsec.Token(`=`, "ASSIGN")
+var stmtsep = parsec.Token(`[\n;]`, "SEP")
+var seps = parsec.Kleene(nil, stmtsep)
+var emptyprogram = regexp.MustCompile(`^[\s;]*$`)
+
+func init() {
+	// sum -> prod (addop prod)*
+	progSum = parsec.And(binaryNode,
+		&progProd, parsec.Kleene(nil, parsec.And(many2many, sumOp, &progProd), nil))
+	// prod-> value (mulop value)*
+	progProd = parsec.And(binaryNode,
+		&progValue, parsec.Kleene(nil, parsec.And(many2many, prodOp, &progValue), nil))
+	// value -> num | ident | "(" expr ")" | "-" value
+	progValue = parsec.OrdChoice(progValueNode,
+		parsec.Int(),
+		parsec.Ident(),
+		parsec.And(exprNode, openparan, &progSum, closeparan),
+		parsec.And(unaryNode, subop, &progValue))
+	// (ident "=" expr sep+)*
+	assigns := parsec.Kleene(nil,
+		parsec.And(assignNode, parsec.Ident(), assignop, &progSum, parsec.Many(nil, stmtsep)))
+	Program = parsec.And(programNode, seps, assigns, &progSum, seps)
+}
+
+// ProgramParse parse `text` using Program, skipping spaces and tabs as
+// white space. Return ErrEmptyProgram if text has no statements and
+// *parsec.SourceError if it can't be parsed, like a program ending with
+// an assignment.
+func ProgramParse(text []byte) (parsec.ParsecNode, error) {
+	if emptyprogram.Match(text) {
+		return nil, ErrEmptyProgram
+	}
+	s := parsec.NewScannerWith(text, parsec.WithWS(`^[ \t\r]+`))
+	res := parsec.Run(Program, s)
+	if res.Err != nil {
+		perr := res.Err.(*parsec.ParseError)
+		line, col := parsec.LineCol(text, perr.Offset)
+		return nil, &parsec.SourceError{Line: line, Col: col, Err: perr}
+	}
+	prog := res.Node.(*ProgramNode)
+	prog.text = text
+	return prog, nil
+}
+
+// ProgramEval evaluate the program parsed by ProgramParse, assigning
+// variables in the order of statements, and return the value of its
+// result expression. Return *EvalError for variables used before they
+// are assigned and for division by zero.
+func ProgramEval(n parsec.ParsecNode) (float64, error) {
+	prog, ok := n.(*ProgramNode)
+	if !ok {
+		panic(fmt.Errorf("unexpected node %T", n))
+	}
+	env := make(map[string]float64)
+	for _, assign := range prog.Assigns {
+		val, err := evalFloat(prog.text, assign.Value, env)
+		if err != nil {
+			return 0, err
+		}
+		env[assign.Name.Value] = val
+	}
+	return evalFloat(prog.text, prog.Result, env)
+}
+
+func evalFloat(
+	text []byte, node parsec.ParsecNode, env map[string]float64) (float64, error) {
+
+	switch n := node.(type) {
+	case int:
+		return float64(n), nil
+
+	case *parsec.Terminal:
+		val, ok := env[n.Value]
+		if !ok {
+			msg := fmt.Sprintf("%v used before assignment", n.Value)
+			return 0, newProgramError(text, n, msg)
+		}
+		return val, nil
+
+	case *UnaryExpr:
+		val, err := evalFloat(text, n.Operand, env)
+		return -val, err
+
+	case *BinaryExpr:
+		left, err := evalFloat(text, n.Left, env)
+		if err != nil {
+			return 0, err
+		}
+		right, err := evalFloat(text, n.Right, env)
+		if err != nil {
+			return 0, err
+		}
+		switch n.Op.Name {
+		case "ADD":
+			return left + right, nil
+		case "SUB":
+			return left - right, nil
+		case "MULT":
+			return left * right, nil
+		case "DIV":
+			if right == 0 {
+				return 0, newProgramError(text, n.Op, "division by zero")
+			}
+			return left / right, nil
+		}
+	}
+	panic(fmt.Errorf("unexpected node %T", node))
+}
+
+func newProgramError(text []byte, t *parsec.Terminal, msg string) *EvalError {
+	line, col := parsec.LineCol(text, t.Position)
+	return &EvalError{Op: t, Msg: msg, Line: line, Col: col}
+}
+
+//----------
+// Nodifiers
+//----------
+
+func progValueNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	if term, ok := ns[0].(*parsec.Terminal); ok && term.Name == "INT" {
+		return exprValueNode(ns)
+	}
+	return ns[0]
+}
+
+func assignNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	return &Assign{Name: ns[0].(*parsec.Terminal), Value: ns[2]}
+}
+
+func programNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	prog := &ProgramNode{Result: ns[2]}
+	for _, n := range ns[1].([]parsec.ParsecNode) {
+		prog.Assigns = append(prog.Assigns, n.(*Assign))
+	}
+	return prog
+}
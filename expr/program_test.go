@@ -0,0 +1,69 @@
+package expr
+
+import "testing"
+
+import "github.com/prataprc/goparsec"
+
+func TestProgram(t *testing.T) {
+	testcases := []struct {
+		text  string
+		value float64
+	}{
+		{"42", 42},
+		{"x = 1 + 2\ny = x * 4\n(y - x) / 2\n", 4.5},
+		{"\n\nx = 10\n\n  y = -x  \n\n x + y * 2\n\n", -10},
+		{"x = 1; x = x + 1; x = x * 10; x", 20},
+		{"a = 3;\nb = a * a ;; \n a + b", 12},
+		{"x = 7\r\nx / 2\r\n", 3.5},
+	}
+	for _, tcase := range testcases {
+		node, err := ProgramParse([]byte(tcase.text))
+		if err != nil {
+			t.Fatalf("%q: %v", tcase.text, err)
+		}
+		if v, err := ProgramEval(node); err != nil || v != tcase.value {
+			t.Errorf("%q: expected %v, got %v %v", tcase.text, tcase.value, v, err)
+		}
+	}
+
+	// statements are retained in order.
+	node, _ := ProgramParse([]byte("a = 1\nb = a\nb"))
+	prog := node.(*ProgramNode)
+	if len(prog.Assigns) != 2 || prog.Assigns[1].Name.Value != "b" {
+		t.Errorf("unexpected %v", prog.Assigns)
+	} else if v := prog.Assigns[1].Value.(*parsec.Terminal); v.Value != "a" {
+		t.Errorf("unexpected %v", prog.Assigns)
+	}
+
+	// use before assignment.
+	node, err := ProgramParse([]byte("x = 1\ny = x + z\nz = 2\ny"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ProgramEval(node)
+	if ref := "z used before assignment at line 2, col 9"; err == nil || err.Error() != ref {
+		t.Errorf("expected %q, got %v", ref, err)
+	} else if everr := err.(*EvalError); everr.Op.Position != 14 {
+		t.Errorf("expected %v, got %v", 14, everr.Op.Position)
+	}
+	node, _ = ProgramParse([]byte("x = 1; y = x / (x - 1); y"))
+	if _, err = ProgramEval(node); err == nil || err.Error() != "division by zero at line 1, col 14" {
+		t.Errorf("unexpected %v", err)
+	}
+
+	// empty programs.
+	for _, text := range []string{"", "  \n\t\n", ";;\n;"} {
+		if _, err := ProgramParse([]byte(text)); err != ErrEmptyProgram {
+			t.Errorf("%q: expected %v, got %v", text, ErrEmptyProgram, err)
+		}
+	}
+
+	// syntax errors.
+	for _, text := range []string{"x = 1", "x = 1 y = 2; y", "x = 1\n2\nx", "x ="} {
+		if _, err := ProgramParse([]byte(text)); err == nil {
+			t.Errorf("%q: expected error", text)
+		} else if _, ok := err.(*parsec.SourceError); !ok {
+			t.Errorf("%q: unexpected %T", text, err)
+		}
+	}
+}
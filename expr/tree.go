@@ -0,0 +1,250 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package expr
+
+import "fmt"
+
+import "github.com/prataprc/goparsec"
+
+// Tree is root Parser for the same expressions as Y, along with unary
+// minus like `-(1 + 2)`, but return the expression as a tree of
+// *BinaryExpr, *UnaryExpr and int values, to be evaluated using Eval.
+var Tree parsec.Parser
+var treeSum, treeProd, treeValue parsec.Parser // circular rats
+
+// BinaryExpr is a binary operation, retaining the operator Terminal,
+// so that errors can point at the operator.
+type BinaryExpr struct {
+	Op          *parsec.Terminal
+	Left, Right parsec.ParsecNode
+	attributes  map[string][]string
+}
+
+// String implement fmt.Stringer interface, printing the expression
+// fully parenthesised.
+func (e *BinaryExpr) String() string {
+	return fmt.Sprintf("(%v %v %v)", e.Left, e.Op.Value, e.Right)
+}
+
+// GetName implement parsec.Queryable interface.
+func (e *BinaryExpr) GetName() string {
+	return "BINARY"
+}
+
+// IsTerminal implement parsec.Queryable interface.
+func (e *BinaryExpr) IsTerminal() bool {
+	return false
+}
+
+// GetValue implement parsec.Queryable interface.
+func (e *BinaryExpr) GetValue() string {
+	return valueOf(e.GetChildren())
+}
+
+// GetChildren implement parsec.Queryable interface, int operands are
+// returned as parsec.NodeValue named INT.
+func (e *BinaryExpr) GetChildren() []parsec.Queryable {
+	return []parsec.Queryable{operand(e.Left), e.Op, operand(e.Right)}
+}
+
+// GetPosition implement parsec.Queryable interface, positions of int
+// operands are not retained, in which case return the operator's.
+func (e *BinaryExpr) GetPosition() int {
+	if pos := operand(e.Left).GetPosition(); pos >= 0 {
+		return pos
+	}
+	return e.Op.Position
+}
+
+// SetAttribute implement parsec.Queryable interface.
+func (e *BinaryExpr) SetAttribute(attrname, value string) parsec.Queryable {
+	e.attributes = setAttribute(e.attributes, attrname, value)
+	return e
+}
+
+// GetAttribute implement parsec.Queryable interface.
+func (e *BinaryExpr) GetAttribute(attrname string) []string {
+	return e.attributes[attrname]
+}
+
+// GetAttributes implement parsec.Queryable interface.
+func (e *BinaryExpr) GetAttributes() map[string][]string {
+	return e.attributes
+}
+
+// UnaryExpr is a unary operation, retaining the operator Terminal.
+type UnaryExpr struct {
+	Op         *parsec.Terminal
+	Operand    parsec.ParsecNode
+	attributes map[string][]string
+}
+
+// String implement fmt.Stringer interface.
+func (e *UnaryExpr) String() string {
+	return fmt.Sprintf("(%v%v)", e.Op.Value, e.Operand)
+}
+
+// GetName implement parsec.Queryable interface.
+func (e *UnaryExpr) GetName() string {
+	return "UNARY"
+}
+
+// IsTerminal implement parsec.Queryable interface.
+func (e *UnaryExpr) IsTerminal() bool {
+	return false
+}
+
+// GetValue implement parsec.Queryable interface.
+func (e *UnaryExpr) GetValue() string {
+	return valueOf(e.GetChildren())
+}
+
+// GetChildren implement parsec.Queryable interface.
+func (e *UnaryExpr) GetChildren() []parsec.Queryable {
+	return []parsec.Queryable{e.Op, operand(e.Operand)}
+}
+
+// GetPosition implement parsec.Queryable interface.
+func (e *UnaryExpr) GetPosition() int {
+	return e.Op.Position
+}
+
+// SetAttribute implement parsec.Queryable interface.
+func (e *UnaryExpr) SetAttribute(attrname, value string) parsec.Queryable {
+	e.attributes = setAttribute(e.attributes, attrname, value)
+	return e
+}
+
+// GetAttribute implement parsec.Queryable interface.
+func (e *UnaryExpr) GetAttribute(attrname string) []string {
+	return e.attributes[attrname]
+}
+
+// GetAttributes implement parsec.Queryable interface.
+func (e *UnaryExpr) GetAttributes() map[string][]string {
+	return e.attributes
+}
+
+// EvalError is returned by Eval, positioned at the operator that failed.
+// Line and Col are known only for errors returned by EvalText.
+type EvalError struct {
+	Op        *parsec.Terminal
+	Msg       string
+	Line, Col int
+}
+
+func (err *EvalError) Error() string {
+	if err.Line > 0 {
+		return fmt.Sprintf("%v at line %v, col %v", err.Msg, err.Line, err.Col)
+	}
+	return fmt.Sprintf("%v at offset %v", err.Msg, err.Op.Position)
+}
+
+// Eval evaluate the expression tree parsed by Tree. Return *EvalError
+// for division by zero.
+func Eval(node parsec.ParsecNode) (int, error) {
+	switch n := node.(type) {
+	case int:
+		return n, nil
+
+	case *UnaryExpr:
+		val, err := Eval(n.Operand)
+		return -val, err
+
+	case *BinaryExpr:
+		left, err := Eval(n.Left)
+		if err != nil {
+			return 0, err
+		}
+		right, err := Eval(n.Right)
+		if err != nil {
+			return 0, err
+		}
+		switch n.Op.Name {
+		case "ADD":
+			return left + right, nil
+		case "SUB":
+			return left - right, nil
+		case "MULT":
+			return left * right, nil
+		case "DIV":
+			if right == 0 {
+				return 0, &EvalError{Op: n.Op, Msg: "division by zero"}
+			}
+			return left / right, nil
+		}
+	}
+	panic(fmt.Errorf("unexpected node %T", node))
+}
+
+// EvalText parse `text` using Tree and evaluate it, evaluation errors
+// are positioned by line and column within text.
+func EvalText(text string) (int, error) {
+	node, err := parsec.ParseString(Tree, text)
+	if err != nil {
+		return 0, err
+	}
+	val, err := Eval(node)
+	if everr, ok := err.(*EvalError); ok {
+		everr.Line, everr.Col = parsec.LineCol([]byte(text), everr.Op.Position)
+	}
+	return val, err
+}
+
+func init() {
+	// sum -> prod (addop prod)*
+	treeSum = parsec.And(binaryNode,
+		&treeProd, parsec.Kleene(nil, parsec.And(many2many, sumOp, &treeProd), nil))
+	// prod-> value (mulop value)*
+	treeProd = parsec.And(binaryNode,
+		&treeValue, parsec.Kleene(nil, parsec.And(many2many, prodOp, &treeValue), nil))
+	// value -> num | "(" expr ")" | "-" value
+	treeValue = parsec.OrdChoice(exprValueNode,
+		intWS(),
+		parsec.And(exprNode, openparan, &treeSum, closeparan),
+		parsec.And(unaryNode, subop, &treeValue))
+	Tree = parsec.OrdChoice(one2one, treeSum)
+}
+
+// binaryNode fold a sequence of operations into left associative
+// BinaryExpr.
+func binaryNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	left := ns[0]
+	for _, x := range ns[1].([]parsec.ParsecNode) {
+		y := x.([]parsec.ParsecNode)
+		left = &BinaryExpr{Op: y[0].(*parsec.Terminal), Left: left, Right: y[1]}
+	}
+	return left
+}
+
+func unaryNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	return &UnaryExpr{Op: ns[0].(*parsec.Terminal), Operand: ns[1]}
+}
+
+// operand return node `n` as parsec.Queryable, int values are wrapped
+// as parsec.NodeValue.
+func operand(n parsec.ParsecNode) parsec.Queryable {
+	if q, ok := n.(parsec.Queryable); ok {
+		return q
+	}
+	return &parsec.NodeValue{Name: "INT", Node: n}
+}
+
+func valueOf(children []parsec.Queryable) string {
+	value := ""
+	for _, child := range children {
+		value += child.GetValue()
+	}
+	return value
+}
+
+func setAttribute(
+	attrs map[string][]string, attrname, value string) map[string][]string {
+
+	if attrs == nil {
+		attrs = make(map[string][]string)
+	}
+	attrs[attrname] = append(attrs[attrname], value)
+	return attrs
+}
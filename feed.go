@@ -0,0 +1,142 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+
+// FeedParser applies a parser on input text that is fed incrementally,
+// like lines read by a REPL. Each call to Feed parses as many top-level
+// nodes as possible from the accumulated input, input that is valid
+// but incomplete is retained for the next call to Feed.
+type FeedParser struct {
+	parser Parser
+	text   []byte
+	cursor int // offset of pending input within text
+}
+
+// NewFeedParser create a new FeedParser to parse top-level nodes using
+// parser `p`.
+func NewFeedParser(p Parser) *FeedParser {
+	return &FeedParser{parser: p}
+}
+
+// Feed a line of input text, newline is appended if line does not end
+// with one. Return complete top-level nodes parsed so far, positions of
+// nodes are offsets into the input fed since the last Reset. Input is
+// incomplete if the parser failed while looking for more input at the
+// end of text. Otherwise return error, along with the nodes parsed
+// before the error, and the pending input is discarded.
+func (fp *FeedParser) Feed(line string) ([]ParsecNode, error) {
+	fp.text = append(fp.text, line...)
+	if len(line) == 0 || line[len(line)-1] != '\n' {
+		fp.text = append(fp.text, '\n')
+	}
+
+	nodes := []ParsecNode{}
+	for {
+		s := NewScanner(fp.text).SkipN(fp.cursor)
+		_, s = s.SkipWS()
+		if s.Endof() {
+			fp.cursor = s.GetCursor()
+			return nodes, nil
+		}
+		hs := &hwmScanner{Scanner: s, hwm: new(int)}
+		node, news := fp.parser(hs)
+		if node == nil || news.GetCursor() == s.GetCursor() {
+			if *hs.hwm >= len(fp.text) {
+				return nodes, nil // incomplete, wait for more input.
+			}
+			err := fmt.Errorf("parse error at offset %v", *hs.hwm)
+			fp.cursor = len(fp.text)
+			return nodes, err
+		}
+		nodes, fp.cursor = append(nodes, node), news.GetCursor()
+	}
+}
+
+// Reset clears the accumulated input.
+func (fp *FeedParser) Reset() {
+	fp.text, fp.cursor = nil, 0
+}
+
+// hwmScanner track the furthest position, high-water-mark, at which the
+// underlying scanner was asked to match input text.
+type hwmScanner struct {
+	Scanner
+	hwm *int // shared by all clones
+}
+
+func (s *hwmScanner) mark(cursor int) {
+	if cursor > *s.hwm {
+		*s.hwm = cursor
+	}
+}
+
+// SetWSPattern implement Scanner{} interface.
+func (s *hwmScanner) SetWSPattern(pattern string) Scanner {
+	s.Scanner.SetWSPattern(pattern)
+	return s
+}
+
+// TrackLineno implement Scanner{} interface.
+func (s *hwmScanner) TrackLineno() Scanner {
+	s.Scanner.TrackLineno()
+	return s
+}
+
+// Clone implement Scanner{} interface.
+func (s *hwmScanner) Clone() Scanner {
+	return &hwmScanner{Scanner: s.Scanner.Clone(), hwm: s.hwm}
+}
+
+// Match implement Scanner{} interface.
+func (s *hwmScanner) Match(pattern string) ([]byte, Scanner) {
+	s.mark(s.GetCursor())
+	token, _ := s.Scanner.Match(pattern)
+	s.mark(s.GetCursor())
+	return token, s
+}
+
+// MatchString implement Scanner{} interface.
+func (s *hwmScanner) MatchString(str string) (bool, Scanner) {
+	s.mark(s.GetCursor())
+	ok, _ := s.Scanner.MatchString(str)
+	s.mark(s.GetCursor())
+	return ok, s
+}
+
+// SubmatchAll implement Scanner{} interface.
+func (s *hwmScanner) SubmatchAll(pattern string) (map[string][]byte, Scanner) {
+	s.mark(s.GetCursor())
+	captures, _ := s.Scanner.SubmatchAll(pattern)
+	s.mark(s.GetCursor())
+	return captures, s
+}
+
+// SkipWS implement Scanner{} interface.
+func (s *hwmScanner) SkipWS() ([]byte, Scanner) {
+	ws, _ := s.Scanner.SkipWS()
+	s.mark(s.GetCursor())
+	return ws, s
+}
+
+// SkipAny implement Scanner{} interface.
+func (s *hwmScanner) SkipAny(pattern string) ([]byte, Scanner) {
+	token, _ := s.Scanner.SkipAny(pattern)
+	s.mark(s.GetCursor())
+	return token, s
+}
+
+// TryMatch implement Scanner{} interface.
+func (s *hwmScanner) TryMatch(pattern string) ([]byte, bool) {
+	s.mark(s.GetCursor())
+	return s.Scanner.TryMatch(pattern)
+}
+
+// SkipN implement Scanner{} interface.
+func (s *hwmScanner) SkipN(n int) Scanner {
+	s.Scanner.SkipN(n)
+	s.mark(s.GetCursor())
+	return s
+}
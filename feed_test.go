@@ -0,0 +1,65 @@
+package parsec
+
+import "testing"
+
+func TestFeedParser(t *testing.T) {
+	sum := Many(nil, Int(), Atom("+", "PLUS"))
+	stmt := And(nil, sum, Atom(";", "SEMICOLON"))
+	fp := NewFeedParser(stmt)
+
+	// incomplete statement is buffered.
+	if nodes, err := fp.Feed("1 + 2"); err != nil {
+		t.Fatal(err)
+	} else if len(nodes) != 0 {
+		t.Fatalf("unexpected %v", nodes)
+	}
+	if nodes, err := fp.Feed("+"); err != nil {
+		t.Fatal(err)
+	} else if len(nodes) != 0 {
+		t.Fatalf("unexpected %v", nodes)
+	}
+	// complete statements, and a pending one.
+	nodes, err := fp.Feed("3; 4; 5")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(nodes) != 2 {
+		t.Fatalf("unexpected %v", nodes)
+	}
+	operands := nodes[0].([]ParsecNode)[0].([]ParsecNode)
+	if len(operands) != 3 {
+		t.Errorf("unexpected %v", operands)
+	} else if term := operands[2].(*Terminal); term.Position != 8 {
+		t.Errorf("expected %v, got %v", 8, term.Position)
+	}
+	if nodes, err := fp.Feed(";"); err != nil {
+		t.Fatal(err)
+	} else if len(nodes) != 1 {
+		t.Fatalf("unexpected %v", nodes)
+	}
+	// blank lines.
+	if nodes, err := fp.Feed(""); err != nil || len(nodes) != 0 {
+		t.Fatalf("unexpected %v %v", nodes, err)
+	}
+
+	// invalid input is reported and discarded.
+	nodes, err = fp.Feed("6; 7 ) ; 8")
+	if err == nil {
+		t.Fatalf("expected error")
+	} else if err.Error() != "parse error at offset 24" {
+		t.Errorf("unexpected %v", err)
+	} else if len(nodes) != 1 {
+		t.Errorf("unexpected %v", nodes)
+	}
+	if nodes, err := fp.Feed("9;"); err != nil || len(nodes) != 1 {
+		t.Fatalf("unexpected %v %v", nodes, err)
+	}
+
+	// reset discards pending input.
+	fp.Feed("10 +")
+	fp.Reset()
+	if nodes, err := fp.Feed("11;"); err != nil || len(nodes) != 1 {
+		t.Fatalf("unexpected %v %v", nodes, err)
+	} else if pos := nodes[0].([]ParsecNode)[1].(*Terminal).Position; pos != 2 {
+		t.Errorf("expected %v, got %v", 2, pos)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+
+// FieldSpec describes a field matched by Fields combinator.
+type FieldSpec struct {
+	Name     string // name of the field, unique within Fields.
+	Parser   Parser
+	Required bool
+}
+
+// FieldsNode is returned by Fields combinator, it is a NonTerminal
+// named FIELDS, with a child NonTerminal, named after the field, for
+// every field that was present. Present tells the fields that were
+// matched, so that a field that is absent can be distinguished from a
+// field that is present with an empty or zero value.
+type FieldsNode struct {
+	*NonTerminal
+	Present map[string]bool
+	values  map[string]ParsecNode
+}
+
+// Field return the node matched for field `name`, and whether the field
+// was present.
+func (fn *FieldsNode) Field(name string) (ParsecNode, bool) {
+	n, ok := fn.values[name]
+	return n, ok
+}
+
+// Fields combinator matches a sequence of fields, in the order of
+// `specs`, where optional fields may be absent. Field is absent if its
+// parser fails or returns MaybeNone. If a required field is absent,
+// Fields will fail without consuming the input. Useful for PATCH-like
+// semantics, where a field that is present with zero value shall be
+// treated differently from a field that is absent. Return *FieldsNode.
+func Fields(specs []FieldSpec) Parser {
+	names := make(map[string]bool)
+	for _, spec := range specs {
+		if names[spec.Name] {
+			panic(fmt.Errorf("Fields has duplicate field %q", spec.Name))
+		}
+		names[spec.Name] = true
+	}
+
+	return func(s Scanner) (ParsecNode, Scanner) {
+		fn := &FieldsNode{
+			NonTerminal: newNonTerminal(s, "FIELDS"),
+			Present:     make(map[string]bool),
+			values:      make(map[string]ParsecNode),
+		}
+		news := s.Clone()
+		for _, spec := range specs {
+			n, ns := doParse(spec.Parser, news.Clone())
+			if _, none := n.(MaybeNone); n == nil || none {
+				if spec.Required {
+					return nil, s
+				}
+				continue
+			}
+			field := newNonTerminal(ns, spec.Name)
+			field.Children = append(field.Children, queryable(n, spec.Name))
+			fn.Children = append(fn.Children, field)
+			fn.Present[spec.Name], fn.values[spec.Name] = true, n
+			news = ns
+		}
+		return fn, news
+	}
+}
@@ -0,0 +1,70 @@
+package parsec
+
+import "testing"
+
+func TestFields(t *testing.T) {
+	field := func(key string, value Parser) Parser {
+		return And(func(ns []ParsecNode) ParsecNode { return ns[1] },
+			Atom(key+":", "KEY"), value)
+	}
+	str := Token(`"[^"]*"`, "STRING")
+	tags := Kleene(nil, Ident(), Atom(",", "COMMA"))
+	y := Fields([]FieldSpec{
+		{Name: "id", Parser: field("id", Int()), Required: true},
+		{Name: "name", Parser: field("name", str)},
+		{Name: "tags", Parser: field("tags", And(nil, Atom("[", "OPEN"),
+			tags, Atom("]", "CLOSE")))},
+		{Name: "note", Parser: Maybe(nil, field("note", str))},
+	})
+
+	// name is absent, tags is present but empty.
+	node, s := y(NewScanner([]byte(`id: 10 tags: []`)))
+	fn, ok := node.(*FieldsNode)
+	if !ok {
+		t.Fatalf("unexpected %T", node)
+	} else if !s.Endof() {
+		t.Errorf("expected end of text, at %v", s.GetCursor())
+	}
+	present := map[string]bool{"id": true, "name": false, "tags": true, "note": false}
+	for name, ref := range present {
+		if fn.Present[name] != ref {
+			t.Errorf("%v: expected %v, got %v", name, ref, fn.Present[name])
+		} else if _, ok := fn.Field(name); ok != ref {
+			t.Errorf("%v: expected %v, got %v", name, ref, ok)
+		}
+	}
+	if n, _ := fn.Field("id"); n.(*Terminal).Value != "10" {
+		t.Errorf("expected %q, got %v", "10", n)
+	}
+	if n, _ := fn.Field("tags"); len(n.([]ParsecNode)[1].([]ParsecNode)) != 0 {
+		t.Errorf("expected empty tags, got %v", n)
+	}
+	if len(fn.Children) != 2 {
+		t.Fatalf("expected %v children, got %v", 2, len(fn.Children))
+	} else if name := fn.Children[1].GetName(); name != "tags" {
+		t.Errorf("expected %q, got %q", "tags", name)
+	}
+
+	// present but empty string is present.
+	node, _ = y(NewScanner([]byte(`id: 1 name: "" note: "x"`)))
+	fn = node.(*FieldsNode)
+	if !fn.Present["name"] || !fn.Present["note"] || fn.Present["tags"] {
+		t.Errorf("unexpected %v", fn.Present)
+	} else if n, _ := fn.Field("name"); n.(*Terminal).Value != `""` {
+		t.Errorf("expected %q, got %v", `""`, n)
+	}
+
+	// missing required field.
+	if node, s := y(NewScanner([]byte(`name: "x"`))); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+	Fields([]FieldSpec{{Name: "a", Parser: Int()}, {Name: "a", Parser: Int()}})
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+
+// ForeignWindow is the maximum number of bytes of remaining input passed
+// to the consumer of Foreign parser, when the scanner can't share its
+// input without copying, like scanners created with NewScannerString
+// and NewScannerAt.
+var ForeignWindow = 64 * 1024
+
+// ForeignConsumer is called by Foreign parser with the remaining `input`
+// starting at `offset`, shall return the number of bytes consumed from
+// input along with the result of decoding them. Input may be shared with
+// the scanner, hence shall not be modified, and may be truncated to
+// ForeignWindow bytes.
+type ForeignConsumer func(input []byte, offset int) (
+	consumed int, result interface{}, err error)
+
+// ForeignNode is returned by Foreign parser, it is a terminal carrying
+// the result of an external parser. ForeignNode implements Queryable.
+type ForeignNode struct {
+	Name       string
+	Pos        int         // offset of the consumed input.
+	Raw        string      // input consumed by the external parser.
+	Result     interface{} // result returned by the external parser.
+	Attributes map[string][]string
+}
+
+// Foreign return parser function to delegate the remaining input to an
+// external parser, like a YAML library or a binary decoder, embedding
+// its result as opaque *ForeignNode named `name`. Scanner is advanced by
+// the bytes consumed, without skipping leading whitespace. Foreign will
+// fail without consuming the input if `consume` consumes no input. If
+// `consume` returns error, or reports more bytes consumed than the input
+// remaining, Foreign fails and the parse is aborted with *ParseError at
+// the offset of the input, refer Aborted.
+func Foreign(name string, consume ForeignConsumer) Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		news := s.Clone()
+		cursor := news.GetCursor()
+		input := foreignInput(news)
+		consumed, result, err := consume(input, cursor)
+		if err != nil {
+			msg := fmt.Sprintf("%v: %v", name, err)
+			abortParse(s, &ParseError{Offset: cursor, Msg: msg, Err: err})
+			return nil, s
+		} else if consumed < 0 || consumed > len(input) {
+			fmsg := "%v consumed %v bytes out of %v remaining"
+			msg := fmt.Sprintf(fmsg, name, consumed, len(input))
+			abortParse(s, &ParseError{Offset: cursor, Msg: msg})
+			return nil, s
+		} else if consumed == 0 {
+			return nil, s
+		}
+		node := &ForeignNode{
+			Name: name, Pos: cursor, Raw: string(input[:consumed]), Result: result,
+		}
+		return node, news.SkipN(consumed)
+	}
+}
+
+// foreignInput return the remaining input of scanner `s`, without
+// copying it if the scanner supports it, else atmost ForeignWindow
+// bytes of it.
+func foreignInput(s Scanner) []byte {
+	if Aborted(s) != nil {
+		return nil
+	}
+	n := s.BytesRemaining()
+	if n < 0 || n > ForeignWindow {
+		n = ForeignWindow
+	}
+	switch ss := s.(type) {
+	case *SimpleScanner:
+		return ss.tokentext()
+	case *StringScanner:
+		return []byte(ss.text[ss.cursor : ss.cursor+n])
+	case *ReaderAtScanner:
+		return ss.blocks.slice(int64(ss.cursor), int64(ss.cursor+n))
+	}
+	input, _ := s.TryMatch(`^(?s).*`)
+	return input
+}
+
+// GetName implement Queryable interface.
+func (fn *ForeignNode) GetName() string {
+	return fn.Name
+}
+
+// IsTerminal implement Queryable interface.
+func (fn *ForeignNode) IsTerminal() bool {
+	return true
+}
+
+// GetValue implement Queryable interface, return the consumed input.
+func (fn *ForeignNode) GetValue() string {
+	return fn.Raw
+}
+
+// GetChildren implement Queryable interface.
+func (fn *ForeignNode) GetChildren() []Queryable {
+	return nil
+}
+
+// GetPosition implement Queryable interface.
+func (fn *ForeignNode) GetPosition() int {
+	return fn.Pos
+}
+
+// SetAttribute implement Queryable interface.
+func (fn *ForeignNode) SetAttribute(attrname, value string) Queryable {
+	if fn.Attributes == nil {
+		fn.Attributes = make(map[string][]string)
+	}
+	fn.Attributes[attrname] = append(fn.Attributes[attrname], value)
+	return fn
+}
+
+// GetAttribute implement Queryable interface.
+func (fn *ForeignNode) GetAttribute(attrname string) []string {
+	return fn.Attributes[attrname]
+}
+
+// GetAttributes implement Queryable interface.
+func (fn *ForeignNode) GetAttributes() map[string][]string {
+	return fn.Attributes
+}
@@ -0,0 +1,102 @@
+package parsec
+
+import "encoding/binary"
+import "errors"
+import "strings"
+import "testing"
+
+func TestForeign(t *testing.T) {
+	// length prefixed blob, 2 byte big-endian length followed by data.
+	blob := func(input []byte, offset int) (int, interface{}, error) {
+		if len(input) < 2 {
+			return 0, nil, nil
+		}
+		n := int(binary.BigEndian.Uint16(input))
+		if len(input) < 2+n {
+			return 0, nil, errors.New("truncated blob")
+		}
+		return 2 + n, string(input[2 : 2+n]), nil
+	}
+	y := And(nil, Atom("blob", "BLOB"), Foreign("DATA", blob), Atom(";", "END"))
+
+	text := []byte("blob\x00\x05hello;")
+	node, s := y(NewScanner(text))
+	if node == nil {
+		t.Fatalf("unexpected nil")
+	} else if !s.Endof() {
+		t.Errorf("expected end of input at %v", s.GetCursor())
+	}
+	fn := node.([]ParsecNode)[1].(*ForeignNode)
+	if fn.GetName() != "DATA" || fn.Result != "hello" {
+		t.Errorf("unexpected %v:%v", fn.GetName(), fn.Result)
+	} else if fn.GetPosition() != 4 {
+		t.Errorf("expected %v, got %v", 4, fn.GetPosition())
+	} else if fn.GetValue() != "\x00\x05hello" {
+		t.Errorf("expected %q, got %q", "\x00\x05hello", fn.GetValue())
+	}
+	if start, end, ok := Span(node); !ok || start != 0 || end != len(text) {
+		t.Errorf("expected [0,%v), got [%v,%v)", len(text), start, end)
+	}
+
+	// error from consumer is positioned.
+	res := Run(y, NewScanner([]byte("blob\x00\x09hello;")))
+	var perr *ParseError
+	if !errors.As(res.Err, &perr) {
+		t.Fatalf("expected *ParseError, got %v", res.Err)
+	} else if perr.Offset != 4 || perr.Err == nil {
+		t.Errorf("unexpected %v", perr)
+	} else if !strings.Contains(perr.Error(), "truncated blob") {
+		t.Errorf("unexpected %v", perr)
+	}
+
+	// consuming zero bytes is a failure.
+	none := func(input []byte, offset int) (int, interface{}, error) {
+		return 0, "nothing", nil
+	}
+	node, s = Foreign("NONE", none)(NewScanner([]byte("hello")))
+	if node != nil || s.GetCursor() != 0 {
+		t.Errorf("unexpected %v at %v", node, s.GetCursor())
+	}
+
+	// over-reporting consumption is caught.
+	greedy := func(input []byte, offset int) (int, interface{}, error) {
+		return len(input) + 1, nil, nil
+	}
+	res = Run(Foreign("GREEDY", greedy), NewScanner([]byte("hello")))
+	if !errors.As(res.Err, &perr) {
+		t.Fatalf("expected *ParseError, got %v", res.Err)
+	} else if ref := "GREEDY consumed 6 bytes out of 5 remaining"; perr.Msg != ref {
+		t.Errorf("expected %q, got %q", ref, perr.Msg)
+	}
+
+	// applied without Run, the parser fails and the error is recorded.
+	s = NewScanner([]byte("hello"))
+	if node, _ = Foreign("GREEDY", greedy)(s); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if !errors.As(Aborted(s), &perr) || perr.Offset != 0 {
+		t.Errorf("unexpected %v", Aborted(s))
+	}
+}
+
+func TestForeignWindow(t *testing.T) {
+	defer func(window int) { ForeignWindow = window }(ForeignWindow)
+	ForeignWindow = 4
+
+	var seen int
+	all := func(input []byte, offset int) (int, interface{}, error) {
+		seen = len(input)
+		return len(input), nil, nil
+	}
+	y := Foreign("ALL", all)
+	// input is shared by SimpleScanner, hence not truncated.
+	if _, s := y(NewScanner([]byte("hello world"))); seen != 11 || !s.Endof() {
+		t.Errorf("expected %v, got %v", 11, seen)
+	}
+	// other scanners copy atmost ForeignWindow bytes.
+	if _, s := y(NewScannerString("hello world")); seen != 4 || s.GetCursor() != 4 {
+		t.Errorf("expected %v, got %v", 4, seen)
+	}
+	if _, s := y(NewScannerString("hey")); seen != 3 || !s.Endof() {
+		t.Errorf("expected %v, got %v", 3, seen)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+import "regexp"
+import "strings"
+import "unicode/utf8"
+
+// GlobNode is returned by Glob parser, it is a Terminal with the glob
+// pattern as its value, along with the compiled Matcher for paths.
+type GlobNode struct {
+	*Terminal
+	Matcher func(path string) bool
+}
+
+// Glob return parser function to match a shell style glob pattern, like
+// `src/**/*.go`, till whitespace, `,` or `;`. In pattern, `*` matches
+// any sequence of characters other than `/`, `?` matches a single
+// character other than `/`, `**` matches any sequence of characters,
+// including `/`, and `**/` matches zero or more directories. Bracket
+// class `[a-z]` matches a character in the class and `[!a-z]`, or
+// `[^a-z]`, a character other than `/` not in the class. Backslash
+// escapes the next character. Return *GlobNode named `name`, fails if
+// the pattern is invalid, like an unclosed or empty bracket class and
+// reversed ranges. Skip leading whitespace.
+func Glob(name string) Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		news := s.Clone()
+		news.SkipWS()
+		cursor := news.GetCursor()
+		tok, _ := news.Match(`^[^\s,;]+`)
+		if tok == nil {
+			return nil, s
+		}
+		regc, err := compileGlob(string(tok))
+		if err != nil {
+			return nil, s
+		}
+		t := newTerminal(news, name, string(tok), cursor)
+		return &GlobNode{Terminal: t, Matcher: regc.MatchString}, news
+	}
+}
+
+// compileGlob translate glob `pattern` to an anchored regular expression.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 3
+		case strings.HasPrefix(pattern[i:], "**"):
+			sb.WriteString(".*")
+			i += 2
+		case c == '*':
+			sb.WriteString("[^/]*")
+			i++
+		case c == '?':
+			sb.WriteString("[^/]")
+			i++
+		case c == '[':
+			n, err := globClass(&sb, pattern[i:])
+			if err != nil {
+				return nil, err
+			}
+			i += n
+		case c == '\\':
+			if i+1 >= len(pattern) {
+				return nil, fmt.Errorf("glob %q ends with backslash", pattern)
+			}
+			_, size := utf8.DecodeRuneInString(pattern[i+1:])
+			sb.WriteString(regexp.QuoteMeta(pattern[i+1 : i+1+size]))
+			i += 1 + size
+		default:
+			_, size := utf8.DecodeRuneInString(pattern[i:])
+			sb.WriteString(regexp.QuoteMeta(pattern[i : i+size]))
+			i += size
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// globClass translate the bracket class at the beginning of `pattern`,
+// and return its length. A `]` right after the opening bracket, or its
+// negation, is part of the class.
+func globClass(sb *strings.Builder, pattern string) (int, error) {
+	i, negate := 1, false
+	if i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^') {
+		i, negate = i+1, true
+	}
+	start := i
+	if i < len(pattern) && pattern[i] == ']' {
+		i++
+	}
+	for i < len(pattern) && pattern[i] != ']' {
+		i++
+	}
+	if i >= len(pattern) {
+		return 0, fmt.Errorf("glob %q has unclosed bracket class", pattern)
+	} else if i == start {
+		return 0, fmt.Errorf("glob %q has empty bracket class", pattern)
+	}
+
+	sb.WriteString("[")
+	if negate {
+		sb.WriteString("^/")
+	}
+	rs := []rune(pattern[start:i])
+	for k := 0; k < len(rs); k++ {
+		if k+2 < len(rs) && rs[k+1] == '-' {
+			if rs[k] > rs[k+2] {
+				fmsg := "glob %q has reversed range %c-%c"
+				return 0, fmt.Errorf(fmsg, pattern, rs[k], rs[k+2])
+			}
+			sb.WriteString(globClassRune(rs[k]) + "-" + globClassRune(rs[k+2]))
+			k += 2
+			continue
+		}
+		sb.WriteString(globClassRune(rs[k]))
+	}
+	sb.WriteString("]")
+	return i + 1, nil
+}
+
+func globClassRune(r rune) string {
+	switch r {
+	case '\\', '[', ']', '^', '-':
+		return `\` + string(r)
+	}
+	return string(r)
+}
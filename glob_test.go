@@ -0,0 +1,70 @@
+package parsec
+
+import "testing"
+
+func TestGlob(t *testing.T) {
+	node, s := Glob("GLOB")(NewScanner([]byte("  src/**/*.go, docs")))
+	gn, ok := node.(*GlobNode)
+	if !ok {
+		t.Fatalf("unexpected %T", node)
+	} else if gn.GetName() != "GLOB" || gn.GetValue() != "src/**/*.go" {
+		t.Errorf("unexpected %v:%v", gn.GetName(), gn.GetValue())
+	} else if gn.GetPosition() != 2 {
+		t.Errorf("expected %v, got %v", 2, gn.GetPosition())
+	} else if s.GetCursor() != 13 {
+		t.Errorf("expected %v, got %v", 13, s.GetCursor())
+	}
+	paths := map[string]bool{
+		"src/main.go":          true,
+		"src/a/b/parser.go":    true,
+		"src/a/parser_test.go": true,
+		"src/main.c":           false,
+		"lib/src/main.go":      false,
+		"src/a/main.go.orig":   false,
+	}
+	for path, ref := range paths {
+		if gn.Matcher(path) != ref {
+			t.Errorf("%q: expected %v, got %v", path, ref, !ref)
+		}
+	}
+
+	testcases := []struct {
+		pattern string
+		path    string
+		ok      bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "a/main.go", false},
+		{"file?.txt", "file1.txt", true},
+		{"file?.txt", "file10.txt", false},
+		{"[a-c]x", "bx", true},
+		{"[a-c]x", "dx", false},
+		{"[!a-c]x", "dx", true},
+		{"[!a-c]x", "ax", false},
+		{"[^a-c]x", "/x", false},
+		{"[]a]", "]", true},
+		{"[a-]", "-", true},
+		{`\*`, "*", true},
+		{`\*`, "a", false},
+		{"**", "a/b/c", true},
+		{"a.(b)+", "a.(b)+", true},
+		{"a.(b)+", "ax(b)", false},
+	}
+	for _, tcase := range testcases {
+		node, _ := Glob("GLOB")(NewScanner([]byte(tcase.pattern)))
+		gn, ok := node.(*GlobNode)
+		if !ok {
+			t.Errorf("%q: unexpected %T", tcase.pattern, node)
+		} else if gn.Matcher(tcase.path) != tcase.ok {
+			t.Errorf("%q on %q: expected %v", tcase.pattern, tcase.path, tcase.ok)
+		}
+	}
+
+	invalids := []string{"src/[a-z", "[]", "[!]", "[z-a]", `a\`, "", "  "}
+	for _, pattern := range invalids {
+		node, s := Glob("GLOB")(NewScanner([]byte(pattern)))
+		if node != nil || s.GetCursor() != 0 {
+			t.Errorf("%q: unexpected %v", pattern, node)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+
+// RuleFunc callback function to construct the parser for a named rule
+// in Grammar. Other rules, including the rule itself, shall be referred
+// via Grammar.Ref, so that recursive grammars can be expressed and
+// middlewares are applied on every reference.
+type RuleFunc func(g *Grammar) Parser
+
+// Middleware function to wrap the parser for a named rule.
+type Middleware func(name string, p Parser) Parser
+
+// Grammar is a collection of named rules. Once built, the same set of
+// rules can be instrumented uniformly, like tracing or memoization,
+// using ApplyMiddleware.
+type Grammar struct {
+	names []string
+	defs  map[string]RuleFunc
+	rules map[string]Parser
+	mws   []Middleware
+	ebnf  map[string]*ebnfExpr // rules defined by GrammarFromEBNF.
+}
+
+// NewGrammar create and return a new instance of Grammar without any
+// rules.
+func NewGrammar() *Grammar {
+	return &Grammar{
+		names: make([]string, 0),
+		defs:  make(map[string]RuleFunc),
+		rules: make(map[string]Parser),
+		mws:   make([]Middleware, 0),
+	}
+}
+
+// Define a new rule identified by `name`. Panics if rule is already
+// defined.
+func (g *Grammar) Define(name string, fn RuleFunc) *Grammar {
+	if _, ok := g.defs[name]; ok {
+		panic(fmt.Errorf("rule %q already defined", name))
+	}
+	g.names = append(g.names, name)
+	g.defs[name] = fn
+	g.rules[name] = g.build(name)
+	return g
+}
+
+// Ref return a parser that refers to rule `name`. Rule need not be
+// defined when Ref is called, but using the parser before defining the
+// rule will panic.
+func (g *Grammar) Ref(name string) Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		p, ok := g.rules[name]
+		if !ok {
+			panic(fmt.Errorf("rule %q not defined", name))
+		}
+		return p(s)
+	}
+}
+
+// Rule return the parser for rule `name`, same as Ref.
+func (g *Grammar) Rule(name string) Parser {
+	return g.Ref(name)
+}
+
+// Names return the list of rule names in the order they are defined.
+func (g *Grammar) Names() []string {
+	names := make([]string, len(g.names))
+	copy(names, g.names)
+	return names
+}
+
+// ApplyMiddleware create a new grammar with the same set of rules,
+// where every rule is wrapped with `fn`. Rules in the new grammar refer
+// to each other through the wrapped parsers, while the original grammar
+// is left untouched. For example, to trace every rule:
+//
+//	tg := g.ApplyMiddleware(func(name string, p Parser) Parser {
+//		return func(s Scanner) (ParsecNode, Scanner) {
+//			fmt.Println(name, s.GetCursor())
+//			return p(s)
+//		}
+//	})
+func (g *Grammar) ApplyMiddleware(fn Middleware) *Grammar {
+	ng := NewGrammar()
+	ng.mws = append(ng.mws, g.mws...)
+	ng.mws = append(ng.mws, fn)
+	for _, name := range g.names {
+		ng.names = append(ng.names, name)
+		ng.defs[name] = g.defs[name]
+	}
+	for _, name := range ng.names {
+		ng.rules[name] = ng.build(name)
+	}
+	return ng
+}
+
+func (g *Grammar) build(name string) Parser {
+	p := g.defs[name](g)
+	for _, mw := range g.mws {
+		p = mw(name, p)
+	}
+	return trackRule(name, p)
+}
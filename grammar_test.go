@@ -0,0 +1,91 @@
+package parsec
+
+import "reflect"
+import "testing"
+
+func makearraygrammar() *Grammar {
+	g := NewGrammar()
+	g.Define("item", func(g *Grammar) Parser {
+		return OrdChoice(nil, Token(`[a-z]+`, "ID"), g.Ref("array"))
+	})
+	g.Define("array", func(g *Grammar) Parser {
+		items := Kleene(nil, g.Ref("item"), Atom(",", "COMMA"))
+		return And(nil, Atom("[", "OPENSQR"), items, Atom("]", "CLOSESQR"))
+	})
+	return g
+}
+
+func TestGrammar(t *testing.T) {
+	g := makearraygrammar()
+	if names := g.Names(); !reflect.DeepEqual(names, []string{"item", "array"}) {
+		t.Errorf("unexpected %v", names)
+	}
+	s := NewScanner([]byte("[a,[b,c],d]"))
+	node, s := g.Rule("array")(s)
+	if node == nil {
+		t.Errorf("expected match")
+	} else if s.Endof() == false {
+		t.Errorf("expected end of text")
+	}
+
+	// panic cases
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("expected panic")
+			}
+		}()
+		g.Define("item", func(g *Grammar) Parser { return Ident() })
+	}()
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("expected panic")
+			}
+		}()
+		g.Rule("undefined")(NewScanner([]byte("a")))
+	}()
+}
+
+func TestApplyMiddleware(t *testing.T) {
+	g := makearraygrammar()
+	counts := make(map[string]int)
+	counter := func(name string, p Parser) Parser {
+		return func(s Scanner) (ParsecNode, Scanner) {
+			counts[name]++
+			return p(s)
+		}
+	}
+	cg := g.ApplyMiddleware(counter)
+
+	text := []byte("[a,[b,c],d]")
+	ref, _ := g.Rule("array")(NewScanner(text))
+	if len(counts) != 0 {
+		t.Errorf("original grammar is instrumented %v", counts)
+	}
+	node, _ := cg.Rule("array")(NewScanner(text))
+	if !reflect.DeepEqual(node, ref) {
+		t.Errorf("expected %v, got %v", ref, node)
+	}
+	// recursive references are instrumented as well.
+	if counts["array"] != 2 || counts["item"] != 5 {
+		t.Errorf("unexpected %v", counts)
+	}
+
+	// middlewares are composable.
+	order := []string{}
+	tracer := func(name string, p Parser) Parser {
+		return func(s Scanner) (ParsecNode, Scanner) {
+			order = append(order, name)
+			return p(s)
+		}
+	}
+	counts = make(map[string]int)
+	tg := cg.ApplyMiddleware(tracer)
+	tg.Rule("array")(NewScanner([]byte("[a]")))
+	if counts["array"] != 1 || counts["item"] != 1 {
+		t.Errorf("unexpected %v", counts)
+	} else if !reflect.DeepEqual(order, []string{"array", "item"}) {
+		t.Errorf("unexpected %v", order)
+	}
+}
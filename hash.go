@@ -0,0 +1,73 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "encoding/binary"
+import "fmt"
+import "hash"
+import "hash/fnv"
+
+// HashNode return a deterministic 64-bit FNV-1a hash of node's content,
+// that is its name, its value and hashes of its children, recursively.
+// Positions and attributes are not part of the hash, hence identical
+// sub-trees found at different places in the input hash to the same
+// value, making it suitable as key for caching sub-trees. Nodes that are
+// neither Queryable, nor []ParsecNode, nor string are hashed using their
+// type and default format.
+func HashNode(node ParsecNode) uint64 {
+	h := fnv.New64a()
+	hashNode(h, node)
+	return h.Sum64()
+}
+
+func hashNode(h hash.Hash64, node ParsecNode) {
+	switch n := node.(type) {
+	case nil:
+		h.Write([]byte{'0'})
+
+	case string:
+		h.Write([]byte{'s'})
+		hashString(h, n)
+
+	case []ParsecNode:
+		h.Write([]byte{'l'})
+		hashChildren(h, len(n), func(i int) ParsecNode { return n[i] })
+
+	case Queryable:
+		if n.IsTerminal() {
+			h.Write([]byte{'t'})
+			hashString(h, n.GetName())
+			hashString(h, n.GetValue())
+			return
+		}
+		children := n.GetChildren()
+		h.Write([]byte{'n'})
+		hashString(h, n.GetName())
+		hashChildren(h, len(children), func(i int) ParsecNode {
+			return children[i]
+		})
+
+	default:
+		h.Write([]byte{'v'})
+		hashString(h, fmt.Sprintf("%T %v", n, n))
+	}
+}
+
+// hashString writes length prefixed str, so that adjacent strings
+// remain unambiguous.
+func hashString(h hash.Hash64, str string) {
+	var scratch [binary.MaxVarintLen64]byte
+	h.Write(scratch[:binary.PutUvarint(scratch[:], uint64(len(str)))])
+	h.Write([]byte(str))
+}
+
+func hashChildren(h hash.Hash64, n int, child func(int) ParsecNode) {
+	var scratch [8]byte
+	binary.BigEndian.PutUint64(scratch[:], uint64(n))
+	h.Write(scratch[:])
+	for i := 0; i < n; i++ {
+		binary.BigEndian.PutUint64(scratch[:], HashNode(child(i)))
+		h.Write(scratch[:])
+	}
+}
@@ -0,0 +1,60 @@
+package parsec
+
+import "testing"
+
+func TestHashNode(t *testing.T) {
+	// same content at different positions.
+	a, b := NewTerminal("INT", "10", 0), NewTerminal("INT", "10", 20)
+	if HashNode(a) != HashNode(b) {
+		t.Errorf("expected same hash for %v and %v", a, b)
+	}
+	if HashNode(a) == HashNode(NewTerminal("INT", "11", 0)) {
+		t.Errorf("expected different hash for different value")
+	} else if HashNode(a) == HashNode(NewTerminal("HEX", "10", 0)) {
+		t.Errorf("expected different hash for different name")
+	}
+	// ambiguous concatenation.
+	x, y := NewTerminal("AB", "C", 0), NewTerminal("A", "BC", 0)
+	if HashNode(x) == HashNode(y) {
+		t.Errorf("expected different hash for %v and %v", x, y)
+	}
+
+	// sub-trees parsed at different places.
+	text := []byte("[a,[b,c]] [a,[b,c]] [a,[c,b]]")
+	y1 := makearraygrammar().Rule("array")
+	nodes, _ := Many(nil, y1)(NewScanner(text))
+	trees := nodes.([]ParsecNode)
+	if HashNode(trees[0]) != HashNode(trees[1]) {
+		t.Errorf("expected same hash for identical sub-trees")
+	} else if HashNode(trees[0]) == HashNode(trees[2]) {
+		t.Errorf("expected different hash for different sub-trees")
+	}
+	cache := map[uint64]ParsecNode{}
+	for _, tree := range trees {
+		cache[HashNode(tree)] = tree
+	}
+	if len(cache) != 2 {
+		t.Errorf("expected %v, got %v", 2, len(cache))
+	}
+
+	// non-terminals, lists and strings are distinguished.
+	nt := NewNonTerminal("INT")
+	nt.Children = append(nt.Children, a)
+	hashes := map[uint64]bool{
+		HashNode(nt):                   true,
+		HashNode([]ParsecNode{a}):      true,
+		HashNode(a):                    true,
+		HashNode("10"):                 true,
+		HashNode(nil):                  true,
+		HashNode([]ParsecNode{}):       true,
+		HashNode([]ParsecNode{nil}):    true,
+		HashNode(MaybeNone("missing")): true,
+		HashNode(10):                   true,
+	}
+	if len(hashes) != 9 {
+		t.Errorf("expected %v distinct hashes, got %v", 9, len(hashes))
+	}
+	if HashNode(nt) != HashNode(nt) {
+		t.Errorf("expected deterministic hash")
+	}
+}
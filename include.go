@@ -0,0 +1,105 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+import "regexp"
+import "sort"
+
+// IncludeFunc return the content of source document `name`, referred by
+// an include directive.
+type IncludeFunc func(name string) ([]byte, error)
+
+// Sources map offsets in the text expanded by ExpandIncludes back to
+// the source document, and the offset within that document, from which
+// the text was read.
+type Sources struct {
+	segments []sourceSegment
+	size     int // length of expanded text.
+}
+
+type sourceSegment struct {
+	start  int    // offset in expanded text.
+	name   string // source document.
+	offset int    // offset in source document.
+}
+
+// ExpandIncludes replace every include directive in `text`, read from
+// source document `name`, with the content of the included document,
+// recursively. `directive` is a regular expression whose first
+// sub-match is the name of the included document, passed to `resolve`.
+//
+// Included content is spliced into the input as is, hence parsers
+// continue transparently across an include boundary: a construct
+// started in the including document can be completed by the included
+// document, and vice-versa. Use Sources.Locate to map the positions of
+// parsed nodes back to their documents. Return error if a document
+// could not be resolved or includes itself, directly or indirectly.
+func ExpandIncludes(
+	name string, text []byte, directive string,
+	resolve IncludeFunc) ([]byte, *Sources, error) {
+
+	regc := regexp.MustCompile(directive)
+	if regc.NumSubexp() < 1 {
+		panic(fmt.Errorf("include directive %q must capture the name", directive))
+	}
+	srcs := &Sources{}
+	out, err := expandIncludes(name, text, regc, resolve, nil, srcs, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	srcs.size = len(out)
+	return out, srcs, nil
+}
+
+func expandIncludes(
+	name string, text []byte, regc *regexp.Regexp, resolve IncludeFunc,
+	stack []string, srcs *Sources, out []byte) ([]byte, error) {
+
+	for _, parent := range stack {
+		if parent == name {
+			return nil, fmt.Errorf("include cycle %v -> %v", stack, name)
+		}
+	}
+	stack = append(stack, name)
+
+	from := 0
+	for _, loc := range regc.FindAllSubmatchIndex(text, -1) {
+		out = srcs.add(out, name, text, from, loc[0])
+		incl := string(text[loc[2]:loc[3]])
+		content, err := resolve(incl)
+		if err != nil {
+			return nil, fmt.Errorf("%v at %v:%v", err, name, loc[0])
+		}
+		out, err = expandIncludes(incl, content, regc, resolve, stack, srcs, out)
+		if err != nil {
+			return nil, err
+		}
+		from = loc[1]
+	}
+	return srcs.add(out, name, text, from, len(text)), nil
+}
+
+func (srcs *Sources) add(out []byte, name string, text []byte, from, till int) []byte {
+	if from < till {
+		segment := sourceSegment{start: len(out), name: name, offset: from}
+		srcs.segments = append(srcs.segments, segment)
+	}
+	return append(out, text[from:till]...)
+}
+
+// Locate return the source document and the offset within that
+// document for `offset` in the expanded text. Return false if offset
+// is outside the expanded text.
+func (srcs *Sources) Locate(offset int) (name string, off int, ok bool) {
+	segments := srcs.segments
+	i := sort.Search(len(segments), func(i int) bool {
+		return segments[i].start > offset
+	})
+	if i == 0 || offset < 0 || offset >= srcs.size {
+		return "", -1, false
+	}
+	segment := segments[i-1]
+	return segment.name, segment.offset + offset - segment.start, true
+}
@@ -0,0 +1,67 @@
+package parsec
+
+import "fmt"
+import "testing"
+
+func TestExpandIncludes(t *testing.T) {
+	docs := map[string]string{
+		"main.json": `[1, 2, #include "rest.json"`,
+		"rest.json": "3,\n #include \"last.json\" ]",
+		"last.json": `4`,
+		"loop.json": `[1, #include "loop.json" ]`,
+	}
+	resolve := func(name string) ([]byte, error) {
+		if doc, ok := docs[name]; ok {
+			return []byte(doc), nil
+		}
+		return nil, fmt.Errorf("%v not found", name)
+	}
+	directive := `#include "([^"]+)"`
+
+	text, srcs, err := ExpandIncludes(
+		"main.json", []byte(docs["main.json"]), directive, resolve)
+	if err != nil {
+		t.Fatal(err)
+	} else if ref := "[1, 2, 3,\n 4 ]"; string(text) != ref {
+		t.Fatalf("expected %q, got %q", ref, text)
+	}
+
+	// array started in main.json is completed by included documents.
+	values := Kleene(nil, Int(), Atom(",", "COMMA"))
+	array := And(nil, Atom("[", "OPENSQR"), values, Atom("]", "CLOSESQR"))
+	node, s := array(NewScanner(text))
+	if node == nil || !s.Endof() {
+		t.Fatalf("expected array, got %v", node)
+	}
+	items := node.([]ParsecNode)[1].([]ParsecNode)
+	if len(items) != 4 {
+		t.Fatalf("expected %v, got %v", 4, items)
+	}
+	refs := [][2]interface{}{
+		{"main.json", 1}, {"main.json", 4}, {"rest.json", 0}, {"last.json", 0},
+	}
+	for i, item := range items {
+		name, off, ok := srcs.Locate(item.(*Terminal).Position)
+		if !ok || name != refs[i][0] || off != refs[i][1] {
+			t.Errorf("expected %v, got %v %v %v", refs[i], name, off, ok)
+		}
+	}
+	closesqr := node.([]ParsecNode)[2].(*Terminal)
+	if name, off, _ := srcs.Locate(closesqr.Position); name != "rest.json" || off != 25 {
+		t.Errorf("unexpected %v %v", name, off)
+	}
+	if _, _, ok := srcs.Locate(len(text)); ok {
+		t.Errorf("expected offset outside text")
+	}
+
+	// errors
+	_, _, err = ExpandIncludes("loop.json", []byte(docs["loop.json"]), directive, resolve)
+	ref := "include cycle [loop.json] -> loop.json"
+	if err == nil || err.Error() != ref {
+		t.Errorf("expected %q, got %v", ref, err)
+	}
+	_, _, err = ExpandIncludes("x", []byte(`1 #include "y"`), directive, resolve)
+	if ref := "y not found at x:2"; err == nil || err.Error() != ref {
+		t.Errorf("expected %q, got %v", ref, err)
+	}
+}
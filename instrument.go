@@ -0,0 +1,94 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "time"
+
+// OnMatchFunc is called by scanners wrapped with InstrumentScanner, with
+// the pattern, the matched bytes, nil if pattern did not match, the
+// cursor position where the match was tried and the time it took.
+type OnMatchFunc func(pattern string, matched []byte, pos int, dur time.Duration)
+
+// InstrumentScanner wrap scanner `s`, and its clones, to call `onMatch`
+// after every pattern match tried by Match, SkipAny and TryMatch, say
+// to profile the patterns used by Token parsers. Instruments can be
+// composed by wrapping an instrumented scanner.
+func InstrumentScanner(s Scanner, onMatch OnMatchFunc) Scanner {
+	return &instrumentScanner{Scanner: s, onMatch: onMatch}
+}
+
+type instrumentScanner struct {
+	Scanner
+	onMatch OnMatchFunc
+}
+
+// SetWSPattern implement Scanner{} interface.
+func (s *instrumentScanner) SetWSPattern(pattern string) Scanner {
+	s.Scanner = s.Scanner.SetWSPattern(pattern)
+	return s
+}
+
+// TrackLineno implement Scanner{} interface.
+func (s *instrumentScanner) TrackLineno() Scanner {
+	s.Scanner = s.Scanner.TrackLineno()
+	return s
+}
+
+// Clone implement Scanner{} interface.
+func (s *instrumentScanner) Clone() Scanner {
+	return &instrumentScanner{Scanner: s.Scanner.Clone(), onMatch: s.onMatch}
+}
+
+// Match implement Scanner{} interface.
+func (s *instrumentScanner) Match(pattern string) ([]byte, Scanner) {
+	pos, start := s.Scanner.GetCursor(), time.Now()
+	token, news := s.Scanner.Match(pattern)
+	s.onMatch(pattern, token, pos, time.Since(start))
+	s.Scanner = news
+	return token, s
+}
+
+// MatchString implement Scanner{} interface.
+func (s *instrumentScanner) MatchString(str string) (bool, Scanner) {
+	ok, news := s.Scanner.MatchString(str)
+	s.Scanner = news
+	return ok, s
+}
+
+// SubmatchAll implement Scanner{} interface.
+func (s *instrumentScanner) SubmatchAll(pattern string) (map[string][]byte, Scanner) {
+	captures, news := s.Scanner.SubmatchAll(pattern)
+	s.Scanner = news
+	return captures, s
+}
+
+// SkipWS implement Scanner{} interface.
+func (s *instrumentScanner) SkipWS() ([]byte, Scanner) {
+	token, news := s.Scanner.SkipWS()
+	s.Scanner = news
+	return token, s
+}
+
+// SkipAny implement Scanner{} interface.
+func (s *instrumentScanner) SkipAny(pattern string) ([]byte, Scanner) {
+	pos, start := s.Scanner.GetCursor(), time.Now()
+	token, news := s.Scanner.SkipAny(pattern)
+	s.onMatch(pattern, token, pos, time.Since(start))
+	s.Scanner = news
+	return token, s
+}
+
+// TryMatch implement Scanner{} interface.
+func (s *instrumentScanner) TryMatch(pattern string) ([]byte, bool) {
+	pos, start := s.Scanner.GetCursor(), time.Now()
+	token, ok := s.Scanner.TryMatch(pattern)
+	s.onMatch(pattern, token, pos, time.Since(start))
+	return token, ok
+}
+
+// SkipN implement Scanner{} interface.
+func (s *instrumentScanner) SkipN(n int) Scanner {
+	s.Scanner = s.Scanner.SkipN(n)
+	return s
+}
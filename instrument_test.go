@@ -0,0 +1,45 @@
+package parsec
+
+import "testing"
+import "time"
+
+func TestInstrumentScanner(t *testing.T) {
+	type match struct {
+		pattern, matched string
+		pos              int
+	}
+	matches, counts := []match{}, 0
+	record := func(pattern string, matched []byte, pos int, dur time.Duration) {
+		if dur < 0 {
+			t.Errorf("unexpected duration %v", dur)
+		}
+		matches = append(matches, match{pattern, string(matched), pos})
+	}
+	count := func(string, []byte, int, time.Duration) { counts++ }
+
+	s := NewScanner([]byte("10, x"))
+	s = InstrumentScanner(InstrumentScanner(s, record), count)
+	y := Kleene(nil, Int(), Atom(",", "COMMA"))
+	node, news := y(s)
+	if len(node.([]ParsecNode)) != 1 {
+		t.Errorf("unexpected %v", node)
+	} else if news.GetCursor() != 3 {
+		t.Errorf("expected %v, got %v", 3, news.GetCursor())
+	}
+	refs := []match{{"^-?[0-9]+", "10", 0}, {"^-?[0-9]+", "", 4}}
+	if len(matches) != len(refs) {
+		t.Fatalf("expected %v, got %v", refs, matches)
+	}
+	for i, ref := range refs {
+		if matches[i] != ref {
+			t.Errorf("expected %v, got %v", ref, matches[i])
+		}
+	}
+	if counts != len(refs) {
+		t.Errorf("expected %v, got %v", len(refs), counts)
+	}
+	// cursor of wrapped scanner is not shared with clones.
+	if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+}
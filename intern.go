@@ -0,0 +1,47 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+// Interner deduplicates token values, shared by a scanner and all its
+// clones, refer InternValues. Custom scanners can use it to intern their
+// token values. Not safe for concurrent use, every goroutine parsing
+// with its own scanner gets its own interner.
+type Interner struct {
+	maxlen int
+	values map[string]string
+}
+
+// NewInterner return an interner for values upto `maxlen` bytes.
+func NewInterner(maxlen int) *Interner {
+	return &Interner{maxlen: maxlen, values: make(map[string]string)}
+}
+
+// Intern return `tok` as string, re-using the string returned earlier
+// for the same value if tok is no longer than maxlen bytes. A nil
+// interner returns tok as a new string.
+func (in *Interner) Intern(tok []byte) string {
+	if in == nil || len(tok) > in.maxlen {
+		return string(tok)
+	}
+	if value, ok := in.values[string(tok)]; ok {
+		return value
+	}
+	value := string(tok)
+	in.values[value] = value
+	return value
+}
+
+// internScanner is implemented by scanners that can intern token values.
+type internScanner interface {
+	interner() *Interner
+}
+
+// tokenValue return `tok` as terminal value, interned if scanner `s` is
+// set to InternValues.
+func tokenValue(s Scanner, tok []byte) string {
+	if is, ok := s.(internScanner); ok {
+		return is.interner().Intern(tok)
+	}
+	return string(tok)
+}
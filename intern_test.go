@@ -0,0 +1,56 @@
+package parsec
+
+import "strings"
+import "testing"
+import "unsafe"
+
+func TestInternValues(t *testing.T) {
+	key := Token(`[a-z]+`, "KEY")
+	y := Many(nil, key)
+	text := strings.Repeat("name verylongkeyname ", 3)
+	same := func(a, b string) bool {
+		return unsafe.StringData(a) == unsafe.StringData(b)
+	}
+
+	newscanners := []func() Scanner{
+		func() Scanner {
+			return NewScanner([]byte(text)).(*SimpleScanner).InternValues(8)
+		},
+		func() Scanner {
+			return NewScannerString(text).(*StringScanner).InternValues(8)
+		},
+	}
+	for _, newfn := range newscanners {
+		node, _ := y(newfn())
+		terms := node.([]ParsecNode)
+		if len(terms) != 6 {
+			t.Fatalf("expected %v, got %v", 6, len(terms))
+		}
+		first, long := terms[0].(*Terminal), terms[1].(*Terminal)
+		for i := 2; i < len(terms); i += 2 {
+			term, longterm := terms[i].(*Terminal), terms[i+1].(*Terminal)
+			if term.Value != first.Value || !same(term.Value, first.Value) {
+				t.Errorf("expected interned %q, got %q", first.Value, term.Value)
+			} else if term.Position == first.Position {
+				t.Errorf("expected distinct positions, got %v", term.Position)
+			}
+			// longer than maxLen are not interned.
+			if longterm.Value != long.Value || same(longterm.Value, long.Value) {
+				t.Errorf("unexpected %q", longterm.Value)
+			}
+		}
+
+		// interned values are not shared across scanners.
+		other, _ := key(newfn())
+		if v := other.(*Terminal).Value; v != first.Value || same(v, first.Value) {
+			t.Errorf("unexpected %q", v)
+		}
+	}
+
+	// without interning
+	node, _ := y(NewScanner([]byte(text)))
+	terms := node.([]ParsecNode)
+	if same(terms[0].(*Terminal).Value, terms[2].(*Terminal).Value) {
+		t.Errorf("unexpected interned value")
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+import "strings"
+
+// interpolateEscapes map escape sequences in literal text of
+// InterpolatedString.
+var interpolateEscapes = map[string]string{
+	`\"`: `"`, `\\`: `\`, `\/`: `/`, `\n`: "\n", `\r`: "\r", `\t`: "\t",
+}
+
+// InterpolatedString return parser function to match a double quoted
+// template string, like `"hello ${name}, you have ${count} messages"`,
+// where expressions enclosed by `open` and `close` delimiters are parsed
+// using `exprParser`. Return NonTerminal named INTERPOLATED, with
+// children alternating between Terminals named LITERAL, for the text
+// between expressions, and expression nodes, wrapped as NodeValue named
+// EXPR if they are not Queryable. Empty literal text is omitted. In
+// literal text, open delimiter escaped by backslash, like `\${`, and
+// escapes \" \\ \/ \n \r \t are decoded, while other escapes are retained
+// as is. Fails if string is not closed on the same line, or if an
+// expression does not match or is not followed by `close`. Skip leading
+// whitespace.
+func InterpolatedString(open, close string, exprParser Parser) Parser {
+	if open == "" || close == "" {
+		panic(fmt.Errorf("InterpolatedString expects delimiters"))
+	}
+	return func(s Scanner) (ParsecNode, Scanner) {
+		news := s.Clone()
+		news.SkipWS()
+		if ok, _ := news.MatchString(`"`); !ok {
+			return nil, s
+		}
+		nt := newNonTerminal(news, "INTERPOLATED")
+		var literal strings.Builder
+		litpos := news.GetCursor()
+		flush := func() {
+			if literal.Len() > 0 {
+				t := newTerminal(news, "LITERAL", literal.String(), litpos)
+				nt.Children = append(nt.Children, t)
+				literal.Reset()
+			}
+		}
+		for {
+			if literal.Len() == 0 {
+				litpos = news.GetCursor()
+			}
+			if ok, _ := news.MatchString(`"`); ok {
+				flush()
+				return nt, news
+			} else if ok, _ := news.MatchString(`\` + open); ok {
+				literal.WriteString(open)
+			} else if ok, _ := news.MatchString(open); ok {
+				flush()
+				node, ns := doParse(exprParser, news)
+				if node == nil {
+					return nil, s
+				}
+				ns.SkipWS()
+				if ok, _ := ns.MatchString(close); !ok {
+					return nil, s
+				}
+				nt.Children = append(nt.Children, queryable(node, "EXPR"))
+				news = ns
+			} else if ch, _ := news.Match(`^(?:\\[^\n]|[^\n])`); ch != nil {
+				if esc, ok := interpolateEscapes[string(ch)]; ok {
+					literal.WriteString(esc)
+				} else {
+					literal.Write(ch)
+				}
+			} else {
+				return nil, s
+			}
+		}
+	}
+}
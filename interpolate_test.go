@@ -0,0 +1,63 @@
+package parsec
+
+import "reflect"
+import "testing"
+
+func TestInterpolatedString(t *testing.T) {
+	segments := func(node ParsecNode) []string {
+		ss := []string{}
+		for _, child := range node.(*NonTerminal).GetChildren() {
+			ss = append(ss, child.GetName()+":"+child.GetValue())
+		}
+		return ss
+	}
+	y := InterpolatedString("${", "}", Ident())
+
+	text := `  "hello ${name}, you have ${ count } messages" tail`
+	node, s := y(NewScanner([]byte(text)))
+	if node == nil {
+		t.Fatalf("unexpected nil")
+	} else if s.GetCursor() != 47 {
+		t.Errorf("expected %v, got %v", 47, s.GetCursor())
+	}
+	ref := []string{
+		"LITERAL:hello ", "IDENT:name", "LITERAL:, you have ", "IDENT:count",
+		"LITERAL: messages",
+	}
+	if got := segments(node); !reflect.DeepEqual(got, ref) {
+		t.Errorf("expected %v, got %v", ref, got)
+	}
+	nt := node.(*NonTerminal)
+	if nt.GetName() != "INTERPOLATED" {
+		t.Errorf("expected %v, got %v", "INTERPOLATED", nt.GetName())
+	} else if pos := nt.Children[2].GetPosition(); pos != 16 {
+		t.Errorf("expected %v, got %v", 16, pos)
+	}
+
+	text = `"cost \${price} is ${price}\n\"ok\""`
+	node, _ = y(NewScanner([]byte(text)))
+	if node == nil {
+		t.Fatalf("unexpected nil")
+	}
+	ref = []string{"LITERAL:cost ${price} is ", "IDENT:price", "LITERAL:\n\"ok\""}
+	if got := segments(node); !reflect.DeepEqual(got, ref) {
+		t.Errorf("expected %v, got %v", ref, got)
+	}
+
+	node, _ = y(NewScanner([]byte(`"${a}${b}"`)))
+	if ref := []string{"IDENT:a", "IDENT:b"}; !reflect.DeepEqual(segments(node), ref) {
+		t.Errorf("expected %v, got %v", ref, segments(node))
+	}
+	node, _ = y(NewScanner([]byte(`""`)))
+	if node == nil || len(node.(*NonTerminal).Children) != 0 {
+		t.Errorf("unexpected %v", node)
+	}
+
+	for _, text := range []string{`"hello ${name"`, `"${}"`, `"hello`, "\"a\nb\"", `x`} {
+		if node, s := y(NewScanner([]byte(text))); node != nil {
+			t.Errorf("%q: unexpected %v", text, node)
+		} else if s.GetCursor() != 0 {
+			t.Errorf("%q: expected %v, got %v", text, 0, s.GetCursor())
+		}
+	}
+}
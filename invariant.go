@@ -0,0 +1,68 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+import "reflect"
+import "runtime"
+
+// Debug builds, compiled with `parsecdebug` build tag, assert the
+// invariants of parser functions applied by combinators, panicking
+// with the name of the offending parser and its cursors:
+//
+//   - on success, returned cursor is within the input cursor and the
+//     end of input text.
+//   - on failure, returned cursor is same as the input cursor.
+//   - Nodify callback is not invoked with a failed node.
+//
+// Standard builds compile out these assertions, refer debugInvariants.
+
+// checkedParse same as doParse, asserting the invariants of `parser`.
+func checkedParse(parser interface{}, s Scanner) (ParsecNode, Scanner) {
+	from, remaining := s.GetCursor(), s.BytesRemaining()
+	var node ParsecNode
+	var news Scanner
+	switch p := parser.(type) {
+	case Parser:
+		node, news = p(s)
+	case *Parser:
+		node, news = (*p)(s)
+	default:
+		panic(fmt.Errorf("type of parser `%T` not supported", parser))
+	}
+
+	cursor := news.GetCursor()
+	if node == nil && cursor != from {
+		fmsg := "parsec: %v failed at cursor %v, returned cursor %v"
+		panic(fmt.Errorf(fmsg, parserName(parser), from, cursor))
+	} else if cursor < from || (remaining >= 0 && cursor > from+remaining) {
+		fmsg := "parsec: %v matched at cursor %v, returned cursor %v " +
+			"outside [%v, %v]"
+		till := from + remaining
+		panic(fmt.Errorf(fmsg, parserName(parser), from, cursor, from, till))
+	}
+	return node, news
+}
+
+// checkedCallback assert that Nodify callback is not invoked with a
+// failed node, before invoking it.
+func checkedCallback(callb Nodify, ns []ParsecNode) ParsecNode {
+	for i, n := range ns {
+		if n == nil {
+			fmsg := "parsec: %v invoked with failed node at index %v"
+			panic(fmt.Errorf(fmsg, parserName(callb), i))
+		}
+	}
+	return callb(ns)
+}
+
+func parserName(fn interface{}) string {
+	if p, ok := fn.(*Parser); ok {
+		fn = *p
+	}
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		return f.Name()
+	}
+	return fmt.Sprintf("%T", fn)
+}
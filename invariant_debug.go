@@ -0,0 +1,9 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+//go:build parsecdebug
+
+package parsec
+
+// debugInvariants enables assertions on parser invariants.
+const debugInvariants = true
@@ -0,0 +1,10 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+//go:build !parsecdebug
+
+package parsec
+
+// debugInvariants disables assertions on parser invariants, build with
+// `parsecdebug` tag to enable them.
+const debugInvariants = false
@@ -0,0 +1,85 @@
+//go:build parsecdebug
+
+package parsec
+
+import "strings"
+import "testing"
+
+type cursorScanner struct {
+	Scanner
+	cursor int
+}
+
+func (s *cursorScanner) GetCursor() int {
+	return s.cursor
+}
+
+func TestInvariants(t *testing.T) {
+	text := []byte("ab")
+	atom := Atom("a", "A")
+	backward := func(s Scanner) (ParsecNode, Scanner) {
+		return NewTerminal("B", "b", 1), NewScanner(text)
+	}
+	pasteof := func(s Scanner) (ParsecNode, Scanner) {
+		return NewTerminal("B", "b", 1), &cursorScanner{s, 10}
+	}
+	consumed := func(s Scanner) (ParsecNode, Scanner) {
+		return nil, s.SkipN(1)
+	}
+	nodify := func(ns []ParsecNode) ParsecNode { return ns }
+
+	testcases := []struct {
+		fn  func()
+		ref string
+	}{
+		{
+			func() { And(nil, atom, Parser(backward))(NewScanner(text)) },
+			"matched at cursor 1, returned cursor 0 outside [1, 2]",
+		},
+		{
+			func() { And(nil, atom, Parser(pasteof))(NewScanner(text)) },
+			"matched at cursor 1, returned cursor 10 outside [1, 2]",
+		},
+		{
+			func() { OrdChoice(nil, Parser(consumed), atom)(NewScanner(text)) },
+			"failed at cursor 0, returned cursor 1",
+		},
+		{
+			func() {
+				ast := NewAST("test", 10)
+				ast.And("AND", nil, atom, Parser(consumed))(NewScanner(text))
+			},
+			"failed at cursor 1, returned cursor 2",
+		},
+		{
+			func() { docallback(nodify, []ParsecNode{atom, nil}) },
+			"invoked with failed node at index 1",
+		},
+	}
+	for i, tcase := range testcases {
+		func() {
+			defer func() {
+				r := recover()
+				err, ok := r.(error)
+				if !ok {
+					t.Fatalf("%v: expected error, got %v", i, r)
+				}
+				msg := err.Error()
+				if !strings.HasPrefix(msg, "parsec: ") {
+					t.Errorf("%v: unexpected %q", i, msg)
+				} else if !strings.Contains(msg, "TestInvariants") {
+					t.Errorf("%v: expected parser name in %q", i, msg)
+				} else if !strings.HasSuffix(msg, tcase.ref) {
+					t.Errorf("%v: expected %q, got %q", i, tcase.ref, msg)
+				}
+			}()
+			tcase.fn()
+		}()
+	}
+
+	// well behaved parsers.
+	y := And(nil, atom, Maybe(nil, String()), Token("b", "B"))
+	if node, _ := y(NewScanner(text)); node == nil {
+		t.Errorf("expected match")
+	}
+}
@@ -4,11 +4,10 @@
 // Package json provide a parser to parse JSON string.
 package json
 
+import "bytes"
 import "strconv"
-import "unicode"
-import "fmt"
 import "unicode/utf8"
-import "unicode/utf16"
+import "fmt"
 
 import "github.com/prataprc/goparsec"
 
@@ -29,31 +28,153 @@ type Num string
 // String is alias for string type denoting JSON `string`
 type String string
 
+// JSONConfig to configure the JSON grammar, default value parses JSON
+// text as per RFC 8259.
+type JSONConfig struct {
+	// Relaxed mode enables non-standard extensions listed below, and
+	// accepts unicode punctuation, like smart quotes, in place of their
+	// ASCII equivalents, refer parsec.PunctuationTable. Contents of
+	// strings are retained as is.
+	Relaxed bool
+	// SpecialFloats in relaxed mode accepts `NaN`, `Infinity` and
+	// `-Infinity` as numbers.
+	SpecialFloats bool
+	// CanonicalNumbers make JSONString emit numbers in the same format as
+	// encoding/json, instead of the format they appeared in the source.
+	CanonicalNumbers bool
+	// TopLevel policy for the value at the top level of JSON text,
+	// default is TopLevelAny.
+	TopLevel TopLevelPolicy
+	// RejectDuplicateKeys fails Parse for objects with a repeated key,
+	// as required by JSONCanonical. Otherwise the last value of a
+	// repeated key is retained.
+	RejectDuplicateKeys bool
+}
+
+// TopLevelPolicy restricts the value at the top level of JSON text.
+// Values nested within arrays and objects are not restricted.
+type TopLevelPolicy int
+
+const (
+	// TopLevelAny accepts any value at the top level, as per RFC 8259.
+	TopLevelAny TopLevelPolicy = iota
+	// TopLevelContainer accepts only object or array at the top level,
+	// as per RFC 4627.
+	TopLevelContainer
+)
+
+func (policy TopLevelPolicy) String() string {
+	switch policy {
+	case TopLevelAny:
+		return "any"
+	case TopLevelContainer:
+		return "container"
+	}
+	return fmt.Sprintf("TopLevelPolicy(%d)", int(policy))
+}
+
 // Y is root Parser, usually called as `s` in CFG theory.
 var Y parsec.Parser
-var value parsec.Parser // circular rats
 
-// NonTerminal rats
-// values -> value | values "," value
-var values = parsec.Kleene(valuesNode, &value, comma())
+func init() {
+	Y = NewJSONParser(JSONConfig{})
+}
 
-// array -> "[" values "]"
-var array = parsec.And(arrayNode, openSqrt(), values, closeSqrt())
+// NewJSONParser return a new root parser for JSON text as per config.
+func NewJSONParser(config JSONConfig) parsec.Parser {
+	var value parsec.Parser // circular rats
 
-// property -> string ":" value
-var property = parsec.And(many2many, sTring(), colon(), &value)
+	// NonTerminal rats
+	// values -> ε | value ("," value)*
+	var values = parsec.List(valuesNode, 0, &value, comma())
 
-// properties -> property | properties "," property
-var properties = parsec.Kleene(propertiesNode, property, comma())
+	// array -> "[" values "]"
+	var array = parsec.And(arrayNode, openSqrt(), values, closeSqrt())
 
-// object -> "{" properties "}"
-var object = parsec.And(objectNode, openBrace(), properties, closeBrace())
+	// property -> string ":" value
+	var property = parsec.And(many2many, sTring(), colon(), &value)
+
+	// properties -> ε | property ("," property)*
+	var properties = propertiesParser(config,
+		parsec.List(valuesNode, 0, property, comma()))
+
+	// object -> "{" properties "}"
+	var object = parsec.And(objectNode, openBrace(), properties, closeBrace())
 
-func init() {
 	// value -> null | true | false | num | string | array | object
-	value = parsec.OrdChoice(valueNode, parsec.Parser(tokenTerm), array, object)
+	value = parsec.OrdChoice(valueNode, valueTerm(config), array, object)
+	if config.TopLevel == TopLevelContainer {
+		// expr -> array | object
+		return parsec.OrdChoice(one2one, array, object)
+	}
 	// expr  -> sum
-	Y = parsec.OrdChoice(one2one, value)
+	return parsec.OrdChoice(one2one, value)
+}
+
+// Parse JSON text as per config and return the root node. Return error
+// if text is not fully parsed, with the offset of the furthest position
+// where a token was expected.
+func Parse(text []byte, config JSONConfig) (parsec.ParsecNode, error) {
+	var offsets []int
+	if config.Relaxed {
+		text, offsets = normalizeRelaxed(text)
+	}
+	original := func(offset int) int { // offset in original text.
+		if offsets == nil {
+			return offset
+		}
+		return offsets[offset]
+	}
+
+	scanner := NewJSONScanner(text)
+	node, s := NewJSONParser(config)(scanner)
+	if node != nil {
+		s.SkipWS()
+	}
+	if node != nil && s.Endof() {
+		return node, nil
+	}
+	if off := *scanner.duplicate; off >= 0 {
+		key, _ := parsec.ScanString(text[off:])
+		fmsg := "json: duplicate key %s at offset %v"
+		return nil, fmt.Errorf(fmsg, key, original(off))
+	}
+	if off, ok := topLevelScalar(text, config); ok {
+		fmsg := "json: top-level scalar at offset %v, TopLevel policy is %v"
+		return nil, fmt.Errorf(fmsg, original(off), config.TopLevel)
+	}
+	offset := *scanner.furthest
+	if node != nil && s.GetCursor() > offset {
+		offset = s.GetCursor()
+	}
+	if literal := specialAt(text, offset); literal != "" && !config.special() {
+		fmsg := "json: unsupported literal %q at offset %v"
+		return nil, fmt.Errorf(fmsg, literal, original(offset))
+	}
+	return nil, fmt.Errorf("json: parse error at offset %v", original(offset))
+}
+
+// Value return the native golang value for parsed JSON node, numbers
+// are returned as float64, including NaN and Infinity values.
+func Value(node parsec.ParsecNode) interface{} {
+	return nativeValue(node)
+}
+
+func (config JSONConfig) special() bool {
+	return config.Relaxed && config.SpecialFloats
+}
+
+// topLevelScalar return the offset of top-level value in text, if it is
+// not an object or array and the policy does not allow it.
+func topLevelScalar(text []byte, config JSONConfig) (int, bool) {
+	if config.TopLevel != TopLevelContainer {
+		return 0, false
+	}
+	_, off := scanWS(text)
+	if off == len(text) || text[off] == '[' || text[off] == '{' {
+		return 0, false
+	}
+	return off, true
 }
 
 //----------
@@ -73,7 +194,7 @@ func valueNode(ns []parsec.ParsecNode) parsec.ParsecNode {
 			return True("true")
 		case "FALSE":
 			return False("false")
-		case "NUM":
+		case "NUM", "SPECIALFLOAT":
 			return Num(n.Value)
 		case "STRING":
 			return String(n.Value)
@@ -89,9 +210,6 @@ func valueNode(ns []parsec.ParsecNode) parsec.ParsecNode {
 }
 
 func valuesNode(ns []parsec.ParsecNode) parsec.ParsecNode {
-	if ns == nil || len(ns) < 1 {
-		return nil
-	}
 	return ns
 }
 
@@ -99,17 +217,30 @@ func arrayNode(ns []parsec.ParsecNode) parsec.ParsecNode {
 	return ns[1]
 }
 
-func propertiesNode(ns []parsec.ParsecNode) parsec.ParsecNode {
-	if ns != nil && len(ns) > 0 {
+// propertiesParser return parser for properties of an object, matched
+// by `list`, as map of key to value. If a key is repeated, fail when
+// config rejects duplicate keys, noting the key's offset with the
+// scanner, else retain the last value.
+func propertiesParser(config JSONConfig, list parsec.Parser) parsec.Parser {
+	return func(s parsec.Scanner) (parsec.ParsecNode, parsec.Scanner) {
+		ns, news := list(s)
+		if ns == nil {
+			return nil, s
+		}
 		m := make(map[string]interface{})
-		for _, n := range ns {
+		for _, n := range ns.([]parsec.ParsecNode) {
 			prop := n.([]parsec.ParsecNode)
 			key := prop[0].(*parsec.Terminal)
+			if _, ok := m[key.Value]; ok && config.RejectDuplicateKeys {
+				if sp := s.(*JSONScanner); *sp.duplicate < 0 {
+					*sp.duplicate = key.Position
+				}
+				return nil, s
+			}
 			m[key.Value] = prop[2]
 		}
-		return m
+		return m, news
 	}
-	return nil
 }
 
 func objectNode(ns []parsec.ParsecNode) parsec.ParsecNode {
@@ -124,18 +255,6 @@ var nullTerminal = &parsec.Terminal{Name: "NULL", Value: "null"}
 var trueTerminal = &parsec.Terminal{Name: "TRUE", Value: "true"}
 var falseTerminal = &parsec.Terminal{Name: "FALSE", Value: "false"}
 
-var escapeCode = [256]byte{ // TODO: size can be optimized
-	'"':  '"',
-	'\\': '\\',
-	'/':  '/',
-	'\'': '\'',
-	'b':  '\b',
-	'f':  '\f',
-	'n':  '\n',
-	'r':  '\r',
-	't':  '\t',
-}
-
 var spaceCode = [256]byte{ // TODO: size can be optimized
 	'\t': 1,
 	'\n': 1,
@@ -169,19 +288,46 @@ func init() {
 // JSONScanner implements parsec.Scanner{} interface used
 // as custom scanner for parsing JSON string.
 type JSONScanner struct {
-	buf    []byte // input buffer
-	cursor int    // cursor within input buffer
+	buf       []byte // input buffer
+	cursor    int    // cursor within input buffer
+	furthest  *int   // furthest cursor where a token was expected
+	duplicate *int   // offset of the first rejected duplicate key, or -1
+	progress  *parsec.Progress
+	interns   *parsec.Interner
 }
 
 // NewJSONScanner return a new Scanner{} interface for parsing
 // JSON string.
 func NewJSONScanner(text []byte) *JSONScanner {
+	duplicate := -1
 	return &JSONScanner{
-		buf:    text,
-		cursor: 0,
+		buf:       text,
+		cursor:    0,
+		furthest:  new(int),
+		duplicate: &duplicate,
 	}
 }
 
+// OnProgress calls `fn` as the scanner, or any of its clones, makes
+// progress, atmost once per `every` bytes. `fn` is called from the
+// parsing goroutine with the furthest position where a token was
+// expected and the length of the input text.
+func (s *JSONScanner) OnProgress(
+	every int, fn parsec.ProgressFunc) parsec.Scanner {
+
+	s.progress = parsec.NewProgress(every, fn, int64(len(s.buf)))
+	return s
+}
+
+// InternValues deduplicates the values of strings, including property
+// keys, upto `maxLen` bytes, parsed with the scanner or any of its
+// clones, so that repeated strings share the same allocation. Interned
+// values are held by the scanner, hence not shared across scanners.
+func (s *JSONScanner) InternValues(maxLen int) parsec.Scanner {
+	s.interns = parsec.NewInterner(maxLen)
+	return s
+}
+
 // SetWSPattern method receiver in Scanner interface.
 func (s *JSONScanner) SetWSPattern(pattern string) parsec.Scanner {
 	return s
@@ -195,8 +341,12 @@ func (s *JSONScanner) TrackLineno() parsec.Scanner {
 // Clone method receiver in Scanner interface.
 func (s *JSONScanner) Clone() parsec.Scanner {
 	return &JSONScanner{
-		buf:    s.buf,
-		cursor: s.cursor,
+		buf:       s.buf,
+		cursor:    s.cursor,
+		furthest:  s.furthest,
+		duplicate: s.duplicate,
+		progress:  s.progress,
+		interns:   s.interns,
 	}
 }
 
@@ -211,8 +361,14 @@ func (s *JSONScanner) Match(pattern string) ([]byte, parsec.Scanner) {
 }
 
 // MatchString method receiver in Scanner interface.
-func (s *JSONScanner) MatchString(pattern string) (bool, parsec.Scanner) {
-	return false, nil
+func (s *JSONScanner) MatchString(str string) (bool, parsec.Scanner) {
+	s.mark()
+	txt := s.buf[s.cursor:]
+	if len(txt) < len(str) || string(txt[:len(str)]) != str {
+		return false, s
+	}
+	s.cursor += len(str)
+	return true, s
 }
 
 // SubmatchAll method receiver in Scanner interface.
@@ -224,7 +380,9 @@ func (s *JSONScanner) SubmatchAll(
 
 // SkipWS method receiver in Scanner interface.
 func (s *JSONScanner) SkipWS() ([]byte, parsec.Scanner) {
-	return nil, nil
+	ws, l := scanWS(s.buf[s.cursor:])
+	s.cursor += l
+	return ws, s
 }
 
 // SkipAny method receiver in Scanner interface.
@@ -232,6 +390,20 @@ func (s *JSONScanner) SkipAny(pattern string) ([]byte, parsec.Scanner) {
 	return nil, nil
 }
 
+// TryMatch method receiver in Scanner interface.
+func (s *JSONScanner) TryMatch(pattern string) ([]byte, bool) {
+	return nil, false
+}
+
+// SkipN method receiver in Scanner interface.
+func (s *JSONScanner) SkipN(n int) parsec.Scanner {
+	if n > len(s.buf)-s.cursor {
+		n = len(s.buf) - s.cursor
+	}
+	s.cursor += n
+	return s
+}
+
 // Lineno method receiver in Scanner interface.
 func (s *JSONScanner) Lineno() int {
 	return 0
@@ -245,6 +417,27 @@ func (s *JSONScanner) Endof() bool {
 	return false
 }
 
+// BytesRemaining method receiver in Scanner interface.
+func (s *JSONScanner) BytesRemaining() int {
+	return len(s.buf) - s.cursor
+}
+
+// reset the cursor to `cursor` after a failed match, so that failing
+// parsers don't consume the whitespace they skipped.
+func (s *JSONScanner) reset(cursor int) (parsec.ParsecNode, parsec.Scanner) {
+	s.cursor = cursor
+	return nil, s
+}
+
+// mark cursor as the furthest position where a token was expected, used
+// for reporting errors.
+func (s *JSONScanner) mark() {
+	if s.furthest != nil && s.cursor > *s.furthest {
+		*s.furthest = s.cursor
+	}
+	s.progress.Update(s.cursor)
+}
+
 func colon() parsec.Parser {
 	return func(s parsec.Scanner) (parsec.ParsecNode, parsec.Scanner) {
 		return matchChar("COLON", ':', s)
@@ -284,41 +477,54 @@ func closeBrace() parsec.Parser {
 func sTring() parsec.Parser {
 	return func(s parsec.Scanner) (parsec.ParsecNode, parsec.Scanner) {
 		sp := s.(*JSONScanner)
-		txt := sp.buf[sp.cursor:]
+		start, txt := sp.cursor, sp.buf[sp.cursor:]
 		// scan for whitespace
 		_, l := scanWS(txt)
 		sp.cursor, txt = sp.cursor+l, txt[l:]
+		sp.mark()
 		if len(txt) < 1 {
-			return nil, sp
+			return sp.reset(start)
 		}
 		// scan for string
 		if txt[0] == '"' {
-			tok, ln := scanString(txt)
+			tok, ln := parsec.ScanString(txt)
 			if tok == nil {
-				return nil, sp
+				return sp.reset(start)
 			}
 			t := &parsec.Terminal{
 				Name:     "STRING",
-				Value:    string(tok[1 : len(tok)-1]),
+				Value:    sp.interns.Intern(tok[1 : len(tok)-1]),
 				Position: sp.cursor,
 			}
 			sp.cursor += ln
 			return t, sp
 		}
-		return nil, sp
+		return sp.reset(start)
+	}
+}
+
+func valueTerm(config JSONConfig) parsec.Parser {
+	if config.special() {
+		return parsec.OrdChoice(
+			one2one, parsec.SpecialFloat(), parsec.Parser(tokenTerm))
 	}
+	return tokenTerm
 }
 
 func tokenTerm(s parsec.Scanner) (parsec.ParsecNode, parsec.Scanner) {
 	sp := s.(*JSONScanner)
-	txt := sp.buf[sp.cursor:]
+	start, txt := sp.cursor, sp.buf[sp.cursor:]
 	_, l := scanWS(txt)
 	sp.cursor, txt = sp.cursor+l, txt[l:]
+	sp.mark()
 	if len(txt) < 1 {
-		return nil, sp
+		return sp.reset(start)
 	}
 
 	if digitCheck[txt[0]] == 1 {
+		if !validNumStart(txt) {
+			return sp.reset(start)
+		}
 		t := scanNum(txt, sp.cursor)
 		sp.cursor += len(t.Value)
 		return t, sp
@@ -332,7 +538,7 @@ func tokenTerm(s parsec.Scanner) (parsec.ParsecNode, parsec.Scanner) {
 			sp.cursor += 4
 			return &t, sp
 		}
-		return nil, sp
+		return sp.reset(start)
 
 	case 't':
 		if txt[1] == 'r' && txt[2] == 'u' && txt[3] == 'e' {
@@ -341,7 +547,7 @@ func tokenTerm(s parsec.Scanner) (parsec.ParsecNode, parsec.Scanner) {
 			sp.cursor += 4
 			return &t, sp
 		}
-		return nil, sp
+		return sp.reset(start)
 
 	case 'f':
 		if txt[1] == 'a' && txt[2] == 'l' && txt[3] == 's' && txt[4] == 'e' {
@@ -357,19 +563,32 @@ func tokenTerm(s parsec.Scanner) (parsec.ParsecNode, parsec.Scanner) {
 		return t, sp
 
 	case '"':
-		tok, ln := scanString(txt)
+		tok, ln := parsec.ScanString(txt)
 		if tok == nil {
-			return nil, sp
+			return sp.reset(start)
 		}
 		t := &parsec.Terminal{
 			Name:     "STRING",
-			Value:    string(tok[1 : len(tok)-1]),
+			Value:    sp.interns.Intern(tok[1 : len(tok)-1]),
 			Position: sp.cursor,
 		}
 		sp.cursor += ln
 		return t, sp
 	}
-	return nil, sp
+	return sp.reset(start)
+}
+
+// validNumStart checks that a sign or a decimal point is followed by a digit.
+func validNumStart(txt []byte) bool {
+	for _, c := range txt {
+		switch {
+		case c >= '0' && c <= '9':
+			return true
+		case c != '-' && c != '+' && c != '.':
+			return false
+		}
+	}
+	return false
 }
 
 func scanNum(txt []byte, cursor int) *parsec.Terminal {
@@ -385,110 +604,13 @@ func scanNum(txt []byte, cursor int) *parsec.Terminal {
 	}
 }
 
-func scanString(txt []byte) ([]byte, int) {
-	if len(txt) < 2 {
-		return nil, 0
-	}
-
-	e := 1
-	for txt[e] != '"' {
-		c := txt[e]
-		if c == '\\' || c == '"' || c < ' ' {
-			break
-		}
-		if c < utf8.RuneSelf {
-			e++
-			continue
-		}
-		r, size := utf8.DecodeRune(txt[e:])
-		if r == utf8.RuneError && size == 1 {
-			return nil, 0
-		}
-		e += size
-	}
-
-	if txt[e] == '"' { // done we have nothing to unquote
-		return txt[:e+1], e + 1
-	}
-
-	out := make([]byte, len(txt)+2*utf8.UTFMax)
-	oute := copy(out, txt[:e]) // copy so far
-
-loop:
-	for e < len(txt) {
-		switch c := txt[e]; {
-		case c == '"':
-			out[oute] = c
-			e++
-			break loop
-
-		case c == '\\':
-			if txt[e+1] == 'u' {
-				r := getu4(txt[e:])
-				if r < 0 { // invalid
-					return nil, 0
-				}
-				e += 6
-				if utf16.IsSurrogate(r) {
-					nextr := getu4(txt[e:])
-					dec := utf16.DecodeRune(r, nextr)
-					if dec != unicode.ReplacementChar { // A valid pair consume
-						oute += utf8.EncodeRune(out[oute:], dec)
-						e += 6
-						break loop
-					}
-					// Invalid surrogate; fall back to replacement rune.
-					r = unicode.ReplacementChar
-				}
-				oute += utf8.EncodeRune(out[oute:], r)
-
-			} else { // escaped with " \ / ' b f n r t
-				out[oute] = escapeCode[txt[e+1]]
-				e += 2
-				oute++
-			}
-
-		case c < ' ': // control character is invalid
-			return nil, 0
-
-		case c < utf8.RuneSelf: // ASCII
-			out[oute] = c
-			oute++
-			e++
-
-		default: // coerce to well-formed UTF-8
-			r, size := utf8.DecodeRune(txt[e:])
-			e += size
-			oute += utf8.EncodeRune(out[oute:], r)
-		}
-	}
-
-	if out[oute] == '"' {
-		return out[:oute+1], e
-	}
-	return nil, 0
-}
-
 func scanWS(txt []byte) ([]byte, int) {
 	for i, c := range txt {
 		if spaceCode[c] != 1 { // if !unicode.IsSpace(run) {
 			return txt[:i], i
 		}
 	}
-	return nil, 0
-}
-
-// getu4 decodes \uXXXX from the beginning of s, returning the hex value,
-// or it returns -1.
-func getu4(s []byte) rune {
-	if len(s) < 6 || s[0] != '\\' || s[1] != 'u' {
-		return -1
-	}
-	r, err := strconv.ParseUint(string(s[2:6]), 16, 64)
-	if err != nil {
-		return -1
-	}
-	return rune(r)
+	return txt, len(txt)
 }
 
 func nativeValue(m interface{}) interface{} {
@@ -528,23 +650,91 @@ func nativeValue(m interface{}) interface{} {
 	return nil
 }
 
+// specialAt return the special float literal at `offset` in text, if
+// it is a whole word, else empty string.
+func specialAt(text []byte, offset int) string {
+	literals := []string{"-Infinity", "+Infinity", "Infinity", "NaN"}
+	for _, literal := range literals {
+		if !bytes.HasPrefix(text[offset:], []byte(literal)) {
+			continue
+		}
+		if end := offset + len(literal); end == len(text) || !wordChar(text[end]) {
+			return literal
+		}
+	}
+	return ""
+}
+
+func wordChar(ch byte) bool {
+	return ch == '_' || ('0' <= ch && ch <= '9') ||
+		('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z')
+}
+
+// normalizeRelaxed is like parsec.NormalizeText, but retains the
+// contents of strings, normalizing only their delimiters. A string opened
+// by `"` is closed only by `"`, so that smart quotes within it are
+// retained, while a string opened by a smart quote can be closed by
+// either.
+func normalizeRelaxed(text []byte) ([]byte, []int) {
+	norm := make([]byte, 0, len(text))
+	offsets := make([]int, 0, len(text)+1)
+	emit := func(b []byte, i int) {
+		norm = append(norm, b...)
+		for j := range b {
+			offsets = append(offsets, i+j)
+		}
+	}
+	quote := rune(0) // delimiter that opened the string, 0 outside strings.
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRune(text[i:])
+		ascii, ok := parsec.PunctuationTable[r]
+		switch {
+		case quote == 0 && ok:
+			norm = append(norm, ascii...)
+			for range ascii {
+				offsets = append(offsets, i)
+			}
+			if ascii == `"` {
+				quote = r
+			}
+		case quote == 0:
+			emit(text[i:i+size], i)
+			if r == '"' {
+				quote = r
+			}
+		case r == '\\' && i+1 < len(text):
+			size = 2 // escaped character.
+			emit(text[i:i+size], i)
+		case r == '"' || (quote != '"' && ascii == `"`):
+			norm = append(norm, '"')
+			offsets = append(offsets, i)
+			quote = 0
+		default:
+			emit(text[i:i+size], i)
+		}
+		i += size
+	}
+	return norm, append(offsets, len(text))
+}
+
 func matchChar(
 	name string,
 	ch byte,
 	s parsec.Scanner) (parsec.ParsecNode, parsec.Scanner) {
 
 	sp := s.(*JSONScanner)
-	i, ln := sp.cursor, len(sp.buf)
+	start, i, ln := sp.cursor, sp.cursor, len(sp.buf)
 	for ; i < ln; i++ {
 		if spaceCode[sp.buf[i]] != 1 { // if !unicode.IsSpace(run) {
 			break
 		}
 	}
 	sp.cursor = i
+	sp.mark()
 	if i < ln && sp.buf[i] == ch {
 		t := &parsec.Terminal{Name: name, Value: string(ch), Position: i}
 		sp.cursor++
 		return t, sp
 	}
-	return nil, sp
+	return sp.reset(start)
 }
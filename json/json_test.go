@@ -6,8 +6,13 @@ package json
 import "encoding/json"
 import "io/ioutil"
 import "fmt"
+import "math"
+import "math/rand"
 import "reflect"
+import "strings"
+import "sync"
 import "testing"
+import "unsafe"
 
 import "github.com/prataprc/goparsec"
 
@@ -232,3 +237,369 @@ func BenchmarkEncJSONLarge(b *testing.B) {
 	}
 	b.SetBytes(int64(len(text)))
 }
+
+func TestSpecialFloats(t *testing.T) {
+	strict := JSONConfig{}
+	relaxed := JSONConfig{Relaxed: true, SpecialFloats: true}
+	refs := map[string]func(float64) bool{
+		"NaN":       math.IsNaN,
+		"Infinity":  func(f float64) bool { return math.IsInf(f, 1) },
+		"-Infinity": func(f float64) bool { return math.IsInf(f, -1) },
+	}
+	for literal, check := range refs {
+		text := []byte(`[10, ` + literal + `]`)
+		// strict mode
+		_, err := Parse(text, strict)
+		if err == nil {
+			t.Errorf("expected error for %q", literal)
+		} else if !strings.Contains(err.Error(), fmt.Sprintf("%q", literal)) {
+			t.Errorf("expected %q in error, got %v", literal, err)
+		} else if !strings.Contains(err.Error(), "offset 5") {
+			t.Errorf("expected offset in error, got %v", err)
+		}
+		if v, _ := Y(NewJSONScanner([]byte(literal))); v != nil {
+			t.Errorf("unexpected %v", v)
+		}
+		// relaxed mode
+		node, err := Parse(text, relaxed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := Value(node).([]interface{})
+		if f := values[1].(float64); !check(f) {
+			t.Errorf("unexpected value %v for %q", f, literal)
+		}
+		// special floats are not enabled by relaxed mode alone.
+		if _, err := Parse(text, JSONConfig{Relaxed: true}); err == nil {
+			t.Errorf("expected error for %q", literal)
+		}
+		// serializer
+		if _, err := JSONString(node, strict); err == nil {
+			t.Errorf("expected error for %q", literal)
+		}
+		out, err := JSONString(node, relaxed)
+		if err != nil {
+			t.Fatal(err)
+		} else if ref := `[10,` + literal + `]`; out != ref {
+			t.Errorf("expected %q, got %q", ref, out)
+		}
+		if _, err := Parse([]byte(out), relaxed); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// syntax errors before a literal are reported as such.
+	_, err := Parse([]byte(`[1,, NaN]`), strict)
+	if ref := "json: parse error at offset 3"; err == nil || err.Error() != ref {
+		t.Errorf("expected %q, got %v", ref, err)
+	}
+	// literals are whole words.
+	_, err = Parse([]byte(`[1, NaNa]`), strict)
+	if ref := "json: parse error at offset 4"; err == nil || err.Error() != ref {
+		t.Errorf("expected %q, got %v", ref, err)
+	}
+
+	// literals within strings are fine in strict mode.
+	if _, err := Parse([]byte(`["NaN", "-Infinity"]`), strict); err != nil {
+		t.Error(err)
+	}
+	// signed zero
+	node, err := Parse([]byte(`-0`), strict)
+	if err != nil {
+		t.Fatal(err)
+	} else if f := Value(node).(float64); f != 0 || !math.Signbit(f) {
+		t.Errorf("expected negative zero, got %v", f)
+	}
+}
+
+func TestListSeparators(t *testing.T) {
+	valid := map[string]interface{}{
+		`{}`:            map[string]interface{}{},
+		`[]`:            []interface{}{},
+		` [ ] `:         []interface{}{},
+		`[[], {}]`:      []interface{}{[]interface{}{}, map[string]interface{}{}},
+		`{"a": []}`:     map[string]interface{}{"a": []interface{}{}},
+		`[1, 2]`:        []interface{}{1.0, 2.0},
+		`{"a":1,"b":2}`: map[string]interface{}{"a": 1.0, "b": 2.0},
+	}
+	for text, ref := range valid {
+		node, err := Parse([]byte(text), JSONConfig{})
+		if err != nil {
+			t.Errorf("%q: %v", text, err)
+		} else if value := Value(node); !reflect.DeepEqual(value, ref) {
+			t.Errorf("%q: expected %v, got %v", text, ref, value)
+		}
+	}
+
+	invalid := map[string]int{
+		`{,}`:            1,
+		`[,]`:            1,
+		`[,1]`:           1,
+		`[1,]`:           3,
+		`[1,,2]`:         3,
+		`{"a":1,}`:       7,
+		`{"a":1,,"b":2}`: 7,
+		`[1 2]`:          3,
+		`[1] 2`:          4,
+		`[`:              1,
+	}
+	for text, offset := range invalid {
+		_, err := Parse([]byte(text), JSONConfig{})
+		ref := fmt.Sprintf("json: parse error at offset %v", offset)
+		if err == nil {
+			t.Errorf("%q: expected error", text)
+		} else if err.Error() != ref {
+			t.Errorf("%q: expected %q, got %q", text, ref, err)
+		}
+	}
+}
+
+func TestTopLevel(t *testing.T) {
+	any := JSONConfig{}
+	container := JSONConfig{TopLevel: TopLevelContainer}
+	scalars := map[string]interface{}{
+		`"hello"`: "hello",
+		`  42`:    42.0,
+		`true`:    true,
+	}
+	for text, ref := range scalars {
+		node, err := Parse([]byte(text), any)
+		if err != nil {
+			t.Errorf("%q: %v", text, err)
+		} else if value := Value(node); !reflect.DeepEqual(value, ref) {
+			t.Errorf("%q: expected %v, got %v", text, ref, value)
+		}
+		_, err = Parse([]byte(text), container)
+		off := len(text) - len(strings.TrimLeft(text, " "))
+		fmsg := "json: top-level scalar at offset %v, TopLevel policy is container"
+		if ref := fmt.Sprintf(fmsg, off); err == nil {
+			t.Errorf("%q: expected error", text)
+		} else if err.Error() != ref {
+			t.Errorf("%q: expected %q, got %q", text, ref, err)
+		}
+	}
+
+	// nested scalars are fine under both policies.
+	text := []byte(`[1, "a", true, {"b": null}]`)
+	ref := []interface{}{
+		1.0, "a", true, map[string]interface{}{"b": nil},
+	}
+	for _, config := range []JSONConfig{any, container} {
+		node, err := Parse(text, config)
+		if err != nil {
+			t.Errorf("%v: %v", config.TopLevel, err)
+		} else if value := Value(node); !reflect.DeepEqual(value, ref) {
+			t.Errorf("%v: expected %v, got %v", config.TopLevel, ref, value)
+		}
+	}
+	// invalid containers are reported as parse error.
+	_, err := Parse([]byte(`[1,]`), container)
+	if ref := "json: parse error at offset 3"; err == nil || err.Error() != ref {
+		t.Errorf("expected %q, got %v", ref, err)
+	}
+}
+
+func TestSmartQuotes(t *testing.T) {
+	text := []byte(`{“name”: “it’s”, "quote": "“a” – b", "range": [−1, 2], “x”: “a” “b”}`)
+	ref := map[string]interface{}{
+		"name":  "it’s",
+		"quote": "“a” – b",
+		"range": []interface{}{-1.0, 2.0},
+	}
+
+	// strict mode
+	_, err := Parse(text, JSONConfig{})
+	if ref := "json: parse error at offset 1"; err == nil || err.Error() != ref {
+		t.Errorf("expected %q, got %v", ref, err)
+	}
+
+	// relaxed mode, error offset is in original text.
+	_, err = Parse(text, JSONConfig{Relaxed: true})
+	offset := strings.Index(string(text), "“b”")
+	if ref := fmt.Sprintf("json: parse error at offset %v", offset); err == nil {
+		t.Errorf("expected error")
+	} else if err.Error() != ref {
+		t.Errorf("expected %q, got %q", ref, err)
+	}
+
+	text = text[:strings.Index(string(text), ", “x”")]
+	text = append(text, '}')
+	node, err := Parse(text, JSONConfig{Relaxed: true})
+	if err != nil {
+		t.Fatal(err)
+	} else if value := Value(node); !reflect.DeepEqual(value, ref) {
+		t.Errorf("expected %v, got %v", ref, value)
+	}
+}
+
+func TestOnProgress(t *testing.T) {
+	text, err := ioutil.ReadFile("./../testdata/medium.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, count := int64(0), 0
+	s := NewJSONScanner(text).OnProgress(1000, func(consumed, total int64) {
+		if total != int64(len(text)) {
+			t.Errorf("expected %v, got %v", len(text), total)
+		} else if consumed/1000 <= last/1000 {
+			t.Errorf("more than once per 1000 bytes, %v after %v", consumed, last)
+		}
+		last, count = consumed, count+1
+	})
+	if node, _ := Y(s); node == nil {
+		t.Fatalf("expected match")
+	}
+	if ref := len(text) / 1000; count != ref {
+		t.Errorf("expected %v, got %v", ref, count)
+	}
+}
+
+// unterminated strings are parse errors.
+func TestUnterminatedString(t *testing.T) {
+	for _, text := range []string{`["hello`, `["hello \"`, `["hello\`} {
+		if _, err := Parse([]byte(text), JSONConfig{}); err == nil {
+			t.Errorf("%q expected error", text)
+		}
+	}
+}
+
+func BenchmarkJSONStrings(b *testing.B) {
+	text := makeStringDoc(2000)
+	for i := 0; i < b.N; i++ {
+		Y(NewJSONScanner(text))
+	}
+	b.SetBytes(int64(len(text)))
+}
+
+// makeStringDoc return JSON array of `n` long strings, with an escape
+// sequence once in a while.
+func makeStringDoc(n int) []byte {
+	words := []string{
+		"lorem", "ipsum", "dolor", "sit", "amet", "héllo", "wörld",
+		`\n`, `\"`, `\u00e9`, `\ud83d\ude00`,
+	}
+	rnd := rand.New(rand.NewSource(1))
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteByte('"')
+		for j := 0; j < 40; j++ {
+			word := words[rnd.Intn(len(words)-4)]
+			if rnd.Intn(16) == 0 {
+				word = words[len(words)-4+rnd.Intn(4)]
+			}
+			sb.WriteString(word + " ")
+		}
+		sb.WriteByte('"')
+	}
+	sb.WriteByte(']')
+	return []byte(sb.String())
+}
+
+func TestInternValues(t *testing.T) {
+	text := []byte(`[{"name": "a", "value": 1}, {"name": "b", "value": "name"}]`)
+	s := NewJSONScanner(text).InternValues(16)
+	node, _ := Y(s)
+	if node == nil {
+		t.Fatalf("expected match")
+	}
+	ref, err := Parse(text, JSONConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	refval, val := Value(ref), Value(node)
+	if !reflect.DeepEqual(refval, val) {
+		t.Errorf("expected %v, got %v", refval, val)
+	}
+	objs := val.([]interface{})
+	obj1, obj2 := objs[0].(map[string]interface{}), objs[1].(map[string]interface{})
+	value := obj2["value"].(string)
+	for key := range obj1 {
+		if key == "name" && unsafe.StringData(key) != unsafe.StringData(value) {
+			t.Errorf("expected interned %q", key)
+		}
+	}
+	for key := range obj2 {
+		if key == "name" && unsafe.StringData(key) != unsafe.StringData(value) {
+			t.Errorf("expected interned %q", key)
+		}
+	}
+}
+
+func BenchmarkJSONLargeIntern(b *testing.B) {
+	text, _ := ioutil.ReadFile("./../testdata/large.json")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Y(NewJSONScanner(text).InternValues(64))
+	}
+	b.SetBytes(int64(len(text)))
+}
+
+// Y is a package-level variable, per-parse state shall live in the
+// scanner so that documents parsed back-to-back, or concurrently, see
+// the same results as a fresh parser.
+func TestSharedParser(t *testing.T) {
+	docs := [][]byte{
+		jsonText,
+		[]byte(`{"a": [1, 2,, 3], "b": true}`),
+		[]byte(`[null, "x", {"y": -1.5e3}]`),
+	}
+	result := func(y parsec.Parser, doc []byte) string {
+		node, s := y(NewJSONScanner(doc))
+		if node != nil {
+			s.SkipWS()
+		}
+		if node == nil || !s.Endof() {
+			return fmt.Sprintf("error at %v", s.GetCursor())
+		}
+		_, err := Parse(doc, JSONConfig{})
+		return fmt.Sprintf("%v %v", Value(node), err)
+	}
+	refs := []string{}
+	for _, doc := range docs {
+		refs = append(refs, result(NewJSONParser(JSONConfig{}), doc))
+	}
+	if refs[1] != "error at 0" {
+		t.Fatalf("expected error, got %v", refs[1])
+	}
+	_, referr := Parse(docs[1], JSONConfig{})
+	check := func() error {
+		for i, doc := range docs {
+			if got := result(Y, doc); got != refs[i] {
+				return fmt.Errorf("expected %v, got %v", refs[i], got)
+			}
+		}
+		if _, err := Parse(docs[1], JSONConfig{}); err.Error() != referr.Error() {
+			return fmt.Errorf("expected %v, got %v", referr, err)
+		}
+		return nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := check(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errch := make(chan error, 8*10)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				if err := check(); err != nil {
+					errch <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errch)
+	for err := range errch {
+		t.Error(err)
+	}
+}
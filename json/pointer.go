@@ -0,0 +1,101 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package json
+
+import "fmt"
+import "strconv"
+import "strings"
+
+import "github.com/prataprc/goparsec"
+
+// pointer -> ("/" reference)*
+var pointer = parsec.Kleene(
+	pointerNode,
+	parsec.And(
+		referenceNode,
+		parsec.AtomExact("/", "SLASH"),
+		parsec.TokenExact(`(?:[^/~]|~[01])*`, "REFERENCE"),
+	),
+)
+
+// JSONPointer parse path as JSON Pointer, RFC 6901, and return the list
+// of unescaped reference tokens. Empty path refers to the whole document
+// and return an empty list.
+func JSONPointer(path string) ([]string, error) {
+	node, s := pointer(parsec.NewScanner([]byte(path)))
+	if !s.Endof() {
+		fmsg := "json: invalid pointer %q at offset %v"
+		return nil, fmt.Errorf(fmsg, path, s.GetCursor())
+	}
+	return node.([]string), nil
+}
+
+// Query parsed JSON document `root` for the node addressed by JSON
+// Pointer. Return false if pointer is invalid or if the addressed node
+// does not exist, like missing properties and out of range indices.
+func Query(root parsec.ParsecNode, pointer string) (parsec.ParsecNode, bool) {
+	refs, err := JSONPointer(pointer)
+	if err != nil {
+		return nil, false
+	}
+	node := root
+	for _, ref := range refs {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			value, ok := n[ref]
+			if !ok {
+				return nil, false
+			}
+			node = value
+
+		case []parsec.ParsecNode:
+			index, ok := arrayIndex(ref)
+			if !ok || index >= len(n) {
+				return nil, false
+			}
+			node = n[index]
+
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+//----------
+// Nodifiers
+//----------
+
+func referenceNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	ref := ns[1].(*parsec.Terminal).Value
+	ref = strings.Replace(ref, "~1", "/", -1)
+	return strings.Replace(ref, "~0", "~", -1)
+}
+
+func pointerNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	refs := make([]string, 0, len(ns))
+	for _, n := range ns {
+		refs = append(refs, n.(string))
+	}
+	return refs
+}
+
+//----------------
+// Local functions
+//----------------
+
+// arrayIndex as per RFC 6901, leading zeros are not allowed and `-`,
+// referring to the element after the last, never exists.
+func arrayIndex(ref string) (int, bool) {
+	if ref == "" || (len(ref) > 1 && ref[0] == '0') {
+		return 0, false
+	}
+	for _, ch := range ref {
+		if ch < '0' || ch > '9' {
+			return 0, false
+		}
+	}
+	index, err := strconv.Atoi(ref)
+	return index, err == nil
+}
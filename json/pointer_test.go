@@ -0,0 +1,86 @@
+package json
+
+import "reflect"
+import "testing"
+
+func TestJSONPointer(t *testing.T) {
+	testcases := map[string][]string{
+		"":        {},
+		"/":       {""},
+		"/foo":    {"foo"},
+		"/foo/0":  {"foo", "0"},
+		"/a~1b":   {"a/b"},
+		"/m~0n":   {"m~n"},
+		"/~01":    {"~1"},
+		"/ /x//y": {" ", "x", "", "y"},
+	}
+	for path, ref := range testcases {
+		refs, err := JSONPointer(path)
+		if err != nil {
+			t.Errorf("%q: %v", path, err)
+		} else if !reflect.DeepEqual(refs, ref) {
+			t.Errorf("%q: expected %q, got %q", path, ref, refs)
+		}
+	}
+	for _, path := range []string{"foo", "/a~2", "/a~", "/a/~b"} {
+		if _, err := JSONPointer(path); err == nil {
+			t.Errorf("expected error for %q", path)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	text := []byte(`{
+		"foo": ["bar", "baz"],
+		"": 0,
+		"a/b": 1,
+		"m~n": 8,
+		"nested": {"list": [{"x": true}, {"x": null, "y": [10, 20]}]}
+	}`)
+	root, err := Parse(text, JSONConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testcases := []struct {
+		pointer string
+		ref     interface{}
+	}{
+		{"/foo/0", "bar"},
+		{"/foo/1", "baz"},
+		{"/", 0.0},
+		{"/a~1b", 1.0},
+		{"/m~0n", 8.0},
+		{"/nested/list/0/x", true},
+		{"/nested/list/1/x", nil},
+		{"/nested/list/1/y/1", 20.0},
+		{"/foo", []interface{}{"bar", "baz"}},
+	}
+	for _, tcase := range testcases {
+		node, ok := Query(root, tcase.pointer)
+		if !ok {
+			t.Errorf("%q: expected node", tcase.pointer)
+		} else if value := Value(node); !reflect.DeepEqual(value, tcase.ref) {
+			t.Errorf("%q: expected %v, got %v", tcase.pointer, tcase.ref, value)
+		}
+	}
+	if node, ok := Query(root, ""); !ok || !reflect.DeepEqual(node, root) {
+		t.Errorf("expected root node")
+	}
+
+	invalid := []string{
+		"foo",                 // invalid pointer
+		"/missing",            // missing property
+		"/foo/2",              // out of range
+		"/foo/-",              // past the last element
+		"/foo/01",             // leading zero
+		"/foo/-1",             // negative index
+		"/foo/x",              // not an index
+		"/foo/0/bar",          // into a string
+		"/nested/list/1/y/10", // out of range
+	}
+	for _, pointer := range invalid {
+		if node, ok := Query(root, pointer); ok {
+			t.Errorf("%q: unexpected %v", pointer, node)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package json
+
+import "fmt"
+import "math"
+import "sort"
+import "strconv"
+import "strings"
+import "unicode/utf16"
+
+import "github.com/prataprc/goparsec"
+
+// JSONString serialize parsed JSON node back to JSON text as per config.
+// Numbers are emitted as they appeared in the source, like `1e3` or
+// `0.5000`, unless config asks for CanonicalNumbers, and object
+// properties are sorted by key. Non-finite numbers are refused unless
+// config allows them.
+func JSONString(node parsec.ParsecNode, config JSONConfig) (string, error) {
+	var sb strings.Builder
+	if err := serialize(&sb, node, config); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func serialize(
+	sb *strings.Builder, node parsec.ParsecNode, config JSONConfig) error {
+
+	switch v := node.(type) {
+	case Null:
+		sb.WriteString("null")
+
+	case True:
+		sb.WriteString("true")
+
+	case False:
+		sb.WriteString("false")
+
+	case Num:
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err == nil && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			if !config.special() {
+				return fmt.Errorf("json: cannot serialize %q in strict mode", v)
+			}
+		} else if err == nil && config.CanonicalNumbers {
+			sb.WriteString(canonicalNumber(f))
+			break
+		}
+		sb.WriteString(string(v))
+
+	case String:
+		sb.WriteString(quoteString(string(v)))
+
+	case []parsec.ParsecNode:
+		sb.WriteByte('[')
+		for i, n := range v {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			if err := serialize(sb, n, config); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte(']')
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		sb.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(quoteString(key))
+			sb.WriteByte(':')
+			if err := serialize(sb, v[key], config); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte('}')
+
+	default:
+		return fmt.Errorf("json: cannot serialize node of type %T", node)
+	}
+	return nil
+}
+
+// JSONCanonical serialize parsed JSON node as canonical JSON, in the
+// style of RFC 8785, for signing and hashing. Output has no white
+// space, object properties are sorted by the UTF-16 code units of their
+// keys, numbers are formatted in their shortest form that round trips,
+// as in ECMAScript, and strings escape only quote, backslash and
+// control characters. Return error for values that canonical JSON does
+// not allow, like NaN and Infinity from relaxed mode. Parsed objects
+// retain only the last value of a repeated key, hence text shall be
+// parsed with RejectDuplicateKeys to reject duplicate keys.
+func JSONCanonical(node parsec.ParsecNode) ([]byte, error) {
+	var sb strings.Builder
+	if err := canonicalize(&sb, node); err != nil {
+		return nil, err
+	}
+	return []byte(sb.String()), nil
+}
+
+func canonicalize(sb *strings.Builder, node parsec.ParsecNode) error {
+	switch v := node.(type) {
+	case Null, True, False, String:
+		return serialize(sb, v, JSONConfig{})
+
+	case Num:
+		f, err := strconv.ParseFloat(string(v), 64)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("json: cannot canonicalize %q", v)
+		} else if err != nil {
+			return fmt.Errorf("json: cannot canonicalize %q: %v", v, err)
+		} else if f == 0 {
+			f = 0 // negative zero is serialized as 0.
+		}
+		sb.WriteString(canonicalNumber(f))
+
+	case []parsec.ParsecNode:
+		sb.WriteByte('[')
+		for i, n := range v {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			if err := canonicalize(sb, n); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte(']')
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return lessUTF16(keys[i], keys[j])
+		})
+		sb.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(quoteString(key))
+			sb.WriteByte(':')
+			if err := canonicalize(sb, v[key]); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte('}')
+
+	default:
+		return fmt.Errorf("json: cannot canonicalize node of type %T", node)
+	}
+	return nil
+}
+
+// lessUTF16 compare strings by their UTF-16 code units.
+func lessUTF16(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
+
+// canonicalNumber format finite `f` the same way as encoding/json.
+func canonicalNumber(f float64) string {
+	format, abs := byte('f'), math.Abs(f)
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	out := strconv.FormatFloat(f, format, -1, 64)
+	// clean up e-09 to e-9
+	if n := len(out); format == 'e' && n >= 4 && out[n-4:n-1] == "e-0" {
+		out = out[:n-2] + out[n-1:]
+	}
+	return out
+}
+
+func quoteString(str string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range str {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < ' ' {
+				fmt.Fprintf(&sb, `\u%04x`, r)
+				continue
+			}
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package json
+
+import "encoding/json"
+import "io/ioutil"
+import "reflect"
+import "testing"
+
+import "github.com/prataprc/goparsec"
+
+func TestJSONString(t *testing.T) {
+	var refs = [][2]string{
+		{`null`, `null`},
+		{` true `, `true`},
+		{`false`, `false`},
+		{`-10.50`, `-10.50`},
+		{`"say \"hello\"\n\t\u0001"`, `"say \"hello\"\n\t\u0001"`},
+		{`[1, "two", [3]]`, `[1,"two",[3]]`},
+		{`{"b": 1, "a": [true, null]}`, `{"a":[true,null],"b":1}`},
+		{`[ {}, [ ] ]`, `[{},[]]`},
+	}
+	for _, ref := range refs {
+		node, err := Parse([]byte(ref[0]), JSONConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := JSONString(node, JSONConfig{})
+		if err != nil {
+			t.Fatal(err)
+		} else if out != ref[1] {
+			t.Errorf("expected %v, got %v", ref[1], out)
+		}
+	}
+
+	// round trip medium.json
+	text, err := ioutil.ReadFile("./../testdata/medium.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := Parse(text, JSONConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := JSONString(node, JSONConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ref, val interface{}
+	json.Unmarshal(text, &ref)
+	if err := json.Unmarshal([]byte(out), &val); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(ref, val) {
+		t.Errorf("round trip mismatch")
+	}
+
+	// unsupported node
+	if _, err := JSONString([]parsec.ParsecNode{10}, JSONConfig{}); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestJSONStringNumbers(t *testing.T) {
+	refs := []string{
+		`1e3`, `1E+3`, `-2.5e-3`, `0.5000`, `100`, `-0`, `-0.0`, `1.0e21`,
+		`0.000000123`, `12345678901234567890`, `3.14159265358979323846`,
+	}
+	for _, ref := range refs {
+		node, err := Parse([]byte(ref), JSONConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// as they appeared in the source.
+		if out, err := JSONString(node, JSONConfig{}); err != nil {
+			t.Fatal(err)
+		} else if out != ref {
+			t.Errorf("expected %v, got %v", ref, out)
+		}
+		// same as encoding/json
+		config := JSONConfig{CanonicalNumbers: true}
+		out, err := JSONString(node, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(Value(node))
+		if err != nil {
+			t.Fatal(err)
+		} else if out != string(data) {
+			t.Errorf("%v: expected %s, got %v", ref, data, out)
+		}
+	}
+
+	config := JSONConfig{CanonicalNumbers: true}
+	node, err := Parse([]byte(`[1e3, 0.5000, -0.0, 1e-7]`), JSONConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := JSONString(node, config); out != `[1000,0.5,-0,1e-7]` {
+		t.Errorf("unexpected %v", out)
+	}
+
+	// special floats are emitted as is.
+	relaxed := JSONConfig{Relaxed: true, SpecialFloats: true}
+	node, err = Parse([]byte(`[NaN, -Infinity, 2.0]`), relaxed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	relaxed.CanonicalNumbers = true
+	if out, _ := JSONString(node, relaxed); out != `[NaN,-Infinity,2]` {
+		t.Errorf("unexpected %v", out)
+	}
+}
+
+func TestJSONCanonical(t *testing.T) {
+	refs := [][2]string{
+		{` { "b" : [ 1 , 2 ] , "a" : null } `, `{"a":null,"b":[1,2]}`},
+		{`[0.1, 1e21, 1e20, 1E-7, -0.0, 100.00, 4.50, 2e-3]`,
+			`[0.1,1e+21,100000000000000000000,1e-7,0,100,4.5,0.002]`},
+		{`[333333333.33333329, 1.7976931348623157e308, 5e-324]`,
+			`[333333333.3333333,1.7976931348623157e+308,5e-324]`},
+		{`"€\u0001\/é"`, "\"€\\u0001/é\""},
+		// keys sorted by UTF-16 code units, U+1F600 sorts before U+FB33.
+		{`{"דּ": 1, "😀": 2, "é": 3, "e": 4, "": 5}`,
+			"{\"\":5,\"e\":4,\"é\":3,\"\U0001F600\":2,\"דּ\":1}"},
+	}
+	for _, ref := range refs {
+		node, err := Parse([]byte(ref[0]), JSONConfig{})
+		if err != nil {
+			t.Fatalf("%v: %v", ref[0], err)
+		}
+		out, err := JSONCanonical(node)
+		if err != nil {
+			t.Fatal(err)
+		} else if string(out) != ref[1] {
+			t.Errorf("expected %v, got %v", ref[1], string(out))
+		}
+		// byte identical output across runs.
+		for i := 0; i < 10; i++ {
+			node, _ := Parse([]byte(ref[0]), JSONConfig{})
+			if again, _ := JSONCanonical(node); string(again) != string(out) {
+				t.Errorf("expected %v, got %v", string(out), string(again))
+			}
+		}
+	}
+
+	// values not allowed in canonical JSON.
+	relaxed := JSONConfig{Relaxed: true, SpecialFloats: true}
+	for _, text := range []string{`[1, NaN]`, `{"a": -Infinity}`} {
+		node, err := Parse([]byte(text), relaxed)
+		if err != nil {
+			t.Fatal(err)
+		} else if _, err := JSONCanonical(node); err == nil {
+			t.Errorf("%v: expected error", text)
+		}
+	}
+	text := []byte(`{"a": 1, "b": {"a": 2, "a": 3}}`)
+	_, err := Parse(text, JSONConfig{RejectDuplicateKeys: true})
+	if ref := `json: duplicate key "a" at offset 23`; err == nil || err.Error() != ref {
+		t.Errorf("expected %q, got %v", ref, err)
+	}
+	// otherwise duplicate keys resolve to the last value.
+	node, err := Parse(text, JSONConfig{})
+	if err != nil {
+		t.Fatal(err)
+	} else if out, _ := JSONCanonical(node); string(out) != `{"a":1,"b":{"a":3}}` {
+		t.Errorf("unexpected %s", out)
+	}
+	if v, ok := Query(node, "/b/a"); !ok || v != Num("3") {
+		t.Errorf("expected %v, got %v", 3, v)
+	} else if out, _ := JSONString(node, JSONConfig{}); out != `{"a":1,"b":{"a":3}}` {
+		t.Errorf("unexpected %v", out)
+	}
+	value := Value(node).(map[string]interface{})
+	if a := value["b"].(map[string]interface{})["a"]; a != 3.0 {
+		t.Errorf("expected %v, got %v", 3.0, a)
+	}
+}
@@ -0,0 +1,76 @@
+//go:build largeinput
+
+package parsec
+
+import "os"
+import "testing"
+
+// repeatReaderAt is a synthetic io.ReaderAt repeating `unit` for `size`
+// bytes, without holding the input in memory.
+type repeatReaderAt struct {
+	unit []byte
+	size int64
+}
+
+func (r repeatReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for n < len(p) && off+int64(n) < r.size {
+		pos := (off + int64(n)) % int64(len(r.unit))
+		n += copy(p[n:], r.unit[pos:])
+	}
+	if int64(n) > r.size-off {
+		n = int(r.size - off)
+	}
+	return n, nil
+}
+
+// TestLargeInput stream 3GiB of JSON values through ReaderAtScanner,
+// checking positions beyond 2GiB. Build with `largeinput` tag and set
+// PARSEC_LARGE_INPUT=1 in environment to run it.
+func TestLargeInput(t *testing.T) {
+	if os.Getenv("PARSEC_LARGE_INPUT") == "" {
+		t.Skip("set PARSEC_LARGE_INPUT=1 to stream 3GiB of input")
+	}
+
+	unit := []byte(`{"id": 12345, "tags": ["a", "b"], "ok": true}` + "\n")
+	count := int64(3<<30) / int64(len(unit))
+	r := repeatReaderAt{unit: unit, size: count * int64(len(unit))}
+
+	value := makejsongrammar().Rule("value")
+	s := NewScannerAt(r, 0, r.size)
+	var n int64
+	for {
+		if _, s = s.SkipWS(); s.Endof() {
+			break
+		}
+		pos := int64(s.GetCursor())
+		node, news := value(s)
+		if node == nil {
+			t.Fatalf("failed at %v", pos)
+		}
+		if want := n * int64(len(unit)); pos != want {
+			t.Fatalf("expected %v, got %v", want, pos)
+		}
+		s, n = news, n+1
+	}
+	if n != count {
+		t.Errorf("expected %v, got %v", count, n)
+	} else if off := int64(s.GetCursor()); off != r.size {
+		t.Errorf("expected %v, got %v", r.size, off)
+	}
+
+	// window of the last value, beyond 2GiB, positions are relative to the
+	// window.
+	off := r.size - int64(len(unit))
+	s = NewScannerAt(r, off, int64(len(unit)))
+	node, s := makejsongrammar().Rule("value")(s)
+	if node == nil {
+		t.Fatalf("expected match")
+	}
+	term := node.([]ParsecNode)[0].([]ParsecNode)[0].(*Terminal)
+	if term.Name != "OPENBRACE" || term.Position != 0 {
+		t.Errorf("unexpected %v %v", term.Name, term.Position)
+	} else if ro := s.(*ReaderAtScanner).ReaderOffset(); ro != off+int64(len(unit))-1 {
+		t.Errorf("expected %v, got %v", off+int64(len(unit))-1, ro)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+
+// MatcherChain is a sequence of match and skip steps applied on a
+// scanner, a lower-level alternative to combinators for extracting
+// tokens from formats that alternate between structured tokens and
+// variable-length skips, like `Name: value\r\n` in HTTP headers. Steps
+// are added by chaining the methods and applied by Result:
+//
+//	matches, s, err := NewMatcherChain(s).
+//		Match(`[A-Za-z-]+`).Match(`:`).Skip(`[ \t]*`).
+//		Match(`[^\r\n]*`).Skip(`\r\n`).Result()
+type MatcherChain struct {
+	s     Scanner
+	steps []chainStep
+}
+
+type chainStep struct {
+	pattern string // as supplied, for error messages.
+	regex   string // pattern anchored at the cursor.
+	skip    bool
+}
+
+// NewMatcherChain create a MatcherChain, without any steps, to be
+// applied on scanner `s`.
+func NewMatcherChain(s Scanner) *MatcherChain {
+	return &MatcherChain{s: s, steps: make([]chainStep, 0)}
+}
+
+// Match add a step that must match `pattern` at the cursor.
+func (mc *MatcherChain) Match(pattern string) *MatcherChain {
+	step := chainStep{pattern: pattern, regex: anchor(pattern)}
+	mc.steps = append(mc.steps, step)
+	return mc
+}
+
+// Skip add a step that skips `pattern` at the cursor, if it matches.
+func (mc *MatcherChain) Skip(pattern string) *MatcherChain {
+	step := chainStep{pattern: pattern, regex: anchor(pattern), skip: true}
+	mc.steps = append(mc.steps, step)
+	return mc
+}
+
+// SkipWS add a step that skips whitespace at the cursor, as per the
+// scanner's white space pattern.
+func (mc *MatcherChain) SkipWS() *MatcherChain {
+	mc.steps = append(mc.steps, chainStep{skip: true})
+	return mc
+}
+
+// Result apply all the steps in sequence, and return the bytes matched
+// by each step, with nil entries for skip steps, and the scanner
+// advanced past the last step. If a Match step fails, return error
+// along with the input scanner as it is.
+func (mc *MatcherChain) Result() ([][]byte, Scanner, error) {
+	matches, news := make([][]byte, 0, len(mc.steps)), mc.s.Clone()
+	for i, step := range mc.steps {
+		switch {
+		case step.skip && step.regex == "":
+			news.SkipWS()
+		case step.skip:
+			news.Match(step.regex)
+		default:
+			tok, _ := news.Match(step.regex)
+			if tok == nil {
+				fmsg := "step %v, %q does not match at offset %v"
+				return nil, mc.s, fmt.Errorf(fmsg, i, step.pattern, news.GetCursor())
+			}
+			matches = append(matches, tok)
+			continue
+		}
+		matches = append(matches, nil)
+	}
+	return matches, news, nil
+}
+
+// anchor `pattern` at the cursor as a whole, including alternations.
+func anchor(pattern string) string {
+	return "^(?:" + pattern + ")"
+}
@@ -0,0 +1,65 @@
+package parsec
+
+import "testing"
+
+func TestMatcherChain(t *testing.T) {
+	text := "Host:  example.com \r\nAccept:text/html, */*\r\n"
+	header := func(s Scanner) ([][]byte, Scanner, error) {
+		return NewMatcherChain(s).
+			Match(`[A-Za-z-]+`).Match(`:`).Skip(`[ \t]*`).
+			Match(`[^\r\n]*`).Skip(`\r\n`).Result()
+	}
+
+	s := NewScanner([]byte(text))
+	refs := [][]string{
+		{"Host", ":", "", "example.com ", ""},
+		{"Accept", ":", "", "text/html, */*", ""},
+	}
+	for _, ref := range refs {
+		matches, news, err := header(s)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(matches) != len(ref) {
+			t.Fatalf("expected %v, got %q", len(ref), matches)
+		}
+		for i, match := range matches {
+			if ref[i] == "" && match != nil {
+				t.Errorf("expected skipped step, got %q", match)
+			} else if ref[i] != "" && string(match) != ref[i] {
+				t.Errorf("expected %q, got %q", ref[i], match)
+			}
+		}
+		s = news
+	}
+	if !s.Endof() {
+		t.Errorf("expected end of text, got %v", s.GetCursor())
+	}
+
+	// SkipWS and failure.
+	s = NewScanner([]byte("  key = 10"))
+	matches, news, err := NewMatcherChain(s).
+		SkipWS().Match(`[a-z]+`).SkipWS().Match(`=`).SkipWS().Match(`[0-9]+`).
+		Result()
+	if err != nil {
+		t.Fatal(err)
+	} else if string(matches[1]) != "key" || string(matches[5]) != "10" {
+		t.Errorf("unexpected %q", matches)
+	} else if !news.Endof() {
+		t.Errorf("expected end of text, got %v", news.GetCursor())
+	}
+	s = NewScanner([]byte("key: 10"))
+	matches, news, err = NewMatcherChain(s).Match(`[a-z]+`).Match(`=`).Result()
+	if ref := "step 1, \"=\" does not match at offset 3"; err == nil {
+		t.Errorf("expected error")
+	} else if err.Error() != ref {
+		t.Errorf("expected %q, got %q", ref, err)
+	} else if matches != nil || news.GetCursor() != 0 {
+		t.Errorf("unexpected %q %v", matches, news.GetCursor())
+	}
+
+	// alternations are anchored as a whole.
+	s = NewScanner([]byte("xx POST"))
+	if _, _, err = NewMatcherChain(s).Match(`GET|POST`).Result(); err == nil {
+		t.Errorf("expected error")
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "sync/atomic"
+
+// memoids generate a unique identifier for every memoized parser.
+var memoids int64
+
+// Memo wraps parser `p` so that its result, for a given cursor position,
+// is computed only once per input text and re-used when the grammar
+// backtracks to the same position. Memoization helps grammars that do
+// a lot of backtracking, while adding overhead to grammars that don't,
+// use CollectStats to measure. Memo table is maintained by the scanner
+// and shared by all its clones, scanners that don't maintain a memo
+// table, like JSONScanner, simply apply `p`. A whole grammar can be
+// memoized using Grammar.ApplyMiddleware.
+func Memo(p Parser) Parser {
+	id := atomic.AddInt64(&memoids, 1)
+	return func(s Scanner) (ParsecNode, Scanner) {
+		ms, ok := s.(memoScanner)
+		if !ok {
+			return p(s)
+		}
+		mt, key := ms.memotable(), memoKey{id: id, cursor: s.GetCursor()}
+		if entry, ok := mt.entries[key]; ok {
+			mt.hits++
+			if entry.node == nil {
+				return nil, s
+			}
+			return entry.node, entry.scanner.Clone()
+		}
+		mt.misses++
+		node, news := p(s.Clone())
+		entry := memoEntry{node: node}
+		if node != nil {
+			entry.scanner = news.Clone()
+		} else {
+			news = s
+		}
+		if mt.entries == nil {
+			mt.entries = make(map[memoKey]memoEntry)
+		}
+		mt.entries[key] = entry
+		return node, news
+	}
+}
+
+// Stats collected by a scanner while parsing its input text.
+type Stats struct {
+	MemoHits   int64 // number of times a memoized result was re-used.
+	MemoMisses int64 // number of times a memoized parser was applied.
+	MemoSize   int   // number of entries in memo table.
+}
+
+// CollectStats return the statistics collected by scanner `s` and its
+// clones, so far. Return zero Stats if scanner does not collect them.
+func CollectStats(s Scanner) Stats {
+	ms, ok := s.(memoScanner)
+	if !ok {
+		return Stats{}
+	}
+	mt := ms.memotable()
+	return Stats{
+		MemoHits: mt.hits, MemoMisses: mt.misses, MemoSize: len(mt.entries),
+	}
+}
+
+// memoScanner is implemented by scanners maintaining a memo table.
+type memoScanner interface {
+	memotable() *memoTable
+}
+
+type memoKey struct {
+	id     int64 // identifies the memoized parser
+	cursor int
+}
+
+type memoEntry struct {
+	node    ParsecNode
+	scanner Scanner // scanner state after applying the parser
+}
+
+type memoTable struct {
+	entries      map[memoKey]memoEntry
+	hits, misses int64
+}
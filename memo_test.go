@@ -0,0 +1,98 @@
+package parsec
+
+import "io/ioutil"
+import "reflect"
+import "testing"
+
+func TestMemo(t *testing.T) {
+	jsontext, err := ioutil.ReadFile("testdata/medium.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testcases := []struct {
+		g    *Grammar
+		rule string
+		text []byte
+	}{
+		{makejsongrammar(), "value", jsontext},
+		{makearithgrammar(), "expr", arithText},
+		{makeambiguousgrammar(), "s", ambiguousText},
+	}
+	for _, tcase := range testcases {
+		ref, refs := tcase.g.Rule(tcase.rule)(NewScanner(tcase.text))
+		if ref == nil {
+			t.Fatalf("expected match for %v", tcase.rule)
+		}
+		mg := tcase.g.ApplyMiddleware(memoize)
+		newscanners := []func([]byte) Scanner{
+			NewScanner,
+			func(text []byte) Scanner { return NewScannerString(string(text)) },
+		}
+		for _, newscanner := range newscanners {
+			node, s := mg.Rule(tcase.rule)(newscanner(tcase.text))
+			if !reflect.DeepEqual(ref, node) {
+				t.Errorf("%v: memoized result differs", tcase.rule)
+			} else if s.GetCursor() != refs.GetCursor() {
+				t.Errorf("expected %v, got %v", refs.GetCursor(), s.GetCursor())
+			}
+		}
+	}
+}
+
+func TestCollectStats(t *testing.T) {
+	// without memoization
+	s := NewScanner(ambiguousText)
+	makeambiguousgrammar().Rule("s")(s)
+	if stats := CollectStats(s); stats != (Stats{}) {
+		t.Errorf("unexpected %+v", stats)
+	}
+
+	// ambiguous grammar is applied once for every position, including
+	// the end of text, and re-used by the second alternative.
+	mg := makeambiguousgrammar().ApplyMiddleware(memoize)
+	s = NewScanner(ambiguousText)
+	node, news := mg.Rule("s")(s)
+	if node == nil {
+		t.Fatalf("expected match")
+	}
+	stats, n := CollectStats(news), len(ambiguousText)
+	if stats != CollectStats(s) {
+		t.Errorf("expected clones to share stats")
+	} else if stats.MemoMisses != int64(n+1) {
+		t.Errorf("expected %v, got %v", n+1, stats.MemoMisses)
+	} else if stats.MemoHits != int64(n) {
+		t.Errorf("expected %v, got %v", n, stats.MemoHits)
+	} else if stats.MemoSize != n+1 {
+		t.Errorf("expected %v, got %v", n+1, stats.MemoSize)
+	}
+
+	// memo table is per scanner.
+	s = NewScanner(ambiguousText)
+	if stats := CollectStats(s); stats != (Stats{}) {
+		t.Errorf("unexpected %+v", stats)
+	}
+
+	// failures are memoized as well.
+	y := Memo(Atom("b", "B"))
+	s = NewScanner([]byte("a"))
+	for i := 0; i < 3; i++ {
+		if node, news := y(s); node != nil {
+			t.Errorf("unexpected %v", node)
+		} else if news != s {
+			t.Errorf("expected same scanner")
+		}
+	}
+	if stats := CollectStats(s); stats.MemoHits != 2 || stats.MemoMisses != 1 {
+		t.Errorf("unexpected %+v", stats)
+	}
+
+	// memoized result does not share scanner state with the caller.
+	y = Memo(Atom("a", "A"))
+	s = NewScanner([]byte("ab"))
+	_, s1 := y(s.Clone())
+	_, s2 := y(s.Clone())
+	s1.MatchString("b")
+	if s1.GetCursor() != 2 || s2.GetCursor() != 1 {
+		t.Errorf("unexpected %v %v", s1.GetCursor(), s2.GetCursor())
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "sync/atomic"
+
+// NodeID return the identifier of Terminal or NonTerminal `n`. Nodes
+// are assigned a monotonically increasing identifier, starting from 1,
+// when they are constructed by parsers using a scanner that was set
+// WithNodeIDs. Return false if `n` does not carry an identifier.
+func NodeID(n ParsecNode) (int64, bool) {
+	var id int64
+	switch node := n.(type) {
+	case *Terminal:
+		id = node.ID
+	case *NonTerminal:
+		id = node.ID
+	}
+	return id, id > 0
+}
+
+// InheritID copy the identifier of node `src` to node `dst`, so that
+// external data attached to `src` remains valid for `dst` when a tree
+// is rewritten. Return dst.
+func InheritID(dst, src ParsecNode) ParsecNode {
+	id, ok := NodeID(src)
+	if !ok {
+		return dst
+	}
+	switch node := dst.(type) {
+	case *Terminal:
+		node.ID = id
+	case *NonTerminal:
+		node.ID = id
+	}
+	return dst
+}
+
+// nodeIDScanner is implemented by scanners that can generate node
+// identifiers.
+type nodeIDScanner interface {
+	nodeidgen() *int64
+}
+
+// newTerminal is same as NewTerminal, assigning identifier to the node
+// if scanner `s` generates them, and noting it for WithFailureSnapshot.
+func newTerminal(s Scanner, name, value string, position int) *Terminal {
+	t := NewTerminal(name, value, position)
+	t.ID = nextNodeID(s)
+	if ft := failureTrackerOf(s); ft != nil {
+		ft.consumed(t)
+	}
+	return t
+}
+
+// newNonTerminal is same as NewNonTerminal, assigning identifier to the
+// node if scanner `s` generates them.
+func newNonTerminal(s Scanner, name string) *NonTerminal {
+	nt := NewNonTerminal(name)
+	nt.ID = nextNodeID(s)
+	return nt
+}
+
+func nextNodeID(s Scanner) int64 {
+	if ns, ok := s.(nodeIDScanner); ok {
+		if gen := ns.nodeidgen(); gen != nil {
+			return atomic.AddInt64(gen, 1)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,84 @@
+package parsec
+
+import "testing"
+
+func TestNodeID(t *testing.T) {
+	text := `[{"a": 10, "b": [1, 2]}, "x", 20]`
+	collect := func(root ParsecNode) []ParsecNode {
+		nodes := []ParsecNode{}
+		var walk func(q Queryable)
+		walk = func(q Queryable) {
+			nodes = append(nodes, q)
+			for _, child := range q.GetChildren() {
+				walk(child)
+			}
+		}
+		walk(root.(Queryable))
+		return nodes
+	}
+
+	scanners := []Scanner{
+		NewScanner([]byte(text)).(*SimpleScanner).WithNodeIDs(),
+		NewScannerString(text).(*StringScanner).WithNodeIDs(),
+	}
+	for _, s := range scanners {
+		ast := NewAST("json", 100)
+		root, _ := ast.Parsewith(makeastjson(ast), s)
+		ids := make(map[int64]bool)
+		for _, node := range collect(root) {
+			id, ok := NodeID(node)
+			if !ok {
+				t.Errorf("expected id for %v", node)
+			} else if ids[id] {
+				t.Errorf("duplicate id %v for %v", id, node)
+			}
+			ids[id] = true
+		}
+	}
+
+	// ids are preserved across a rewrite that uses InheritID.
+	ast := NewAST("json", 100)
+	s := NewScanner([]byte(text)).(*SimpleScanner).WithNodeIDs()
+	root, _ := ast.Parsewith(makeastjson(ast), s)
+	rename := func(name string) Nodify {
+		return func(ns []ParsecNode) ParsecNode {
+			nt := NewNonTerminal(name)
+			for _, n := range ns {
+				nt.Children = append(nt.Children, n.(Queryable))
+			}
+			return nt
+		}
+	}
+	rules := map[string]Nodify{"OBJECT": rename("object")}
+	node := ApplyNodify(root, rules).(Queryable).GetChildren()[1]
+	if id, ok := NodeID(node.GetChildren()[0]); ok {
+		t.Errorf("unexpected id %v", id)
+	}
+
+	root, _ = ast.Reset().Parsewith(makeastjson(ast), s.Clone())
+	values := root.GetChildren()[1]
+	refid, _ := NodeID(values.GetChildren()[0])
+	rules = map[string]Nodify{
+		"OBJECT": func(ns []ParsecNode) ParsecNode {
+			return InheritID(rename("object")(ns), values.GetChildren()[0])
+		},
+	}
+	node = ApplyNodify(root, rules).(Queryable).GetChildren()[1]
+	object := node.GetChildren()[0]
+	if id, ok := NodeID(object); !ok || id != refid {
+		t.Errorf("expected %v, got %v", refid, id)
+	} else if object.GetName() != "object" {
+		t.Errorf("unexpected %v", object.GetName())
+	}
+
+	// no ids are assigned without WithNodeIDs.
+	root, _ = ast.Reset().Parsewith(makeastjson(ast), NewScanner([]byte(text)))
+	for _, node := range collect(root) {
+		if id, ok := NodeID(node); ok || id != 0 {
+			t.Errorf("unexpected id %v for %v", id, node)
+		}
+	}
+	if id, ok := NodeID("str"); ok || id != 0 {
+		t.Errorf("unexpected id %v", id)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+import "strings"
+
+// ApplyNodify walks the tree rooted at `root`, parsed with nil
+// callbacks, and replaces every NonTerminal whose Name is in `rules`
+// with the output of its Nodify callback, applied to its Children.
+// Tree is transformed bottom-up, so callbacks receive children that are
+// already transformed. If a callback returns nil, the node is removed
+// from its parent. NonTerminal nodes are updated in place, and callback
+// output that does not implement Queryable is placed in its parent as
+// NodeValue. Return the transformed root.
+func ApplyNodify(root ParsecNode, rules map[string]Nodify) ParsecNode {
+	switch n := root.(type) {
+	case *NonTerminal:
+		children := n.Children[:0]
+		for _, child := range n.Children {
+			switch c := ApplyNodify(child, rules).(type) {
+			case nil:
+			case Queryable:
+				children = append(children, c)
+			default:
+				nv := &NodeValue{Name: child.GetName(), Node: c}
+				children = append(children, nv)
+			}
+		}
+		n.Children = children
+		if callb, ok := rules[n.Name]; ok {
+			ns := make([]ParsecNode, 0, len(children))
+			for _, child := range children {
+				if nv, ok := child.(*NodeValue); ok {
+					ns = append(ns, nv.Node)
+					continue
+				}
+				ns = append(ns, child)
+			}
+			return callb(ns)
+		}
+		return n
+
+	case []ParsecNode:
+		ns := make([]ParsecNode, 0, len(n))
+		for _, child := range n {
+			if c := ApplyNodify(child, rules); c != nil {
+				ns = append(ns, c)
+			}
+		}
+		return ns
+	}
+	return root
+}
+
+// MergeTerminals concatenate the values of *Terminal nodes in `nodes`,
+// like characters matched by Many, into a single Terminal named `name`,
+// positioned at the first terminal. Other nodes are ignored. If `nodes`
+// has no terminals, the Terminal's value is empty and its position is 0.
+// Can be used as Nodify callback:
+//
+//	ident := Many(func(ns []ParsecNode) ParsecNode {
+//		return MergeTerminals(ns, "IDENT")
+//	}, Token(`[a-z]`, "CHAR"))
+func MergeTerminals(nodes []ParsecNode, name string) *Terminal {
+	var sb strings.Builder
+	position, first := 0, true
+	for _, node := range nodes {
+		if t, ok := node.(*Terminal); ok {
+			if first {
+				position, first = t.Position, false
+			}
+			sb.WriteString(t.Value)
+		}
+	}
+	return NewTerminal(name, sb.String(), position)
+}
+
+// AstToMap convert the children of NonTerminal `node` to a map, keyed
+// by the name of child nodes. Terminal children are converted to their
+// string value and NonTerminal children are converted recursively. If
+// more than one child has the same name, their values are collected as
+// []interface{}. Missing nodes, like MaybeNone, are skipped. Children of
+// []ParsecNode are converted the same way, and a Terminal `node` is
+// converted to a map with its own name as the key. Return nil for other
+// types of nodes.
+func AstToMap(node ParsecNode) map[string]interface{} {
+	var children []ParsecNode
+	switch n := node.(type) {
+	case nil, MaybeNone:
+		return nil
+	case []ParsecNode:
+		children = n
+	case Queryable:
+		if n.IsTerminal() {
+			children = []ParsecNode{n}
+			break
+		}
+		for _, child := range n.GetChildren() {
+			children = append(children, child)
+		}
+	default:
+		return nil
+	}
+
+	m, lists := make(map[string]interface{}), make(map[string][]interface{})
+	for _, child := range children {
+		q, ok := child.(Queryable)
+		if _, none := child.(MaybeNone); !ok || none {
+			continue
+		}
+		var value interface{}
+		if nv, ok := q.(*NodeValue); ok {
+			value = nv.Node
+		} else if q.IsTerminal() {
+			value = q.GetValue()
+		} else {
+			value = AstToMap(q)
+		}
+		name := q.GetName()
+		if v, ok := m[name]; !ok {
+			m[name] = value
+		} else if list, ok := lists[name]; ok {
+			lists[name] = append(list, value)
+			m[name] = lists[name]
+		} else {
+			lists[name] = []interface{}{v, value}
+			m[name] = lists[name]
+		}
+	}
+	return m
+}
+
+// NodeValue implements Queryable interface for nodes that are not
+// Queryable, like the output of a Nodify callback applied by
+// ApplyNodify, so that they can be placed as child of NonTerminal.
+type NodeValue struct {
+	Name       string     // name of the node that was transformed.
+	Node       ParsecNode // transformed node.
+	Attributes map[string][]string
+}
+
+// GetName implement Queryable interface.
+func (nv *NodeValue) GetName() string {
+	return nv.Name
+}
+
+// IsTerminal implement Queryable interface.
+func (nv *NodeValue) IsTerminal() bool {
+	return true
+}
+
+// GetValue implement Queryable interface.
+func (nv *NodeValue) GetValue() string {
+	return fmt.Sprintf("%v", nv.Node)
+}
+
+// GetChildren implement Queryable interface.
+func (nv *NodeValue) GetChildren() []Queryable {
+	return nil
+}
+
+// GetPosition implement Queryable interface.
+func (nv *NodeValue) GetPosition() int {
+	if start, _, ok := Span(nv.Node); ok {
+		return start
+	}
+	return -1
+}
+
+// SetAttribute implement Queryable interface.
+func (nv *NodeValue) SetAttribute(attrname, value string) Queryable {
+	if nv.Attributes == nil {
+		nv.Attributes = make(map[string][]string)
+	}
+	nv.Attributes[attrname] = append(nv.Attributes[attrname], value)
+	return nv
+}
+
+// GetAttribute implement Queryable interface.
+func (nv *NodeValue) GetAttribute(attrname string) []string {
+	return nv.Attributes[attrname]
+}
+
+// GetAttributes implement Queryable interface.
+func (nv *NodeValue) GetAttributes() map[string][]string {
+	return nv.Attributes
+}
+
+// queryable return `n` as Queryable, wrapping it as NodeValue named
+// `name` if it is not.
+func queryable(n ParsecNode, name string) Queryable {
+	if q, ok := n.(Queryable); ok {
+		return q
+	}
+	return &NodeValue{Name: name, Node: n}
+}
@@ -0,0 +1,167 @@
+package parsec
+
+import "reflect"
+import "testing"
+
+func makeastjson(ast *AST) Parser {
+	var value Parser
+	comma := Atom(",", "COMMA")
+	str := Token(`"[^"]*"`, "STRING")
+	values := ast.Kleene("VALUES", nil, &value, comma)
+	array := ast.And("ARRAY", nil,
+		Atom("[", "OPENSQR"), values, Atom("]", "CLOSESQR"))
+	property := ast.And("PROPERTY", nil, str, Atom(":", "COLON"), &value)
+	properties := ast.Kleene("PROPERTIES", nil, property, comma)
+	object := ast.And("OBJECT", nil,
+		Atom("{", "OPENBRACE"), properties, Atom("}", "CLOSEBRACE"))
+	value = ast.OrdChoice("VALUE", nil, str, Int(), array, object)
+	return value
+}
+
+func TestApplyNodify(t *testing.T) {
+	text := `[{"a": 10, "b": [1, {"c": "x"}]}, 20, {}]`
+	ast := NewAST("json", 100)
+	root, _ := ast.Parsewith(makeastjson(ast), NewScanner([]byte(text)))
+	if root == nil {
+		t.Fatalf("expected match")
+	}
+
+	props := func(ns []ParsecNode) ParsecNode {
+		m := make(map[string]ParsecNode)
+		for _, n := range ns {
+			prop := n.(*NonTerminal)
+			key := prop.Children[0].GetValue()
+			value := prop.Children[2]
+			if nv, ok := value.(*NodeValue); ok {
+				m[key[1:len(key)-1]] = nv.Node
+				continue
+			}
+			m[key[1:len(key)-1]] = value
+		}
+		return m
+	}
+	node := ApplyNodify(root, map[string]Nodify{"PROPERTIES": props})
+
+	// only PROPERTIES are transformed, rest of the tree is untouched.
+	array := node.(*NonTerminal)
+	values := array.Children[1].(*NonTerminal)
+	if array.Name != "ARRAY" || values.Name != "VALUES" {
+		t.Fatalf("unexpected %v %v", array.Name, values.Name)
+	} else if len(values.Children) != 3 {
+		t.Fatalf("unexpected %v", values.Children)
+	}
+	obj := values.Children[0].(*NonTerminal)
+	m := obj.Children[1].(*NodeValue).Node.(map[string]ParsecNode)
+	if obj.Name != "OBJECT" || len(m) != 2 {
+		t.Errorf("unexpected %v %v", obj.Name, m)
+	} else if v := m["a"].(*Terminal); v.Value != "10" || v.Position != 7 {
+		t.Errorf("unexpected %v", v)
+	}
+	// inner objects are transformed before the outer ones.
+	inner := m["b"].(*NonTerminal).Children[1].(*NonTerminal).Children[1]
+	innerm := inner.(*NonTerminal).Children[1].(*NodeValue).Node
+	ref := map[string]ParsecNode{"c": NewTerminal("STRING", `"x"`, 26)}
+	if !reflect.DeepEqual(innerm, ref) {
+		t.Errorf("unexpected %v", innerm)
+	}
+	if v := values.Children[1].(*Terminal); v.Value != "20" {
+		t.Errorf("unexpected %v", v)
+	}
+	empty := values.Children[2].(*NonTerminal).Children[1].(*NodeValue)
+	if m := empty.Node.(map[string]ParsecNode); len(m) != 0 {
+		t.Errorf("unexpected %v", empty)
+	}
+
+	// callback returning nil deletes the node from its parent.
+	root, _ = ast.Reset().Parsewith(makeastjson(ast), NewScanner([]byte(text)))
+	drop := func(ns []ParsecNode) ParsecNode { return nil }
+	node = ApplyNodify(root, map[string]Nodify{"OBJECT": drop})
+	values = node.(*NonTerminal).Children[1].(*NonTerminal)
+	if len(values.Children) != 1 || values.Children[0].GetValue() != "20" {
+		t.Errorf("unexpected %v", values.Children)
+	}
+	if node = ApplyNodify(node, map[string]Nodify{"ARRAY": drop}); node != nil {
+		t.Errorf("unexpected %v", node)
+	}
+
+	// []ParsecNode from package level combinators are walked as well.
+	list := []ParsecNode{NewTerminal("INT", "1", 0), NewNonTerminal("OBJECT")}
+	node = ApplyNodify(list, map[string]Nodify{"OBJECT": drop})
+	if ns := node.([]ParsecNode); len(ns) != 1 || ns[0] != list[0] {
+		t.Errorf("unexpected %v", node)
+	}
+}
+
+func TestMergeTerminals(t *testing.T) {
+	ident := Many(func(ns []ParsecNode) ParsecNode {
+		return MergeTerminals(ns, "IDENT")
+	}, Token(`[a-z]`, "CHAR"))
+	node, s := ident(NewScanner([]byte("  abc d")))
+	term := node.(*Terminal)
+	if term.Name != "IDENT" || term.Value != "abcd" || term.Position != 2 {
+		t.Errorf("unexpected %v", term)
+	} else if !s.Endof() {
+		t.Errorf("expected end of text, got %v", s.GetCursor())
+	}
+
+	nodes := []ParsecNode{MaybeNone("missing"), NewTerminal("A", "x", 4), "y", NewTerminal("B", "z", 6)}
+	if term := MergeTerminals(nodes, "XZ"); term.Value != "xz" || term.Position != 4 {
+		t.Errorf("unexpected %v", term)
+	}
+	if term := MergeTerminals(nil, "EMPTY"); term.Value != "" || term.Position != 0 {
+		t.Errorf("unexpected %v", term)
+	}
+}
+
+func TestAstToMap(t *testing.T) {
+	text := `[{"a": 10, "b": [1, 2]}, "x", 20]`
+	ast := NewAST("json", 100)
+	root, _ := ast.Parsewith(makeastjson(ast), NewScanner([]byte(text)))
+	m := AstToMap(root)
+	ref := map[string]interface{}{
+		"OPENSQR": "[",
+		"VALUES": map[string]interface{}{
+			"OBJECT": map[string]interface{}{
+				"OPENBRACE": "{",
+				"PROPERTIES": map[string]interface{}{
+					"PROPERTY": []interface{}{
+						map[string]interface{}{
+							"STRING": `"a"`, "COLON": ":", "INT": "10",
+						},
+						map[string]interface{}{
+							"STRING": `"b"`, "COLON": ":",
+							"ARRAY": map[string]interface{}{
+								"OPENSQR":  "[",
+								"VALUES":   map[string]interface{}{"INT": []interface{}{"1", "2"}},
+								"CLOSESQR": "]",
+							},
+						},
+					},
+				},
+				"CLOSEBRACE": "}",
+			},
+			"STRING": `"x"`,
+			"INT":    "20",
+		},
+		"CLOSESQR": "]",
+	}
+	if !reflect.DeepEqual(m, ref) {
+		t.Errorf("expected %v, got %v", ref, m)
+	}
+
+	// terminals, lists and missing nodes.
+	term := NewTerminal("INT", "10", 0)
+	if m := AstToMap(term); !reflect.DeepEqual(m, map[string]interface{}{"INT": "10"}) {
+		t.Errorf("unexpected %v", m)
+	}
+	list := []ParsecNode{term, MaybeNone("missing"), "str", &NodeValue{Name: "X", Node: 1}}
+	ref = map[string]interface{}{"INT": "10", "X": 1}
+	if m := AstToMap(list); !reflect.DeepEqual(m, ref) {
+		t.Errorf("expected %v, got %v", ref, m)
+	}
+	if m := AstToMap("str"); m != nil {
+		t.Errorf("unexpected %v", m)
+	} else if m := AstToMap(MaybeNone("missing")); m != nil {
+		t.Errorf("unexpected %v", m)
+	}
+}
@@ -7,6 +7,9 @@ type NonTerminal struct {
 	Name       string      // contains terminal's token type
 	Children   []Queryable // list of children to this node.
 	Attributes map[string][]string
+	Parent     *NonTerminal // set by BuildWithParents, nil for root.
+	ID         int64        // node identifier, refer NodeID.
+	span       []int        // [start, end) when children were dropped.
 }
 
 // NewNonTerminal create and return a new NonTerminal instance.
@@ -46,6 +49,9 @@ func (nt *NonTerminal) GetChildren() []Queryable {
 
 // GetPosition implement Queryable interface.
 func (nt *NonTerminal) GetPosition() int {
+	if nt.span != nil {
+		return nt.span[0]
+	}
 	if nodes := nt.GetChildren(); len(nodes) > 0 {
 		return nodes[0].GetPosition()
 	}
@@ -80,3 +86,37 @@ func (nt *NonTerminal) GetAttribute(attrname string) []string {
 func (nt *NonTerminal) GetAttributes() map[string][]string {
 	return nt.Attributes
 }
+
+// Ancestor return the nearest ancestor of nt named `name`, or nil if
+// there is none. Parent references are set by BuildWithParents.
+func (nt *NonTerminal) Ancestor(name string) *NonTerminal {
+	for parent := nt.Parent; parent != nil; parent = parent.Parent {
+		if parent.Name == name {
+			return parent
+		}
+	}
+	return nil
+}
+
+// BuildWithParents traverse the tree rooted at `root` and set Parent of
+// every NonTerminal to the NonTerminal containing it as child, enabling
+// bottom-up traversal. NonTerminals that are not a child of another
+// NonTerminal, like root, shall have nil Parent. Return root.
+func BuildWithParents(root ParsecNode) ParsecNode {
+	setParents(root, nil)
+	return root
+}
+
+func setParents(node ParsecNode, parent *NonTerminal) {
+	switch n := node.(type) {
+	case *NonTerminal:
+		n.Parent = parent
+		for _, child := range n.Children {
+			setParents(child, n)
+		}
+	case []ParsecNode:
+		for _, child := range n {
+			setParents(child, nil)
+		}
+	}
+}
@@ -37,3 +37,59 @@ func TestNonTerminal(t *testing.T) {
 		t.Errorf("expected %v, got %v", ref2, x)
 	}
 }
+
+func TestBuildWithParents(t *testing.T) {
+	text := `[{"a": [1, {"b": 2}]}]`
+	ast := NewAST("json", 100)
+	root, _ := ast.Parsewith(makeastjson(ast), NewScanner([]byte(text)))
+	if node := BuildWithParents(root); node != root {
+		t.Fatalf("expected root, got %v", node)
+	}
+
+	var check func(nt *NonTerminal)
+	check = func(nt *NonTerminal) {
+		for _, child := range nt.Children {
+			if c, ok := child.(*NonTerminal); ok {
+				if c.Parent != nt {
+					t.Errorf("%v expected parent %v, got %v", c.Name, nt.Name, c.Parent)
+				}
+				check(c)
+			}
+		}
+	}
+	if nt := root.(*NonTerminal); nt.Parent != nil {
+		t.Errorf("unexpected %v", nt.Parent)
+	} else {
+		check(nt)
+	}
+
+	// innermost PROPERTY finds its ancestors bottom-up.
+	var inner *NonTerminal
+	var find func(q Queryable)
+	find = func(q Queryable) {
+		if nt, ok := q.(*NonTerminal); ok && nt.Name == "PROPERTY" {
+			inner = nt
+		}
+		for _, child := range q.GetChildren() {
+			find(child)
+		}
+	}
+	find(root)
+	if prop := inner.Ancestor("PROPERTY"); prop == nil {
+		t.Errorf("expected outer PROPERTY")
+	} else if key := prop.Children[0].GetValue(); key != `"a"` {
+		t.Errorf("unexpected %v", key)
+	} else if prop.Ancestor("PROPERTY") != nil {
+		t.Errorf("unexpected ancestor")
+	} else if array := inner.Ancestor("ARRAY"); array == root {
+		t.Errorf("expected inner ARRAY")
+	} else if array.Ancestor("ARRAY") != root {
+		t.Errorf("expected %v", root)
+	}
+
+	// nodes returned to the pool forget their parent.
+	ast.Reset()
+	if nt := ast.getnt("x", nil); nt.Parent != nil {
+		t.Errorf("unexpected %v", nt.Parent)
+	}
+}
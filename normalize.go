@@ -0,0 +1,89 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "sort"
+import "unicode/utf8"
+
+// PunctuationTable maps unicode punctuation, typically introduced by
+// word processors, to their ASCII equivalents. Used by NormalizeText
+// and scanners set to NormalizePunctuation. Applications can add or
+// remove entries before creating the scanner.
+var PunctuationTable = map[rune]string{
+	'‘':      "'", // left single quotation mark
+	'’':      "'", // right single quotation mark
+	'‚':      "'", // single low-9 quotation mark
+	'‛':      "'", // single high-reversed-9 quotation mark
+	'′':      "'", // prime
+	'“':      `"`, // left double quotation mark
+	'”':      `"`, // right double quotation mark
+	'„':      `"`, // double low-9 quotation mark
+	'‟':      `"`, // double high-reversed-9 quotation mark
+	'″':      `"`, // double prime
+	'‐':      "-", // hyphen
+	'‑':      "-", // non-breaking hyphen
+	'‒':      "-", // figure dash
+	'–':      "-", // en dash
+	'—':      "-", // em dash
+	'−':      "-", // minus sign
+	'\u00a0': " ", // no-break space
+}
+
+// NormalizeText replace unicode punctuation in `text`, listed in
+// PunctuationTable, with their ASCII equivalents. Return the normalized
+// text, and the offset in `text` for every offset in normalized text,
+// including its end. Note that punctuation within quoted strings is
+// normalized as well.
+func NormalizeText(text []byte) ([]byte, []int) {
+	norm := make([]byte, 0, len(text))
+	offsets := make([]int, 0, len(text)+1)
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRune(text[i:])
+		if ascii, ok := PunctuationTable[r]; ok {
+			norm = append(norm, ascii...)
+			for range ascii {
+				offsets = append(offsets, i)
+			}
+		} else {
+			norm = append(norm, text[i:i+size]...)
+			for j := 0; j < size; j++ {
+				offsets = append(offsets, i+j)
+			}
+		}
+		i += size
+	}
+	return norm, append(offsets, len(text))
+}
+
+// NormalizePunctuation match patterns and strings against input text
+// with unicode punctuation replaced by their ASCII equivalents, refer
+// NormalizeText. Matched tokens are returned in normalized form, while
+// cursor and positions of terminals refer to the original text, use
+// RawValue to get the original bytes of a terminal.
+func (s *SimpleScanner) NormalizePunctuation() Scanner {
+	text, offsets := NormalizeText(s.matchbuf())
+	s.norm = &normalized{text: text, offsets: offsets}
+	return s
+}
+
+// RawValue return the bytes in input text from which terminal `t` was
+// matched, which may differ from its value if the scanner was set to
+// NormalizePunctuation.
+func (s *SimpleScanner) RawValue(t *Terminal) []byte {
+	start, end := t.Position, t.Position+len(t.Value)
+	if s.norm != nil {
+		end = s.norm.offsets[s.norm.index(start)+len(t.Value)]
+	}
+	return s.buf[start:end]
+}
+
+type normalized struct {
+	text    []byte // normalized input text
+	offsets []int  // offset in input text for every offset in text.
+}
+
+// index return the offset in normalized text for `offset` in input text.
+func (norm *normalized) index(offset int) int {
+	return sort.SearchInts(norm.offsets, offset)
+}
@@ -0,0 +1,60 @@
+package parsec
+
+import "testing"
+
+func TestNormalizePunctuation(t *testing.T) {
+	text := []byte("key = “hello world” – 10 ‑ ‘x’")
+	str := Token(`"[^"]*"`, "STRING")
+	dash := Token(`-`, "DASH")
+	char := Token(`'.'`, "CHAR")
+	y := And(nil, Ident(), Atom("=", "EQUAL"), str, dash, Int(), dash, char)
+
+	// strict mode is unaffected.
+	if node, _ := y(NewScanner(text)); node != nil {
+		t.Errorf("unexpected %v", node)
+	}
+
+	s := NewScanner(text).(*SimpleScanner)
+	node, news := y(s.NormalizePunctuation())
+	if node == nil || !news.Endof() {
+		t.Fatalf("expected match, got %v at %v", node, news.GetCursor())
+	}
+	nodes := node.([]ParsecNode)
+	refs := []struct {
+		value, raw string
+		pos        int
+	}{
+		{`"hello world"`, "“hello world”", 6},
+		{"-", "–", 24},
+		{"10", "10", 28},
+		{"-", "‑", 31},
+		{"'x'", "‘x’", 35},
+	}
+	for i, ref := range refs {
+		term := nodes[i+2].(*Terminal)
+		if term.Value != ref.value || term.Position != ref.pos {
+			t.Errorf("expected %v, got %v %v", ref, term.Value, term.Position)
+		} else if raw := string(s.RawValue(term)); raw != ref.raw {
+			t.Errorf("expected %q, got %q", ref.raw, raw)
+		} else if raw != string(text[ref.pos:ref.pos+len(raw)]) {
+			t.Errorf("unexpected %q", raw)
+		}
+	}
+
+	// String parser on normalized text.
+	s = NewScanner([]byte(` “a\"b”`)).(*SimpleScanner)
+	if v, news := String()(s.NormalizePunctuation()); v != `"a"b"` {
+		t.Errorf("expected %q, got %v", `"a"b"`, v)
+	} else if !news.Endof() {
+		t.Errorf("expected end of text, got %v", news.GetCursor())
+	}
+
+	// SkipN and MatchString count normalized bytes.
+	s = NewScanner([]byte("—x—")).(*SimpleScanner)
+	news = s.NormalizePunctuation().SkipN(1)
+	if news.GetCursor() != 3 {
+		t.Errorf("expected %v, got %v", 3, news.GetCursor())
+	} else if ok, news := news.MatchString("x-"); !ok || !news.Endof() {
+		t.Errorf("unexpected %v %v", ok, news.GetCursor())
+	}
+}
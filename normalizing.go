@@ -0,0 +1,129 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+// NormalizingScanner wrap scanner `s`, and its clones, to skip white
+// space, as per its white space pattern, before every match attempt, so
+// that grammars allowing arbitrary white space between any two tokens,
+// like SQL, can be written with parsers that don't skip white space,
+// like TokenExact. White space is skipped only if the match succeeds,
+// and is discarded, only the matched tokens are returned. GetCursor,
+// Lineno and the position of tokens refer to the next token, and Endof
+// is true if only white space remains. WordBoundary checks the text
+// right after the previous token.
+func NormalizingScanner(s Scanner) Scanner {
+	return &normalizingScanner{Scanner: s}
+}
+
+type normalizingScanner struct {
+	Scanner
+	next Scanner // clone of Scanner past white space, if not nil.
+}
+
+// SetWSPattern implement Scanner{} interface.
+func (s *normalizingScanner) SetWSPattern(pattern string) Scanner {
+	s.Scanner, s.next = s.Scanner.SetWSPattern(pattern), nil
+	return s
+}
+
+// TrackLineno implement Scanner{} interface.
+func (s *normalizingScanner) TrackLineno() Scanner {
+	s.Scanner, s.next = s.Scanner.TrackLineno(), nil
+	return s
+}
+
+// Clone implement Scanner{} interface.
+func (s *normalizingScanner) Clone() Scanner {
+	news := &normalizingScanner{Scanner: s.Scanner.Clone()}
+	if s.next != nil {
+		news.next = s.next.Clone()
+	}
+	return news
+}
+
+// GetCursor implement Scanner{} interface.
+func (s *normalizingScanner) GetCursor() int {
+	return s.skipped().GetCursor()
+}
+
+// Match implement Scanner{} interface.
+func (s *normalizingScanner) Match(pattern string) ([]byte, Scanner) {
+	token, news := s.skipped().Match(pattern)
+	s.adopt(news, token != nil)
+	return token, s
+}
+
+// MatchString implement Scanner{} interface.
+func (s *normalizingScanner) MatchString(str string) (bool, Scanner) {
+	ok, news := s.skipped().MatchString(str)
+	s.adopt(news, ok)
+	return ok, s
+}
+
+// SubmatchAll implement Scanner{} interface.
+func (s *normalizingScanner) SubmatchAll(pattern string) (map[string][]byte, Scanner) {
+	captures, news := s.skipped().SubmatchAll(pattern)
+	s.adopt(news, captures != nil)
+	return captures, s
+}
+
+// SkipWS implement Scanner{} interface.
+func (s *normalizingScanner) SkipWS() ([]byte, Scanner) {
+	token, news := s.Scanner.SkipWS()
+	s.Scanner, s.next = news, nil
+	return token, s
+}
+
+// SkipAny implement Scanner{} interface.
+func (s *normalizingScanner) SkipAny(pattern string) ([]byte, Scanner) {
+	token, news := s.skipped().SkipAny(pattern)
+	s.adopt(news, token != nil)
+	return token, s
+}
+
+// TryMatch implement Scanner{} interface.
+func (s *normalizingScanner) TryMatch(pattern string) ([]byte, bool) {
+	return s.skipped().TryMatch(pattern)
+}
+
+// SkipN implement Scanner{} interface.
+func (s *normalizingScanner) SkipN(n int) Scanner {
+	if n > 0 {
+		s.Scanner, s.next = s.skipped().SkipN(n), nil
+	}
+	return s
+}
+
+// Lineno implement Scanner{} interface.
+func (s *normalizingScanner) Lineno() int {
+	return s.skipped().Lineno()
+}
+
+// Endof implement Scanner{} interface.
+func (s *normalizingScanner) Endof() bool {
+	return s.skipped().Endof()
+}
+
+// BytesRemaining implement Scanner{} interface.
+func (s *normalizingScanner) BytesRemaining() int {
+	return s.skipped().BytesRemaining()
+}
+
+// skipped return a clone of the wrapped scanner past white space,
+// cached till the wrapped scanner moves. Failed matches on the clone
+// leave its cursor as is, hence it is used for matching as well.
+func (s *normalizingScanner) skipped() Scanner {
+	if s.next == nil {
+		_, s.next = s.Scanner.Clone().SkipWS()
+	}
+	return s.next
+}
+
+// adopt `news` if the match succeeded, else the cursor is left before
+// the white space.
+func (s *normalizingScanner) adopt(news Scanner, ok bool) {
+	if ok {
+		s.Scanner, s.next = news, nil
+	}
+}
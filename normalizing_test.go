@@ -0,0 +1,68 @@
+package parsec
+
+import "testing"
+
+func TestNormalizingScanner(t *testing.T) {
+	kw := func(word string) Parser { return AtomExact(word, word) }
+	ident := TokenExact(`[a-z_]+`, "IDENT")
+	columns := List(nil, 1, ident, kw(","))
+	y := And(nil, kw("SELECT"), columns, kw("FROM"), ident, kw(";"))
+
+	text := "  SELECT id,\n\tname ,email\nFROM\n  users ; \n"
+	s := NormalizingScanner(NewScanner([]byte(text)))
+	node, news := y(s)
+	if node == nil {
+		t.Fatalf("expected match")
+	} else if !news.Endof() {
+		t.Errorf("expected end of text, got %v", news.GetCursor())
+	}
+	ns := node.([]ParsecNode)
+	refs := []struct {
+		value string
+		pos   int
+	}{{"id", 9}, {"name", 14}, {"email", 20}}
+	cols := ns[1].([]ParsecNode)
+	if len(cols) != len(refs) {
+		t.Fatalf("expected %v, got %v", len(refs), len(cols))
+	}
+	for i, col := range cols {
+		term, ref := col.(*Terminal), refs[i]
+		if term.Value != ref.value || term.Position != ref.pos {
+			t.Errorf("expected %q at %v, got %q at %v",
+				ref.value, ref.pos, term.Value, term.Position)
+		}
+	}
+	if table := ns[3].(*Terminal); table.Value != "users" || table.Position != 33 {
+		t.Errorf("unexpected %v", table)
+	}
+
+	// failed match does not move the cursor.
+	s = NormalizingScanner(NewScanner([]byte(" SELECT")))
+	if s.GetCursor() != 1 {
+		t.Errorf("expected %v, got %v", 1, s.GetCursor())
+	}
+	if tok, news := s.Match(`^FROM`); tok != nil || news.GetCursor() != 1 {
+		t.Errorf("unexpected %q at %v", tok, news.GetCursor())
+	}
+	// failed match leaves white space before the cursor unskipped.
+	s = NormalizingScanner(NewScanner([]byte("id  FROM")))
+	s.Match(`^id`)
+	if tok, _ := s.TryMatch(`^[A-Z]+`); string(tok) != "FROM" {
+		t.Errorf("unexpected %q", tok)
+	} else if tok, news := s.Match(`^[0-9]+`); tok != nil || news.GetCursor() != 4 {
+		t.Errorf("unexpected %q at %v", tok, news.GetCursor())
+	} else if tok, _ := news.SkipWS(); string(tok) != "  " {
+		t.Errorf("unexpected %q", tok)
+	}
+	// boundary checks see the text right after a token.
+	word := AtBoundary(kw("SELECT"), WordBoundary)
+	for text, ok := range map[string]bool{"SELECT id": true, "SELECTid": false} {
+		if node, _ := word(NormalizingScanner(NewScanner([]byte(text)))); (node != nil) != ok {
+			t.Errorf("%q: unexpected %v", text, node)
+		}
+	}
+	// without normalisation, TokenExact does not skip white space.
+	if node, _ := y(NewScanner([]byte(text))); node != nil {
+		t.Errorf("unexpected %v", node)
+	}
+}
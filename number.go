@@ -0,0 +1,131 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "strconv"
+import "strings"
+
+// NumberSpec configures the number literals matched by
+// ConfigurableNumber. Zero value matches strict JSON numbers without
+// exponent, that is, optional minus sign, integer part without leading
+// zeros and optional fraction.
+type NumberSpec struct {
+	LeadingPlus  bool // allow `+` sign, like `+10`.
+	Exponent     bool // allow exponent, like `1.5e-3`.
+	Hex          bool // allow hexadecimal integers, like `0x1F`.
+	Underscores  bool // allow underscores between digits, like `1_000`.
+	LeadingZeros bool // allow leading zeros, like `007`.
+}
+
+// JSONNumbers is the NumberSpec for number literals in JSON.
+var JSONNumbers = NumberSpec{Exponent: true}
+
+// NumberNode is returned by ConfigurableNumber, it is a Terminal named
+// INT, FLOAT or HEX, with the matched text as its value, along with its
+// typed value as Number.
+type NumberNode struct {
+	*Terminal
+	Number interface{} // int64, or float64 for floats and out of range ints.
+}
+
+// ConfigurableNumber return parser function to match a number literal
+// as per `spec`. The literal shall not be followed by a letter, digit or
+// underscore, so that `012` is not matched as `0` when leading zeros are
+// not allowed. Return *NumberNode. Skip leading whitespace.
+func ConfigurableNumber(spec NumberSpec) Parser {
+	pattern := "^" + spec.pattern()
+	return func(s Scanner) (ParsecNode, Scanner) {
+		news := s.Clone()
+		news.SkipWS()
+		cursor := news.GetCursor()
+		tok, _ := news.Match(pattern)
+		if tok == nil {
+			return nil, s
+		} else if _, ok := news.TryMatch(`^[0-9A-Za-z_]`); ok {
+			return nil, s
+		}
+		name, number := parseNumber(string(tok))
+		t := newTerminal(news, name, tokenValue(news, tok), cursor)
+		return &NumberNode{Terminal: t, Number: number}, news
+	}
+}
+
+// FlexibleNumber return parser function to match a number literal as
+// per JSONNumbers, either bare, like `42`, or within double quotes, like
+// `"42"`, as found in data from inconsistent sources. Quoted value shall
+// be a number literal in its entirety, otherwise FlexibleNumber fails
+// without consuming the input. Return *NumberNode for both, with the
+// literal, without quotes, as its value and position. Skip leading
+// whitespace.
+func FlexibleNumber() Parser {
+	bare := ConfigurableNumber(JSONNumbers)
+	quoted := `^"` + JSONNumbers.pattern() + `"`
+	return func(s Scanner) (ParsecNode, Scanner) {
+		if node, news := bare(s); node != nil {
+			return node, news
+		}
+		news := s.Clone()
+		news.SkipWS()
+		cursor := news.GetCursor()
+		tok, _ := news.Match(quoted)
+		if tok == nil {
+			return nil, s
+		}
+		tok = tok[1 : len(tok)-1]
+		name, number := parseNumber(string(tok))
+		t := newTerminal(news, name, tokenValue(news, tok), cursor+1)
+		return &NumberNode{Terminal: t, Number: number}, news
+	}
+}
+
+func (spec NumberSpec) pattern() string {
+	digits, hexdigits := `[0-9]+`, `[0-9a-fA-F]+`
+	if spec.Underscores {
+		digits, hexdigits = `[0-9](?:_?[0-9])*`, `[0-9a-fA-F](?:_?[0-9a-fA-F])*`
+	}
+	integer := digits
+	if !spec.LeadingZeros {
+		integer = `(?:0|[1-9](?:_?[0-9])*)`
+		if !spec.Underscores {
+			integer = `(?:0|[1-9][0-9]*)`
+		}
+	}
+	sign := `-?`
+	if spec.LeadingPlus {
+		sign = `[+-]?`
+	}
+	decimal := integer + `(?:\.` + digits + `)?`
+	if spec.Exponent {
+		decimal += `(?:[eE][+-]?` + digits + `)?`
+	}
+	if spec.Hex {
+		return sign + `(?:0[xX]` + hexdigits + `|` + decimal + `)`
+	}
+	return sign + decimal
+}
+
+// parseNumber return the terminal name and typed value for literal.
+func parseNumber(literal string) (string, interface{}) {
+	text := strings.Replace(literal, "_", "", -1)
+	sign, digits := "", strings.TrimLeft(text, "+-")
+	if strings.HasPrefix(text, "-") {
+		sign = "-"
+	}
+	if len(digits) > 1 && (digits[1] == 'x' || digits[1] == 'X') {
+		if i, err := strconv.ParseInt(sign+digits[2:], 16, 64); err == nil {
+			return "HEX", i
+		}
+		f, _ := strconv.ParseFloat(sign+digits+"p0", 64)
+		return "HEX", f
+	}
+	if !strings.ContainsAny(digits, ".eE") {
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return "INT", i
+		}
+		f, _ := strconv.ParseFloat(text, 64)
+		return "INT", f
+	}
+	f, _ := strconv.ParseFloat(text, 64)
+	return "FLOAT", f
+}
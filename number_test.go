@@ -0,0 +1,98 @@
+package parsec
+
+import "testing"
+
+func TestConfigurableNumber(t *testing.T) {
+	relaxed := NumberSpec{
+		LeadingPlus: true, Exponent: true, Hex: true,
+		Underscores: true, LeadingZeros: true,
+	}
+	testcases := []struct {
+		spec    NumberSpec
+		text    string
+		name    string
+		number  interface{}
+		matched bool
+	}{
+		{JSONNumbers, "10", "INT", int64(10), true},
+		{JSONNumbers, " -0.5", "FLOAT", -0.5, true},
+		{JSONNumbers, "1.5e3", "FLOAT", 1500.0, true},
+		{JSONNumbers, "+1", "", nil, false},
+		{JSONNumbers, "012", "", nil, false},
+		{JSONNumbers, "0x1F", "", nil, false},
+		{JSONNumbers, "1_000", "", nil, false},
+		{NumberSpec{}, "1e3", "", nil, false},
+		{NumberSpec{LeadingPlus: true}, "+1", "INT", int64(1), true},
+		{NumberSpec{LeadingZeros: true}, "012", "INT", int64(12), true},
+		{NumberSpec{Hex: true}, "-0x1F", "HEX", int64(-31), true},
+		{NumberSpec{Hex: true}, "0xG", "", nil, false},
+		{NumberSpec{Underscores: true}, "1_000.0_1", "FLOAT", 1000.01, true},
+		{NumberSpec{Underscores: true}, "1__000", "", nil, false},
+		{NumberSpec{Underscores: true}, "1000_", "", nil, false},
+		{relaxed, "+0_0_7", "INT", int64(7), true},
+		{relaxed, "0xFF_FF", "HEX", int64(0xffff), true},
+		{relaxed, "1_0e1_0", "FLOAT", 1e11, true},
+		{relaxed, "0x8000000000000000", "HEX", float64(1 << 63), true},
+		{relaxed, "99999999999999999999", "INT", 1e20, true},
+	}
+	for _, tcase := range testcases {
+		s := NewScanner([]byte(tcase.text))
+		node, news := ConfigurableNumber(tcase.spec)(s)
+		if !tcase.matched {
+			if node != nil || news.GetCursor() != 0 {
+				t.Errorf("%q: unexpected match %v", tcase.text, node)
+			}
+			continue
+		}
+		n, ok := node.(*NumberNode)
+		if !ok {
+			t.Errorf("%q: unexpected %T", tcase.text, node)
+			continue
+		}
+		value := tcase.text[len(tcase.text)-len(n.Value):]
+		if n.Name != tcase.name || n.Value != value {
+			t.Errorf("%q: unexpected %v %q", tcase.text, n.Name, n.Value)
+		} else if n.Number != tcase.number {
+			t.Errorf("%q: expected %v, got %v", tcase.text, tcase.number, n.Number)
+		} else if !news.Endof() {
+			t.Errorf("%q: expected end of text, got %v", tcase.text, news.GetCursor())
+		}
+	}
+}
+
+func TestFlexibleNumber(t *testing.T) {
+	testcases := []struct {
+		text     string
+		name     string
+		number   interface{}
+		position int
+	}{
+		{"42", "INT", int64(42), 0},
+		{` "42"`, "INT", int64(42), 2},
+		{`"3.14"`, "FLOAT", 3.14, 1},
+		{"-1e2", "FLOAT", -100.0, 0},
+	}
+	for _, tcase := range testcases {
+		node, s := FlexibleNumber()(NewScanner([]byte(tcase.text)))
+		n, ok := node.(*NumberNode)
+		if !ok {
+			t.Errorf("%q: unexpected %T", tcase.text, node)
+			continue
+		}
+		if n.Name != tcase.name || n.Number != tcase.number {
+			t.Errorf("%q: unexpected %v %v", tcase.text, n.Name, n.Number)
+		} else if n.Position != tcase.position {
+			t.Errorf("%q: expected %v, got %v", tcase.text, tcase.position, n.Position)
+		} else if !s.Endof() {
+			t.Errorf("%q: expected end of text, got %v", tcase.text, s.GetCursor())
+		}
+	}
+
+	// quoted value that is not a number.
+	for _, text := range []string{`"abc"`, `"42abc"`, `" 42"`, `"42`, `""`} {
+		node, s := FlexibleNumber()(NewScanner([]byte(text)))
+		if node != nil || s.GetCursor() != 0 {
+			t.Errorf("%q: unexpected match %v", text, node)
+		}
+	}
+}
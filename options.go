@@ -0,0 +1,143 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+// ScannerOption configures the scanner created by NewScannerWith. Options
+// are independent of each other, hence can be supplied in any order.
+type ScannerOption func(*SimpleScanner)
+
+// Limits on the work done by parsers, for untrusted input, refer
+// WithLimits.
+type Limits struct {
+	// MaxBacktrack is the number of bytes the scanner, and its clones,
+	// can rewind from the furthest position reached, refer
+	// WithMaxBacktrack. Zero means no limit.
+	MaxBacktrack int
+	// BacktrackBudget is the cumulative cost of backtracking, refer
+	// WithBacktrackBudget. Zero means no limit.
+	BacktrackBudget int64
+}
+
+// WithWS set the white space pattern skipped by SkipWS, same as
+// SetWSPattern.
+func WithWS(pattern string) ScannerOption {
+	return func(s *SimpleScanner) {
+		s.wsPattern = continuationPattern(pattern, s.continuation)
+	}
+}
+
+// WithLineno track line number as cursor moves forward, same as
+// TrackLineno.
+func WithLineno() ScannerOption {
+	return func(s *SimpleScanner) {
+		s.tracklineno = true
+	}
+}
+
+// WithCaseFolding match patterns and strings against lower-cased input
+// text, refer NewFoldingScanner.
+func WithCaseFolding() ScannerOption {
+	return func(s *SimpleScanner) {
+		s.fold = foldbytes(s.buf)
+	}
+}
+
+// WithProgress calls `fn` as the scanner, or any of its clones,
+// consumes input text, atmost once per `every` bytes. `fn` is called
+// from the parsing goroutine with the furthest cursor position and the
+// length of the input text.
+func WithProgress(every int, fn ProgressFunc) ScannerOption {
+	return func(s *SimpleScanner) {
+		s.progress = NewProgress(every, fn, int64(len(s.buf)))
+	}
+}
+
+// WithNodeIDs enables identifiers for nodes constructed while parsing
+// with the scanner, or any of its clones, refer NodeID.
+func WithNodeIDs() ScannerOption {
+	return func(s *SimpleScanner) {
+		s.nodeids = new(int64)
+	}
+}
+
+// WithMaxBacktrack limits the number of bytes the scanner, and its
+// clones, can rewind from the furthest position reached. Cloning a
+// scanner behind the limit, which is how parsers backtrack, aborts the
+// parse with *ParseError, whose cause is *BacktrackError matching
+// ErrBacktrackLimit, refer Aborted. Useful to bound the parse time of
+// untrusted input.
+func WithMaxBacktrack(bytes int) ScannerOption {
+	return func(s *SimpleScanner) {
+		s.backtrack, s.limited = newBacktrack(bytes), true
+		s.backtrack.update(s.cursor)
+	}
+}
+
+// WithBacktrackBudget tracks the cost of backtracking, that is the
+// number of bytes the scanner had advanced before parsers resumed from
+// an earlier position, discarding the advance. Backtracking is noted
+// when parsers clone the scanner at an earlier position, which is how
+// combinators resume. Once the cumulative cost exceeds `budget`, the
+// parse is aborted with *ParseError, whose cause is ErrBudgetExceeded,
+// refer Aborted. If budget is zero, cost is only tracked, refer
+// BacktrackStats.
+func WithBacktrackBudget(budget int64) ScannerOption {
+	return func(s *SimpleScanner) {
+		s.budget = &backtrackBudget{
+			limit:  budget,
+			last:   s.cursor,
+			points: make(map[int]*BacktrackPoint),
+			costly: make(map[int]int64),
+		}
+		s.limited = true
+	}
+}
+
+// WithLimits apply the non-zero `limits`, same as WithMaxBacktrack and
+// WithBacktrackBudget.
+func WithLimits(limits Limits) ScannerOption {
+	return func(s *SimpleScanner) {
+		if limits.MaxBacktrack > 0 {
+			WithMaxBacktrack(limits.MaxBacktrack)(s)
+		}
+		if limits.BacktrackBudget > 0 {
+			WithBacktrackBudget(limits.BacktrackBudget)(s)
+		}
+	}
+}
+
+// WithInterning deduplicates the values of terminals, upto `maxLen`
+// bytes, matched by Token parsers with the scanner, or any of its
+// clones, so that repeated tokens, like keys in a large document, share
+// the same string. Interned values are held by the scanner, hence not
+// shared across scanners.
+func WithInterning(maxLen int) ScannerOption {
+	return func(s *SimpleScanner) {
+		s.interns = NewInterner(maxLen)
+	}
+}
+
+// WithLineContinuation make SkipWS, on the scanner and its clones, skip
+// line continuations, `marker` followed by newline, like a trailing
+// backslash in shell scripts and Makefiles, along with white space.
+// Continuations are treated only as white space, hence a token split
+// across a continuation is not joined, and parsers that don't skip white
+// space, like TokenExact, don't skip continuations, unless requested
+// using TokenExactContinued. Cursor positions and
+// line numbers still refer to the physical lines of input text.
+func WithLineContinuation(marker byte) ScannerOption {
+	return func(s *SimpleScanner) {
+		s.continuation = string(marker)
+		s.wsPattern = continuationPattern(s.wsPattern, s.continuation)
+	}
+}
+
+// WithTabWidth set tab stops every `width` columns for ColumnPosition,
+// on the scanner and its clones. By default, tab is counted as one
+// column.
+func WithTabWidth(width int) ScannerOption {
+	return func(s *SimpleScanner) {
+		s.tabwidth = width
+	}
+}
@@ -0,0 +1,147 @@
+package parsec
+
+import "fmt"
+import "reflect"
+import "testing"
+
+func ExampleWithWS() {
+	// only spaces and tabs are white space.
+	s := NewScannerWith([]byte("a \n b"), WithWS(`^[ \t]+`))
+	_, s = Ident()(s)
+	node, _ := Ident()(s)
+	fmt.Println(node)
+	// Output:
+	// <nil>
+}
+
+func ExampleWithLineno() {
+	s := NewScannerWith([]byte("a\nb\nc"), WithLineno())
+	_, s = Many(nil, Ident())(s)
+	fmt.Println(s.Lineno())
+	// Output:
+	// 3
+}
+
+func ExampleWithCaseFolding() {
+	s := NewScannerWith([]byte("SELECT Name"), WithCaseFolding())
+	node, _ := And(nil, Token(`select`, "SELECT"), Token(`name`, "IDENT"))(s)
+	fmt.Println(node.([]ParsecNode)[1].(*Terminal).Value)
+	// Output:
+	// Name
+}
+
+func ExampleWithProgress() {
+	text := []byte("a b c d")
+	show := func(consumed, total int64) { fmt.Printf("%v/%v ", consumed, total) }
+	Many(nil, Ident())(NewScannerWith(text, WithProgress(4, show)))
+	fmt.Println()
+	// Output:
+	// 4/7
+}
+
+func ExampleWithNodeIDs() {
+	node, _ := Ident()(NewScannerWith([]byte("a"), WithNodeIDs()))
+	fmt.Println(NodeID(node))
+	// Output:
+	// 1 true
+}
+
+func ExampleWithMaxBacktrack() {
+	y := OrdChoice(nil, And(nil, Ident(), Ident(), Atom(";", "SEMI")), Ident())
+	res := Run(y, NewScannerWith([]byte("abc def"), WithMaxBacktrack(2)))
+	fmt.Println(res.Err)
+	// Output:
+	// backtrack of 7 bytes from offset 7 exceeds limit 2 at offset 0
+}
+
+func ExampleWithLimits() {
+	y := OrdChoice(nil, And(nil, Ident(), Ident(), Atom(";", "SEMI")), Ident())
+	s := NewScannerWith([]byte("abc def"), WithLimits(Limits{BacktrackBudget: 4}))
+	fmt.Println(Run(y, s).Err)
+	// Output:
+	// backtrack cost of 7 bytes exceeds budget 4 at offset 0
+}
+
+func ExampleWithBacktrackBudget() {
+	y := OrdChoice(nil, And(nil, Ident(), Atom(";", "SEMI")), Ident())
+	s := NewScannerWith([]byte("abc"), WithBacktrackBudget(0))
+	Run(y, s)
+	fmt.Println(BacktrackStats(s).Total)
+	// Output:
+	// 3
+}
+
+func ExampleWithInterning() {
+	s := NewScannerWith([]byte("key key"), WithInterning(16))
+	node, _ := Many(nil, Ident())(s)
+	ns := node.([]ParsecNode)
+	fmt.Println(ns[0].(*Terminal).Value == ns[1].(*Terminal).Value)
+	// Output:
+	// true
+}
+
+func ExampleWithLineContinuation() {
+	s := NewScannerWith([]byte("a \\\n b"), WithLineContinuation('\\'))
+	node, _ := Many(nil, Ident())(s)
+	fmt.Println(len(node.([]ParsecNode)))
+	// Output:
+	// 2
+}
+
+func ExampleWithTabWidth() {
+	s := NewScannerWith([]byte("\tx"), WithTabWidth(4)).SkipN(1)
+	fmt.Println(s.(*SimpleScanner).ColumnPosition())
+	// Output:
+	// 5
+}
+
+func TestScannerOptions(t *testing.T) {
+	text := []byte(`{"a": [1, 2.5, "x"], "b": {"c": null}}`)
+
+	// zero options is same as before options.
+	ref := &SimpleScanner{
+		buf:          text,
+		lineno:       1,
+		patternCache: NewScanner(nil).(*SimpleScanner).patternCache,
+		wsPattern:    `^[ \t\r\n]+`,
+		scanState:    &scanState{},
+	}
+	if s := NewScannerWith(text); !reflect.DeepEqual(s, ref) {
+		t.Errorf("expected %#v, got %#v", ref, s)
+	}
+
+	g := makejsongrammar()
+	refnode, _ := g.Rule("value")(NewScanner(text))
+	progress := 0
+	s := NewScannerWith(text,
+		WithWS(`^[ \t\r\n]+`),
+		WithLineno(),
+		WithCaseFolding(),
+		WithProgress(1, func(_, _ int64) { progress++ }),
+		WithNodeIDs(),
+		WithLimits(Limits{MaxBacktrack: 64, BacktrackBudget: 1024}),
+		WithBacktrackBudget(1024),
+		WithInterning(16),
+		WithLineContinuation('\\'),
+		WithTabWidth(4),
+	)
+	res := Run(g.Rule("value"), s)
+	if res.Err != nil {
+		t.Fatalf("unexpected %v", res.Err)
+	} else if progress == 0 {
+		t.Errorf("expected progress")
+	}
+	// node identifiers are the only difference.
+	reftokens := collectTerminals(refnode, nil)
+	tokens := collectTerminals(res.Node, nil)
+	if len(tokens) != len(reftokens) || len(tokens) != 12 {
+		t.Fatalf("expected %v tokens, got %v", len(reftokens), len(tokens))
+	}
+	for i, token := range tokens {
+		if token.Name != reftokens[i].Name || token.Value != reftokens[i].Value {
+			t.Errorf("expected %v, got %v", reftokens[i], token)
+		} else if _, ok := NodeID(token); !ok {
+			t.Errorf("expected node identifier for %v", token)
+		}
+	}
+}
@@ -4,6 +4,9 @@
 package parsec
 
 import "fmt"
+import "reflect"
+import "strings"
+import "unicode/utf8"
 
 // ParsecNode for parsers return input text as parsed nodes.
 type ParsecNode interface{}
@@ -51,6 +54,83 @@ func And(callb Nodify, parsers ...interface{}) Parser {
 	}
 }
 
+// Compose2 combinator is same as And for a sequence of two parsers,
+// where `fn` receives the matching nodes as arguments, avoiding the
+// allocation of []ParsecNode. If `fn` returns nil, Compose2 will fail
+// without consuming the input.
+func Compose2(
+	p1, p2 Parser, fn func(ParsecNode, ParsecNode) ParsecNode) Parser {
+
+	return func(s Scanner) (ParsecNode, Scanner) {
+		n1, news := doParse(p1, s.Clone())
+		if n1 == nil {
+			return nil, s
+		}
+		n2, news := doParse(p2, news)
+		if n2 == nil {
+			return nil, s
+		}
+		if node := fn(n1, n2); node != nil {
+			return node, news
+		}
+		return nil, s
+	}
+}
+
+// Compose3 combinator same as Compose2 for a sequence of three parsers.
+func Compose3(
+	p1, p2, p3 Parser,
+	fn func(ParsecNode, ParsecNode, ParsecNode) ParsecNode) Parser {
+
+	return func(s Scanner) (ParsecNode, Scanner) {
+		n1, news := doParse(p1, s.Clone())
+		if n1 == nil {
+			return nil, s
+		}
+		n2, news := doParse(p2, news)
+		if n2 == nil {
+			return nil, s
+		}
+		n3, news := doParse(p3, news)
+		if n3 == nil {
+			return nil, s
+		}
+		if node := fn(n1, n2, n3); node != nil {
+			return node, news
+		}
+		return nil, s
+	}
+}
+
+// Compose4 combinator same as Compose2 for a sequence of four parsers.
+func Compose4(
+	p1, p2, p3, p4 Parser,
+	fn func(ParsecNode, ParsecNode, ParsecNode, ParsecNode) ParsecNode) Parser {
+
+	return func(s Scanner) (ParsecNode, Scanner) {
+		n1, news := doParse(p1, s.Clone())
+		if n1 == nil {
+			return nil, s
+		}
+		n2, news := doParse(p2, news)
+		if n2 == nil {
+			return nil, s
+		}
+		n3, news := doParse(p3, news)
+		if n3 == nil {
+			return nil, s
+		}
+		n4, news := doParse(p4, news)
+		if n4 == nil {
+			return nil, s
+		}
+		if node := fn(n1, n2, n3, n4); node != nil {
+			return node, news
+		}
+		return nil, s
+	}
+}
+
 // OrdChoice combinator accepts a list of `Parser`, or
 // reference to a parser, where atleast one of the parser
 // must match the input string. Return a parser function
@@ -74,6 +154,53 @@ func OrdChoice(callb Nodify, parsers ...interface{}) Parser {
 	}
 }
 
+// OrdChoiceOrDefault combinator is same as OrdChoice, but if none of the
+// parsers match the input, it returns `defaultNode` without consuming any
+// input, hence never fails. Useful within And for elements that have a
+// sensible default when missing. `defaultNode` is not passed to callback.
+// Panics if `defaultNode` is nil.
+func OrdChoiceOrDefault(
+	defaultNode ParsecNode, callb Nodify, parsers ...interface{}) Parser {
+
+	if defaultNode == nil {
+		panic(fmt.Errorf("OrdChoiceOrDefault needs a non-nil default node"))
+	}
+	choice := OrdChoice(callb, parsers...)
+	return func(s Scanner) (ParsecNode, Scanner) {
+		if node, news := choice(s); node != nil {
+			return node, news
+		}
+		return defaultNode, s
+	}
+}
+
+// DebugAmbiguity enables ambiguity detection in ExclusiveChoice
+// combinator, meant to be set while developing a grammar.
+var DebugAmbiguity = false
+
+// ExclusiveChoice combinator is same as OrdChoice, but when
+// DebugAmbiguity is set, all the parsers are tried on the input and it
+// panics if more than one of them match, which is a sign of ambiguous
+// grammar.
+func ExclusiveChoice(callb Nodify, parsers ...interface{}) Parser {
+	choice := OrdChoice(callb, parsers...)
+	return func(s Scanner) (ParsecNode, Scanner) {
+		if DebugAmbiguity {
+			matches := []int{}
+			for i, parser := range parsers {
+				if n, _ := doParse(parser, s.Clone()); n != nil {
+					matches = append(matches, i)
+				}
+			}
+			if len(matches) > 1 {
+				fmsg := "ambiguous choice at offset %v, parsers %v match"
+				panic(fmt.Errorf(fmsg, s.GetCursor(), matches))
+			}
+		}
+		return choice(s)
+	}
+}
+
 // Kleene combinator accepts two parsers, or reference to
 // parsers, namely opScan and sepScan, where opScan parser
 // will be used to match input string and contruct ParsecNode,
@@ -175,6 +302,75 @@ func Many(callb Nodify, parsers ...interface{}) Parser {
 	}
 }
 
+// ManyReuse combinator is same as Many, but collects the nodes matched
+// by `op` in the user supplied buffer `buf`, which is reset to zero
+// length, without reallocating, on every call. `sep` is optional and
+// can be nil. Useful for parsers applied millions of times, like on
+// every line of a log, where buffer can be allocated once and reused
+// across independent inputs. Slice passed to callback, or returned if
+// callback is nil, is only valid until the next call, and ManyReuse
+// shall not be applied recursively or concurrently with the same buffer.
+func ManyReuse(buf *[]ParsecNode, callb Nodify, op, sep Parser) Parser {
+	if buf == nil {
+		panic(fmt.Errorf("ManyReuse expects a buffer"))
+	}
+	return func(s Scanner) (ParsecNode, Scanner) {
+		var n ParsecNode
+		ns := (*buf)[:0]
+		news := s.Clone()
+		for {
+			if n, news = doParse(op, news); n == nil {
+				break
+			}
+			ns = append(ns, n)
+			if sep != nil {
+				if n, news = doParse(sep, news); n == nil {
+					break
+				}
+			}
+		}
+		*buf = ns
+		if len(ns) > 0 {
+			if node := docallback(callb, ns); node != nil {
+				return node, news
+			}
+		}
+		return nil, s
+	}
+}
+
+// Take combinator matches upto `n` items using `item` parser, with
+// `sep` parser, if not nil, between them. Stops after the n-th item,
+// leaving the rest of the input, including the separator that follows,
+// unconsumed. Unlike Many, Take succeeds with fewer than `n` items, or
+// none, if the list ends early. Useful for previewing the first few
+// items of a large list. Return []ParsecNode of matched items.
+func Take(n int, item, sep Parser) Parser {
+	if n < 0 {
+		panic(fmt.Errorf("Take expects non-negative count, got %v", n))
+	}
+	return func(s Scanner) (ParsecNode, Scanner) {
+		ns := make([]ParsecNode, 0, n)
+		news := s.Clone()
+		for len(ns) < n {
+			ls := news
+			if len(ns) > 0 && sep != nil {
+				if sn, ss := doParse(sep, news.Clone()); sn != nil {
+					ls = ss
+				} else {
+					break
+				}
+			}
+			nd, ls := doParse(item, ls.Clone())
+			if nd == nil {
+				break
+			}
+			ns, news = append(ns, nd), ls
+		}
+		return ns, news
+	}
+}
+
 // ManyUntil combinator accepts three parsers, or references to
 // parsers, namely opScan, sepScan and untilScan, where opScan parser
 // will be used to match input string and contruct ParsecNode,
@@ -230,6 +426,39 @@ func ManyUntil(callb Nodify, parsers ...interface{}) Parser {
 	}
 }
 
+// List combinator accepts an item parser and a separator parser, or
+// references to parsers, to match a list of items separated by `sep`,
+// with atleast `min` items. Separator is neither allowed before the first
+// item nor after the last item, that is, separator is consumed only when
+// it is followed by an item. Matched items, without separators, are
+// passed as argument to Nodify callback, which shall be an empty list
+// when min is ZERO and there are no items. If less than `min` items
+// match, or if Nodify callback returns nil, List will fail without
+// consuming the input.
+func List(callb Nodify, min int, item, sep interface{}) Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		ns := make([]ParsecNode, 0)
+		n, news := doParse(item, s.Clone())
+		for n != nil {
+			ns = append(ns, n)
+			var ls Scanner
+			if n, ls = doParse(sep, news.Clone()); n == nil {
+				break
+			}
+			if n, ls = doParse(item, ls); n != nil {
+				news = ls
+			}
+		}
+		if len(ns) < min {
+			return nil, s
+		}
+		if node := docallback(callb, ns); node != nil {
+			return node, news
+		}
+		return nil, s
+	}
+}
+
 // Maybe combinator accepts a single parser, or reference to
 // a parser, and tries to match the input stream with it. If
 // parser fails to match the input, returns MaybeNone.
@@ -246,11 +475,555 @@ func Maybe(callb Nodify, parser interface{}) Parser {
 	}
 }
 
+// ElementSpec specify an element in the sequence matched by AndOpt
+// combinator, Parser can be a `Parser` or reference to a parser.
+type ElementSpec struct {
+	Parser   interface{}
+	Optional bool
+}
+
+// Req return ElementSpec for an element that must match the input.
+func Req(parser interface{}) ElementSpec {
+	return ElementSpec{Parser: parser}
+}
+
+// Opt return ElementSpec for an element that may be missing in input.
+func Opt(parser interface{}) ElementSpec {
+	return ElementSpec{Parser: parser, Optional: true}
+}
+
+// AndOpt combinator is similar to And combinator, but some of the elements
+// in the sequence can be optional. The list of ParsecNode passed to
+// Nodify callback will always have one entry for each element, with
+// `nil` in place of missing optional elements, so that positions of
+// elements remain the same irrespective of the input. If a required
+// element fails, AndOpt will fail without consuming the input.
+func AndOpt(callb Nodify, parsers ...ElementSpec) Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		var ns = make([]ParsecNode, 0, len(parsers))
+		news := s.Clone()
+		for _, spec := range parsers {
+			n, nexts := doParse(spec.Parser, news.Clone())
+			if n == nil && !spec.Optional {
+				return nil, s
+			} else if n != nil {
+				news = nexts
+			}
+			ns = append(ns, n)
+		}
+		if node := docallback(callb, ns); node != nil {
+			return node, news
+		}
+		return nil, s
+	}
+}
+
+// AndStruct combinator is similar to And combinator, but instead of
+// Nodify callback, matching nodes are populated into a struct. `p` must
+// be a pointer to struct with one field for each parser, in the same
+// order, like:
+//
+//	type ObjectRule struct {
+//		Open    *Terminal
+//		Content []ParsecNode
+//		Close   *Terminal
+//	}
+//	y := AndStruct(&ObjectRule{}, open, content, close)
+//
+// For every match, a new instance of the struct is populated and
+// returned as pointer, `p` itself is only used for its type. Missing
+// nodes, like MaybeNone, leave the field with its zero value. Panics if
+// a node cannot be assigned to its field.
+func AndStruct(p interface{}, parsers ...interface{}) Parser {
+	typ := reflect.TypeOf(p)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		panic(fmt.Errorf("AndStruct expects pointer to struct, got %T", p))
+	} else if typ = typ.Elem(); typ.Kind() != reflect.Struct {
+		panic(fmt.Errorf("AndStruct expects pointer to struct, got %T", p))
+	} else if typ.NumField() != len(parsers) {
+		fmsg := "AndStruct %v has %v fields for %v parsers"
+		panic(fmt.Errorf(fmsg, typ, typ.NumField(), len(parsers)))
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		if field := typ.Field(i); field.PkgPath != "" {
+			fmsg := "AndStruct field %v.%v is not exported"
+			panic(fmt.Errorf(fmsg, typ, field.Name))
+		}
+	}
+
+	return func(s Scanner) (ParsecNode, Scanner) {
+		var n ParsecNode
+		val, news := reflect.New(typ), s.Clone()
+		for i, parser := range parsers {
+			n, news = doParse(parser, news)
+			if n == nil {
+				return nil, s
+			}
+			setStructField(val.Elem(), i, n)
+		}
+		return val.Interface(), news
+	}
+}
+
+// WithMaxMatchLength combinator limits the number of bytes, including
+// skipped whitespace, that parser `p` can consume. If `p` would consume
+// more than `n` bytes, it fails without consuming the input. When used
+// with SimpleScanner, `p` shall only see the next n+1 bytes of input, so
+// patterns like an unterminated quoted string won't scan the entire
+// remaining input before failing.
+func WithMaxMatchLength(n int, p Parser) Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		start, ls := s.GetCursor(), s.Clone()
+		var buf []byte
+		var state, forked *scanState
+		ss, ok := ls.(*SimpleScanner)
+		if ok && ss.norm == nil && ss.BytesRemaining() > n+1 {
+			buf, state = ss.buf, ss.scanState
+			// results memoized on limited text are not valid otherwise.
+			ss.limit(start + n + 1)
+			forked = state.fork()
+			ss.scanState = forked
+		}
+		node, news := p(ls)
+		if forked != nil && forked.abort != nil {
+			state.abortParse(forked.abort)
+		}
+		if node == nil || news.GetCursor()-start > n {
+			return nil, s
+		}
+		if nss, ok := news.(*SimpleScanner); ok && buf != nil {
+			nss.buf, nss.scanState = buf, state
+		}
+		return node, news
+	}
+}
+
+// TypedSettings combinator matches a sequence of `key: value` pairs,
+// separated by whitespace, where the value is parsed by the parser
+// selected from `schema` for that key, like Int() for `retries` and a
+// duration parser for `timeout`. Value parsers can coerce the value to
+// a typed ParsecNode using their Nodify callback. Return settings as
+// map[string]ParsecNode, if a key repeats, its last value is returned.
+// If a key is not found in schema, or if its value parser fails,
+// TypedSettings will fail without consuming the input. Never fails if
+// there are no settings.
+func TypedSettings(schema map[string]Parser) Parser {
+	key := Token(`[A-Za-z_][0-9A-Za-z_.-]*`, "KEY")
+	colon := Atom(":", "COLON")
+	return func(s Scanner) (ParsecNode, Scanner) {
+		settings := make(map[string]ParsecNode)
+		news := s.Clone()
+		for {
+			kn, ks := key(news.Clone())
+			if kn == nil {
+				break
+			}
+			cn, cs := colon(ks)
+			if cn == nil {
+				break
+			}
+			name := kn.(*Terminal).Value
+			p, ok := schema[name]
+			if !ok {
+				return nil, s
+			}
+			value, vs := p(cs)
+			if value == nil {
+				return nil, s
+			}
+			settings[name], news = value, vs
+		}
+		return settings, news
+	}
+}
+
+// Region combinator matches text bracketed by `open` and `close`
+// parsers, without parsing the text in between. Nested brackets are
+// balanced, `close` is tried before `open` so that both can be the same,
+// like quotes. Note that brackets within quoted text are not treated
+// specially. Returns a Terminal, named `name`, with verbatim inner text
+// as its Value and the offset of the inner text as its Position, that
+// is, inner text spans from Position to Position+len(Value).
+// Inner text can later be parsed with a different grammar, for example
+// by scanning it with NewScanner. If the region is not closed, Region
+// will fail without consuming the input.
+func Region(open, close Parser, name string) Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		node, news := open(s.Clone())
+		if node == nil {
+			return nil, s
+		}
+		inner, depth := news.Clone(), 1
+		for {
+			till := news.GetCursor()
+			if node, ns := close(news.Clone()); node != nil {
+				if depth--; depth == 0 {
+					value := string(scanText(inner, till))
+					return newTerminal(ns, name, value, inner.GetCursor()), ns
+				}
+				news = ns
+				continue
+			}
+			if node, ns := open(news.Clone()); node != nil {
+				depth, news = depth+1, ns
+				continue
+			}
+			if news.Endof() {
+				return nil, s
+			}
+			_, news = news.Match(`^(?s).`)
+		}
+	}
+}
+
+// DocComment return parser function to match a block comment, bracketed
+// by `open` and `close` delimiters, and capture it, instead of skipping
+// it as white space, say for documentation extractors. If `nested` is
+// true, comments can be nested like `/* a /* b */ c */`, otherwise the
+// comment ends at the first `close`. Return NonTerminal named COMMENT
+// with Terminals named OPEN, TEXT and CLOSE as its children, where TEXT
+// is the comment text without its outer delimiters, hence the node spans
+// the comment along with its delimiters. If the comment is not closed,
+// DocComment will fail without consuming the input. Skip leading
+// whitespace.
+func DocComment(open, close string, nested bool) Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		news := s.Clone()
+		news.SkipWS()
+		start := news.GetCursor()
+		if ok, _ := news.MatchString(open); !ok {
+			return nil, s
+		}
+		inner, depth := news.Clone(), 1
+		for !news.Endof() {
+			till := news.GetCursor()
+			if ok, _ := news.MatchString(close); ok {
+				if depth--; depth > 0 {
+					continue
+				}
+				nt := newNonTerminal(news, "COMMENT")
+				text := string(scanText(inner, till))
+				nt.Children = append(nt.Children,
+					newTerminal(news, "OPEN", open, start),
+					newTerminal(news, "TEXT", text, inner.GetCursor()),
+					newTerminal(news, "CLOSE", close, till))
+				return nt, news
+			}
+			if nested {
+				if ok, _ := news.MatchString(open); ok {
+					depth++
+					continue
+				}
+			}
+			news.Match(`^(?s).`)
+		}
+		return nil, s
+	}
+}
+
+// AtBoundary combinator applies parser `p` and succeeds only if
+// `boundary` returns true at the position where `p` ended, otherwise it
+// fails without consuming the input. `boundary` is called with a clone
+// of the scanner, hence it is free to move the cursor, and is typically
+// used to prevent tokens from bleeding into the following input, for
+// example keywords shall be followed by a WordBoundary.
+func AtBoundary(p Parser, boundary func(s Scanner) bool) Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		node, news := p(s.Clone())
+		if node == nil || !boundary(news.Clone()) {
+			return nil, s
+		}
+		return node, news
+	}
+}
+
+// WordBoundary return true if scanner is at the end of input text or the
+// next character is not an identifier character, that is not a letter,
+// digit or underscore. Can be used as boundary for AtBoundary.
+func WordBoundary(s Scanner) bool {
+	if ns, ok := s.(*normalizingScanner); ok { // text after previous token.
+		s = ns.Scanner
+	}
+	if s.Endof() {
+		return true
+	}
+	_, ok := s.TryMatch(`^[\pL\pN_]`)
+	return !ok
+}
+
+// Predicate return a parser that matches, without consuming any input,
+// if `fn` returns true for the scanner. `fn` is called with a clone of
+// the scanner, hence it can look ahead freely. Return an empty Terminal
+// named PREDICATE on success. Useful for context sensitive decisions,
+// like selecting an alternative in OrdChoice.
+func Predicate(fn func(Scanner) bool) Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		if fn(s.Clone()) {
+			return newTerminal(s, "PREDICATE", "", s.GetCursor()), s
+		}
+		return nil, s
+	}
+}
+
+// Lookahead combinator matches, without consuming any input, if parser
+// `p` matches at the cursor, like the `&` predicate in PEG. Return an
+// empty Terminal named PREDICATE on success.
+func Lookahead(p interface{}) Parser {
+	return Predicate(func(s Scanner) bool {
+		node, _ := doParse(p, s)
+		return node != nil
+	})
+}
+
+// Not combinator matches, without consuming any input, if parser `p`
+// does not match at the cursor, like the `!` predicate in PEG. Return an
+// empty Terminal named PREDICATE on success.
+func Not(p interface{}) Parser {
+	return Predicate(func(s Scanner) bool {
+		node, _ := doParse(p, s)
+		return node == nil
+	})
+}
+
+// ByteDispatch combinator peeks the next byte in input, without
+// skipping whitespace, and applies the parser selected from `table` for
+// that byte. Selected parser is applied from the dispatch byte, hence it
+// can match the byte as well. Useful for tagged, fixed format inputs. If
+// there is no parser for the byte, or at the end of input, ByteDispatch
+// will fail without consuming the input.
+func ByteDispatch(table map[byte]Parser) Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		tok, ok := s.TryMatch(`^(?s).`)
+		if !ok || len(tok) == 0 {
+			return nil, s
+		}
+		p, ok := table[tok[0]]
+		if !ok {
+			return nil, s
+		}
+		if node, news := p(s.Clone()); node != nil {
+			return node, news
+		}
+		return nil, s
+	}
+}
+
+// Guarded combinator peeks the next byte in input, after skipping
+// whitespace, and applies parser `p` only if that byte is in `firstSet`.
+// Otherwise, or at the end of input, Guarded fails without consuming the
+// input. Useful to skip expensive alternatives of an ordered choice
+// that can be decided by their first character.
+func Guarded(firstSet string, p Parser) Parser {
+	if len(firstSet) == 0 {
+		panic(fmt.Errorf("Guarded expects atleast one byte in first set"))
+	}
+	var set [256]bool
+	for i := 0; i < len(firstSet); i++ {
+		set[firstSet[i]] = true
+	}
+	return func(s Scanner) (ParsecNode, Scanner) {
+		if ps, ok := s.(peekScanner); ok {
+			if ch, ok := ps.peekByte(); !ok || !set[ch] {
+				return nil, s
+			}
+			return p(s)
+		}
+		_, news := s.Clone().SkipWS()
+		tok, ok := news.TryMatch(`^(?s).`)
+		if !ok || len(tok) == 0 || !set[tok[0]] {
+			return nil, s
+		}
+		return p(s)
+	}
+}
+
+// peekScanner is implemented by scanners that can peek the next byte
+// after white space, without cloning the scanner.
+type peekScanner interface {
+	peekByte() (byte, bool)
+}
+
+// Heredoc combinator parse a shell style here document. `intro` shall
+// match the introducer, like `<<EOF` or `<<~EOF`, and the rest of its
+// line shall be blank. Following lines, until a line equal to the
+// marker, are returned as Terminal named HEREDOC, with the text of the
+// lines as its value. Marker is the value matched by `intro` without
+// the leading `<<`. With `<<~` introducer, the common leading whitespace
+// of body lines is stripped and the terminating line may be indented.
+// If the terminating line is missing, Heredoc fails without consuming
+// the input.
+func Heredoc(intro Parser) Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		node, news := intro(s.Clone())
+		var marker string
+		switch n := node.(type) {
+		case *Terminal:
+			marker = n.Value
+		case string:
+			marker = n
+		default:
+			return nil, s
+		}
+		marker = strings.TrimPrefix(marker, "<<")
+		indented := strings.HasPrefix(marker, "~")
+		marker = strings.TrimPrefix(marker, "~")
+		if tok, _ := news.Match(`^[ \t]*\r?\n`); tok == nil {
+			return nil, s
+		}
+
+		start, lines := news.GetCursor(), []string{}
+		for !news.Endof() {
+			tok, _ := news.Match(`^[^\n]*\n?`)
+			line := strings.TrimRight(string(tok), "\r\n")
+			if indented {
+				if strings.TrimLeft(line, " \t") == marker {
+					return heredocNode(news, lines, indented, start), news
+				}
+			} else if line == marker {
+				return heredocNode(news, lines, indented, start), news
+			}
+			lines = append(lines, string(tok))
+		}
+		return nil, s
+	}
+}
+
+func heredocNode(
+	s Scanner, lines []string, indented bool, position int) ParsecNode {
+
+	if indented {
+		indent := -1
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			n := len(line) - len(strings.TrimLeft(line, " \t"))
+			if indent < 0 || n < indent {
+				indent = n
+			}
+		}
+		for i, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				lines[i] = strings.TrimLeft(line, " \t")
+			} else {
+				lines[i] = line[indent:]
+			}
+		}
+	}
+	return newTerminal(s, "HEREDOC", strings.Join(lines, ""), position)
+}
+
+// AttrList combinator parse zero or more markup attributes, separated
+// by whitespace, like `id="x" disabled checked=yes`. Attribute names are
+// matched by `name`, and values can be double quoted, single quoted or
+// unquoted, matched by `value`. Attributes without `=` are boolean
+// attributes. Return []ParsecNode of NonTerminal named ATTRIBUTE, in the
+// order they appear, with the name node as its first child followed by
+// a Terminal named VALUE, skipped for boolean attributes. VALUE of
+// quoted attributes is the text within the quotes, and the quote is set
+// as its `quote` attribute. If an attribute name repeats, the last one
+// wins, replacing the earlier one in its place. Name and value nodes
+// that are not Queryable are wrapped as NodeValue. Never fails.
+func AttrList(name Parser, value Parser) Parser {
+	equal := Atom("=", "EQUAL")
+	quoted := Token(`(?:"[^"]*"|'[^']*')`, "VALUE")
+
+	return func(s Scanner) (ParsecNode, Scanner) {
+		attrs, index := []ParsecNode{}, make(map[string]int)
+		news := s.Clone()
+		for {
+			if ws, _ := news.Clone().SkipWS(); len(attrs) > 0 && len(ws) == 0 {
+				break // attributes must be separated by whitespace.
+			}
+			nn, ns := name(news.Clone())
+			if nn == nil {
+				break
+			}
+			attr := newNonTerminal(ns, "ATTRIBUTE")
+			key := queryable(nn, "NAME")
+			attr.Children = append(attr.Children, key)
+			if en, es := equal(ns.Clone()); en != nil {
+				if qn, qs := quoted(es.Clone()); qn != nil {
+					t := qn.(*Terminal)
+					quote := t.Value[:1]
+					t.Value, t.Position = t.Value[1:len(t.Value)-1], t.Position+1
+					t.SetAttribute("quote", quote)
+					attr.Children, ns = append(attr.Children, t), qs
+				} else if vn, vs := value(es.Clone()); vn != nil {
+					attr.Children = append(attr.Children, queryable(vn, "VALUE"))
+					ns = vs
+				} else {
+					break
+				}
+			}
+			if i, ok := index[key.GetValue()]; ok {
+				attrs[i] = attr
+			} else {
+				index[key.GetValue()] = len(attrs)
+				attrs = append(attrs, attr)
+			}
+			news = ns
+		}
+		return attrs, news
+	}
+}
+
+// HeaderDispatch combinator parse a header using `header` parser, and
+// the body following it using the parser returned by `chooseBody` for
+// the parsed header, like a version field selecting the grammar for
+// that version of the document. Return NonTerminal named DOCUMENT with
+// header and body nodes as its children, nodes that are not Queryable
+// are wrapped as NodeValue named HEADER and BODY. If header or body
+// does not match, HeaderDispatch will fail without consuming the input.
+// If chooseBody returns error, like for an unknown version, HeaderDispatch
+// fails and the parse is aborted with *ParseError at the start of the
+// body, refer Aborted.
+func HeaderDispatch(
+	header Parser, chooseBody func(header ParsecNode) (Parser, error)) Parser {
+
+	return func(s Scanner) (ParsecNode, Scanner) {
+		hn, hs := header(s.Clone())
+		if hn == nil {
+			return nil, s
+		}
+		body, err := chooseBody(hn)
+		if err != nil {
+			_, ws := hs.Clone().SkipWS()
+			abortParse(s, &ParseError{Offset: ws.GetCursor(), Msg: err.Error()})
+			return nil, s
+		}
+		bn, bs := body(hs)
+		if bn == nil {
+			return nil, s
+		}
+		doc := newNonTerminal(bs, "DOCUMENT")
+		doc.Children = append(doc.Children, queryable(hn, "HEADER"))
+		doc.Children = append(doc.Children, queryable(bn, "BODY"))
+		return doc, bs
+	}
+}
+
+// ParseSingleLine parse `text`, after trimming trailing whitespace and
+// newlines, with parser `p`. Return error if `p` does not match or does
+// not consume the entire text. Handy for table driven tests on string
+// literals.
+func ParseSingleLine(text string, p Parser) (ParsecNode, error) {
+	text = strings.TrimRight(text, " \t\r\n")
+	node, s := p(NewScanner([]byte(text)))
+	if node == nil || !s.Endof() {
+		return nil, fmt.Errorf("parse error at offset %v", s.GetCursor())
+	}
+	return node, nil
+}
+
 //----------------
 // Local functions
 //----------------
 
 func doParse(parser interface{}, s Scanner) (ParsecNode, Scanner) {
+	if debugInvariants {
+		return checkedParse(parser, s)
+	}
 	switch p := parser.(type) {
 	case Parser:
 		return p(s)
@@ -262,8 +1035,58 @@ func doParse(parser interface{}, s Scanner) (ParsecNode, Scanner) {
 }
 
 func docallback(callb Nodify, ns []ParsecNode) ParsecNode {
-	if callb != nil {
+	if debugInvariants && callb != nil {
+		return checkedCallback(callb, ns)
+	} else if callb != nil {
 		return callb(ns)
 	}
 	return ns
 }
+
+func setStructField(val reflect.Value, i int, n ParsecNode) {
+	field, nval := val.Field(i), reflect.ValueOf(n)
+	if nval.Type().AssignableTo(field.Type()) {
+		field.Set(nval)
+		return
+	} else if _, ok := n.(MaybeNone); ok {
+		return
+	}
+	fmsg := "AndStruct cannot assign %T to %v.%v"
+	panic(fmt.Errorf(fmsg, n, val.Type(), val.Type().Field(i).Name))
+}
+
+// scanText return input text from scanner's cursor until offset `till`.
+func scanText(s Scanner, till int) []byte {
+	from := s.GetCursor()
+	switch ss := s.(type) {
+	case *SimpleScanner:
+		return ss.buf[from:till]
+	case *StringScanner:
+		return []byte(ss.text[from:till])
+	case *ReaderAtScanner:
+		return ss.blocks.slice(int64(from), int64(till))
+	}
+	text, s := []byte{}, s.Clone()
+	for s.GetCursor() < till {
+		ch, _ := s.Match(`^(?s).`)
+		text = append(text, ch...)
+	}
+	return text
+}
+
+// excerptLen is the maximum length of source text quoted in messages.
+const excerptLen = 40
+
+// truncateValid return at most `max` bytes of source text `b`, for
+// excerpts in messages, cut on a rune boundary so that the excerpt is
+// valid UTF-8. An ellipsis is appended if `b` is truncated.
+func truncateValid(b []byte, max int) string {
+	if len(b) <= max {
+		return string(b)
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(b[cut]) {
+		cut--
+	}
+	return string(b[:cut]) + "..."
+}
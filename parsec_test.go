@@ -1,8 +1,13 @@
 package parsec
 
+import "bytes"
 import "fmt"
 import "reflect"
+import "strconv"
+import "strings"
 import "testing"
+import "unicode/utf8"
+import "time"
 
 var _ = fmt.Sprintf("dummy")
 
@@ -21,6 +26,55 @@ func TestAnd(t *testing.T) {
 	}
 }
 
+func TestCompose(t *testing.T) {
+	value := func(n ParsecNode) string { return n.(*Terminal).Value }
+	key, eq, num := Ident(), Atom("=", "EQUAL"), Int()
+	semi := Atom(";", "SEMI")
+
+	y2 := Compose2(key, num, func(n1, n2 ParsecNode) ParsecNode {
+		return value(n1) + ":" + value(n2)
+	})
+	y3 := Compose3(key, eq, num, func(n1, _, n3 ParsecNode) ParsecNode {
+		return value(n1) + ":" + value(n3)
+	})
+	y4 := Compose4(key, eq, num, semi, func(n1, _, n3, _ ParsecNode) ParsecNode {
+		return value(n1) + ":" + value(n3)
+	})
+	testcases := []struct {
+		y      Parser
+		text   string
+		failed []string
+	}{
+		{y2, "x 10", []string{"? 10", "x ?"}},
+		{y3, "x = 10", []string{"? = 10", "x ? 10", "x = ?"}},
+		{y4, "x = 10;", []string{"? = 10;", "x ? 10;", "x = ?;", "x = 10?"}},
+	}
+	for _, tcase := range testcases {
+		node, s := tcase.y(NewScanner([]byte(tcase.text)))
+		if node != "x:10" {
+			t.Errorf("%q: expected %q, got %v", tcase.text, "x:10", node)
+		} else if !s.Endof() {
+			t.Errorf("%q: expected end of text", tcase.text)
+		}
+		// any parser failing, fails without consuming the input.
+		for _, text := range tcase.failed {
+			if node, s := tcase.y(NewScanner([]byte(text))); node != nil {
+				t.Errorf("%q: unexpected %v", text, node)
+			} else if s.GetCursor() != 0 {
+				t.Errorf("%q: expected %v, got %v", text, 0, s.GetCursor())
+			}
+		}
+	}
+
+	// nil from callback.
+	y2 = Compose2(key, num, func(n1, n2 ParsecNode) ParsecNode { return nil })
+	if node, s := y2(NewScanner([]byte("x 10"))); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+}
+
 func TestOrdChoice(t *testing.T) {
 	y := OrdChoice(func(ns []ParsecNode) ParsecNode {
 		return nil
@@ -36,6 +90,40 @@ func TestOrdChoice(t *testing.T) {
 	}
 }
 
+func TestOrdChoiceOrDefault(t *testing.T) {
+	first := func(ns []ParsecNode) ParsecNode { return ns[0] }
+	mode := OrdChoiceOrDefault("rw", first, Atom("ro", "RO"), Atom("rw", "RW"))
+	y := And(nil, Ident(), mode, Atom(";", "SEMI"))
+
+	node, s := y(NewScanner([]byte("disk ro;")))
+	if node == nil || !s.Endof() {
+		t.Fatalf("expected match, got %v", node)
+	} else if term := node.([]ParsecNode)[1].(*Terminal); term.Value != "ro" {
+		t.Errorf("expected %v, got %v", "ro", term.Value)
+	}
+	node, s = y(NewScanner([]byte("disk ;")))
+	if node == nil || !s.Endof() {
+		t.Fatalf("expected match, got %v", node)
+	} else if mode := node.([]ParsecNode)[1]; mode != "rw" {
+		t.Errorf("expected %v, got %v", "rw", mode)
+	}
+
+	// default doesn't consume input.
+	node, s = mode(NewScanner([]byte(" xyz")))
+	if node != "rw" || s.GetCursor() != 0 {
+		t.Errorf("unexpected %v at %v", node, s.GetCursor())
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("expected panic")
+			}
+		}()
+		OrdChoiceOrDefault(nil, nil, Atom("ro", "RO"))
+	}()
+}
+
 func TestStrEOF(t *testing.T) {
 	word := String()
 	Y := Many(
@@ -103,6 +191,82 @@ func TestMany(t *testing.T) {
 	}()
 }
 
+func TestManyReuse(t *testing.T) {
+	buf := make([]ParsecNode, 0, 8)
+	y := ManyReuse(&buf, nil, Token(`\w+`, "W"), Atom(",", "COMMA"))
+	for _, text := range []string{"one,two,three", "four", "five,six"} {
+		node, s := y(NewScanner([]byte(text)))
+		if node == nil {
+			t.Fatalf("ManyReuse() didn't match %q", text)
+		} else if !s.Endof() {
+			t.Errorf("expected end of text for %q", text)
+		}
+		ns := node.([]ParsecNode)
+		words := []string{}
+		for _, n := range ns {
+			words = append(words, n.(*Terminal).Value)
+		}
+		if got := strings.Join(words, ","); got != text {
+			t.Errorf("expected %q, got %q", text, got)
+		} else if &ns[0] != &buf[:1][0] {
+			t.Errorf("expected nodes in the supplied buffer")
+		}
+	}
+	if cap(buf) != 8 {
+		t.Errorf("expected buffer to be reused, got capacity %v", cap(buf))
+	}
+
+	// no match and nil returned by callback.
+	if node, s := y(NewScanner([]byte(",x"))); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+	y = ManyReuse(&buf, func(_ []ParsecNode) ParsecNode { return nil },
+		Token(`\w+`, "W"), nil)
+	if node, s := y(NewScanner([]byte("one two"))); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+}
+
+func TestTake(t *testing.T) {
+	y := Take(3, Int(), Atom(",", "COMMA"))
+	testcases := []struct {
+		text   string
+		ints   string
+		remain string
+	}{
+		{"1,2,3,4,5", "1,2,3", ",4,5"},
+		{"1,2", "1,2", ""},
+		{"1,2,", "1,2", ","},
+		{"1,2,x", "1,2", ",x"},
+		{"x", "", "x"},
+	}
+	for _, tcase := range testcases {
+		node, s := y(NewScanner([]byte(tcase.text)))
+		if node == nil {
+			t.Fatalf("Take() didn't match %q", tcase.text)
+		}
+		ints := []string{}
+		for _, n := range node.([]ParsecNode) {
+			ints = append(ints, n.(*Terminal).Value)
+		}
+		ss := s.(*SimpleScanner)
+		if got := strings.Join(ints, ","); got != tcase.ints {
+			t.Errorf("expected %q, got %q", tcase.ints, got)
+		} else if got := string(ss.buf[ss.cursor:]); got != tcase.remain {
+			t.Errorf("expected %q, got %q", tcase.remain, got)
+		}
+	}
+
+	node, s := Take(2, Int(), nil)(NewScanner([]byte("1 2 3")))
+	if ns := node.([]ParsecNode); len(ns) != 2 || s.GetCursor() != 3 {
+		t.Errorf("unexpected %v at %v", ns, s.GetCursor())
+	}
+}
+
 func TestManyUntil(t *testing.T) {
 	// Return nil
 	w := Token("\\w+", "W")
@@ -258,3 +422,831 @@ func TestForwardReference(t *testing.T) {
 func allTokens(ns []ParsecNode) ParsecNode {
 	return ns
 }
+
+func TestAndOpt(t *testing.T) {
+	// number -> sign? digits ('.' digits)? exp?
+	sign := Token(`[+-]`, "SIGN")
+	digits := TokenExact(`[0-9]+`, "DIGITS")
+	fraction := TokenExact(`\.[0-9]+`, "FRACTION")
+	exp := TokenExact(`[eE][+-]?[0-9]+`, "EXP")
+	y := AndOpt(nil, Opt(sign), Req(digits), Opt(fraction), Opt(&exp))
+
+	refs := map[string][]string{
+		"10":        {"", "10", "", ""},
+		"-10":       {"-", "10", "", ""},
+		"10.5":      {"", "10", ".5", ""},
+		"+10e3":     {"+", "10", "", "e3"},
+		"-10.25E-2": {"-", "10", ".25", "E-2"},
+	}
+	for text, ref := range refs {
+		node, s := y(NewScanner([]byte(text)))
+		if node == nil {
+			t.Errorf("expected match for %q", text)
+			continue
+		} else if s.Endof() == false {
+			t.Errorf("expected end of text for %q", text)
+		}
+		nodes := node.([]ParsecNode)
+		if len(nodes) != 4 {
+			t.Errorf("expected %v slots, got %v", 4, len(nodes))
+			continue
+		}
+		for i, n := range nodes {
+			if ref[i] == "" && n != nil {
+				t.Errorf("%q expected nil at %v, got %v", text, i, n)
+			} else if ref[i] != "" && n.(*Terminal).Value != ref[i] {
+				t.Errorf("%q expected %q at %v, got %v", text, ref[i], i, n)
+			}
+		}
+	}
+
+	// missing required element
+	node, s := y(NewScanner([]byte("-.5")))
+	if node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+	// nil return from callback
+	y = AndOpt(func(_ []ParsecNode) ParsecNode { return nil }, Req(digits))
+	if node, s := y(NewScanner([]byte("10"))); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+}
+
+func TestWithMaxMatchLength(t *testing.T) {
+	str := WithMaxMatchLength(8, Token(`"[^"]*"`, "STR"))
+	y := And(nil, str, Token(`[a-z]+`, "WORD"))
+
+	node, s := y(NewScanner([]byte(`"hello" world`)))
+	if node == nil {
+		t.Fatalf("expected match")
+	} else if s.Endof() == false {
+		t.Errorf("expected end of text")
+	}
+	nodes := node.([]ParsecNode)
+	if v := nodes[0].(*Terminal).Value; v != `"hello"` {
+		t.Errorf("expected %q, got %q", `"hello"`, v)
+	} else if v := nodes[1].(*Terminal).Value; v != "world" {
+		t.Errorf("expected %q, got %q", "world", v)
+	}
+
+	// exceeds the limit
+	node, s = str(NewScanner([]byte(`"hello world"`)))
+	if node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+	word := WithMaxMatchLength(5, Token(`[a-z]+`, "WORD"))
+	if node, _ := word(NewScanner([]byte(`abcdef`))); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if node, _ := word(NewScanner([]byte(`abcde f`))); node == nil {
+		t.Errorf("expected match")
+	}
+
+	// parser shall not see beyond the limit.
+	seen := 0
+	peek := func(s Scanner) (ParsecNode, Scanner) {
+		seen = len(s.(*SimpleScanner).buf) - s.GetCursor()
+		return nil, s
+	}
+	text := []byte(`"` + strings.Repeat("a", 1000))
+	WithMaxMatchLength(8, peek)(NewScanner(text))
+	if seen != 9 {
+		t.Errorf("expected %v, got %v", 9, seen)
+	}
+
+	// a failed match shall not limit the scanner's folded text.
+	s = NewFoldingScanner([]byte(`ABCDEF GHI`))
+	if node, s = word(s); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if node, _ = Token(`[a-z]+ [a-z]+`, "WORDS")(s); node == nil {
+		t.Errorf("expected match")
+	}
+}
+
+func TestRegion(t *testing.T) {
+	text := []byte(`config {"a": [1, {"b": 2}], "c": null} rest`)
+	region := Region(Atom("{", "OPEN"), Atom("}", "CLOSE"), "REGION")
+	y := And(nil, Ident(), region, Ident())
+	node, s := y(NewScanner(text))
+	if node == nil {
+		t.Fatalf("expected match")
+	} else if !s.Endof() {
+		t.Fatalf("expected end of text")
+	}
+	term := node.([]ParsecNode)[1].(*Terminal)
+	ref := `"a": [1, {"b": 2}], "c": null`
+	if term.Name != "REGION" || term.Value != ref {
+		t.Errorf("unexpected %v", term)
+	} else if term.Position != 8 {
+		t.Errorf("expected %v, got %v", 8, term.Position)
+	}
+
+	// parse the region later with JSON grammar.
+	inner := "{" + term.Value + "}"
+	for _, s := range []Scanner{NewScanner([]byte(inner)), NewScannerString(inner)} {
+		if node, s := makejsony()(s); node == nil {
+			t.Errorf("expected match")
+		} else if !s.Endof() {
+			t.Errorf("expected end of text")
+		}
+	}
+
+	// same region on other scanners.
+	ss := NewScannerString(string(text))
+	if node, _ := y(ss); node.([]ParsecNode)[1].(*Terminal).Value != ref {
+		t.Errorf("expected %q, got %v", ref, node)
+	}
+	rs := NewScannerAt(bytes.NewReader(text), 0, int64(len(text)))
+	if node, _ := y(rs); node.([]ParsecNode)[1].(*Terminal).Value != ref {
+		t.Errorf("expected %q, got %v", ref, node)
+	}
+
+	// same open and close.
+	quoted := Region(AtomExact("'", "Q"), AtomExact("'", "Q"), "QUOTED")
+	if node, _ := quoted(NewScanner([]byte(`'{a}' b`))); node == nil {
+		t.Errorf("expected match")
+	} else if v := node.(*Terminal).Value; v != "{a}" {
+		t.Errorf("expected %q, got %q", "{a}", v)
+	}
+
+	// unclosed region.
+	s = NewScanner([]byte(`{"a": {}`))
+	if node, news := region(s); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if news.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, news.GetCursor())
+	}
+}
+
+func TestAtBoundary(t *testing.T) {
+	keyword := AtBoundary(Atom("if", "IF"), WordBoundary)
+	first := func(ns []ParsecNode) ParsecNode { return ns[0] }
+	y := OrdChoice(first, keyword, Ident())
+	for _, text := range []string{"if", " if (x)", "if\n", "if+"} {
+		node, _ := y(NewScanner([]byte(text)))
+		if name := node.(*Terminal).Name; name != "IF" {
+			t.Errorf("expected %v for %q, got %v", "IF", text, name)
+		}
+	}
+	for _, text := range []string{"iffy", "if_x", "if2", "ifé"} {
+		s := NewScanner([]byte(text))
+		if node, news := keyword(s); node != nil {
+			t.Errorf("unexpected %v for %q", node, text)
+		} else if news.GetCursor() != 0 {
+			t.Errorf("expected %v, got %v", 0, news.GetCursor())
+		}
+		node, _ := y(NewScanner([]byte(text)))
+		if name := node.(*Terminal).Name; name != "IDENT" {
+			t.Errorf("expected %v for %q, got %v", "IDENT", text, name)
+		}
+	}
+
+	// boundary can move the cursor without affecting the result.
+	eol := func(s Scanner) bool {
+		s.SkipAny(`^[ \t]*`)
+		_, ok := s.TryMatch(`^\n`)
+		return ok || s.Endof()
+	}
+	y = AtBoundary(Int(), eol)
+	node, s := y(NewScanner([]byte("10  \n20")))
+	if node == nil {
+		t.Fatalf("expected match")
+	} else if s.GetCursor() != 2 {
+		t.Errorf("expected %v, got %v", 2, s.GetCursor())
+	}
+	if node, _ := y(NewScanner([]byte("10 20"))); node != nil {
+		t.Errorf("unexpected %v", node)
+	}
+}
+
+func TestByteDispatch(t *testing.T) {
+	// records tagged by type byte: I for 4 digit integer, S for 3 letter
+	// code and \xff for a 2 byte binary field.
+	field := func(pattern, name string) Parser {
+		return TokenExact(pattern, name)
+	}
+	record := ByteDispatch(map[byte]Parser{
+		'I':  field(`I[0-9]{4}`, "INT"),
+		'S':  field(`S[A-Z]{3}`, "CODE"),
+		0xff: field(`(?s)...`, "BIN"),
+	})
+	text := []byte("I0042SABC\xff\x00\nI1234")
+	node, s := Many(nil, record)(NewScanner(text))
+	if node == nil {
+		t.Fatalf("expected match")
+	} else if !s.Endof() {
+		t.Fatalf("expected end of text, at %v", s.GetCursor())
+	}
+	names := []string{"INT", "CODE", "BIN", "INT"}
+	for i, n := range node.([]ParsecNode) {
+		if term := n.(*Terminal); term.Name != names[i] {
+			t.Errorf("expected %v, got %v", names[i], term.Name)
+		}
+	}
+	if term := node.([]ParsecNode)[1].(*Terminal); term.Value != "SABC" {
+		t.Errorf("expected %q, got %q", "SABC", term.Value)
+	} else if term.Position != 5 {
+		t.Errorf("expected %v, got %v", 5, term.Position)
+	}
+
+	// unknown byte, failing field parser and end of input.
+	for _, text := range []string{"X0042", "I00x2", " I0042", ""} {
+		s := NewScanner([]byte(text))
+		if node, news := record(s); node != nil {
+			t.Errorf("unexpected %v for %q", node, text)
+		} else if news.GetCursor() != 0 {
+			t.Errorf("expected %v, got %v", 0, news.GetCursor())
+		}
+	}
+}
+
+func TestGuarded(t *testing.T) {
+	calls := 0
+	var num Parser = func(s Scanner) (ParsecNode, Scanner) {
+		calls++
+		return Int()(s)
+	}
+	word := Ident()
+	plain := OrdChoice(nil, num, word)
+	guarded := OrdChoice(nil, Guarded("+-0123456789", num), word)
+
+	// guarded choice match the same input as the plain choice.
+	for _, text := range []string{" 42", " -7", "  abc", "x1", "", "?"} {
+		calls = 0
+		node1, s1 := plain(NewScanner([]byte(text)))
+		node2, s2 := guarded(NewScanner([]byte(text)))
+		if !reflect.DeepEqual(node1, node2) {
+			t.Errorf("for %q expected %v, got %v", text, node1, node2)
+		} else if s1.GetCursor() != s2.GetCursor() {
+			t.Errorf("for %q expected %v, got %v", text, s1.GetCursor(), s2.GetCursor())
+		}
+	}
+
+	// guarded parser is not tried for other first characters.
+	calls = 0
+	if node, _ := guarded(NewScanner([]byte("  abc"))); node == nil {
+		t.Errorf("expected match")
+	} else if calls != 0 {
+		t.Errorf("expected %v, got %v", 0, calls)
+	}
+	// whitespace is skipped before peeking, and input is not consumed on
+	// failure.
+	y := Guarded("0123456789", num)
+	if node, _ := y(NewScanner([]byte("\n  12"))); node == nil {
+		t.Errorf("expected match")
+	}
+	if node, s := y(NewScanner([]byte("  x"))); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+	// scanner's white space pattern is honoured.
+	s := NewScanner([]byte("\n  12")).SetWSPattern(`^[ \t]+`)
+	if node, _ := y(s); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if node, _ := y(NewScannerString("\n  12")); node == nil {
+		t.Errorf("expected match")
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected panic for empty first set")
+		}
+	}()
+	Guarded("", num)
+}
+
+func TestList(t *testing.T) {
+	y := List(nil, 0, Int(), Atom(",", "COMMA"))
+	testcases := []struct {
+		text   string
+		count  int
+		cursor int
+	}{
+		{"", 0, 0},
+		{"1", 1, 1},
+		{"1, 2,3", 3, 6},
+		{",1", 0, 0},   // leading separator
+		{"1,", 1, 1},   // trailing separator
+		{"1,,2", 1, 1}, // empty item
+		{"1 2", 1, 1},
+	}
+	for _, tcase := range testcases {
+		node, s := y(NewScanner([]byte(tcase.text)))
+		if node == nil {
+			t.Errorf("%q: expected match", tcase.text)
+		} else if ns := node.([]ParsecNode); len(ns) != tcase.count {
+			t.Errorf("%q: expected %v, got %v", tcase.text, tcase.count, ns)
+		} else if s.GetCursor() != tcase.cursor {
+			t.Errorf("%q: expected %v, got %v", tcase.text, tcase.cursor, s.GetCursor())
+		}
+	}
+
+	// minimum count
+	y = List(nil, 2, Int(), Atom(",", "COMMA"))
+	for _, text := range []string{"", "1", "1,", "1,x"} {
+		if node, s := y(NewScanner([]byte(text))); node != nil {
+			t.Errorf("%q: unexpected %v", text, node)
+		} else if s.GetCursor() != 0 {
+			t.Errorf("%q: expected %v, got %v", text, 0, s.GetCursor())
+		}
+	}
+	if node, _ := y(NewScanner([]byte("1,2"))); node == nil {
+		t.Errorf("expected match")
+	}
+
+	// separator and item by reference, and nodify callback.
+	var item Parser = Ident()
+	var sep Parser = Atom(";", "SEMICOLON")
+	count := func(ns []ParsecNode) ParsecNode {
+		if len(ns) == 0 {
+			return nil
+		}
+		return len(ns)
+	}
+	y = List(count, 0, &item, &sep)
+	if node, _ := y(NewScanner([]byte("a; b; c"))); node != 3 {
+		t.Errorf("expected %v, got %v", 3, node)
+	} else if node, s := y(NewScanner([]byte("1"))); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+}
+
+func TestTypedSettings(t *testing.T) {
+	duration := And(
+		func(ns []ParsecNode) ParsecNode {
+			d, err := time.ParseDuration(ns[0].(*Terminal).Value)
+			if err != nil {
+				return nil
+			}
+			return d
+		},
+		Token(`[0-9]+(ns|us|ms|s|m|h)`, "DURATION"),
+	)
+	integer := And(
+		func(ns []ParsecNode) ParsecNode {
+			n, _ := strconv.Atoi(ns[0].(*Terminal).Value)
+			return n
+		},
+		AtBoundary(Int(), WordBoundary),
+	)
+	y := TypedSettings(map[string]Parser{
+		"timeout": duration,
+		"retries": integer,
+		"name":    String(),
+	})
+
+	text := "timeout: 5s\nretries: 3\nname: \"primary\"\nretries: 4 end"
+	node, s := y(NewScanner([]byte(text)))
+	ref := map[string]ParsecNode{
+		"timeout": 5 * time.Second, "retries": 4, "name": `"primary"`,
+	}
+	if !reflect.DeepEqual(node, ref) {
+		t.Errorf("expected %v, got %v", ref, node)
+	} else if s.GetCursor() != len(text)-4 {
+		t.Errorf("expected %v, got %v", len(text)-4, s.GetCursor())
+	}
+
+	// type mismatch, unknown key.
+	for _, text := range []string{"retries: abc", "retries: 3abc", "timeout: 5", "colour: red"} {
+		if node, s := y(NewScanner([]byte(text))); node != nil {
+			t.Errorf("%q: unexpected %v", text, node)
+		} else if s.GetCursor() != 0 {
+			t.Errorf("%q: expected %v, got %v", text, 0, s.GetCursor())
+		}
+	}
+
+	// no settings.
+	if node, s := y(NewScanner([]byte("10"))); len(node.(map[string]ParsecNode)) != 0 {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+}
+
+func TestAndStruct(t *testing.T) {
+	type objectRule struct {
+		Open    *Terminal
+		Content []ParsecNode
+		Sign    ParsecNode
+		Close   *Terminal
+	}
+	ident := Token(`[a-z]+`, "IDENT")
+	content := Kleene(nil, ident, Atom(",", "COMMA"))
+	first := func(ns []ParsecNode) ParsecNode { return ns[0] }
+	sign := Maybe(first, Atom("!", "BANG"))
+	y := AndStruct(&objectRule{},
+		Atom("{", "OPEN"), content, sign, Atom("}", "CLOSE"))
+
+	node, s := y(NewScanner([]byte("{ a, b ! }")))
+	obj, ok := node.(*objectRule)
+	if !ok {
+		t.Fatalf("unexpected %T", node)
+	} else if !s.Endof() {
+		t.Errorf("expected end of text")
+	} else if obj.Open.Value != "{" || obj.Close.Value != "}" {
+		t.Errorf("unexpected %v %v", obj.Open, obj.Close)
+	} else if len(obj.Content) != 2 {
+		t.Errorf("unexpected %v", obj.Content)
+	} else if obj.Sign.(*Terminal).Value != "!" {
+		t.Errorf("unexpected %v", obj.Sign)
+	}
+	// every match returns a new instance, missing nodes are kept as is
+	// when field can hold them.
+	node, _ = y(NewScanner([]byte("{}")))
+	if other := node.(*objectRule); other == obj {
+		t.Errorf("expected new instance")
+	} else if len(other.Content) != 0 || other.Sign != MaybeNone("missing") {
+		t.Errorf("unexpected %v", other)
+	}
+	// no match
+	if node, s = y(NewScanner([]byte("{ a"))); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+
+	// missing nodes leave zero value for fields that cannot hold them.
+	type optRule struct {
+		Sign  *Terminal
+		Ident *Terminal
+	}
+	node, _ = AndStruct(&optRule{}, sign, ident)(NewScanner([]byte("x")))
+	if opt := node.(*optRule); opt.Sign != nil || opt.Ident.Value != "x" {
+		t.Errorf("unexpected %v", opt)
+	}
+
+	// panic cases
+	type unexported struct{ open *Terminal }
+	type wrongtype struct{ Open *NonTerminal }
+	panics := []func(){
+		func() { AndStruct(objectRule{}, ident, ident, ident, ident) },
+		func() { AndStruct(new(int), ident) },
+		func() { AndStruct(&objectRule{}, ident) },
+		func() { AndStruct(&unexported{}, ident) },
+		func() { AndStruct(&wrongtype{}, ident)(NewScanner([]byte("x"))) },
+	}
+	for i, fn := range panics {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("%v expected panic", i)
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+func TestParseSingleLine(t *testing.T) {
+	y := And(nil, Int(), Atom("+", "PLUS"), Int())
+	node, err := ParseSingleLine(" 10 + 20 \n", y)
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	} else if ns := node.([]ParsecNode); len(ns) != 3 {
+		t.Errorf("unexpected %v", ns)
+	} else if v := ns[2].(*Terminal).Value; v != "20" {
+		t.Errorf("expected %v, got %v", "20", v)
+	}
+
+	errtexts := map[string]string{
+		"10 + ":    "parse error at offset 0",
+		"10 + 20;": "parse error at offset 7",
+		"":         "parse error at offset 0",
+	}
+	for text, ref := range errtexts {
+		if _, err := ParseSingleLine(text, y); err == nil || err.Error() != ref {
+			t.Errorf("%q expected %v, got %v", text, ref, err)
+		}
+	}
+}
+
+func TestHeredoc(t *testing.T) {
+	y := And(nil,
+		Token(`cat`, "CMD"), Heredoc(Token(`<<~?[A-Z]+`, "INTRO")),
+		Token(`echo`, "CMD"))
+
+	// basic heredoc.
+	text := "cat <<EOF\nhello\n  world\nEOF\necho"
+	node, s := y(NewScanner([]byte(text)).TrackLineno())
+	if node == nil {
+		t.Fatalf("expected match")
+	} else if !s.Endof() || s.Lineno() != 5 {
+		t.Errorf("unexpected %v %v", s.GetCursor(), s.Lineno())
+	}
+	doc := node.([]ParsecNode)[1].(*Terminal)
+	if doc.Value != "hello\n  world\n" || doc.Position != 10 {
+		t.Errorf("unexpected %q %v", doc.Value, doc.Position)
+	}
+	// marker must be the whole line.
+	text = "cat <<EOF\nEOFS\n EOF\nEOF\r\necho"
+	node, _ = y(NewScanner([]byte(text)))
+	if doc := node.([]ParsecNode)[1].(*Terminal); doc.Value != "EOFS\n EOF\n" {
+		t.Errorf("unexpected %q", doc.Value)
+	}
+
+	// indented heredoc.
+	text = "cat <<~EOF\n    hello\n\n      world\n  EOF\necho"
+	node, _ = y(NewScanner([]byte(text)))
+	if node == nil {
+		t.Fatalf("expected match")
+	}
+	doc = node.([]ParsecNode)[1].(*Terminal)
+	if doc.Value != "hello\n\n  world\n" {
+		t.Errorf("unexpected %q", doc.Value)
+	}
+
+	// unterminated heredoc, and introducer followed by text.
+	for _, text := range []string{"cat <<EOF\nhello\nworld\n", "cat <<EOF x\nEOF\n"} {
+		h := Heredoc(Token(`<<~?[A-Z]+`, "INTRO"))
+		s := NewScanner([]byte(text)).SkipN(3)
+		if node, s := h(s); node != nil {
+			t.Errorf("unexpected %v", node)
+		} else if s.GetCursor() != 3 {
+			t.Errorf("expected %v, got %v", 3, s.GetCursor())
+		}
+		if _, err := ParseSingleLine(text, y); err == nil {
+			t.Errorf("expected error")
+		}
+	}
+}
+
+func TestTruncateValid(t *testing.T) {
+	refs := []struct {
+		text string
+		max  int
+		out  string
+	}{
+		{"hello world", 20, "hello world"},
+		{"hello world", 11, "hello world"},
+		{"hello world", 5, "hello..."},
+		{"hello", 0, "..."},
+		{"abé", 3, "ab..."},            // 2-byte rune
+		{"ab世界", 4, "ab..."},           // 3-byte rune, cut after 2 bytes
+		{"ab世界", 5, "ab世..."},          // 3-byte rune, cut on boundary
+		{"ab\U0001F600cd", 5, "ab..."}, // 4-byte rune, cut after 3 bytes
+		{"ab\U0001F600cd", 6, "ab\U0001F600..."},
+	}
+	for _, ref := range refs {
+		out := truncateValid([]byte(ref.text), ref.max)
+		if out != ref.out {
+			t.Errorf("%q,%v expected %q, got %q", ref.text, ref.max, ref.out, out)
+		} else if !utf8.ValidString(out) {
+			t.Errorf("%q,%v invalid utf8 %q", ref.text, ref.max, out)
+		}
+	}
+}
+
+func TestExclusiveChoice(t *testing.T) {
+	ident := Token(`[a-z]+`, "IDENT")
+	keyword := Atom("if", "IF")
+	number := Int()
+	ambiguous := ExclusiveChoice(nil, keyword, ident)
+	disjoint := ExclusiveChoice(nil, number, ident)
+
+	defer func() { DebugAmbiguity = false }()
+	for _, debug := range []bool{false, true} {
+		DebugAmbiguity = debug
+		for _, text := range []string{"10", "iffy"} {
+			node, _ := disjoint(NewScanner([]byte(text)))
+			if node == nil {
+				t.Errorf("expected match for %q", text)
+			}
+		}
+		// ambiguity is detected only when input is ambiguous.
+		node, _ := ambiguous(NewScanner([]byte("10")))
+		if node != nil {
+			t.Errorf("unexpected %v", node)
+		}
+	}
+
+	DebugAmbiguity = false
+	node, _ := ambiguous(NewScanner([]byte("if")))
+	if node.([]ParsecNode)[0].(*Terminal).Name != "IF" {
+		t.Errorf("unexpected %v", node)
+	}
+	DebugAmbiguity = true
+	func() {
+		defer func() {
+			r := recover()
+			ref := "ambiguous choice at offset 0, parsers [0 1] match"
+			if err, ok := r.(error); !ok || err.Error() != ref {
+				t.Errorf("expected %v, got %v", ref, r)
+			}
+		}()
+		ambiguous(NewScanner([]byte("if")))
+	}()
+}
+
+func TestPredicate(t *testing.T) {
+	blankline := Predicate(func(s Scanner) bool {
+		_, ok := s.TryMatch(`^[ \t]*(?:\n|$)`)
+		return ok
+	})
+	paragraph := And(nil, Token(`[^\n]+`, "LINE"))
+	y := OrdChoice(nil, And(nil, blankline, TokenExact(`[ \t]*\n?`, "BLANK")), paragraph)
+
+	node, s := y(NewScanner([]byte("  \nhello")))
+	if node == nil {
+		t.Fatalf("expected match")
+	}
+	ns := node.([]ParsecNode)[0].([]ParsecNode)
+	if pred := ns[0].(*Terminal); pred.Name != "PREDICATE" || pred.Position != 0 {
+		t.Errorf("unexpected %v", pred)
+	} else if s.GetCursor() != 3 {
+		t.Errorf("expected %v, got %v", 3, s.GetCursor())
+	}
+
+	// predicate fails without consuming input, scanner is not modified.
+	s = NewScanner([]byte("hello"))
+	seen := Predicate(func(s Scanner) bool {
+		s.SkipN(3)
+		return false
+	})
+	if node, news := seen(s); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if news.GetCursor() != 0 || s.GetCursor() != 0 {
+		t.Errorf("unexpected %v %v", news.GetCursor(), s.GetCursor())
+	}
+	node, _ = y(s)
+	if ns := node.([]ParsecNode)[0].([]ParsecNode); ns[0].(*Terminal).Value != "hello" {
+		t.Errorf("unexpected %v", ns)
+	}
+}
+
+func TestAttrList(t *testing.T) {
+	name := Token(`[A-Za-z_:][-A-Za-z0-9_:.]*`, "NAME")
+	value := Token("[^\\s\"'=<>`]+", "VALUE")
+	y := AttrList(name, value)
+
+	type attr struct{ name, value, quote string }
+	check := func(node ParsecNode, refs []attr) {
+		attrs := node.([]ParsecNode)
+		if len(attrs) != len(refs) {
+			t.Fatalf("expected %v, got %v", len(refs), len(attrs))
+		}
+		for i, ref := range refs {
+			nt := attrs[i].(*NonTerminal)
+			if nt.Name != "ATTRIBUTE" || nt.Children[0].GetValue() != ref.name {
+				t.Errorf("expected %v, got %v", ref.name, nt.Children[0])
+			} else if ref.value == "" && len(nt.Children) != 1 {
+				t.Errorf("expected boolean attribute, got %v", nt.Children)
+			} else if ref.value == "" {
+				continue
+			}
+			v := nt.Children[1]
+			if v.GetName() != "VALUE" || v.GetValue() != ref.value {
+				t.Errorf("expected %v, got %v", ref.value, v.GetValue())
+			} else if quote := v.GetAttribute("quote"); ref.quote != "" &&
+				(len(quote) != 1 || quote[0] != ref.quote) {
+				t.Errorf("expected %v, got %v", ref.quote, quote)
+			}
+		}
+	}
+
+	text := `id="x" disabled checked=yes`
+	node, s := y(NewScanner([]byte(text)))
+	if !s.Endof() {
+		t.Errorf("expected end of text, got %v", s.GetCursor())
+	}
+	check(node, []attr{{"id", "x", `"`}, {"disabled", "", ""}, {"checked", "yes", ""}})
+	if v := node.([]ParsecNode)[0].(*NonTerminal).Children[1]; v.GetPosition() != 4 {
+		t.Errorf("expected %v, got %v", 4, v.GetPosition())
+	}
+
+	// duplicates, last wins in place of the first.
+	text = `class='a b' id = x class="c" />`
+	node, s = y(NewScanner([]byte(text)))
+	check(node, []attr{{"class", "c", `"`}, {"id", "x", ""}})
+	if s.GetCursor() != 28 {
+		t.Errorf("expected %v, got %v", 28, s.GetCursor())
+	}
+
+	// attributes must be separated by whitespace.
+	node, s = y(NewScanner([]byte(`a="1"b="2"`)))
+	check(node, []attr{{"a", "1", `"`}})
+	if s.GetCursor() != 5 {
+		t.Errorf("expected %v, got %v", 5, s.GetCursor())
+	}
+	// attribute without value is not consumed.
+	node, s = y(NewScanner([]byte(`a b= >`)))
+	check(node, []attr{{"a", "", ""}})
+	if s.GetCursor() != 1 {
+		t.Errorf("expected %v, got %v", 1, s.GetCursor())
+	}
+	// quoted value is matched only at the cursor.
+	node, _ = y(NewScanner([]byte(`a=x b='y'`)))
+	check(node, []attr{{"a", "x", ""}, {"b", "y", "'"}})
+	// no attributes.
+	if node, s = y(NewScanner([]byte(`>`))); len(node.([]ParsecNode)) != 0 {
+		t.Errorf("unexpected %v", node)
+	}
+}
+
+func TestHeaderDispatch(t *testing.T) {
+	header := And(nil, Atom("version", "KEY"), Atom(":", "COLON"), Int())
+	bodies := map[string]Parser{
+		"1": Token(`[a-z]+`, "NAME"),
+		"2": And(nil, Token(`[a-z]+`, "NAME"), Atom("=", "EQUAL"), Int()),
+	}
+	y := HeaderDispatch(header, func(hn ParsecNode) (Parser, error) {
+		version := hn.([]ParsecNode)[2].(*Terminal).Value
+		if body, ok := bodies[version]; ok {
+			return body, nil
+		}
+		return nil, fmt.Errorf("unknown version %v", version)
+	})
+
+	node, s := y(NewScanner([]byte("version: 2\nretries = 3")))
+	if !s.Endof() {
+		t.Fatalf("expected end of text, got %v", s.GetCursor())
+	}
+	doc := node.(*NonTerminal)
+	if doc.Name != "DOCUMENT" || len(doc.Children) != 2 {
+		t.Fatalf("unexpected %v", doc)
+	}
+	hdr, body := doc.Children[0].(*NodeValue), doc.Children[1].(*NodeValue)
+	if hdr.Name != "HEADER" || body.Name != "BODY" {
+		t.Errorf("unexpected %v %v", hdr.Name, body.Name)
+	} else if ns := body.Node.([]ParsecNode); len(ns) != 3 {
+		t.Errorf("unexpected %v", ns)
+	} else if v := ns[2].(*Terminal).Value; v != "3" {
+		t.Errorf("expected %v, got %v", "3", v)
+	}
+
+	// version 1 body does not match version 2 grammar.
+	node, s = y(NewScanner([]byte("version: 1\nretries = 3")))
+	if name := node.(*NonTerminal).Children[1].GetName(); name != "NAME" {
+		t.Errorf("expected %v, got %v", "NAME", name)
+	} else if s.GetCursor() != 18 {
+		t.Errorf("expected %v, got %v", 18, s.GetCursor())
+	}
+	if node, s = y(NewScanner([]byte("version: 2\nretries"))); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+
+	// unknown version.
+	s = NewScanner([]byte("version: 3\nretries = 3"))
+	if node, _ = y(s); node != nil {
+		t.Errorf("unexpected %v", node)
+	}
+	ref := "unknown version 3 at offset 11"
+	if err := Aborted(s); err == nil || err.Error() != ref {
+		t.Errorf("expected %q, got %v", ref, err)
+	}
+}
+
+func TestDocComment(t *testing.T) {
+	check := func(node ParsecNode, text string, from, till int) {
+		t.Helper()
+		nt, ok := node.(*NonTerminal)
+		if !ok || nt.Name != "COMMENT" || len(nt.Children) != 3 {
+			t.Fatalf("unexpected %v", node)
+		}
+		if v := nt.Children[1].GetValue(); v != text {
+			t.Errorf("expected %q, got %q", text, v)
+		}
+		if start, end, _ := Span(nt); start != from || end != till {
+			t.Errorf("expected [%v, %v), got [%v, %v)", from, till, start, end)
+		}
+	}
+
+	text := " /** Add two numbers. */ func add()"
+	node, s := DocComment("/**", "*/", false)(NewScanner([]byte(text)))
+	check(node, " Add two numbers. ", 1, 24)
+	if s.GetCursor() != 24 {
+		t.Errorf("expected %v, got %v", 24, s.GetCursor())
+	}
+
+	// nested
+	text = "/* a /* b */ c */ d */"
+	node, s = DocComment("/*", "*/", true)(NewScanner([]byte(text)))
+	check(node, " a /* b */ c ", 0, 17)
+	node, s = DocComment("/*", "*/", false)(NewScanner([]byte(text)))
+	check(node, " a /* b ", 0, 12)
+	if s.GetCursor() != 12 {
+		t.Errorf("expected %v, got %v", 12, s.GetCursor())
+	}
+	// same delimiters, like doc strings.
+	node, _ = DocComment(`"""`, `"""`, false)(NewScanner([]byte(`"""doc""" x`)))
+	check(node, "doc", 0, 9)
+
+	// not closed, or not a comment.
+	for _, text := range []string{"/* a /* b */", "// a", ""} {
+		s := NewScanner([]byte(text))
+		if node, news := DocComment("/*", "*/", true)(s); node != nil {
+			t.Errorf("%q: unexpected %v", text, node)
+		} else if news.GetCursor() != 0 {
+			t.Errorf("%q: expected %v, got %v", text, 0, news.GetCursor())
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+import "os"
+import "sort"
+import "unicode/utf8"
+
+// SourceError is returned by ParseString and ParseFile, positioning the
+// parse error by line and column, starting from 1, within the named
+// input. Column counts characters from the beginning of the line.
+type SourceError struct {
+	Name string // file name, empty for ParseString.
+	Line int
+	Col  int
+	Err  *ParseError
+}
+
+func (err *SourceError) Error() string {
+	if err.Name == "" {
+		return fmt.Sprintf("%v:%v: %v", err.Line, err.Col, err.Err.Msg)
+	}
+	return fmt.Sprintf("%v:%v:%v: %v", err.Name, err.Line, err.Col, err.Err.Msg)
+}
+
+// Unwrap return the underlying *ParseError.
+func (err *SourceError) Unwrap() error {
+	return err.Err
+}
+
+// ParseString apply parser `p` on `text` using Run, that is the text
+// shall be consumed completely, except for trailing whitespace. If `p`
+// fails, the error is positioned at the furthest cursor reached while
+// parsing. Return *SourceError on failure.
+func ParseString(p Parser, text string) (ParsecNode, error) {
+	return parseSource(p, "", []byte(text))
+}
+
+// ParseFile same as ParseString, for the content of file at `path`,
+// and the error is positioned within the file. Return error if the file
+// can't be read.
+func ParseFile(p Parser, path string) (ParsecNode, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseSource(p, path, text)
+}
+
+// ParseWithTokens apply parser `p` on `text` using Run, and return the
+// root node along with every Terminal in the tree, in source order, for
+// consumers like syntax highlighters that need the token stream
+// alongside the structure. Terminals are collected from the children of
+// NonTerminal and []ParsecNode nodes, including nodes embedding
+// *Terminal, like NumberNode. Return nil if `p` fails.
+func ParseWithTokens(p Parser, text []byte) (ParsecNode, []*Terminal) {
+	res := Run(p, NewScanner(text))
+	if res.Err != nil {
+		return nil, nil
+	}
+	tokens := collectTerminals(res.Node, []*Terminal{})
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return tokens[i].Position < tokens[j].Position
+	})
+	return res.Node, tokens
+}
+
+func collectTerminals(node ParsecNode, tokens []*Terminal) []*Terminal {
+	switch n := node.(type) {
+	case *Terminal:
+		return append(tokens, n)
+	case *NumberNode:
+		return append(tokens, n.Terminal)
+	case *Base64Node:
+		return append(tokens, n.Terminal)
+	case *ErrorNode:
+		return append(tokens, n.Terminal)
+	case *GlobNode:
+		return append(tokens, n.Terminal)
+	case []ParsecNode:
+		for _, child := range n {
+			tokens = collectTerminals(child, tokens)
+		}
+	case *NodeValue:
+		return collectTerminals(n.Node, tokens)
+	case Queryable:
+		for _, child := range n.GetChildren() {
+			tokens = collectTerminals(child, tokens)
+		}
+	}
+	return tokens
+}
+
+func parseSource(p Parser, name string, text []byte) (ParsecNode, error) {
+	furthest := 0
+	s := NewScannerWith(text,
+		WithProgress(1, func(cursor, total int64) { furthest = int(cursor) }))
+	res := Run(p, s)
+	if res.Err == nil {
+		return res.Node, nil
+	}
+	perr := res.Err.(*ParseError)
+	if furthest > perr.Offset && perr.Err == nil {
+		perr.Offset = furthest
+	}
+	line, col := LineCol(text, perr.Offset)
+	return nil, &SourceError{Name: name, Line: line, Col: col, Err: perr}
+}
+
+// LineCol return the line and column, starting from 1, of `offset` in
+// `text`, where column counts characters from the beginning of the line.
+func LineCol(text []byte, offset int) (line, col int) {
+	line, from := 1, 0
+	for i := 0; i < offset && i < len(text); i++ {
+		if text[i] == '\n' {
+			line, from = line+1, i+1
+		}
+	}
+	if offset > len(text) {
+		offset = len(text)
+	}
+	return line, column(text[from:offset], 0)
+}
+
+// column return the column, starting from 1, after the characters in
+// `prefix` of a line. If `tabwidth` is > 0, tabs advance the column to
+// the next tab stop.
+func column(prefix []byte, tabwidth int) int {
+	if tabwidth <= 0 {
+		return utf8.RuneCount(prefix) + 1
+	}
+	col := 1
+	for _, r := range string(prefix) {
+		if r == '\t' {
+			col = ((col-1)/tabwidth+1)*tabwidth + 1
+			continue
+		}
+		col++
+	}
+	return col
+}
@@ -0,0 +1,115 @@
+package parsec
+
+import "errors"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "testing"
+
+func TestParseString(t *testing.T) {
+	y := Kleene(nil, And(nil, Token(`[a-z]+`, "KEY"), Atom("=", "EQUAL"), Int()))
+
+	node, err := ParseString(y, "a = 1\nb = 2\n")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(node.([]ParsecNode)) != 2 {
+		t.Errorf("unexpected %v", node)
+	}
+
+	// trailing garbage, positioned at the furthest cursor.
+	_, err = ParseString(y, "a = 1\nbé = 2\n")
+	var serr *SourceError
+	if !errors.As(err, &serr) {
+		t.Fatalf("unexpected %v", err)
+	} else if ref := "2:2: parse error"; err.Error() != ref {
+		t.Errorf("expected %q, got %q", ref, err.Error())
+	} else if serr.Err.Offset != 7 {
+		t.Errorf("expected %v, got %v", 7, serr.Err.Offset)
+	}
+	_, err = ParseString(y, "a = 1\nb = x")
+	if ref := "2:5: parse error"; err == nil || err.Error() != ref {
+		t.Errorf("expected %q, got %v", ref, err)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	y := Kleene(nil, And(nil, Token(`[a-z]+`, "KEY"), Atom("=", "EQUAL"), Int()))
+
+	dir, err := ioutil.TempDir("", "parsec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "settings.txt")
+	if err := ioutil.WriteFile(path, []byte("a = 1\nb = 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if node, err := ParseFile(y, path); err != nil {
+		t.Fatal(err)
+	} else if len(node.([]ParsecNode)) != 2 {
+		t.Errorf("unexpected %v", node)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("a = 1\n\n  b = 2 ;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ParseFile(y, path)
+	if ref := path + ":3:9: parse error"; err == nil || err.Error() != ref {
+		t.Errorf("expected %q, got %v", ref, err)
+	}
+
+	// missing file.
+	_, err = ParseFile(y, filepath.Join(dir, "missing.txt"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("unexpected %v", err)
+	}
+}
+
+func TestParseWithTokens(t *testing.T) {
+	ast := NewAST("list", 100)
+	var value Parser
+	number := ConfigurableNumber(JSONNumbers)
+	values := ast.Kleene("VALUES", nil, &value, Atom(",", "COMMA"))
+	list := ast.And("LIST", nil, Atom("[", "OPEN"), values, Atom("]", "CLOSE"))
+	value = ast.OrdChoice("VALUE", nil, number, Ident(), list)
+
+	text := []byte("[1, [a, [2.5]], b]")
+	node, tokens := ParseWithTokens(value, text)
+	if node == nil {
+		t.Fatalf("expected match")
+	}
+	// flattened walk of the tree.
+	var flatten func(q Queryable) []string
+	flatten = func(q Queryable) []string {
+		if q.IsTerminal() {
+			return []string{q.GetValue()}
+		}
+		values := []string{}
+		for _, child := range q.GetChildren() {
+			values = append(values, flatten(child)...)
+		}
+		return values
+	}
+	ref := flatten(node.(Queryable))
+	if len(tokens) != len(ref) {
+		t.Fatalf("expected %v tokens, got %v", len(ref), len(tokens))
+	}
+	offset := -1
+	for i, token := range tokens {
+		if token.Value != ref[i] {
+			t.Errorf("expected %q, got %q", ref[i], token.Value)
+		} else if token.Position <= offset {
+			t.Errorf("%q at %v is out of order", token.Value, token.Position)
+		}
+		offset = token.Position
+	}
+	if tokens[5].Name != "FLOAT" {
+		t.Errorf("expected %v, got %v", "FLOAT", tokens[5].Name)
+	}
+
+	if node, tokens := ParseWithTokens(value, []byte("[1,")); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if tokens != nil {
+		t.Errorf("unexpected %v", tokens)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+// PEGParser construct parsers using the vocabulary of parsing expression
+// grammars, each operator is implemented by the combinator with the same
+// semantics:
+//
+//	e1 e2 ...   Sequence       And
+//	e1 / e2     OrderedChoice  OrdChoice
+//	e*          ZeroOrMore     Kleene
+//	e+          OneOrMore      Many
+//	e?          Optional       Maybe
+//	&e          AndPredicate   Lookahead
+//	!e          NotPredicate   Not
+//
+// Nodify, if not nil, is supplied as callback to the combinators that
+// accept one.
+type PEGParser struct {
+	Nodify Nodify
+}
+
+// Sequence matches all of `parsers` in order, refer And.
+func (peg *PEGParser) Sequence(parsers ...Parser) Parser {
+	return And(peg.Nodify, peg.args(parsers)...)
+}
+
+// OrderedChoice matches the first of `parsers` that succeeds, refer
+// OrdChoice.
+func (peg *PEGParser) OrderedChoice(parsers ...Parser) Parser {
+	return OrdChoice(peg.Nodify, peg.args(parsers)...)
+}
+
+// ZeroOrMore matches `p` zero or more times, refer Kleene.
+func (peg *PEGParser) ZeroOrMore(p Parser) Parser {
+	return Kleene(peg.Nodify, p)
+}
+
+// OneOrMore matches `p` one or more times, refer Many.
+func (peg *PEGParser) OneOrMore(p Parser) Parser {
+	return Many(peg.Nodify, p)
+}
+
+// Optional matches `p` once or none, refer Maybe.
+func (peg *PEGParser) Optional(p Parser) Parser {
+	return Maybe(peg.Nodify, p)
+}
+
+// AndPredicate matches, without consuming input, if `p` matches, refer
+// Lookahead.
+func (peg *PEGParser) AndPredicate(p Parser) Parser {
+	return Lookahead(p)
+}
+
+// NotPredicate matches, without consuming input, if `p` does not match,
+// refer Not.
+func (peg *PEGParser) NotPredicate(p Parser) Parser {
+	return Not(p)
+}
+
+func (peg *PEGParser) args(parsers []Parser) []interface{} {
+	args := make([]interface{}, 0, len(parsers))
+	for _, p := range parsers {
+		args = append(args, p)
+	}
+	return args
+}
@@ -0,0 +1,52 @@
+package parsec
+
+import "testing"
+
+func TestPEGParser(t *testing.T) {
+	peg := &PEGParser{}
+	keyword := Keywords("if", "else")
+	ident := peg.Sequence(peg.NotPredicate(keyword), Ident())
+	call := peg.Sequence(
+		ident, Atom("(", "("),
+		peg.Optional(peg.Sequence(Int(), peg.ZeroOrMore(peg.Sequence(Atom(",", ","), Int())))),
+		Atom(")", ")"))
+	stmt := peg.OrderedChoice(peg.Sequence(peg.AndPredicate(Atom("if", "IF")), keyword), call)
+	stmts := peg.OneOrMore(stmt)
+
+	// hand-written form of the same grammar.
+	refident := And(nil, Not(keyword), Ident())
+	refcall := And(nil,
+		refident, Atom("(", "("),
+		Maybe(nil, And(nil, Int(), Kleene(nil, And(nil, Atom(",", ","), Int())))),
+		Atom(")", ")"))
+	refstmt := OrdChoice(nil, And(nil, Lookahead(Atom("if", "IF")), keyword), refcall)
+	refstmts := Many(nil, refstmt)
+
+	for _, text := range []string{"f(1, 2) g() if", "else", "if(1)", "iffy(2)"} {
+		ref, rs := refstmts(NewScanner([]byte(text)))
+		node, s := stmts(NewScanner([]byte(text)))
+		if !EqualNodes(ref, node) {
+			t.Errorf("%q: expected %v, got %v", text, ref, node)
+		} else if s.GetCursor() != rs.GetCursor() {
+			t.Errorf("%q: expected %v, got %v", text, rs.GetCursor(), s.GetCursor())
+		}
+	}
+
+	// predicates don't consume input.
+	if node, s := ident(NewScanner([]byte("else"))); node != nil {
+		t.Errorf("unexpected %v at %v", node, s.GetCursor())
+	}
+	node, s := stmts(NewScanner([]byte("f(1, 2) iffy()")))
+	if ns := node.([]ParsecNode); len(ns) != 2 || !s.Endof() {
+		t.Errorf("unexpected %v at %v", node, s.GetCursor())
+	}
+
+	// callback
+	count := 0
+	peg = &PEGParser{Nodify: func(ns []ParsecNode) ParsecNode { count++; return ns }}
+	y := peg.Sequence(peg.OneOrMore(Int()), peg.Optional(Atom(";", ";")))
+	y(NewScanner([]byte("1 2;")))
+	if count != 3 {
+		t.Errorf("expected %v, got %v", 3, count)
+	}
+}
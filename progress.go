@@ -0,0 +1,40 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+// ProgressFunc is called with the number of bytes consumed so far, and
+// the total number of bytes in input, -1 if not known.
+type ProgressFunc func(consumed, total int64)
+
+// Progress reports the furthest cursor position, shared by a scanner
+// and all its clones, refer WithProgress. Custom scanners can use it to
+// implement OnProgress.
+type Progress struct {
+	every int64
+	fn    ProgressFunc
+	total int64
+	next  int64 // report when consumed reaches next
+}
+
+// NewProgress return a Progress calling `fn` atmost once per `every`
+// bytes consumed, of `total` bytes in input.
+func NewProgress(every int, fn ProgressFunc, total int64) *Progress {
+	if every < 1 {
+		every = 1
+	}
+	return &Progress{every: int64(every), fn: fn, total: total, next: int64(every)}
+}
+
+// Update progress to `cursor`, fn is called synchronously, hence from
+// the parsing goroutine, atmost once per `every` bytes consumed. No-op
+// on a nil Progress.
+func (p *Progress) Update(cursor int) {
+	if p == nil {
+		return
+	}
+	if consumed := int64(cursor); consumed >= p.next {
+		p.next = (consumed/p.every + 1) * p.every
+		p.fn(consumed, p.total)
+	}
+}
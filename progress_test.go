@@ -0,0 +1,59 @@
+package parsec
+
+import "bytes"
+import "strings"
+import "testing"
+
+func TestOnProgress(t *testing.T) {
+	text := []byte(strings.Repeat("word ", 200)) // 1000 bytes
+	y := Kleene(nil, OrdChoice(nil, Atom("words", "WORDS"), Ident()))
+
+	type report struct{ consumed, total int64 }
+	newscanners := map[string]func() Scanner{
+		"simple": func() Scanner {
+			return NewScanner(text)
+		},
+		"string": func() Scanner {
+			return NewScannerString(string(text))
+		},
+		"readerat": func() Scanner {
+			r := bytes.NewReader(text)
+			return newScannerAt(r, 0, int64(len(text)), 64, 2)
+		},
+	}
+	for name, newscanner := range newscanners {
+		// no callbacks without the option.
+		if node, s := y(newscanner()); node == nil || s.GetCursor() != 999 {
+			t.Fatalf("%v: expected full match", name)
+		}
+
+		reports := []report{}
+		fn := func(consumed, total int64) {
+			reports = append(reports, report{consumed, total})
+		}
+		s := newscanner()
+		switch ss := s.(type) {
+		case *SimpleScanner:
+			s = ss.OnProgress(100, fn)
+		case *StringScanner:
+			s = ss.OnProgress(100, fn)
+		case *ReaderAtScanner:
+			s = ss.OnProgress(100, fn)
+		}
+		if node, s := y(s); node == nil || s.GetCursor() != 999 {
+			t.Fatalf("%v: expected full match", name)
+		}
+		// once per 100 bytes, though Atom("words") backtracks, and the
+		// last report is from skipping the trailing whitespace.
+		if len(reports) != 10 {
+			t.Fatalf("%v: expected %v, got %v", name, 10, reports)
+		}
+		for i, r := range reports {
+			if r.total != 1000 {
+				t.Errorf("%v: expected %v, got %v", name, 1000, r.total)
+			} else if from := int64(i+1) * 100; r.consumed < from || r.consumed >= from+100 {
+				t.Errorf("%v: unexpected %v", name, r.consumed)
+			}
+		}
+	}
+}
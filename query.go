@@ -0,0 +1,49 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "net/url"
+import "strings"
+
+// QueryPair is a key/value pair parsed by QueryString.
+type QueryPair struct {
+	Key      string
+	Value    string
+	HasValue bool // false for keys without `=`, like `debug` in `debug&x=1`.
+}
+
+// QueryString return parser function to match URL query string, like
+// `k1=v1&k2=v2`, till whitespace or `#`. Keys and values are percent
+// decoded, with `+` decoded as space. Values can be empty, like `k=`,
+// or missing, like `k`, and empty pairs, like in `a=1&&b=2`, are
+// skipped. Return pairs as []QueryPair, in the order they appear,
+// including repeated keys. Fails if the query string is empty or has an
+// invalid percent encoding. Skip leading whitespace.
+func QueryString() Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		news := s.Clone()
+		news.SkipWS()
+		tok, _ := news.Match(`^[^\s#]+`)
+		if tok == nil {
+			return nil, s
+		}
+		pairs := []QueryPair{}
+		for _, part := range strings.Split(string(tok), "&") {
+			if part == "" {
+				continue
+			}
+			key, value, hasValue := part, "", false
+			if i := strings.IndexByte(part, '='); i >= 0 {
+				key, value, hasValue = part[:i], part[i+1:], true
+			}
+			key, kerr := url.QueryUnescape(key)
+			value, verr := url.QueryUnescape(value)
+			if kerr != nil || verr != nil {
+				return nil, s
+			}
+			pairs = append(pairs, QueryPair{Key: key, Value: value, HasValue: hasValue})
+		}
+		return pairs, news
+	}
+}
@@ -0,0 +1,33 @@
+package parsec
+
+import "reflect"
+import "testing"
+
+func TestQueryString(t *testing.T) {
+	text := " name=J%C3%BCrgen+Smith&empty=&flag&tag=a%26b&tag=c&&k%3D=v%3D #frag"
+	node, s := QueryString()(NewScanner([]byte(text)))
+	if node == nil {
+		t.Fatalf("unexpected nil")
+	} else if s.GetCursor() != 62 {
+		t.Errorf("expected %v, got %v", 62, s.GetCursor())
+	}
+	ref := []QueryPair{
+		{Key: "name", Value: "Jürgen Smith", HasValue: true},
+		{Key: "empty", Value: "", HasValue: true},
+		{Key: "flag", Value: "", HasValue: false},
+		{Key: "tag", Value: "a&b", HasValue: true},
+		{Key: "tag", Value: "c", HasValue: true},
+		{Key: "k=", Value: "v=", HasValue: true},
+	}
+	if pairs := node.([]QueryPair); !reflect.DeepEqual(pairs, ref) {
+		t.Errorf("expected %v, got %v", ref, pairs)
+	}
+
+	for _, text := range []string{"", "  ", "#frag", "a=%zz", "a%2=1"} {
+		if node, s := QueryString()(NewScanner([]byte(text))); node != nil {
+			t.Errorf("%q: unexpected %v", text, node)
+		} else if s.GetCursor() != 0 {
+			t.Errorf("%q: expected %v, got %v", text, 0, s.GetCursor())
+		}
+	}
+}
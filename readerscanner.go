@@ -0,0 +1,450 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "bytes"
+import "container/list"
+import "fmt"
+import "io"
+import "regexp"
+import "unicode/utf8"
+
+// default settings for ReaderAtScanner.
+const (
+	readerBlockSize = 64 * 1024
+	readerMaxBlocks = 16
+)
+
+// maxInt is the largest cursor position, on 32-bit platforms windows
+// larger than 2GiB can't be addressed by GetCursor.
+const maxInt = int64(^uint(0) >> 1)
+
+// ReaderAtScanner implements Scanner interface for a window of input
+// text read from io.ReaderAt, like a memory mapped file. Input is paged
+// in lazily, in fixed size blocks, and a small LRU cache of blocks is
+// shared by the scanner and all its clones to make backtracking cheap.
+// Cursor positions are relative to the beginning of the window.
+// Panics if reading from io.ReaderAt fails.
+type ReaderAtScanner struct {
+	blocks       *blockCache
+	cursor       int // cursor within the window
+	lineno       int
+	patternCache map[string]*regexp.Regexp
+	wsPattern    string // white space pattern used by SkipWS()
+	*scanState
+	// settings
+	tracklineno bool
+}
+
+// NewScannerAt create and return a new instance of ReaderAtScanner
+// object, to scan `n` bytes of input starting from offset `off` in `r`.
+// Panics if `n` is too large for int positions, that is beyond 2GiB on
+// 32-bit platforms, use a smaller window instead.
+func NewScannerAt(r io.ReaderAt, off, n int64) Scanner {
+	return newScannerAt(r, off, n, readerBlockSize, readerMaxBlocks)
+}
+
+func newScannerAt(r io.ReaderAt, off, n int64, blocksize, maxblocks int) Scanner {
+	if n > maxInt {
+		panic(fmt.Errorf("window of %v bytes exceeds int positions", n))
+	}
+	return &ReaderAtScanner{
+		blocks:       newBlockCache(r, off, n, blocksize, maxblocks),
+		cursor:       0,
+		lineno:       1,
+		patternCache: make(map[string]*regexp.Regexp),
+		wsPattern:    defaultWS,
+		scanState:    &scanState{},
+		tracklineno:  false,
+	}
+}
+
+// OnProgress same as WithProgress option, where total is the size
+// of the window.
+func (s *ReaderAtScanner) OnProgress(every int, fn ProgressFunc) Scanner {
+	s.progress = NewProgress(every, fn, s.blocks.size)
+	return s
+}
+
+// WithNodeIDs same as WithNodeIDs option.
+func (s *ReaderAtScanner) WithNodeIDs() Scanner {
+	s.nodeids = new(int64)
+	return s
+}
+
+// SetMaxBacktrack same as WithMaxBacktrack option. Blocks behind
+// the limit are discarded from the cache as the scanner advances, hence
+// grammars that backtrack within the limit scan large inputs holding
+// only the blocks covering the limit.
+func (s *ReaderAtScanner) SetMaxBacktrack(bytes int) Scanner {
+	s.backtrack, s.limited = newBacktrack(bytes), true
+	s.backtrack.update(s.cursor)
+	return s
+}
+
+// InternValues same as WithInterning option.
+func (s *ReaderAtScanner) InternValues(maxLen int) Scanner {
+	s.interns = NewInterner(maxLen)
+	return s
+}
+
+// LineContinuation same as WithLineContinuation option.
+func (s *ReaderAtScanner) LineContinuation(marker byte) Scanner {
+	s.continuation = string(marker)
+	s.wsPattern = continuationPattern(s.wsPattern, s.continuation)
+	return s
+}
+
+// CurrentLine same as SimpleScanner.CurrentLine, the line is limited to
+// the window and read block by block.
+func (s *ReaderAtScanner) CurrentLine() []byte {
+	from, till := s.blocks.lineBounds(int64(s.cursor))
+	return bytes.TrimSuffix(s.blocks.slice(from, till), []byte("\r"))
+}
+
+// TabWidth same as WithTabWidth option.
+func (s *ReaderAtScanner) TabWidth(width int) Scanner {
+	s.tabwidth = width
+	return s
+}
+
+// ColumnPosition same as SimpleScanner.ColumnPosition.
+func (s *ReaderAtScanner) ColumnPosition() int {
+	from, _ := s.blocks.lineBounds(int64(s.cursor))
+	return column(s.blocks.slice(from, int64(s.cursor)), s.tabwidth)
+}
+
+// Read same as SimpleScanner.Read.
+func (s *ReaderAtScanner) Read(p []byte) (n int, err error) {
+	start := int64(s.cursor)
+	if len(p) > 0 && start >= s.blocks.size {
+		return 0, io.EOF
+	}
+	till := start + int64(len(p))
+	if till > s.blocks.size {
+		till = s.blocks.size
+	}
+	n = copy(p, s.blocks.slice(start, till))
+	s.advance(p[:n])
+	return n, nil
+}
+
+//---- Scanner{} interface.
+
+// SetWSPattern implement Scanner{} interface.
+func (s *ReaderAtScanner) SetWSPattern(pattern string) Scanner {
+	s.wsPattern = continuationPattern(pattern, s.continuation)
+	return s
+}
+
+// TrackLineno implement Scanner{} interface.
+func (s *ReaderAtScanner) TrackLineno() Scanner {
+	s.tracklineno = true
+	return s
+}
+
+// Clone implement Scanner{} interface.
+func (s *ReaderAtScanner) Clone() Scanner {
+	s.rewind(s.cursor)
+	return &ReaderAtScanner{
+		blocks:       s.blocks,
+		cursor:       s.cursor,
+		lineno:       s.lineno,
+		patternCache: s.patternCache,
+		wsPattern:    s.wsPattern,
+		scanState:    s.scanState,
+		tracklineno:  s.tracklineno,
+	}
+}
+
+// GetCursor implement Scanner{} interface.
+func (s *ReaderAtScanner) GetCursor() int {
+	return s.cursor
+}
+
+// ReaderOffset return the offset of cursor in io.ReaderAt, that is the
+// offset of the window plus the cursor, which can be beyond the window's
+// size, like positions in a large file scanned in windows.
+func (s *ReaderAtScanner) ReaderOffset() int64 {
+	return s.blocks.off + int64(s.cursor)
+}
+
+// Match implement Scanner{} interface.
+func (s *ReaderAtScanner) Match(pattern string) ([]byte, Scanner) {
+	if s.abort != nil {
+		return nil, s
+	}
+	regc := getPattern(s.patternCache, pattern)
+	rr := &blockRuneReader{blocks: s.blocks, pos: int64(s.cursor)}
+	if loc := regc.FindReaderIndex(rr); loc != nil {
+		start := int64(s.cursor)
+		token := s.blocks.slice(start+int64(loc[0]), start+int64(loc[1]))
+		s.advance(token)
+		return token, s
+	}
+	return nil, s
+}
+
+// MatchString implement Scanner{} interface.
+func (s *ReaderAtScanner) MatchString(str string) (bool, Scanner) {
+	start := int64(s.cursor)
+	if s.abort != nil || start+int64(len(str)) > s.blocks.size {
+		return false, s
+	}
+	token := s.blocks.slice(start, start+int64(len(str)))
+	if string(token) != str {
+		return false, s
+	}
+	s.advance(token)
+	return true, s
+}
+
+// SubmatchAll implement Scanner{} interface.
+func (s *ReaderAtScanner) SubmatchAll(
+	patt string) (map[string][]byte, Scanner) {
+
+	values, offsets := s.submatches(patt)
+	return captureMap(getPattern(s.patternCache, patt), values, offsets), s
+}
+
+// submatches implement submatchScanner{} interface.
+func (s *ReaderAtScanner) submatches(pattern string) ([][]byte, []int) {
+	if s.abort != nil {
+		return nil, nil
+	}
+	regc := getPattern(s.patternCache, pattern)
+	rr := &blockRuneReader{blocks: s.blocks, pos: int64(s.cursor)}
+	locs := regc.FindReaderSubmatchIndex(rr)
+	if locs == nil {
+		return nil, nil
+	}
+	start := int64(s.cursor)
+	values, offsets := make([][]byte, len(locs)/2), make([]int, len(locs)/2)
+	for i := range values {
+		if offsets[i] = -1; locs[2*i] >= 0 {
+			from, till := start+int64(locs[2*i]), start+int64(locs[2*i+1])
+			values[i], offsets[i] = s.blocks.slice(from, till), int(from)
+		}
+	}
+	s.advance(values[0])
+	return values, offsets
+}
+
+// SkipWS implement Scanner{} interface.
+func (s *ReaderAtScanner) SkipWS() ([]byte, Scanner) {
+	return s.SkipAny(s.wsPattern)
+}
+
+// SkipAny implement Scanner{} interface.
+func (s *ReaderAtScanner) SkipAny(pattern string) ([]byte, Scanner) {
+	if pattern[0] != '^' {
+		pattern = "^" + pattern
+	}
+	return s.Match(pattern)
+}
+
+// TryMatch implement Scanner{} interface.
+func (s *ReaderAtScanner) TryMatch(pattern string) ([]byte, bool) {
+	if s.abort != nil {
+		return nil, false
+	}
+	regc := getPattern(s.patternCache, pattern)
+	rr := &blockRuneReader{blocks: s.blocks, pos: int64(s.cursor)}
+	if loc := regc.FindReaderIndex(rr); loc != nil {
+		start := int64(s.cursor)
+		return s.blocks.slice(start+int64(loc[0]), start+int64(loc[1])), true
+	}
+	return nil, false
+}
+
+// SkipN implement Scanner{} interface.
+func (s *ReaderAtScanner) SkipN(n int) Scanner {
+	if s.abort != nil { // input behind the backtrack limit is discarded.
+		return s
+	}
+	start, till := int64(s.cursor), int64(s.cursor)+int64(n)
+	if till > s.blocks.size {
+		till = s.blocks.size
+	}
+	if s.tracklineno {
+		s.advance(s.blocks.slice(start, till))
+	} else {
+		s.cursor = int(till)
+		s.progress.Update(s.cursor)
+		s.backtrack.update(s.cursor)
+		s.blocks.discard(int64(s.backtrack.low()))
+	}
+	return s
+}
+
+// Lineno implement Scanner{} interface.
+func (s *ReaderAtScanner) Lineno() int {
+	return s.lineno
+}
+
+// Endof implement Scanner{} interface.
+func (s *ReaderAtScanner) Endof() bool {
+	return int64(s.cursor) >= s.blocks.size
+}
+
+// BytesRemaining implement Scanner{} interface.
+func (s *ReaderAtScanner) BytesRemaining() int {
+	return int(s.blocks.size) - s.cursor
+}
+
+//---- local methods
+
+func (s *ReaderAtScanner) advance(token []byte) {
+	if s.tracklineno {
+		s.lineno += bytes.Count(token, []byte{'\n'})
+	}
+	s.cursor += len(token)
+	s.progress.Update(s.cursor)
+	s.backtrack.update(s.cursor)
+	s.blocks.discard(int64(s.backtrack.low()))
+}
+
+// blockCache pages in input text from io.ReaderAt in fixed size blocks,
+// least recently used blocks are evicted after `maxblocks`.
+type blockCache struct {
+	r         io.ReaderAt
+	off       int64 // offset of the window in r
+	size      int64 // size of the window
+	blocksize int
+	maxblocks int
+	blocks    map[int64]*list.Element
+	lru       *list.List
+}
+
+type block struct {
+	index int64
+	data  []byte
+}
+
+func newBlockCache(
+	r io.ReaderAt, off, size int64, blocksize, maxblocks int) *blockCache {
+
+	return &blockCache{
+		r:         r,
+		off:       off,
+		size:      size,
+		blocksize: blocksize,
+		maxblocks: maxblocks,
+		blocks:    make(map[int64]*list.Element),
+		lru:       list.New(),
+	}
+}
+
+// get block containing offset `pos` within the window.
+func (bc *blockCache) get(pos int64) *block {
+	index := pos / int64(bc.blocksize)
+	if elem, ok := bc.blocks[index]; ok {
+		bc.lru.MoveToFront(elem)
+		return elem.Value.(*block)
+	}
+
+	start := index * int64(bc.blocksize)
+	ln := int64(bc.blocksize)
+	if start+ln > bc.size {
+		ln = bc.size - start
+	}
+	data := make([]byte, ln)
+	if n, err := bc.r.ReadAt(data, bc.off+start); err != nil {
+		if err != io.EOF || int64(n) < ln {
+			panic(err)
+		}
+	}
+	blk := &block{index: index, data: data}
+	bc.blocks[index] = bc.lru.PushFront(blk)
+	if bc.lru.Len() > bc.maxblocks {
+		elem := bc.lru.Back()
+		bc.lru.Remove(elem)
+		delete(bc.blocks, elem.Value.(*block).index)
+	}
+	return blk
+}
+
+// discard least recently used blocks that end before offset `low`,
+// which the scanner can no longer backtrack to, refer SetMaxBacktrack.
+func (bc *blockCache) discard(low int64) {
+	for elem := bc.lru.Back(); elem != nil; elem = bc.lru.Back() {
+		blk := elem.Value.(*block)
+		if (blk.index+1)*int64(bc.blocksize) > low {
+			return
+		}
+		bc.lru.Remove(elem)
+		delete(bc.blocks, blk.index)
+	}
+}
+
+// slice return a copy of input text between offsets [from, till).
+func (bc *blockCache) slice(from, till int64) []byte {
+	out := make([]byte, 0, till-from)
+	for from < till {
+		blk := bc.get(from)
+		boff := from - blk.index*int64(bc.blocksize)
+		n := int64(len(blk.data)) - boff
+		if from+n > till {
+			n = till - from
+		}
+		out = append(out, blk.data[boff:boff+n]...)
+		from += n
+	}
+	return out
+}
+
+// lineBounds return the offsets of the line containing offset `pos`,
+// excluding the newlines before and after it.
+func (bc *blockCache) lineBounds(pos int64) (from, till int64) {
+	for from = pos; from > 0; {
+		blk := bc.get(from - 1)
+		base := blk.index * int64(bc.blocksize)
+		if i := bytes.LastIndexByte(blk.data[:from-base], '\n'); i >= 0 {
+			from = base + int64(i) + 1
+			break
+		}
+		from = base
+	}
+	for till = pos; till < bc.size; {
+		blk := bc.get(till)
+		base := blk.index * int64(bc.blocksize)
+		if i := bytes.IndexByte(blk.data[till-base:], '\n'); i >= 0 {
+			till += int64(i)
+			break
+		}
+		till = base + int64(len(blk.data))
+	}
+	return from, till
+}
+
+// blockRuneReader implements io.RuneReader on blockCache starting from
+// offset `pos`.
+type blockRuneReader struct {
+	blocks *blockCache
+	pos    int64
+}
+
+func (br *blockRuneReader) ReadRune() (rune, int, error) {
+	bc := br.blocks
+	if br.pos >= bc.size {
+		return 0, 0, io.EOF
+	}
+	blk := bc.get(br.pos)
+	boff := br.pos - blk.index*int64(bc.blocksize)
+	data := blk.data[boff:]
+	if c := data[0]; c < utf8.RuneSelf {
+		br.pos++
+		return rune(c), 1, nil
+	}
+	if !utf8.FullRune(data) { // rune spans across blocks.
+		till := br.pos + utf8.UTFMax
+		if till > bc.size {
+			till = bc.size
+		}
+		data = bc.slice(br.pos, till)
+	}
+	r, size := utf8.DecodeRune(data)
+	br.pos += int64(size)
+	return r, size, nil
+}
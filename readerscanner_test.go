@@ -0,0 +1,84 @@
+package parsec
+
+import "bytes"
+import "errors"
+import "io/ioutil"
+import "reflect"
+import "testing"
+
+func TestReaderAtScanner(t *testing.T) {
+	testScannerContract(t, func(text []byte) Scanner {
+		return NewScannerAt(bytes.NewReader(text), 0, int64(len(text)))
+	})
+	// tiny blocks, to exercise runes and tokens spanning blocks.
+	testScannerContract(t, func(text []byte) Scanner {
+		r := bytes.NewReader(text)
+		return newScannerAt(r, 0, int64(len(text)), 3, 2)
+	})
+}
+
+func TestReaderAtScannerWindow(t *testing.T) {
+	text := []byte("xxxxhello worldyyyy")
+	s := newScannerAt(bytes.NewReader(text), 4, 11, 4, 2)
+	y := Many(nil, Token(`[a-z]+`, "WORD"))
+	node, s := y(s)
+	if node == nil {
+		t.Fatalf("expected match")
+	}
+	nodes := node.([]ParsecNode)
+	if len(nodes) != 2 {
+		t.Fatalf("unexpected %v", nodes)
+	} else if v := nodes[1].(*Terminal).Value; v != "world" {
+		t.Errorf("expected %q, got %q", "world", v)
+	} else if p := nodes[1].(*Terminal).Position; p != 6 {
+		t.Errorf("expected %v, got %v", 6, p)
+	} else if !s.Endof() {
+		t.Errorf("expected end of text")
+	} else if off := s.(*ReaderAtScanner).ReaderOffset(); off != 15 {
+		t.Errorf("expected %v, got %v", 15, off)
+	}
+
+	// read errors panic.
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("expected panic")
+			}
+		}()
+		NewScannerAt(failReaderAt{}, 0, 10).Match(`^x`)
+	}()
+}
+
+func TestReaderAtScannerJSON(t *testing.T) {
+	text, err := ioutil.ReadFile("testdata/medium.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	y := makejsony()
+	ref, s := y(NewScanner(text))
+	if ref == nil {
+		t.Fatalf("expected match")
+	}
+	r := bytes.NewReader(text)
+	node, rs := y(newScannerAt(r, 0, int64(len(text)), 7, 4))
+	if !reflect.DeepEqual(ref, node) {
+		t.Errorf("mismatch between byte scanner and reader scanner")
+	} else if rs.GetCursor() != s.GetCursor() {
+		t.Errorf("expected %v, got %v", s.GetCursor(), rs.GetCursor())
+	}
+}
+
+func BenchmarkReaderAtScanJSON(b *testing.B) {
+	text, _ := ioutil.ReadFile("testdata/medium.json")
+	y := makejsony()
+	for i := 0; i < b.N; i++ {
+		y(NewScannerAt(bytes.NewReader(text), 0, int64(len(text))))
+	}
+	b.SetBytes(int64(len(text)))
+}
+
+type failReaderAt struct{}
+
+func (r failReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return 0, errors.New("read failure")
+}
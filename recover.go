@@ -0,0 +1,179 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+
+// DefaultMaxErrors is the number of errors recovered by Run, in recovery
+// mode, before it gives up on the rest of the input.
+const DefaultMaxErrors = 20
+
+// ErrorNode is returned by Recover combinator in place of the input
+// skipped after an error. It is a Terminal named ERROR with the skipped
+// text as its value.
+type ErrorNode struct {
+	*Terminal
+	Err *ParseError
+}
+
+// errorCollector collects errors recovered while parsing, shared by a
+// scanner and all its clones.
+type errorCollector struct {
+	max  int
+	errs ErrorList
+	full bool // rest of the input was skipped after max errors.
+}
+
+// errorScanner is implemented by scanners that can collect recovered
+// errors.
+type errorScanner interface {
+	errorcollector() *errorCollector
+	setErrorCollector(ec *errorCollector)
+}
+
+// Recover combinator applies parser `p`, in recovery mode, if `p` fails
+// the input is skipped till the end of next match for `sync` pattern, or
+// till the end of input, and an ErrorNode is returned for the skipped
+// text. Recovered errors are reported by Run as ErrorList. After the
+// maximum number of errors, rest of the input is skipped and the
+// truncation is noted as the last error. Recovery mode is enabled by
+// Run, WithRecovery, otherwise Recover is same as `p`. Never recovers at
+// the end of input.
+func Recover(p Parser, sync string) Parser {
+	if sync[0] != '^' {
+		sync = "^(?s:.*?)" + sync
+	}
+	return func(s Scanner) (ParsecNode, Scanner) {
+		if node, news := p(s.Clone()); node != nil {
+			return node, news
+		}
+		es, ok := s.(errorScanner)
+		if !ok || es.errorcollector() == nil {
+			return nil, s
+		}
+		_, news := s.Clone().SkipWS()
+		if news.Endof() {
+			return nil, s
+		}
+
+		ec, from, start := es.errorcollector(), news.Clone(), news.GetCursor()
+		err := &ParseError{Offset: start, Msg: "parse error"}
+		if len(ec.errs) >= ec.max {
+			news.SkipN(news.BytesRemaining())
+			err.Msg = "too many errors, skipped till end of input"
+			if ec.full { // truncation is noted only once.
+				err = nil
+			}
+			ec.full = true
+		} else if tok, _ := news.Match(sync); tok == nil {
+			news.SkipN(news.BytesRemaining())
+		}
+		if err != nil {
+			ec.errs = append(ec.errs, err)
+		}
+		value := string(scanText(from, news.GetCursor()))
+		t := newTerminal(news, "ERROR", value, start)
+		return &ErrorNode{Terminal: t, Err: err}, news
+	}
+}
+
+// RunOption configures Run.
+type RunOption func(*runConfig)
+
+type runConfig struct {
+	maxErrors int             // recovery mode, if > 0.
+	drops     map[string]bool // refer DropTokens.
+	snapshot  bool            // refer WithFailureSnapshot.
+}
+
+// WithRecovery enables recovery mode, refer Recover, collecting upto
+// `maxErrors` errors. If maxErrors is zero, DefaultMaxErrors is used.
+func WithRecovery(maxErrors int) RunOption {
+	if maxErrors <= 0 {
+		maxErrors = DefaultMaxErrors
+	}
+	return func(config *runConfig) {
+		config.maxErrors = maxErrors
+	}
+}
+
+// Result of applying a parser using Run.
+type Result struct {
+	Node     ParsecNode // root node, nil if parser failed.
+	Scanner  Scanner    // scanner with remaining input.
+	Err      error
+	Snapshot *Snapshot // if parser failed, refer WithFailureSnapshot.
+}
+
+// Run applies parser `p` on scanner `s`, input text shall be consumed
+// completely, except for trailing whitespace. If parser fails, or the
+// parse is aborted, refer Aborted, or parser panics with *ParseError,
+// Err is *ParseError. In recovery mode, errors recovered by Recover
+// combinator are reported as ErrorList. Recovery mode is supported by
+// scanners created with NewScanner, NewScannerString and NewScannerAt,
+// and panics with other scanners. WithFailureSnapshot has the same
+// restriction.
+func Run(p Parser, s Scanner, opts ...RunOption) (res Result) {
+	var config runConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	var ec *errorCollector
+	if config.maxErrors > 0 {
+		es, ok := s.(errorScanner)
+		if !ok {
+			panic(fmt.Errorf("recovery mode is not supported by %T", s))
+		}
+		ec = &errorCollector{max: config.maxErrors}
+		es.setErrorCollector(ec)
+	}
+	if config.drops != nil {
+		ds, ok := s.(dropScanner)
+		if !ok {
+			panic(fmt.Errorf("DropTokens is not supported by %T", s))
+		}
+		ds.setDropTokens(config.drops)
+	}
+	if config.snapshot {
+		ss, ok := s.(snapshotScanner)
+		if !ok {
+			panic(fmt.Errorf("WithFailureSnapshot is not supported by %T", s))
+		}
+		ft := &failureTracker{furthest: -1}
+		ss.setFailureTracker(ft)
+		start := s.Clone()
+		defer func() {
+			if perr, ok := res.Err.(*ParseError); ok {
+				res.Snapshot = ft.snapshot(start, perr.Offset)
+			}
+		}()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			perr, ok := r.(*ParseError)
+			if !ok {
+				panic(r)
+			}
+			res = Result{Scanner: s, Err: perr}
+		}
+	}()
+
+	node, news := p(s)
+	res = Result{Node: node, Scanner: news}
+	if err := Aborted(s); err != nil {
+		res.Node, res.Err = nil, err
+		return res
+	}
+	if node != nil {
+		_, news = news.SkipWS()
+	}
+	if node == nil || !news.Endof() {
+		res.Node = nil
+		res.Err = &ParseError{Offset: news.GetCursor(), Msg: "parse error"}
+	} else if ec != nil && len(ec.errs) > 0 {
+		res.Err = ec.errs
+	}
+	return res
+}
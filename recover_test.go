@@ -0,0 +1,79 @@
+package parsec
+
+import "errors"
+import "fmt"
+import "strings"
+import "testing"
+
+func TestRecover(t *testing.T) {
+	first := func(ns []ParsecNode) ParsecNode { return ns[0] }
+	stmt := Recover(And(first, Int(), Atom(";", "SEMICOLON")), ";")
+	y := Kleene(nil, stmt)
+
+	// without recovery mode, Recover is same as the parser.
+	s := NewScanner([]byte("10; x; 20;"))
+	res := Run(y, s)
+	if res.Err == nil {
+		t.Errorf("expected error")
+	} else if ref := "parse error at offset 4"; res.Err.Error() != ref {
+		t.Errorf("expected %q, got %q", ref, res.Err.Error())
+	}
+
+	// recovered errors.
+	res = Run(y, NewScanner([]byte("10; x; 20; y y; 30;")), WithRecovery(0))
+	var errs ErrorList
+	if !errors.As(fmt.Errorf("wrapped: %w", res.Err), &errs) {
+		t.Fatalf("expected ErrorList, got %v", res.Err)
+	} else if len(errs) != 2 {
+		t.Fatalf("expected %v, got %v", 2, errs)
+	} else if errs[0].Offset != 4 || errs[1].Offset != 11 {
+		t.Errorf("unexpected %v", errs)
+	}
+	ref := "2 errors: parse error at offset 4; parse error at offset 11"
+	if res.Err.Error() != ref {
+		t.Errorf("expected %q, got %q", ref, res.Err.Error())
+	}
+	nodes := res.Node.([]ParsecNode)
+	if len(nodes) != 5 {
+		t.Fatalf("expected %v, got %v", 5, nodes)
+	} else if en, ok := nodes[3].(*ErrorNode); !ok {
+		t.Errorf("expected ErrorNode, got %T", nodes[3])
+	} else if en.GetName() != "ERROR" || en.GetValue() != "y y;" {
+		t.Errorf("unexpected %v %q", en.GetName(), en.GetValue())
+	}
+
+	// errors exactly at the maximum.
+	text := strings.Repeat("x; 1; ", 3)
+	res = Run(y, NewScanner([]byte(text)), WithRecovery(3))
+	if errs, ok := res.Err.(ErrorList); !ok || len(errs) != 3 {
+		t.Fatalf("unexpected %v", res.Err)
+	}
+	ref = "3 errors: parse error at offset 0; parse error at offset 6; " +
+		"parse error at offset 12"
+	if res.Err.Error() != ref {
+		t.Errorf("expected %q, got %q", ref, res.Err.Error())
+	}
+	if nodes := res.Node.([]ParsecNode); len(nodes) != 6 {
+		t.Errorf("expected %v, got %v", 6, len(nodes))
+	}
+
+	// errors beyond the maximum are truncated.
+	text = strings.Repeat("x; 1; ", 5)
+	res = Run(y, NewScannerString(text), WithRecovery(3))
+	errs, _ = res.Err.(ErrorList)
+	if len(errs) != 4 {
+		t.Fatalf("expected %v, got %v", 4, errs)
+	}
+	ref = "too many errors, skipped till end of input at offset 18"
+	if errs[3].Error() != ref {
+		t.Errorf("expected %q, got %q", ref, errs[3].Error())
+	}
+	ref = "4 errors: parse error at offset 0; parse error at offset 6; " +
+		"parse error at offset 12; and 1 more"
+	if res.Err.Error() != ref {
+		t.Errorf("expected %q, got %q", ref, res.Err.Error())
+	}
+	if nodes := res.Node.([]ParsecNode); len(nodes) != 7 {
+		t.Errorf("expected %v, got %v", 7, len(nodes))
+	}
+}
@@ -8,7 +8,8 @@ import "reflect"
 import "unsafe"
 import "unicode"
 import "bytes"
-import "strings"
+import "io"
+import "unicode/utf8"
 
 // Scanner interface defines necessary methods to match the input stream.
 type Scanner interface {
@@ -51,12 +52,28 @@ type Scanner interface {
 	// Returns Scanner after advancing its cursor.
 	SkipAny(pattern string) ([]byte, Scanner)
 
+	// TryMatch the input stream with `pattern` without advancing the
+	// scanner's cursor. Return matching string and a bool indicating
+	// if the match was successful.
+	TryMatch(pattern string) ([]byte, bool)
+
+	// SkipN advances the scanner's cursor by `n` bytes, or until the end
+	// of input stream, typically used after TryMatch:
+	//		if token, ok := s.TryMatch(pattern); ok {
+	//			s = s.SkipN(len(token))
+	//		}
+	SkipN(n int) Scanner
+
 	// Lineno return the current line-number of the cursor.
 	Lineno() int
 
 	// Endof detects whether end-of-file is reached in the input
 	// stream and return a boolean indicating the same.
 	Endof() bool
+
+	// BytesRemaining return the number of bytes in input stream after the
+	// cursor, without creating a slice of remaining input.
+	BytesRemaining() int
 }
 
 // SimpleScanner implements Scanner interface based on
@@ -67,10 +84,39 @@ type SimpleScanner struct {
 	lineno       int
 	patternCache map[string]*regexp.Regexp
 	wsPattern    string // white space pattern used by SkipWS()
+	*scanState
 	// settings
 	tracklineno bool
 }
 
+// scanState is the state of a scanner that is optional, or specific to
+// a parse, shared by the scanner and all its clones so that Clone copies
+// a single pointer.
+type scanState struct {
+	fold         []byte // case folded input buffer, if not nil used for matching
+	norm         *normalized
+	continuation string // line continuation marker, refer LineContinuation.
+	tabwidth     int    // tab stops for ColumnPosition, refer TabWidth.
+	memo         *memoTable
+	progress     *Progress
+	nodeids      *int64 // generate node identifiers, if not nil.
+	backtrack    *backtrack
+	budget       *backtrackBudget // refer WithBacktrackBudget.
+	limited      bool             // backtrack or budget is set.
+	amb          *ambPath         // refer AllParses.
+	errors       *errorCollector
+	expects      *expectations   // refer RunParser.
+	snapshot     *failureTracker // refer WithFailureSnapshot.
+	rules        []string        // active Grammar rules, for snapshot and budget.
+	abort        *ParseError     // refer Aborted.
+	drops        map[string]bool // terminals omitted by AST, refer DropTokens.
+	interns      *Interner
+}
+
+// defaultWS is the white space pattern of scanners, unless set otherwise
+// using SetWSPattern.
+const defaultWS = `^[ \t\r\n]+`
+
 // NewScanner create and return a new instance of SimpleScanner object.
 func NewScanner(text []byte) Scanner {
 	return &SimpleScanner{
@@ -78,35 +124,150 @@ func NewScanner(text []byte) Scanner {
 		cursor:       0,
 		lineno:       1,
 		patternCache: make(map[string]*regexp.Regexp),
-		wsPattern:    `^[ \t\r\n]+`,
+		wsPattern:    defaultWS,
+		scanState:    &scanState{},
 		tracklineno:  false,
 	}
 }
 
+// NewScannerWith create and return a new instance of SimpleScanner
+// object, same as NewScanner, configured by `opts`.
+func NewScannerWith(text []byte, opts ...ScannerOption) Scanner {
+	s := NewScanner(text).(*SimpleScanner)
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// NewFoldingScanner create and return a new instance of SimpleScanner
+// object that matches patterns and strings against lower-cased input text,
+// while matched tokens and cursor positions still refer to the original
+// text. Useful for case-insensitive grammars, where patterns are specified
+// in lower case, Atom and AtomExact parsers shall use the matched text as
+// terminal's value. Same as NewScannerWith WithCaseFolding.
+func NewFoldingScanner(text []byte) Scanner {
+	return NewScannerWith(text, WithCaseFolding())
+}
+
+// OnProgress same as WithProgress option.
+//
+// Deprecated: use NewScannerWith with WithProgress option.
+func (s *SimpleScanner) OnProgress(every int, fn ProgressFunc) Scanner {
+	WithProgress(every, fn)(s)
+	return s
+}
+
+// WithNodeIDs same as WithNodeIDs option.
+//
+// Deprecated: use NewScannerWith with WithNodeIDs option.
+func (s *SimpleScanner) WithNodeIDs() Scanner {
+	WithNodeIDs()(s)
+	return s
+}
+
+// SetMaxBacktrack same as WithMaxBacktrack option.
+//
+// Deprecated: use NewScannerWith with WithMaxBacktrack, or WithLimits,
+// option.
+func (s *SimpleScanner) SetMaxBacktrack(bytes int) Scanner {
+	WithMaxBacktrack(bytes)(s)
+	return s
+}
+
+// SetBacktrackBudget same as WithBacktrackBudget option.
+//
+// Deprecated: use NewScannerWith with WithBacktrackBudget, or
+// WithLimits, option.
+func (s *SimpleScanner) SetBacktrackBudget(budget int64) Scanner {
+	WithBacktrackBudget(budget)(s)
+	return s
+}
+
+// InternValues same as WithInterning option.
+//
+// Deprecated: use NewScannerWith with WithInterning option.
+func (s *SimpleScanner) InternValues(maxLen int) Scanner {
+	WithInterning(maxLen)(s)
+	return s
+}
+
+// LineContinuation same as WithLineContinuation option.
+//
+// Deprecated: use NewScannerWith with WithLineContinuation option.
+func (s *SimpleScanner) LineContinuation(marker byte) Scanner {
+	WithLineContinuation(marker)(s)
+	return s
+}
+
+// CurrentLine return the line of input text around the cursor, from the
+// newline before the cursor till the newline after, or the end of text,
+// excluding the newlines and trailing carriage return. Cursor on a
+// newline belongs to the line it terminates. Meant for diagnostics, like
+// showing the offending line when a parse fails, it scans the text on
+// every call and does not depend on TrackLineno.
+func (s *SimpleScanner) CurrentLine() []byte {
+	from := bytes.LastIndexByte(s.buf[:s.cursor], '\n') + 1
+	till := len(s.buf)
+	if i := bytes.IndexByte(s.buf[s.cursor:], '\n'); i >= 0 {
+		till = s.cursor + i
+	}
+	return bytes.TrimSuffix(s.buf[from:till], []byte("\r"))
+}
+
+// TabWidth same as WithTabWidth option.
+//
+// Deprecated: use NewScannerWith with WithTabWidth option.
+func (s *SimpleScanner) TabWidth(width int) Scanner {
+	WithTabWidth(width)(s)
+	return s
+}
+
+// ColumnPosition return the column of cursor within its line, starting
+// from 1, counting characters from the beginning of the line and
+// advancing to the next tab stop for tabs, refer TabWidth. Meant for
+// placing error markers, like `^` under the offending character in
+// CurrentLine, it scans the text on every call.
+func (s *SimpleScanner) ColumnPosition() int {
+	from := bytes.LastIndexByte(s.buf[:s.cursor], '\n') + 1
+	return column(s.buf[from:s.cursor], s.tabwidth)
+}
+
+// Read implement io.Reader interface, filling `p` with input text from
+// the cursor and advancing the cursor past the bytes read. Return
+// io.EOF at the end of input. Useful to hand over the rest of the input,
+// like a sub-region, to libraries accepting io.Reader.
+func (s *SimpleScanner) Read(p []byte) (n int, err error) {
+	text := s.tokentext()
+	if len(p) > 0 && len(text) == 0 {
+		return 0, io.EOF
+	}
+	n = copy(p, text)
+	s.advanceto(s.endof(n))
+	return n, nil
+}
+
 //---- Scanner{} interface.
 
-// SetWSPattern implement Scanner{} interface.
+// SetWSPattern implement Scanner{} interface, same as WithWS option.
 func (s *SimpleScanner) SetWSPattern(pattern string) Scanner {
-	s.wsPattern = pattern
+	WithWS(pattern)(s)
 	return s
 }
 
-// TrackLineno implement Scanner{} interface.
+// TrackLineno implement Scanner{} interface, same as WithLineno option.
 func (s *SimpleScanner) TrackLineno() Scanner {
-	s.tracklineno = true
+	WithLineno()(s)
 	return s
 }
 
 // Clone implement Scanner{} interface.
 func (s *SimpleScanner) Clone() Scanner {
-	return &SimpleScanner{
-		buf:          s.buf,
-		cursor:       s.cursor,
-		lineno:       s.lineno,
-		patternCache: s.patternCache,
-		wsPattern:    s.wsPattern,
-		tracklineno:  s.tracklineno,
+	if s.limited { // kept to a single test, so that Clone is inlined.
+		s.rewind(s.cursor)
 	}
+	news := *s
+	return &news
 }
 
 // GetCursor implement Scanner{} interface.
@@ -116,53 +277,65 @@ func (s *SimpleScanner) GetCursor() int {
 
 // Match implement Scanner{} interface.
 func (s *SimpleScanner) Match(pattern string) ([]byte, Scanner) {
+	if s.abort != nil {
+		return nil, s
+	}
 	regc := s.getPattern(pattern)
-	if token := regc.Find(s.buf[s.cursor:]); token != nil {
-		if s.tracklineno && len(token) > 0 {
-			s.lineno += len(bytes.Split(token, []byte{'\n'})) - 1
+	if s.fold == nil && s.norm == nil { // match input text as is.
+		if token := regc.Find(s.buf[s.cursor:]); token != nil {
+			s.advanceto(s.cursor + len(token))
+			return token, s
 		}
-		s.cursor += len(token)
-		return token, s
+		return nil, s
+	}
+	if loc := regc.FindIndex(s.matchtext()); loc != nil {
+		return s.matched(loc), s
 	}
 	return nil, s
 }
 
 // MatchString implement Scanner{} interface.
 func (s *SimpleScanner) MatchString(str string) (bool, Scanner) {
-	ln := len(str)
-	if len(s.buf[s.cursor:]) < ln {
-		return false, s
-	} else if bytes.Compare(s.buf[s.cursor:s.cursor+ln], []byte(str)) != 0 {
+	if s.abort != nil {
 		return false, s
 	}
-	if s.tracklineno && len(str) > 0 {
-		s.lineno += len(strings.Split(str, "\n")) - 1
+	ln, text := len(str), s.matchtext()
+	if s.fold != nil {
+		str = string(foldbytes([]byte(str)))
 	}
-	s.cursor += ln
+	if len(text) < ln {
+		return false, s
+	} else if bytes.Compare(text[:ln], []byte(str)) != 0 {
+		return false, s
+	}
+	s.advanceto(s.endof(ln))
 	return true, s
 }
 
 // SubmatchAll implement Scanner{} interface.
 func (s *SimpleScanner) SubmatchAll(patt string) (map[string][]byte, Scanner) {
-	regc := s.getPattern(patt)
-	matches := regc.FindSubmatch(s.buf[s.cursor:])
-
-	if matches != nil {
-		captures := make(map[string][]byte)
-		names := regc.SubexpNames()
-		for i, name := range names {
-			if i == 0 || name == "" || matches[i] == nil {
-				continue
-			}
-			captures[name] = matches[i]
-		}
-		if s.tracklineno && len(matches[0]) > 0 {
-			s.lineno += len(bytes.Split(matches[0], []byte{'\n'})) - 1
+	values, offsets := s.submatches(patt)
+	return captureMap(s.getPattern(patt), values, offsets), s
+}
+
+// submatches implement submatchScanner{} interface.
+func (s *SimpleScanner) submatches(pattern string) ([][]byte, []int) {
+	if s.abort != nil {
+		return nil, nil
+	}
+	locs := s.getPattern(pattern).FindSubmatchIndex(s.matchtext())
+	if locs == nil {
+		return nil, nil
+	}
+	text := s.tokentext()
+	values, offsets := make([][]byte, len(locs)/2), make([]int, len(locs)/2)
+	for i := range values {
+		if offsets[i] = -1; locs[2*i] >= 0 {
+			values[i], offsets[i] = text[locs[2*i]:locs[2*i+1]], s.endof(locs[2*i])
 		}
-		s.cursor += len(matches[0])
-		return captures, s
 	}
-	return nil, s
+	s.advanceto(s.endof(locs[1]))
+	return values, offsets
 }
 
 // SkipWS implement Scanner{} interface.
@@ -178,6 +351,31 @@ func (s *SimpleScanner) SkipAny(pattern string) ([]byte, Scanner) {
 	return s.Match(pattern)
 }
 
+// TryMatch implement Scanner{} interface.
+func (s *SimpleScanner) TryMatch(pattern string) ([]byte, bool) {
+	if s.abort != nil {
+		return nil, false
+	}
+	regc := s.getPattern(pattern)
+	if s.fold == nil && s.norm == nil { // match input text as is.
+		token := regc.Find(s.buf[s.cursor:])
+		return token, token != nil
+	}
+	if loc := regc.FindIndex(s.matchtext()); loc != nil {
+		return s.tokentext()[loc[0]:loc[1]], true
+	}
+	return nil, false
+}
+
+// SkipN implement Scanner{} interface.
+func (s *SimpleScanner) SkipN(n int) Scanner {
+	if text := s.tokentext(); n > len(text) {
+		n = len(text)
+	}
+	s.advanceto(s.endof(n))
+	return s
+}
+
 // Lineno implement Scanner{} interface.
 func (s *SimpleScanner) Lineno() int {
 	return s.lineno
@@ -188,6 +386,11 @@ func (s *SimpleScanner) Endof() bool {
 	return s.cursor >= len(s.buf)
 }
 
+// BytesRemaining implement Scanner{} interface.
+func (s *SimpleScanner) BytesRemaining() int {
+	return len(s.buf) - s.cursor
+}
+
 // SkipWSUnicode for looping through runes checking for whitespace.
 func (s *SimpleScanner) SkipWSUnicode() ([]byte, Scanner) {
 	for i, r := range bytes2str(s.buf[s.cursor:]) {
@@ -199,30 +402,260 @@ func (s *SimpleScanner) SkipWSUnicode() ([]byte, Scanner) {
 		}
 		token := s.buf[s.cursor : s.cursor+i]
 		s.cursor += len(token)
+		s.progress.Update(s.cursor)
+		s.backtrack.update(s.cursor)
+		s.budget.advance(s.cursor)
 		return token, s
 	}
 	token := s.buf[s.cursor:]
 	s.cursor += len(token)
+	s.progress.Update(s.cursor)
+	s.backtrack.update(s.cursor)
+	s.budget.advance(s.cursor)
 	return token, s
 }
 
 //---- local methods
 
+func (st *scanState) memotable() *memoTable {
+	if st.memo == nil {
+		st.memo = &memoTable{}
+	}
+	return st.memo
+}
+
+func (st *scanState) nodeidgen() *int64 {
+	return st.nodeids
+}
+
+func (st *scanState) aborted() *ParseError {
+	return st.abort
+}
+
+// abortParse record `perr`, unless the parse is already aborted, so that
+// all further matches fail.
+func (st *scanState) abortParse(perr *ParseError) {
+	if st.abort == nil {
+		st.abort = perr
+	}
+}
+
+// rewind note that the scanner, or one of its clones, is cloned at
+// `cursor`, which is how parsers backtrack, aborting the parse beyond
+// the backtrack limit or budget.
+func (st *scanState) rewind(cursor int) {
+	if perr := st.backtrack.check(cursor); perr != nil {
+		st.abortParse(perr)
+	}
+	if perr := st.budget.resume(cursor, st.rules); perr != nil {
+		st.abortParse(perr)
+	}
+}
+
+func (st *scanState) errorcollector() *errorCollector {
+	return st.errors
+}
+
+func (st *scanState) setErrorCollector(ec *errorCollector) {
+	st.errors = ec
+}
+
+func (st *scanState) expectations() *expectations {
+	return st.expects
+}
+
+func (st *scanState) setExpectations(ex *expectations) {
+	st.expects = ex
+}
+
+func (st *scanState) failureTracker() *failureTracker {
+	return st.snapshot
+}
+
+func (st *scanState) setFailureTracker(ft *failureTracker) {
+	st.snapshot = ft
+}
+
+// rulestack return the stack of active Grammar rules, if they are
+// tracked for failure snapshot or backtrack budget, else nil.
+func (st *scanState) rulestack() *[]string {
+	if st.snapshot == nil && st.budget == nil {
+		return nil
+	}
+	return &st.rules
+}
+
+func (st *scanState) droptokens() map[string]bool {
+	return st.drops
+}
+
+func (st *scanState) setDropTokens(drops map[string]bool) {
+	st.drops = drops
+}
+
+func (st *scanState) interner() *Interner {
+	return st.interns
+}
+
+func (st *scanState) continuationMarker() string {
+	return st.continuation
+}
+
+// isolated return a copy of the state with only its settings, sharing
+// no mutable state, refer ContextScanner.
+func (st *scanState) isolated() *scanState {
+	return &scanState{
+		fold:         st.fold,
+		norm:         st.norm,
+		continuation: st.continuation,
+		tabwidth:     st.tabwidth,
+		abort:        st.abort,
+	}
+}
+
+// isolate implement isolatingScanner{} interface.
+func (s *SimpleScanner) isolate(patterns ...string) Scanner {
+	news := *s
+	news.patternCache = make(map[string]*regexp.Regexp, len(patterns)+1)
+	for _, pattern := range append(patterns, s.wsPattern) {
+		news.patternCache[pattern] = s.getPattern(pattern)
+	}
+	news.scanState = s.scanState.isolated()
+	return &news
+}
+
+// adopt implement isolatingScanner{} interface.
+func (s *SimpleScanner) adopt(iso Scanner) {
+	s.advanceto(iso.(*SimpleScanner).cursor)
+}
+
+// peekByte return the next byte of input text, as matched by patterns,
+// after white space, without moving the cursor. Return false at the end
+// of input.
+func (s *SimpleScanner) peekByte() (byte, bool) {
+	if s.abort != nil {
+		return 0, false
+	}
+	text := s.matchtext()
+	if s.wsPattern != defaultWS {
+		if loc := s.getPattern(s.wsPattern).FindIndex(text); loc != nil {
+			text = text[loc[1]:]
+		}
+	} else {
+		for len(text) > 0 && (text[0] == ' ' || text[0] == '\t' ||
+			text[0] == '\r' || text[0] == '\n') {
+			text = text[1:]
+		}
+	}
+	if len(text) == 0 {
+		return 0, false
+	}
+	return text[0], true
+}
+
+// matched advance the cursor past the match at location `loc`, relative
+// to cursor, and return the matching token.
+func (s *SimpleScanner) matched(loc []int) []byte {
+	token := s.tokentext()[loc[0]:loc[1]]
+	s.advanceto(s.endof(len(token)))
+	return token
+}
+
+// advanceto move the cursor forward to offset `end` in input text.
+func (s *SimpleScanner) advanceto(end int) {
+	if s.tracklineno && end > s.cursor {
+		s.lineno += bytes.Count(s.buf[s.cursor:end], []byte{'\n'})
+	}
+	s.cursor = end
+	s.progress.Update(s.cursor)
+	s.backtrack.update(s.cursor)
+	s.budget.advance(s.cursor)
+}
+
+// matchtext return the text, from cursor, that shall be matched with
+// patterns and strings.
+func (s *SimpleScanner) matchtext() []byte {
+	if s.norm != nil {
+		return s.norm.text[s.norm.index(s.cursor):]
+	}
+	return s.matchbuf()[s.cursor:]
+}
+
+// tokentext return the text, from cursor, from which matching tokens
+// are returned. Same as matchtext, except that case folded input is not
+// returned as token.
+func (s *SimpleScanner) tokentext() []byte {
+	if s.norm != nil {
+		return s.norm.text[s.norm.index(s.cursor):]
+	}
+	return s.buf[s.cursor:]
+}
+
+// endof return the offset in input text after `n` bytes of matchtext.
+func (s *SimpleScanner) endof(n int) int {
+	if s.norm != nil {
+		return s.norm.offsets[s.norm.index(s.cursor)+n]
+	}
+	return s.cursor + n
+}
+
 func (s *SimpleScanner) getPattern(pattern string) *regexp.Regexp {
-	regc, ok := s.patternCache[pattern]
+	return getPattern(s.patternCache, pattern)
+}
+
+func (s *SimpleScanner) matchbuf() []byte {
+	if s.fold != nil {
+		return s.fold[:len(s.buf)]
+	}
+	return s.buf
+}
+
+// limit input text till offset `end`.
+func (s *SimpleScanner) limit(end int) {
+	if end < len(s.buf) {
+		s.buf = s.buf[:end]
+	}
+}
+
+// fork return a copy of the state, with a memo table of its own, for a
+// clone that shall not share its memoized results or its settings.
+func (st *scanState) fork() *scanState {
+	newst := *st
+	newst.memo = nil
+	return &newst
+}
+
+func (s *SimpleScanner) resetcursor() {
+	s.cursor = 0
+}
+
+func getPattern(cache map[string]*regexp.Regexp, pattern string) *regexp.Regexp {
+	regc, ok := cache[pattern]
 	if !ok {
 		var err error
 		if regc, err = regexp.Compile(pattern); err != nil {
 			panic(err)
 		}
-		s.patternCache[pattern] = regc
+		cache[pattern] = regc
 	}
 
 	return regc
 }
 
-func (s *SimpleScanner) resetcursor() {
-	s.cursor = 0
+// foldbytes lower-case text rune by rune, runes whose lower-case form has a
+// different encoded length are left as is so that offsets are preserved.
+func foldbytes(text []byte) []byte {
+	out := make([]byte, len(text))
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRune(text[i:])
+		if lr := unicode.ToLower(r); lr != r && utf8.RuneLen(lr) == size {
+			utf8.EncodeRune(out[i:], lr)
+		} else {
+			copy(out[i:i+size], text[i:i+size])
+		}
+		i += size
+	}
+	return out
 }
 
 func bytes2str(bytes []byte) string {
@@ -3,7 +3,10 @@
 
 package parsec
 
+import "bufio"
 import "bytes"
+import "encoding/json"
+import "io"
 import "reflect"
 import "strings"
 import "testing"
@@ -229,6 +232,123 @@ func TestTrackLineno(t *testing.T) {
 	}
 }
 
+func TestCurrentLine(t *testing.T) {
+	text := "[section]\r\n  x = invalid!\n\nlast"
+	type liner interface {
+		CurrentLine() []byte
+	}
+	scanners := map[string]func() Scanner{
+		"simple": func() Scanner { return NewScanner([]byte(text)) },
+		"string": func() Scanner { return NewScannerString(text) },
+		"reader": func() Scanner {
+			return newScannerAt(strings.NewReader(text), 0, int64(len(text)), 4, 2)
+		},
+	}
+	refs := map[int]string{
+		0:  "[section]",
+		9:  "[section]",
+		10: "[section]",
+		11: "  x = invalid!",
+		17: "  x = invalid!",
+		25: "  x = invalid!",
+		26: "",
+		27: "last",
+		31: "last",
+	}
+	for name, newscanner := range scanners {
+		for cursor, ref := range refs {
+			s := newscanner().SkipN(cursor)
+			if line := string(s.(liner).CurrentLine()); line != ref {
+				t.Errorf("%v at %v: expected %q, got %q", name, cursor, ref, line)
+			}
+		}
+	}
+}
+
+func TestColumnPosition(t *testing.T) {
+	text := "ab\n\tx = é!\n"
+	type columner interface {
+		TabWidth(width int) Scanner
+		ColumnPosition() int
+	}
+	scanners := map[string]func() Scanner{
+		"simple": func() Scanner { return NewScanner([]byte(text)) },
+		"string": func() Scanner { return NewScannerString(text) },
+		"reader": func() Scanner {
+			return newScannerAt(strings.NewReader(text), 0, int64(len(text)), 4, 2)
+		},
+	}
+	// cursor: {column, column with tab width 4}
+	refs := map[int][2]int{
+		0:  {1, 1},
+		2:  {3, 3},
+		3:  {1, 1},
+		4:  {2, 5},
+		8:  {6, 9},
+		10: {7, 10},
+		12: {1, 1},
+	}
+	for name, newscanner := range scanners {
+		for cursor, ref := range refs {
+			s := newscanner().SkipN(cursor).(columner)
+			if col := s.ColumnPosition(); col != ref[0] {
+				t.Errorf("%v at %v: expected %v, got %v", name, cursor, ref[0], col)
+			}
+			s = newscanner().(columner).TabWidth(4).SkipN(cursor).(columner)
+			if col := s.ColumnPosition(); col != ref[1] {
+				t.Errorf("%v at %v: expected %v, got %v", name, cursor, ref[1], col)
+			}
+		}
+	}
+}
+
+func TestScannerRead(t *testing.T) {
+	text := "payload: {\"a\": [1, 2]}\nline two\n"
+	scanners := map[string]func() Scanner{
+		"simple": func() Scanner { return NewScanner([]byte(text)) },
+		"string": func() Scanner { return NewScannerString(text) },
+		"reader": func() Scanner {
+			return newScannerAt(strings.NewReader(text), 0, int64(len(text)), 4, 2)
+		},
+	}
+	for name, newscanner := range scanners {
+		s := newscanner()
+		if tok, _ := s.Match(`^payload:`); tok == nil {
+			t.Fatalf("%v: expected match", name)
+		}
+		var value map[string]interface{}
+		if err := json.NewDecoder(s.(io.Reader)).Decode(&value); err != nil {
+			t.Errorf("%v: unexpected %v", name, err)
+		} else if ref := []interface{}{1.0, 2.0}; !reflect.DeepEqual(value["a"], ref) {
+			t.Errorf("%v: expected %v, got %v", name, ref, value["a"])
+		}
+		if !s.Endof() {
+			t.Errorf("%v: expected end of input, at %v", name, s.GetCursor())
+		}
+
+		s = newscanner().SkipN(len("payload: "))
+		buf := make([]byte, 5)
+		if n, err := s.(io.Reader).Read(buf); n != 5 || err != nil {
+			t.Errorf("%v: unexpected %v, %v", name, n, err)
+		} else if string(buf) != `{"a":` || s.GetCursor() != 14 {
+			t.Errorf("%v: unexpected %q at %v", name, buf, s.GetCursor())
+		}
+		lines := []string{}
+		sc := bufio.NewScanner(s.(io.Reader))
+		for sc.Scan() {
+			lines = append(lines, sc.Text())
+		}
+		if ref := []string{" [1, 2]}", "line two"}; !reflect.DeepEqual(lines, ref) {
+			t.Errorf("%v: expected %q, got %q", name, ref, lines)
+		}
+		if n, err := s.(io.Reader).Read(buf); n != 0 || err != io.EOF {
+			t.Errorf("%v: expected EOF, got %v, %v", name, n, err)
+		} else if n, err := s.(io.Reader).Read(nil); n != 0 || err != nil {
+			t.Errorf("%v: unexpected %v, %v", name, n, err)
+		}
+	}
+}
+
 func TestUnicode(t *testing.T) {
 	text := "号分隔值, 逗号分隔值"
 	ytok := TokenExact(`[^,]+`, "FIELD")
@@ -286,3 +406,217 @@ func BenchmarkSScanSkipAny(b *testing.B) {
 		s.(*SimpleScanner).resetcursor()
 	}
 }
+
+func TestFoldingScanner(t *testing.T) {
+	text := []byte(`SELECT Name FROM Users`)
+	y := And(nil,
+		Token(`select`, "SELECT"), Token(`[a-z]+`, "FIELD"),
+		Token(`from`, "FROM"), Token(`[a-z]+`, "TABLE"),
+	)
+	// plain scanner is case sensitive.
+	if node, _ := y(NewScanner(text)); node != nil {
+		t.Errorf("unexpected match %v", node)
+	}
+
+	node, s := y(NewFoldingScanner(text))
+	if node == nil {
+		t.Fatalf("expected match")
+	} else if s.Endof() == false {
+		t.Errorf("expected end of text")
+	}
+	refs := []string{"SELECT", "Name", "FROM", "Users"}
+	poss := []int{0, 7, 12, 17}
+	for i, n := range node.([]ParsecNode) {
+		term := n.(*Terminal)
+		if term.Value != refs[i] {
+			t.Errorf("expected %q, got %q", refs[i], term.Value)
+		} else if term.Position != poss[i] {
+			t.Errorf("expected %v, got %v", poss[i], term.Position)
+		}
+	}
+
+	// Atom value is the matched text.
+	y = And(nil, Atom("select", "SELECT"), AtomExact(" name", "FIELD"))
+	node, _ = y(NewFoldingScanner(text))
+	if node == nil {
+		t.Fatalf("expected match")
+	}
+	for i, ref := range []string{"SELECT", " Name"} {
+		if v := node.([]ParsecNode)[i].(*Terminal).Value; v != ref {
+			t.Errorf("expected %q, got %q", ref, v)
+		}
+	}
+
+	// MatchString and SubmatchAll
+	s = NewFoldingScanner([]byte(`KEY=Value`))
+	if ok, _ := s.MatchString("Key"); !ok {
+		t.Errorf("expected match")
+	}
+	captures, s := s.SubmatchAll(`^(?P<eq>=)(?P<value>value)`)
+	if v := string(captures["value"]); v != "Value" {
+		t.Errorf("expected %q, got %q", "Value", v)
+	} else if s.Endof() == false {
+		t.Errorf("expected end of text")
+	}
+
+	// runes whose lower case differ in length are preserved.
+	text = []byte("İSTANBUL ÇAY")
+	s = NewFoldingScanner(text)
+	tok, s := s.Match(`^\S+`)
+	if string(tok) != "İSTANBUL" {
+		t.Errorf("expected %q, got %q", "İSTANBUL", tok)
+	}
+	tok, _ = s.Match(`^ çay`)
+	if string(tok) != " ÇAY" {
+		t.Errorf("expected %q, got %q", " ÇAY", tok)
+	}
+}
+
+func TestScannerContract(t *testing.T) {
+	testScannerContract(t, NewScanner)
+	testScannerContract(t, NewFoldingScanner)
+}
+
+// testScannerContract verifies the behaviour expected from every Scanner
+// implementation, newScanner shall create a new scanner for text.
+func testScannerContract(t *testing.T, newScanner func([]byte) Scanner) {
+	s := newScanner([]byte("example text 号分隔值"))
+	if s.GetCursor() != 0 || s.Endof() {
+		t.Fatalf("unexpected cursor %v", s.GetCursor())
+	} else if n := s.BytesRemaining(); n != 25 {
+		t.Fatalf("expected %v, got %v", 25, n)
+	}
+	// Match
+	m, s := s.Match(`^ex.*l`)
+	if string(m) != "exampl" {
+		t.Fatalf("expected %q, got %q", "exampl", m)
+	} else if s.GetCursor() != 6 {
+		t.Fatalf("expected %v, got %v", 6, s.GetCursor())
+	}
+	if m, s = s.Match(`^xyz`); m != nil {
+		t.Fatalf("unexpected %q", m)
+	} else if s.GetCursor() != 6 {
+		t.Fatalf("expected %v, got %v", 6, s.GetCursor())
+	}
+	// Clone
+	c := s.Clone()
+	if _, c = c.Match(`^e`); c.GetCursor() != 7 {
+		t.Fatalf("expected %v, got %v", 7, c.GetCursor())
+	} else if s.GetCursor() != 6 {
+		t.Fatalf("expected %v, got %v", 6, s.GetCursor())
+	}
+	// MatchString
+	if ok, _ := s.MatchString("xyz"); ok {
+		t.Fatalf("unexpected match")
+	} else if ok, s = s.MatchString("e"); !ok {
+		t.Fatalf("expected match")
+	} else if s.GetCursor() != 7 {
+		t.Fatalf("expected %v, got %v", 7, s.GetCursor())
+	}
+	// SkipWS
+	if ws, s := s.SkipWS(); string(ws) != " " {
+		t.Fatalf("expected %q, got %q", " ", ws)
+	} else if s.GetCursor() != 8 {
+		t.Fatalf("expected %v, got %v", 8, s.GetCursor())
+	}
+	// SubmatchAll
+	captures, s := s.SubmatchAll(`^(?P<X>te)(?P<Y>x)(?P<Z>q)?`)
+	if len(captures) != 2 {
+		t.Fatalf("unexpected %v", captures)
+	} else if x, y := string(captures["X"]), string(captures["Y"]); x != "te" {
+		t.Fatalf("expected %q, got %q", "te", x)
+	} else if y != "x" {
+		t.Fatalf("expected %q, got %q", "x", y)
+	} else if s.GetCursor() != 11 {
+		t.Fatalf("expected %v, got %v", 11, s.GetCursor())
+	}
+	if captures, _ := s.SubmatchAll(`^(?P<X>q)`); captures != nil {
+		t.Fatalf("unexpected %v", captures)
+	}
+	// SkipAny, unicode and Endof
+	if m, s = s.SkipAny(`[t ]+`); string(m) != "t " {
+		t.Fatalf("expected %q, got %q", "t ", m)
+	} else if n := s.BytesRemaining(); n != 12 {
+		t.Fatalf("expected %v, got %v", 12, n)
+	} else if m, s = s.Match(`^[^,]+`); string(m) != "号分隔值" {
+		t.Fatalf("expected %q, got %q", "号分隔值", m)
+	} else if !s.Endof() || s.BytesRemaining() != 0 {
+		t.Fatalf("expected end of text")
+	}
+
+	// SetWSPattern
+	s = newScanner([]byte("// comment\nnext")).SetWSPattern(`^//[^\n]*\n`)
+	if _, s = s.SkipWS(); s.GetCursor() != 11 {
+		t.Fatalf("expected %v, got %v", 11, s.GetCursor())
+	}
+
+	// TrackLineno
+	s = newScanner([]byte("hello \n  \t \nworld \n\"say\"")).TrackLineno()
+	y := OrdChoice(nil, Token(`\w+`, "WORD"), Atom(`"say"`, "STR"))
+	lines := []int{1, 3, 4}
+	for i := 0; i < 3; i++ {
+		node, news := y(s)
+		if node == nil {
+			t.Fatalf("expected match")
+		} else if news.Lineno() != lines[i] {
+			t.Fatalf("expected %v, got %v", lines[i], news.Lineno())
+		}
+		s = news
+	}
+	if !s.Endof() {
+		t.Fatalf("expected end of text")
+	}
+
+	// String terminal
+	s = newScanner([]byte(` "hello \"world\"" `))
+	if node, _ := String()(s); node != `"hello "world""` {
+		t.Fatalf("unexpected %v", node)
+	}
+
+	// TryMatch and SkipN
+	s = newScanner([]byte("key\n= value")).TrackLineno()
+	tok, ok := s.TryMatch(`^\w+\s*`)
+	if !ok || string(tok) != "key\n" {
+		t.Fatalf("expected %q, got %q", "key\n", tok)
+	} else if s.GetCursor() != 0 {
+		t.Fatalf("expected %v, got %v", 0, s.GetCursor())
+	}
+	if s = s.SkipN(len(tok)); s.GetCursor() != 4 {
+		t.Fatalf("expected %v, got %v", 4, s.GetCursor())
+	} else if s.Lineno() != 2 {
+		t.Fatalf("expected %v, got %v", 2, s.Lineno())
+	}
+	if tok, ok := s.TryMatch(`^x*`); !ok || len(tok) != 0 {
+		t.Fatalf("expected empty match, got %q %v", tok, ok)
+	} else if _, ok := s.TryMatch(`^x`); ok {
+		t.Fatalf("unexpected match")
+	}
+	if s = s.SkipN(100); !s.Endof() {
+		t.Fatalf("expected end of text")
+	}
+
+	// empty text
+	s = newScanner([]byte(""))
+	if !s.Endof() {
+		t.Fatalf("expected end of text")
+	} else if m, _ := s.Match(`^x`); m != nil {
+		t.Fatalf("unexpected %q", m)
+	}
+}
+
+// makejsony return a JSON grammar built from the standard terminals, to
+// compare different scanner implementations.
+func makejsony() Parser {
+	var value Parser
+	comma := Atom(",", "COMMA")
+	num := Token(`-?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?`, "NUM")
+	values := Kleene(nil, &value, comma)
+	array := And(nil, Atom("[", "OPENSQR"), values, Atom("]", "CLOSESQR"))
+	property := And(nil, String(), Atom(":", "COLON"), &value)
+	properties := Kleene(nil, property, comma)
+	object := And(nil, Atom("{", "OPENBRACE"), properties, Atom("}", "CLOSEBRACE"))
+	value = OrdChoice(nil,
+		String(), num, Keywords("true", "false", "null"), array, object,
+	)
+	return value
+}
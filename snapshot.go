@@ -0,0 +1,186 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "encoding/json"
+import "fmt"
+import "io"
+import "strings"
+
+// SnapshotTokens is the number of tokens, consumed before the failure,
+// captured by WithFailureSnapshot.
+var SnapshotTokens = 8
+
+// Snapshot of a failed parse, refer WithFailureSnapshot, to be attached
+// to bug reports either as text, using Write, or as JSON.
+type Snapshot struct {
+	Offset  int             `json:"offset"` // furthest failure.
+	Line    int             `json:"line"`
+	Col     int             `json:"col"`
+	Rules   []string        `json:"rules"`   // Grammar rules active at Offset.
+	Excerpt string          `json:"excerpt"` // input line at Offset, truncated.
+	Tokens  []SnapshotToken `json:"tokens"`  // last tokens consumed.
+}
+
+// SnapshotToken is a terminal consumed before the failure.
+type SnapshotToken struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Offset int    `json:"offset"`
+}
+
+// Write render snapshot in human readable form, like:
+//
+//	parse failed at line 2, col 7, offset 20
+//	rules: value > object > property > value
+//	     "b": }
+//	          ^
+//	tokens: NUM "2", CLOSESQR "]", COMMA ",", COLON ":"
+func (snap *Snapshot) Write(w io.Writer) error {
+	var sb strings.Builder
+	fmsg := "parse failed at line %v, col %v, offset %v\n"
+	fmt.Fprintf(&sb, fmsg, snap.Line, snap.Col, snap.Offset)
+	fmt.Fprintf(&sb, "rules: %v\n", strings.Join(snap.Rules, " > "))
+	fmt.Fprintf(&sb, "    %v\n", snap.Excerpt)
+	fmt.Fprintf(&sb, "    %v^\n", caretIndent(snap.Excerpt, snap.Col))
+	tokens := make([]string, 0, len(snap.Tokens))
+	for _, token := range snap.Tokens {
+		tokens = append(tokens, fmt.Sprintf("%v %q", token.Name, token.Value))
+	}
+	fmt.Fprintf(&sb, "tokens: %v\n", strings.Join(tokens, ", "))
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// JSON return snapshot as indented JSON document.
+func (snap *Snapshot) JSON() ([]byte, error) {
+	return json.MarshalIndent(snap, "", "  ")
+}
+
+// WithFailureSnapshot option for Run, if the parse fails, Result has a
+// Snapshot of the furthest failure, with the stack of Grammar rules that
+// were active there, the line of input and the last few tokens consumed,
+// refer SnapshotTokens. Supported by scanners created with NewScanner,
+// NewScannerString and NewScannerAt, and panics with other scanners.
+// Rules and tokens are tracked only with this option.
+func WithFailureSnapshot() RunOption {
+	return func(config *runConfig) {
+		config.snapshot = true
+	}
+}
+
+// failureTracker track the furthest failure and the tokens consumed,
+// shared by a scanner and all its clones.
+type failureTracker struct {
+	furthest int
+	rules    []string // stack at furthest failure.
+	tokens   []SnapshotToken
+}
+
+// snapshotScanner is implemented by scanners that can track failures.
+type snapshotScanner interface {
+	failureTracker() *failureTracker
+	setFailureTracker(ft *failureTracker)
+}
+
+// ruleScanner is implemented by scanners that can track the stack of
+// Grammar rules.
+type ruleScanner interface {
+	rulestack() *[]string
+}
+
+func failureTrackerOf(s Scanner) *failureTracker {
+	if ss, ok := s.(snapshotScanner); ok {
+		return ss.failureTracker()
+	}
+	return nil
+}
+
+// trackRule wrap Grammar rule `name`, to track the stack of rules when
+// scanner has a failureTracker or a backtrackBudget.
+func trackRule(name string, p Parser) Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		rs, ok := s.(ruleScanner)
+		if !ok {
+			return p(s)
+		}
+		stack := rs.rulestack()
+		if stack == nil {
+			return p(s)
+		}
+		*stack = append(*stack, name)
+		defer func() { *stack = (*stack)[:len(*stack)-1] }()
+		node, news := p(s)
+		if ft := failureTrackerOf(s); node == nil && ft != nil {
+			_, ws := s.Clone().SkipWS()
+			ft.fail(ws.GetCursor(), *stack)
+		}
+		return node, news
+	}
+}
+
+// fail note the `stack` of rules if `cursor` is the furthest failure, or
+// the stack is shallower than the one noted at the same cursor, so that
+// the outermost rule that failed there is at the top of the stack.
+func (ft *failureTracker) fail(cursor int, stack []string) {
+	if cursor > ft.furthest || (cursor == ft.furthest && len(stack) < len(ft.rules)) {
+		ft.furthest = cursor
+		ft.rules = append(ft.rules[:0], stack...)
+	}
+}
+
+// consumed note terminal `t`, ignoring terminals matched again after
+// backtracking.
+func (ft *failureTracker) consumed(t *Terminal) {
+	if n := len(ft.tokens); n > 0 && t.Position <= ft.tokens[n-1].Offset {
+		return
+	}
+	value := truncateValid([]byte(t.Value), excerptLen)
+	token := SnapshotToken{Name: t.Name, Value: value, Offset: t.Position}
+	if ft.tokens = append(ft.tokens, token); len(ft.tokens) > SnapshotTokens {
+		ft.tokens = ft.tokens[len(ft.tokens)-SnapshotTokens:]
+	}
+}
+
+// snapshot of the failure at `offset`, where `start` is the scanner
+// Run was started with.
+func (ft *failureTracker) snapshot(start Scanner, offset int) *Snapshot {
+	rules := ft.rules
+	if ft.furthest >= offset {
+		offset = ft.furthest
+	} else {
+		rules = nil // failed after the rules, like trailing text.
+	}
+	snap := &Snapshot{Offset: offset, Rules: rules, Tokens: ft.tokens}
+	if snap.Rules == nil {
+		snap.Rules = []string{}
+	}
+	if snap.Tokens == nil {
+		snap.Tokens = []SnapshotToken{}
+	}
+	prefix := scanText(start.Clone(), offset)
+	line, col := LineCol(prefix, len(prefix))
+	snap.Line, snap.Col = line+start.Lineno()-1, col
+	from := strings.LastIndexByte(string(prefix), '\n') + 1
+	rest, _ := start.Clone().SkipN(offset - start.GetCursor()).TryMatch(`^[^\r\n]*`)
+	snap.Excerpt = string(prefix[from:]) + truncateValid(rest, excerptLen)
+	return snap
+}
+
+// caretIndent return white space to position a caret under column `col`
+// of `line`, retaining tabs.
+func caretIndent(line string, col int) string {
+	var sb strings.Builder
+	for _, r := range line {
+		if col--; col <= 0 {
+			break
+		}
+		if r == '\t' {
+			sb.WriteByte('\t')
+		} else {
+			sb.WriteByte(' ')
+		}
+	}
+	return sb.String()
+}
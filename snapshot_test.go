@@ -0,0 +1,101 @@
+package parsec
+
+import "bytes"
+import "encoding/json"
+import "reflect"
+import "strings"
+import "testing"
+
+func TestFailureSnapshot(t *testing.T) {
+	text := "{\"a\": [1, 2],\n \"b\": }"
+	value := makejsongrammar().Rule("value")
+	res := Run(value, NewScanner([]byte(text)), WithFailureSnapshot())
+	if res.Err == nil || res.Snapshot == nil {
+		t.Fatalf("expected failure snapshot, got %v", res.Err)
+	}
+	snap := res.Snapshot
+	if snap.Offset != 20 || snap.Line != 2 || snap.Col != 7 {
+		t.Errorf("unexpected %v %v %v", snap.Offset, snap.Line, snap.Col)
+	}
+	if ref := []string{"value", "object", "property", "value"}; !reflect.DeepEqual(snap.Rules, ref) {
+		t.Errorf("expected %v, got %v", ref, snap.Rules)
+	}
+	if ref := ` "b": }`; snap.Excerpt != ref {
+		t.Errorf("expected %q, got %q", ref, snap.Excerpt)
+	}
+	if n := len(snap.Tokens); n != SnapshotTokens {
+		t.Fatalf("expected %v, got %v", SnapshotTokens, n)
+	} else if last := snap.Tokens[n-1]; last.Name != "COLON" || last.Offset != 18 {
+		t.Errorf("unexpected %v", last)
+	}
+
+	var buf bytes.Buffer
+	if err := snap.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	refs := []string{
+		"parse failed at line 2, col 7, offset 20\n",
+		"rules: value > object > property > value\n",
+		"     \"b\": }\n          ^\n",
+		`tokens: COLON ":", OPENSQR "[", NUM "1", COMMA ",", NUM "2", CLOSESQR "]", COMMA ",", COLON ":"`,
+	}
+	for _, ref := range refs {
+		if !strings.Contains(out, ref) {
+			t.Errorf("expected %q in %q", ref, out)
+		}
+	}
+
+	data, err := snap.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Snapshot
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(&decoded, snap) {
+		t.Errorf("expected %v, got %v", snap, decoded)
+	}
+
+	// trailing text fails outside the rules.
+	res = Run(value, NewScannerString("[1, 2]\n  3"), WithFailureSnapshot())
+	if snap := res.Snapshot; snap == nil || snap.Offset != 9 || len(snap.Rules) != 0 {
+		t.Errorf("unexpected %v", snap)
+	} else if snap.Line != 2 || snap.Col != 3 || snap.Excerpt != "  3" {
+		t.Errorf("unexpected %v %v %q", snap.Line, snap.Col, snap.Excerpt)
+	}
+
+	// excerpt is truncated after the failure.
+	text = "[1, 2]\n  3 " + strings.Repeat("é", 40)
+	res = Run(value, NewScannerString(text), WithFailureSnapshot())
+	if ref := "  3 " + strings.Repeat("é", 19) + "..."; res.Snapshot.Excerpt != ref {
+		t.Errorf("expected %q, got %q", ref, res.Snapshot.Excerpt)
+	}
+
+	// successful parse has no snapshot.
+	res = Run(value, NewScannerString(`{"a": [1, 2]}`), WithFailureSnapshot())
+	if res.Err != nil || res.Snapshot != nil {
+		t.Errorf("unexpected %v %v", res.Err, res.Snapshot)
+	}
+}
+
+func TestFailureSnapshotOff(t *testing.T) {
+	text := []byte(`{"a": [1, 2, {"b": null}], "c": "x", "d": [true, }`)
+	value := makejsongrammar().Rule("value")
+	if res := Run(value, NewScanner(text)); res.Err == nil || res.Snapshot != nil {
+		t.Fatalf("unexpected %v %v", res.Err, res.Snapshot)
+	}
+	// rules and tokens are not tracked without the option.
+	off := testing.AllocsPerRun(10, func() { Run(value, NewScanner(text)) })
+	on := testing.AllocsPerRun(10, func() {
+		Run(value, NewScanner(text), WithFailureSnapshot())
+	})
+	if off >= on {
+		t.Errorf("expected fewer than %v allocations, got %v", on, off)
+	}
+	s := NewScanner(text)
+	Run(value, s)
+	if ft := failureTrackerOf(s); ft != nil {
+		t.Errorf("unexpected %v", ft)
+	}
+}
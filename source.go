@@ -0,0 +1,78 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+// Span return the offsets [start, end) of input text from which `node`
+// was parsed. Terminal nodes span from their position to position plus
+// the length of their value, while non-terminal nodes, []ParsecNode and
+// Queryable, span from the start of their first child till the end of
+// their last child. Missing nodes, like nil and MaybeNone, don't
+// contribute to the span. Return false if node, or any of its children,
+// is of a type that does not carry its position, like the string
+// returned by String() parser, or if node does not cover any input text.
+func Span(node ParsecNode) (start, end int, ok bool) {
+	start, end = -1, -1
+	ok = spanOf(node, &start, &end)
+	if !ok || start < 0 {
+		return -1, -1, false
+	}
+	return start, end, true
+}
+
+// SourceText return the exact bytes from `original` input text from
+// which `node` was parsed, using its Span. Return false if span of node
+// is not known or does not fall within the original text.
+func SourceText(node ParsecNode, original []byte) ([]byte, bool) {
+	start, end, ok := Span(node)
+	if !ok || end > len(original) {
+		return nil, false
+	}
+	return original[start:end], true
+}
+
+func spanOf(node ParsecNode, start, end *int) bool {
+	switch n := node.(type) {
+	case nil, MaybeNone:
+		return true
+
+	case *Terminal:
+		extendSpan(n.Position, n.Position+len(n.Value), start, end)
+		return true
+
+	case []ParsecNode:
+		for _, child := range n {
+			if !spanOf(child, start, end) {
+				return false
+			}
+		}
+		return true
+
+	case Queryable:
+		if n.IsTerminal() {
+			if pos := n.GetPosition(); pos >= 0 {
+				extendSpan(pos, pos+len(n.GetValue()), start, end)
+			}
+			return true
+		}
+		if nt, ok := n.(*NonTerminal); ok && nt.span != nil {
+			extendSpan(nt.span[0], nt.span[1], start, end)
+		}
+		for _, child := range n.GetChildren() {
+			if !spanOf(child, start, end) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func extendSpan(from, till int, start, end *int) {
+	if *start < 0 || from < *start {
+		*start = from
+	}
+	if till > *end {
+		*end = till
+	}
+}
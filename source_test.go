@@ -0,0 +1,76 @@
+package parsec
+
+import "testing"
+
+func TestSourceText(t *testing.T) {
+	var value Parser
+	comma := Atom(",", "COMMA")
+	str := Token(`"(?:[^"\\]|\\.)*"`, "STRING")
+	num := Token(`-?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?`, "NUM")
+	values := Kleene(nil, &value, comma)
+	array := And(nil, Atom("[", "OPENSQR"), values, Atom("]", "CLOSESQR"))
+	property := And(nil, str, Atom(":", "COLON"), &value)
+	properties := Kleene(nil, property, comma)
+	object := And(nil, Atom("{", "OPENBRACE"), properties, Atom("}", "CLOSEBRACE"))
+	value = OrdChoice(nil, str, num, Atom("null", "NULL"), array, object)
+
+	text := []byte(` {"a" : [1, {"b":  "x\"y"} ,null ], "c": -2.5e3 } `)
+	root, _ := value(NewScanner(text))
+	if root == nil {
+		t.Fatalf("expected match")
+	}
+	source := func(node ParsecNode) string {
+		src, ok := SourceText(node, text)
+		if !ok {
+			t.Fatalf("expected source for %v", node)
+		}
+		return string(src)
+	}
+	object1 := root.([]ParsecNode)[0].([]ParsecNode)
+	if ref := string(text[1 : len(text)-1]); source(root) != ref {
+		t.Errorf("expected %q, got %q", ref, source(root))
+	}
+	props := object1[1].([]ParsecNode)
+	ref := `"a" : [1, {"b":  "x\"y"} ,null ]`
+	if src := source(props[0]); src != ref {
+		t.Errorf("expected %q, got %q", ref, src)
+	}
+	array1 := props[0].([]ParsecNode)[2].([]ParsecNode)[0]
+	if src := source(array1); src != `[1, {"b":  "x\"y"} ,null ]` {
+		t.Errorf("unexpected %q", src)
+	}
+	items := array1.([]ParsecNode)[1].([]ParsecNode)
+	if src := source(items[1]); src != `{"b":  "x\"y"}` {
+		t.Errorf("unexpected %q", src)
+	}
+	if start, end, _ := Span(items[1]); start != 12 || end != 26 {
+		t.Errorf("unexpected span %v %v", start, end)
+	}
+	if src := source(props[1]); src != `"c": -2.5e3` {
+		t.Errorf("unexpected %q", src)
+	}
+
+	// nodes without position.
+	if _, ok := SourceText([]ParsecNode{"str", root}, text); ok {
+		t.Errorf("expected no source for string node")
+	} else if _, ok := SourceText(MaybeNone("missing"), text); ok {
+		t.Errorf("expected no source for missing node")
+	} else if _, ok := SourceText([]ParsecNode{}, text); ok {
+		t.Errorf("expected no source for empty list")
+	} else if _, ok := SourceText(root, text[:10]); ok {
+		t.Errorf("expected no source beyond text")
+	}
+
+	// AST nodes.
+	ast := NewAST("source", 100)
+	sum := ast.Many("sum", nil, Int(), Atom("+", "PLUS"))
+	y := ast.And("expr", nil, Atom("(", "OPEN"), sum, Atom(")", "CLOSE"))
+	text = []byte("x = ( 1 + 2 )")
+	qnode, _ := ast.Parsewith(y, NewScanner(text[4:]))
+	if src, _ := SourceText(qnode, text[4:]); string(src) != "( 1 + 2 )" {
+		t.Errorf("unexpected %q", src)
+	}
+	if src, _ := SourceText(qnode.GetChildren()[1], text[4:]); string(src) != "1 + 2" {
+		t.Errorf("unexpected %q", src)
+	}
+}
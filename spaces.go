@@ -0,0 +1,92 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+// WithOrWithoutSpaces combinator applies parser `p`, and if it fails,
+// retries `p` with SkipWS toggled, so that a grammar written for spaced
+// input can tolerate minified input and vice versa. With SkipWS
+// toggled, whitespace is not skipped where the scanner's white space
+// pattern would skip it, and `[ \t\r\n]+` is skipped elsewhere, like
+// for a scanner set with a white space pattern that skips nothing.
+// Note that input that fails both attempts is parsed twice, and SkipWS
+// costs an additional match in the second attempt, hence wrap the
+// smallest parser that needs it.
+func WithOrWithoutSpaces(p Parser) Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		if node, news := p(s.Clone()); node != nil {
+			return node, news
+		}
+		node, news := p(&toggleWSScanner{Scanner: s.Clone()})
+		if ts, ok := news.(*toggleWSScanner); node != nil && ok {
+			return node, ts.Scanner
+		}
+		return nil, s
+	}
+}
+
+// toggleWSScanner wraps a scanner to toggle its SkipWS.
+type toggleWSScanner struct {
+	Scanner
+}
+
+// SetWSPattern implement Scanner{} interface.
+func (s *toggleWSScanner) SetWSPattern(pattern string) Scanner {
+	s.Scanner = s.Scanner.SetWSPattern(pattern)
+	return s
+}
+
+// TrackLineno implement Scanner{} interface.
+func (s *toggleWSScanner) TrackLineno() Scanner {
+	s.Scanner = s.Scanner.TrackLineno()
+	return s
+}
+
+// Clone implement Scanner{} interface.
+func (s *toggleWSScanner) Clone() Scanner {
+	return &toggleWSScanner{Scanner: s.Scanner.Clone()}
+}
+
+// Match implement Scanner{} interface.
+func (s *toggleWSScanner) Match(pattern string) ([]byte, Scanner) {
+	token, news := s.Scanner.Match(pattern)
+	s.Scanner = news
+	return token, s
+}
+
+// MatchString implement Scanner{} interface.
+func (s *toggleWSScanner) MatchString(str string) (bool, Scanner) {
+	ok, news := s.Scanner.MatchString(str)
+	s.Scanner = news
+	return ok, s
+}
+
+// SubmatchAll implement Scanner{} interface.
+func (s *toggleWSScanner) SubmatchAll(pattern string) (map[string][]byte, Scanner) {
+	captures, news := s.Scanner.SubmatchAll(pattern)
+	s.Scanner = news
+	return captures, s
+}
+
+// SkipWS implement Scanner{} interface, toggled.
+func (s *toggleWSScanner) SkipWS() ([]byte, Scanner) {
+	if token, _ := s.Scanner.Clone().SkipWS(); len(token) > 0 {
+		return nil, s
+	}
+	token, news := s.Scanner.SkipAny(`^[ \t\r\n]+`)
+	s.Scanner = news
+	return token, s
+}
+
+// SkipAny implement Scanner{} interface.
+func (s *toggleWSScanner) SkipAny(pattern string) ([]byte, Scanner) {
+	token, news := s.Scanner.SkipAny(pattern)
+	s.Scanner = news
+	return token, s
+}
+
+// SkipN implement Scanner{} interface.
+func (s *toggleWSScanner) SkipN(n int) Scanner {
+	s.Scanner = s.Scanner.SkipN(n)
+	return s
+}
@@ -0,0 +1,48 @@
+package parsec
+
+import "testing"
+
+func TestWithOrWithoutSpaces(t *testing.T) {
+	// grammar for minified input, scanner does not skip whitespace.
+	pair := And(nil, Token(`[a-z]+`, "KEY"), Atom("=", "EQUAL"), Int())
+	y := WithOrWithoutSpaces(pair)
+	for _, text := range []string{"retries=3", "retries = 3", "retries =\n 3"} {
+		s := NewScanner([]byte(text)).SetWSPattern(`^`)
+		if node, _ := pair(s.Clone()); node != nil && text != "retries=3" {
+			t.Errorf("%q: unexpected match without WithOrWithoutSpaces", text)
+		}
+		node, news := y(s)
+		if node == nil {
+			t.Errorf("%q: expected match", text)
+		} else if v := node.([]ParsecNode)[2].(*Terminal).Value; v != "3" {
+			t.Errorf("%q: expected %v, got %v", text, "3", v)
+		} else if !news.Endof() {
+			t.Errorf("%q: expected end of text, got %v", text, news.GetCursor())
+		}
+		// returned scanner is not toggled.
+		if _, ok := news.(*SimpleScanner); !ok {
+			t.Errorf("%q: unexpected %T", text, news)
+		}
+	}
+
+	// grammar with significant space, scanner skips whitespace.
+	words := And(nil, Token(`[a-z]+`, "WORD"), Atom(" ", "SPACE"), Token(`[a-z]+`, "WORD"))
+	y = WithOrWithoutSpaces(words)
+	if node, _ := words(NewScanner([]byte("hello world"))); node != nil {
+		t.Errorf("unexpected match without WithOrWithoutSpaces")
+	}
+	node, news := y(NewScanner([]byte("hello world")))
+	if node == nil {
+		t.Errorf("expected match")
+	} else if !news.Endof() {
+		t.Errorf("expected end of text, got %v", news.GetCursor())
+	}
+
+	// fails both attempts.
+	s := NewScanner([]byte("retries:3"))
+	if node, news := WithOrWithoutSpaces(pair)(s); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if news != s || news.GetCursor() != 0 {
+		t.Errorf("expected input scanner, got %v", news.GetCursor())
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "io"
+import "regexp"
+import "strings"
+
+// StringScanner implements Scanner interface for input text supplied as
+// string, avoiding a copy of the input into byte-slice. Matched tokens
+// are returned as a copy of the matching text.
+type StringScanner struct {
+	text         string // input text
+	cursor       int    // cursor within input text
+	lineno       int
+	patternCache map[string]*regexp.Regexp
+	wsPattern    string // white space pattern used by SkipWS()
+	*scanState
+	// settings
+	tracklineno bool
+}
+
+// NewScannerString create and return a new instance of StringScanner
+// object.
+func NewScannerString(text string) Scanner {
+	return &StringScanner{
+		text:         text,
+		cursor:       0,
+		lineno:       1,
+		patternCache: make(map[string]*regexp.Regexp),
+		wsPattern:    defaultWS,
+		scanState:    &scanState{},
+		tracklineno:  false,
+	}
+}
+
+// OnProgress same as WithProgress option.
+func (s *StringScanner) OnProgress(every int, fn ProgressFunc) Scanner {
+	s.progress = NewProgress(every, fn, int64(len(s.text)))
+	return s
+}
+
+// WithNodeIDs same as WithNodeIDs option.
+func (s *StringScanner) WithNodeIDs() Scanner {
+	s.nodeids = new(int64)
+	return s
+}
+
+// SetMaxBacktrack same as WithMaxBacktrack option.
+func (s *StringScanner) SetMaxBacktrack(bytes int) Scanner {
+	s.backtrack, s.limited = newBacktrack(bytes), true
+	s.backtrack.update(s.cursor)
+	return s
+}
+
+// InternValues same as WithInterning option.
+func (s *StringScanner) InternValues(maxLen int) Scanner {
+	s.interns = NewInterner(maxLen)
+	return s
+}
+
+// LineContinuation same as WithLineContinuation option.
+func (s *StringScanner) LineContinuation(marker byte) Scanner {
+	s.continuation = string(marker)
+	s.wsPattern = continuationPattern(s.wsPattern, s.continuation)
+	return s
+}
+
+// CurrentLine same as SimpleScanner.CurrentLine.
+func (s *StringScanner) CurrentLine() []byte {
+	from := strings.LastIndexByte(s.text[:s.cursor], '\n') + 1
+	till := len(s.text)
+	if i := strings.IndexByte(s.text[s.cursor:], '\n'); i >= 0 {
+		till = s.cursor + i
+	}
+	return []byte(strings.TrimSuffix(s.text[from:till], "\r"))
+}
+
+// TabWidth same as WithTabWidth option.
+func (s *StringScanner) TabWidth(width int) Scanner {
+	s.tabwidth = width
+	return s
+}
+
+// ColumnPosition same as SimpleScanner.ColumnPosition.
+func (s *StringScanner) ColumnPosition() int {
+	from := strings.LastIndexByte(s.text[:s.cursor], '\n') + 1
+	return column([]byte(s.text[from:s.cursor]), s.tabwidth)
+}
+
+// Read same as SimpleScanner.Read.
+func (s *StringScanner) Read(p []byte) (n int, err error) {
+	if len(p) > 0 && s.cursor >= len(s.text) {
+		return 0, io.EOF
+	}
+	n = copy(p, s.text[s.cursor:])
+	s.advance(s.text[s.cursor : s.cursor+n])
+	return n, nil
+}
+
+//---- Scanner{} interface.
+
+// SetWSPattern implement Scanner{} interface.
+func (s *StringScanner) SetWSPattern(pattern string) Scanner {
+	s.wsPattern = continuationPattern(pattern, s.continuation)
+	return s
+}
+
+// TrackLineno implement Scanner{} interface.
+func (s *StringScanner) TrackLineno() Scanner {
+	s.tracklineno = true
+	return s
+}
+
+// Clone implement Scanner{} interface.
+func (s *StringScanner) Clone() Scanner {
+	s.rewind(s.cursor)
+	return &StringScanner{
+		text:         s.text,
+		cursor:       s.cursor,
+		lineno:       s.lineno,
+		patternCache: s.patternCache,
+		wsPattern:    s.wsPattern,
+		scanState:    s.scanState,
+		tracklineno:  s.tracklineno,
+	}
+}
+
+// GetCursor implement Scanner{} interface.
+func (s *StringScanner) GetCursor() int {
+	return s.cursor
+}
+
+// Match implement Scanner{} interface.
+func (s *StringScanner) Match(pattern string) ([]byte, Scanner) {
+	if s.abort != nil {
+		return nil, s
+	}
+	regc := getPattern(s.patternCache, pattern)
+	if loc := regc.FindStringIndex(s.text[s.cursor:]); loc != nil {
+		token := s.text[s.cursor+loc[0] : s.cursor+loc[1]]
+		s.advance(token)
+		return []byte(token), s
+	}
+	return nil, s
+}
+
+// MatchString implement Scanner{} interface.
+func (s *StringScanner) MatchString(str string) (bool, Scanner) {
+	if s.abort != nil || !strings.HasPrefix(s.text[s.cursor:], str) {
+		return false, s
+	}
+	s.advance(str)
+	return true, s
+}
+
+// SubmatchAll implement Scanner{} interface.
+func (s *StringScanner) SubmatchAll(patt string) (map[string][]byte, Scanner) {
+	values, offsets := s.submatches(patt)
+	return captureMap(getPattern(s.patternCache, patt), values, offsets), s
+}
+
+// submatches implement submatchScanner{} interface.
+func (s *StringScanner) submatches(pattern string) ([][]byte, []int) {
+	if s.abort != nil {
+		return nil, nil
+	}
+	text := s.text[s.cursor:]
+	locs := getPattern(s.patternCache, pattern).FindStringSubmatchIndex(text)
+	if locs == nil {
+		return nil, nil
+	}
+	values, offsets := make([][]byte, len(locs)/2), make([]int, len(locs)/2)
+	for i := range values {
+		if offsets[i] = -1; locs[2*i] >= 0 {
+			values[i] = []byte(text[locs[2*i]:locs[2*i+1]])
+			offsets[i] = s.cursor + locs[2*i]
+		}
+	}
+	s.advance(text[locs[0]:locs[1]])
+	return values, offsets
+}
+
+// SkipWS implement Scanner{} interface.
+func (s *StringScanner) SkipWS() ([]byte, Scanner) {
+	return s.SkipAny(s.wsPattern)
+}
+
+// SkipAny implement Scanner{} interface.
+func (s *StringScanner) SkipAny(pattern string) ([]byte, Scanner) {
+	if pattern[0] != '^' {
+		pattern = "^" + pattern
+	}
+	return s.Match(pattern)
+}
+
+// TryMatch implement Scanner{} interface.
+func (s *StringScanner) TryMatch(pattern string) ([]byte, bool) {
+	if s.abort != nil {
+		return nil, false
+	}
+	regc := getPattern(s.patternCache, pattern)
+	if loc := regc.FindStringIndex(s.text[s.cursor:]); loc != nil {
+		return []byte(s.text[s.cursor+loc[0] : s.cursor+loc[1]]), true
+	}
+	return nil, false
+}
+
+// SkipN implement Scanner{} interface.
+func (s *StringScanner) SkipN(n int) Scanner {
+	if n > len(s.text)-s.cursor {
+		n = len(s.text) - s.cursor
+	}
+	s.advance(s.text[s.cursor : s.cursor+n])
+	return s
+}
+
+// Lineno implement Scanner{} interface.
+func (s *StringScanner) Lineno() int {
+	return s.lineno
+}
+
+// Endof implement Scanner{} interface.
+func (s *StringScanner) Endof() bool {
+	return s.cursor >= len(s.text)
+}
+
+// BytesRemaining implement Scanner{} interface.
+func (s *StringScanner) BytesRemaining() int {
+	return len(s.text) - s.cursor
+}
+
+//---- local methods
+
+func (s *StringScanner) advance(token string) {
+	if s.tracklineno {
+		s.lineno += strings.Count(token, "\n")
+	}
+	s.cursor += len(token)
+	s.progress.Update(s.cursor)
+	s.backtrack.update(s.cursor)
+}
+
+// isolate implement isolatingScanner{} interface.
+func (s *StringScanner) isolate(patterns ...string) Scanner {
+	news := *s
+	news.patternCache = make(map[string]*regexp.Regexp, len(patterns)+1)
+	for _, pattern := range append(patterns, s.wsPattern) {
+		news.patternCache[pattern] = getPattern(s.patternCache, pattern)
+	}
+	news.scanState = s.scanState.isolated()
+	return &news
+}
+
+// adopt implement isolatingScanner{} interface.
+func (s *StringScanner) adopt(iso Scanner) {
+	s.advance(s.text[s.cursor:iso.(*StringScanner).cursor])
+}
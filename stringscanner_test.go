@@ -0,0 +1,38 @@
+package parsec
+
+import "io/ioutil"
+import "reflect"
+import "testing"
+
+func TestStringScanner(t *testing.T) {
+	testScannerContract(t, func(text []byte) Scanner {
+		return NewScannerString(string(text))
+	})
+}
+
+func TestStringScannerJSON(t *testing.T) {
+	text, err := ioutil.ReadFile("testdata/medium.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	y := makejsony()
+	ref, s := y(NewScanner(text))
+	if ref == nil {
+		t.Fatalf("expected match")
+	}
+	node, ss := y(NewScannerString(string(text)))
+	if !reflect.DeepEqual(ref, node) {
+		t.Errorf("mismatch between byte scanner and string scanner")
+	} else if ss.GetCursor() != s.GetCursor() {
+		t.Errorf("expected %v, got %v", s.GetCursor(), ss.GetCursor())
+	}
+}
+
+func BenchmarkStringScanJSON(b *testing.B) {
+	text, _ := ioutil.ReadFile("testdata/medium.json")
+	str, y := string(text), makejsony()
+	for i := 0; i < b.N; i++ {
+		y(NewScannerString(str))
+	}
+	b.SetBytes(int64(len(text)))
+}
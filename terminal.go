@@ -8,6 +8,7 @@ type Terminal struct {
 	Value      string // value of the terminal
 	Position   int    // Offset into the text stream where token was identified
 	Attributes map[string][]string
+	ID         int64 // node identifier, refer NodeID.
 }
 
 // NewTerminal create a new Terminal instance. Supply the name of the
@@ -10,6 +10,10 @@ used with AST combinators and return a Terminal node.
 
 package parsec
 
+import "bytes"
+import "fmt"
+import "regexp"
+import "sort"
 import "strings"
 import "strconv"
 import "unicode"
@@ -21,16 +25,24 @@ import "unicode/utf16"
 // AST combinators. Skip leading whitespace.
 func String() Parser {
 	return func(s Scanner) (ParsecNode, Scanner) {
-		s.SkipWS()
-		scanner := s.(*SimpleScanner)
+		scanner, ok := s.(*SimpleScanner)
+		if !ok || scanner.norm != nil || scanner.abort != nil {
+			_, news := s.Clone().SkipWS()
+			if node, news := scanStringToken(news); node != nil {
+				return node, news
+			}
+			return nil, s
+		}
+		cursor, lineno := scanner.cursor, scanner.lineno
+		scanner.SkipWS()
 		if !scanner.Endof() && scanner.buf[scanner.cursor] == '"' {
-			str, readn := scanString(scanner.buf[scanner.cursor:])
-			if str == nil || len(str) == 0 {
-				return nil, scanner
+			str, readn := ScanString(scanner.buf[scanner.cursor:])
+			if str != nil && len(str) > 0 {
+				scanner.cursor += readn
+				return string(str), scanner
 			}
-			scanner.cursor += readn
-			return string(str), scanner
 		}
+		scanner.cursor, scanner.lineno = cursor, lineno
 		return nil, scanner
 	}
 }
@@ -47,6 +59,32 @@ func Float() Parser {
 	return Token(`[+-]?([0-9]+\.[0-9]*|\.[0-9]+)`, "FLOAT")
 }
 
+// SpecialFloat return parser function to match a non-finite float
+// literal, one of `NaN`, `Infinity`, `+Infinity` and `-Infinity`, in the
+// input stream. Supply `spellings` to match a different set of literals,
+// longer literals are tried first. Skip leading whitespace.
+func SpecialFloat(spellings ...string) Parser {
+	if len(spellings) == 0 {
+		spellings = []string{"NaN", "Infinity", "+Infinity", "-Infinity"}
+	}
+	literals := make([]string, len(spellings))
+	copy(literals, spellings)
+	sort.SliceStable(literals, func(i, j int) bool {
+		return len(literals[i]) > len(literals[j])
+	})
+	return func(s Scanner) (ParsecNode, Scanner) {
+		for _, literal := range literals {
+			news := s.Clone()
+			news.SkipWS()
+			cursor := news.GetCursor()
+			if ok, _ := news.MatchString(literal); ok {
+				return newTerminal(news, "SPECIALFLOAT", literal, cursor), news
+			}
+		}
+		return nil, s
+	}
+}
+
 // Hex return parser function to match a hexadecimal
 // literal in the input stream. Skip leading whitespace.
 func Hex() Parser {
@@ -85,7 +123,7 @@ func Token(pattern string, name string) Parser {
 		news.SkipWS()
 		cursor := news.GetCursor()
 		if tok, _ := news.Match(pattern); tok != nil {
-			return NewTerminal(name, string(tok), cursor), news
+			return newTerminal(news, name, tokenValue(news, tok), cursor), news
 		}
 		return nil, s
 	}
@@ -99,12 +137,121 @@ func TokenExact(pattern string, name string) Parser {
 		news := s.Clone()
 		cursor := news.GetCursor()
 		if tok, _ := news.Match("^" + pattern); tok != nil {
-			return NewTerminal(name, string(tok), cursor), news
+			return newTerminal(news, name, tokenValue(news, tok), cursor), news
 		}
 		return nil, s
 	}
 }
 
+// Gap return parser function to match the run of whitespace and
+// comments at the cursor, without skipping it, so that the gap between
+// two tokens can be captured verbatim, say by formatters. Comments can
+// be `// line` and `/* block */` comments. Return a Terminal named
+// `name` with the gap as its value, which is empty if there is no gap at
+// the cursor, hence never fails. An unterminated block comment is not
+// part of the gap.
+func Gap(name string) Parser {
+	pattern := `^(?:[ \t\r\n]+|//[^\n]*|/\*(?s:.*?)\*/)*`
+	return func(s Scanner) (ParsecNode, Scanner) {
+		news := s.Clone()
+		cursor := news.GetCursor()
+		tok, _ := news.Match(pattern)
+		return newTerminal(news, name, tokenValue(news, tok), cursor), news
+	}
+}
+
+// TokenNamed is similar to Token, but return a NonTerminal, named
+// `name`, with a child Terminal for each capture group in `pattern`
+// that participated in the match, in the order the groups are declared.
+// Child terminals are named after the named groups, like `year` in
+// `(?P<year>\d{4})`, and unnamed groups are named GROUPn where n is the
+// group's index in the pattern. Groups sharing the same name, say across
+// alternations, yield one child for each group that matched. Skip
+// leading whitespace.
+func TokenNamed(pattern string, name string) Parser {
+	if pattern == "" {
+		panic(fmt.Errorf("TokenNamed() %q has empty pattern", name))
+	} else if pattern[0] != '^' {
+		pattern = "^" + pattern
+	}
+	regc := regexp.MustCompile(pattern)
+	names := regc.SubexpNames()
+	for i := 1; i < len(names); i++ {
+		if names[i] == "" {
+			names[i] = "GROUP" + strconv.Itoa(i)
+		}
+	}
+	return func(s Scanner) (ParsecNode, Scanner) {
+		news := s.Clone()
+		news.SkipWS()
+		values, offsets := submatchesOf(news, pattern, regc)
+		if values == nil {
+			return nil, s
+		}
+		nt := newNonTerminal(news, name)
+		for i := 1; i < len(names); i++ {
+			if offsets[i] >= 0 {
+				value := tokenValue(news, values[i])
+				t := newTerminal(news, names[i], value, offsets[i])
+				nt.Children = append(nt.Children, t)
+			}
+		}
+		return nt, news
+	}
+}
+
+// submatchScanner is implemented by scanners that can return the
+// capture groups of a pattern matched at the cursor.
+type submatchScanner interface {
+	// submatches match `pattern` at the cursor, and return the value and
+	// offset of the match and of every capture group, offset is -1 for
+	// groups that did not participate in the match.
+	submatches(pattern string) ([][]byte, []int)
+}
+
+// submatchesOf match `pattern`, compiled as `regc`, using scanner `s`,
+// refer submatchScanner. With other scanners, groups are found by
+// matching regc on the matched token.
+func submatchesOf(
+	s Scanner, pattern string, regc *regexp.Regexp) ([][]byte, []int) {
+
+	if ss, ok := s.(submatchScanner); ok {
+		return ss.submatches(pattern)
+	}
+	cursor := s.GetCursor()
+	tok, _ := s.Match(pattern)
+	if tok == nil {
+		return nil, nil
+	}
+	locs := regc.FindSubmatchIndex(tok)
+	n := len(regc.SubexpNames())
+	values, offsets := make([][]byte, n), make([]int, n)
+	for i := range values {
+		if offsets[i] = -1; locs != nil && locs[2*i] >= 0 {
+			values[i], offsets[i] = tok[locs[2*i]:locs[2*i+1]], cursor+locs[2*i]
+		}
+	}
+	return values, offsets
+}
+
+// captureMap return the `values` of named groups in `regc` that
+// participated in the match, refer submatchScanner, nil if there is no
+// match.
+func captureMap(
+	regc *regexp.Regexp, values [][]byte, offsets []int) map[string][]byte {
+
+	if values == nil {
+		return nil
+	}
+	captures := make(map[string][]byte)
+	for i, name := range regc.SubexpNames() {
+		if i > 0 && name != "" && offsets[i] >= 0 {
+			captures[name] = values[i]
+		}
+	}
+	return captures
+}
+
 // Atom is similar to Token, takes a string to match with input
 // byte-by-byte. Internally uses the MatchString() API from Scanner.
 // Skip leading whitespace. For example:
@@ -116,7 +263,8 @@ func Atom(match string, name string) Parser {
 		news.SkipWS()
 		cursor := news.GetCursor()
 		if ok, _ := news.MatchString(match); ok {
-			return NewTerminal(name, match, cursor), news
+			value := matchedValue(news, match, cursor)
+			return newTerminal(news, name, value, cursor), news
 		}
 		return nil, s
 	}
@@ -129,12 +277,168 @@ func AtomExact(match string, name string) Parser {
 		news := s.Clone()
 		cursor := news.GetCursor()
 		if ok, _ := news.MatchString(match); ok {
-			return NewTerminal(name, match, cursor), news
+			value := matchedValue(news, match, cursor)
+			return newTerminal(news, name, value, cursor), news
+		}
+		return nil, s
+	}
+}
+
+// matchedValue return the input text matched by string `match` from
+// `cursor` till scanner's cursor, which differs from match when the
+// scanner matches case folded or normalized text.
+func matchedValue(s Scanner, match string, cursor int) string {
+	if ss, ok := s.(*SimpleScanner); ok && (ss.fold != nil || ss.norm != nil) {
+		return string(ss.buf[cursor:ss.cursor])
+	}
+	return match
+}
+
+// Keywords return parser function to match any one of the `words` in
+// the input stream, where the matching word shall be followed by a
+// WordBoundary, so that `null` won't match the prefix of `nullable`.
+// Longer words are tried first. Return Terminal named KEYWORD, with the
+// matched word as its value. Skip leading whitespace.
+func Keywords(words ...string) Parser {
+	if len(words) == 0 {
+		panic("Keywords() expects atleast one word")
+	}
+	sorted := make([]string, len(words))
+	copy(sorted, words)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+	return func(s Scanner) (ParsecNode, Scanner) {
+		for _, word := range sorted {
+			news := s.Clone()
+			news.SkipWS()
+			cursor := news.GetCursor()
+			if ok, _ := news.MatchString(word); ok && WordBoundary(news.Clone()) {
+				value := matchedValue(news, word, cursor)
+				return newTerminal(news, "KEYWORD", value, cursor), news
+			}
+		}
+		return nil, s
+	}
+}
+
+// UUID return parser function to match a UUID in its canonical form of
+// 8-4-4-4-12 hex digits, optionally enclosed in braces or prefixed with
+// `urn:uuid:`. Return Terminal named UUID, with the matched text as its
+// value and the lowercased UUID, without braces or prefix, as its
+// `canonical` attribute. Skip leading whitespace.
+func UUID() Parser {
+	pattern := `^(?:(?i:urn:uuid:)[0-9A-Za-z-]+|\{[0-9A-Za-z-]+\}|[0-9A-Za-z-]+)`
+	return func(s Scanner) (ParsecNode, Scanner) {
+		news := s.Clone()
+		news.SkipWS()
+		cursor := news.GetCursor()
+		if tok, _ := news.Match(pattern); tok != nil {
+			if canonical, ok := canonicalUUID(string(tok)); ok {
+				t := newTerminal(news, "UUID", string(tok), cursor)
+				t.SetAttribute("canonical", canonical)
+				return t, news
+			}
+		}
+		return nil, s
+	}
+}
+
+// Literals return parser function to match any one of the `literals`,
+// like units "px", "em" and "%", same as a choice of Atom parsers where
+// each Terminal is named after its literal in upper case. Longer
+// literals are tried first, so that "em" is not matched as "e". Skip
+// leading whitespace. Panics if there are no literals or a literal is
+// empty.
+func Literals(literals ...string) Parser {
+	return LiteralsNamed(strings.ToUpper, literals...)
+}
+
+// LiteralsNamed is same as Literals, where each Terminal is named as
+// returned by `namer` for its literal.
+func LiteralsNamed(namer func(literal string) string, literals ...string) Parser {
+	if len(literals) == 0 {
+		panic("Literals() expects atleast one literal")
+	}
+	sorted := make([]string, len(literals))
+	copy(sorted, literals)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+	names := make(map[string]string, len(sorted))
+	for _, literal := range sorted {
+		if literal == "" {
+			panic("Literals() expects non-empty literals")
+		}
+		names[literal] = namer(literal)
+	}
+	return func(s Scanner) (ParsecNode, Scanner) {
+		for _, literal := range sorted {
+			news := s.Clone()
+			news.SkipWS()
+			cursor := news.GetCursor()
+			if ok, _ := news.MatchString(literal); ok {
+				value := matchedValue(news, literal, cursor)
+				return newTerminal(news, names[literal], value, cursor), news
+			}
 		}
 		return nil, s
 	}
 }
 
+// PriorityRule specify a token for PrioritizedTokens, matching regular
+// expression Pattern as a Terminal named Name.
+type PriorityRule struct {
+	Pattern  string
+	Name     string
+	Priority int
+}
+
+// PrioritizedTokens return parser function to match a single token as
+// per one of the `rules`, where the matching rule with highest Priority
+// wins, like keywords over identifiers, and longest match breaks ties
+// between rules of same priority, after which the rule declared first
+// wins. Return Terminal named after the winning rule. Skip leading
+// whitespace. Panics if there are no rules, or a rule has no pattern.
+func PrioritizedTokens(rules []PriorityRule) Parser {
+	if len(rules) == 0 {
+		panic("PrioritizedTokens() expects atleast one rule")
+	}
+	patterns := make([]string, len(rules))
+	for i, rule := range rules {
+		if rule.Pattern == "" {
+			panic(fmt.Errorf("PrioritizedTokens() rule %q has empty pattern", rule.Name))
+		}
+		patterns[i] = "^(?:" + rule.Pattern + ")"
+	}
+	return func(s Scanner) (ParsecNode, Scanner) {
+		var best *PriorityRule
+		var bestTok []byte
+		var bests Scanner
+		var bestCursor int
+		for i := range rules {
+			news := s.Clone()
+			news.SkipWS()
+			cursor := news.GetCursor()
+			tok, _ := news.Match(patterns[i])
+			if tok == nil {
+				continue
+			}
+			rule := &rules[i]
+			if best == nil || rule.Priority > best.Priority ||
+				(rule.Priority == best.Priority && len(tok) > len(bestTok)) {
+
+				best, bestTok, bests, bestCursor = rule, tok, news, cursor
+			}
+		}
+		if best == nil {
+			return nil, s
+		}
+		value := tokenValue(bests, bestTok)
+		return newTerminal(bests, best.Name, value, bestCursor), bests
+	}
+}
+
 // OrdTokens to parse a single token based on one of the
 // specified `patterns`. Skip leading whitespaces.
 func OrdTokens(patterns []string, names []string) Parser {
@@ -155,7 +459,7 @@ func OrdTokens(patterns []string, names []string) Parser {
 		cursor := news.GetCursor()
 		if captures, _ := news.SubmatchAll(ordPattern); captures != nil {
 			for name, tok := range captures {
-				return NewTerminal(name, string(tok), cursor), news
+				return newTerminal(news, name, tokenValue(news, tok), cursor), news
 			}
 		}
 		return nil, s
@@ -186,6 +490,37 @@ func NoEnd() Parser {
 	}
 }
 
+// SkipToNextLine return parser function to skip the rest of the line at
+// cursor, including the newline, for line oriented formats like INI
+// files, where bad lines can be skipped, say by combining it with
+// Many as OrdChoice(nil, line, SkipToNextLine()). Return a Terminal named
+// SKIPPED_LINE with the content of the skipped line, excluding the line
+// ending, fails only at the end of input. Doesn't skip leading whitespace.
+func SkipToNextLine() Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		if s.Endof() {
+			return nil, s
+		}
+		news := s.Clone()
+		cursor := news.GetCursor()
+		tok, _ := news.Match(`^[^\n]*\n?`)
+		line := bytes.TrimSuffix(bytes.TrimSuffix(tok, []byte("\n")), []byte("\r"))
+		return newTerminal(news, "SKIPPED_LINE", tokenValue(news, line), cursor), news
+	}
+}
+
+// scanStringToken match double quoted string using Scanner interface, for
+// scanners other than SimpleScanner.
+func scanStringToken(s Scanner) (ParsecNode, Scanner) {
+	news := s.Clone()
+	if tok, _ := news.Match(`^"(?:[^"\\]|\\.)*"`); tok != nil {
+		if str, _ := ScanString(tok); len(str) > 0 {
+			return string(str), news
+		}
+	}
+	return nil, s
+}
+
 var escapeCode = [256]byte{ // TODO: size can be optimized
 	'"':  '"',
 	'\\': '\\',
@@ -198,88 +533,111 @@ var escapeCode = [256]byte{ // TODO: size can be optimized
 	't':  '\t',
 }
 
-func scanString(txt []byte) (tok []byte, readn int) {
-	if len(txt) < 2 {
+// ScanString scan double quoted string from the beginning of txt and
+// return it, quotes included, with escape sequences folded, along with
+// the number of bytes consumed. Return nil if txt does not begin with a
+// well formed string, like an unterminated string. Text between escapes
+// is located with bytes.IndexAny and copied in bulk, so long strings
+// with few escapes are cheap to scan.
+func ScanString(txt []byte) ([]byte, int) {
+	if len(txt) < 2 || txt[0] != '"' {
 		return nil, 0
 	}
 
+	// out is allocated on the first escape, until then the quoted text is
+	// returned as is.
+	var out []byte
 	e := 1
-	for txt[e] != '"' {
-		c := txt[e]
-		if c == '\\' || c == '"' || c < ' ' {
-			break
+	for {
+		n := bytes.IndexAny(txt[e:], `"\`)
+		if n < 0 { // unterminated string
+			return nil, 0
 		}
-		if c < utf8.RuneSelf {
-			e++
-			continue
+		run := txt[e : e+n]
+		for _, c := range run {
+			if c < ' ' { // control character is invalid
+				return nil, 0
+			}
 		}
-		r, size := utf8.DecodeRune(txt[e:])
-		if r == utf8.RuneError && size == 1 {
+		if utf8.Valid(run) {
+			if out != nil {
+				out = append(out, run...)
+			}
+		} else if out == nil {
 			return nil, 0
+		} else { // coerce to well-formed UTF-8
+			for i := 0; i < len(run); {
+				r, size := utf8.DecodeRune(run[i:])
+				out = utf8.AppendRune(out, r)
+				i += size
+			}
 		}
-		e += size
-	}
-
-	if txt[e] == '"' { // done we have nothing to unquote
-		return txt[:e+1], e + 1
-	}
+		e += n
 
-	out := make([]byte, len(txt)+2*utf8.UTFMax)
-	oute := copy(out, txt[:e]) // copy so far
-
-loop:
-	for e < len(txt) {
-		switch c := txt[e]; {
-		case c == '"':
-			out[oute] = c
-			e++
-			break loop
-
-		case c == '\\':
-			if txt[e+1] == 'u' {
-				r := getu4(txt[e:])
-				if r < 0 { // invalid
-					return nil, 0
-				}
-				e += 6
-				if utf16.IsSurrogate(r) {
-					nextr := getu4(txt[e:])
-					dec := utf16.DecodeRune(r, nextr)
-					if dec != unicode.ReplacementChar { // A valid pair consume
-						oute += utf8.EncodeRune(out[oute:], dec)
-						e += 6
-						break loop
-					}
-					// Invalid surrogate; fall back to replacement rune.
-					r = unicode.ReplacementChar
-				}
-				oute += utf8.EncodeRune(out[oute:], r)
-
-			} else { // escaped with " \ / ' b f n r t
-				out[oute] = escapeCode[txt[e+1]]
-				e += 2
-				oute++
+		if txt[e] == '"' {
+			if out == nil { // done we have nothing to unquote
+				return txt[:e+1], e + 1
 			}
+			return append(out, '"'), e + 1
+		}
 
-		case c < ' ': // control character is invalid
+		// fold the escape sequence.
+		if out == nil {
+			out = make([]byte, 0, 2*e+utf8.UTFMax)
+			out = append(out, txt[:e]...) // copy so far
+		}
+		if e+1 >= len(txt) {
 			return nil, 0
 
-		case c < utf8.RuneSelf: // ASCII
-			out[oute] = c
-			oute++
-			e++
+		} else if txt[e+1] == 'u' {
+			r := getu4(txt[e:])
+			if r < 0 { // invalid
+				return nil, 0
+			}
+			e += 6
+			if utf16.IsSurrogate(r) {
+				nextr := getu4(txt[e:])
+				dec := utf16.DecodeRune(r, nextr)
+				if dec != unicode.ReplacementChar { // A valid pair consume
+					out = utf8.AppendRune(out, dec)
+					e += 6
+					continue
+				}
+				// Invalid surrogate; fall back to replacement rune.
+				r = unicode.ReplacementChar
+			}
+			out = utf8.AppendRune(out, r)
 
-		default: // coerce to well-formed UTF-8
-			r, size := utf8.DecodeRune(txt[e:])
-			e += size
-			oute += utf8.EncodeRune(out[oute:], r)
+		} else { // escaped with " \ / ' b f n r t
+			out = append(out, escapeCode[txt[e+1]])
+			e += 2
 		}
 	}
+}
 
-	if out[oute] == '"' {
-		return out[:oute+1], e
+// canonicalUUID validate the group lengths and hex digits of uuid and
+// return it lowercased, without braces or urn prefix.
+func canonicalUUID(uuid string) (string, bool) {
+	if len(uuid) > 9 && strings.EqualFold(uuid[:9], "urn:uuid:") {
+		uuid = uuid[9:]
+	} else if strings.HasPrefix(uuid, "{") {
+		uuid = uuid[1 : len(uuid)-1]
+	}
+	groups := strings.Split(uuid, "-")
+	if len(groups) != 5 {
+		return "", false
+	}
+	for i, group := range groups {
+		if len(group) != [5]int{8, 4, 4, 4, 12}[i] {
+			return "", false
+		}
+		for _, c := range []byte(group) {
+			if !strings.ContainsRune("0123456789abcdefABCDEF", rune(c)) {
+				return "", false
+			}
+		}
 	}
-	return nil, 0
+	return strings.ToLower(uuid), true
 }
 
 // getu4 decodes \uXXXX from the beginning of s, returning the hex value,
@@ -2,6 +2,12 @@ package parsec
 
 import "testing"
 import "fmt"
+import "math/rand"
+import "reflect"
+import "strings"
+import "unicode"
+import "unicode/utf8"
+import "unicode/utf16"
 
 var _ = fmt.Sprintf("dummy")
 
@@ -67,15 +73,10 @@ func TestTerminalString(t *testing.T) {
 	}
 
 	// malformed string
-	func() {
-		defer func() {
-			if r := recover(); r == nil {
-				t.Errorf("expected panic")
-			}
-		}()
-		s = NewScanner([]byte(`"hello`))
-		String()(s)
-	}()
+	s = NewScanner([]byte(`"hello`))
+	if node, s = String()(s); node != nil || s.GetCursor() != 0 {
+		t.Errorf("unexpected %v %v", node, s.GetCursor())
+	}
 }
 
 func TestTerminalChar(t *testing.T) {
@@ -149,6 +150,44 @@ func TestTerminalFloat(t *testing.T) {
 	}
 }
 
+func TestTerminalSpecialFloat(t *testing.T) {
+	for _, literal := range []string{"NaN", "Infinity", "+Infinity", "-Infinity"} {
+		s := NewScanner([]byte(" " + literal + ","))
+		node, s := SpecialFloat()(s)
+		if node == nil {
+			t.Errorf("expected match for %q", literal)
+			continue
+		}
+		terminal := node.(*Terminal)
+		if terminal.Value != literal {
+			t.Errorf("expected %v, got %v", literal, terminal.Value)
+		} else if terminal.Name != "SPECIALFLOAT" {
+			t.Errorf("expected %v, got %v", "SPECIALFLOAT", terminal.Name)
+		} else if terminal.Position != 1 {
+			t.Errorf("expected %v, got %v", 1, terminal.Position)
+		} else if c := s.GetCursor(); c != len(literal)+1 {
+			t.Errorf("expected %v, got %v", len(literal)+1, c)
+		}
+	}
+	// not special float
+	s := NewScanner([]byte(`nan`))
+	if node, s := SpecialFloat()(s); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+	// configured spellings
+	y := SpecialFloat("inf", "-inf", "nan")
+	s = NewScanner([]byte(`-inf`))
+	if node, _ := y(s); node.(*Terminal).Value != "-inf" {
+		t.Errorf("expected %v, got %v", "-inf", node)
+	}
+	s = NewScanner([]byte(`Infinity`))
+	if node, _ := y(s); node != nil {
+		t.Errorf("unexpected %v", node)
+	}
+}
+
 func TestTerminalHex(t *testing.T) {
 	s := NewScanner([]byte(`0x10ab`))
 	node, _ := Hex()(s)
@@ -443,3 +482,528 @@ func BenchmarkTerminalOrdTokens(b *testing.B) {
 		Y(s)
 	}
 }
+
+func TestTokenNamed(t *testing.T) {
+	checkchildren := func(node ParsecNode, names, values []string, poss []int) {
+		t.Helper()
+		nt := node.(*NonTerminal)
+		if len(nt.Children) != len(names) {
+			t.Fatalf("expected %v children, got %v", len(names), nt.Children)
+		}
+		for i, child := range nt.Children {
+			term := child.(*Terminal)
+			if term.Name != names[i] {
+				t.Errorf("expected %v, got %v", names[i], term.Name)
+			} else if term.Value != values[i] {
+				t.Errorf("expected %v, got %v", values[i], term.Value)
+			} else if term.Position != poss[i] {
+				t.Errorf("expected %v, got %v", poss[i], term.Position)
+			}
+		}
+	}
+
+	// named groups
+	y := TokenNamed(`(?P<year>\d{4})-(?P<month>\d{2})`, "DATE")
+	node, s := y(NewScanner([]byte(" 2024-06 rest")))
+	if node == nil {
+		t.Fatalf("expected match")
+	} else if name := node.(*NonTerminal).Name; name != "DATE" {
+		t.Errorf("expected %v, got %v", "DATE", name)
+	} else if s.GetCursor() != 8 {
+		t.Errorf("expected %v, got %v", 8, s.GetCursor())
+	}
+	checkchildren(
+		node, []string{"year", "month"}, []string{"2024", "06"}, []int{1, 6})
+
+	// mixed named and unnamed groups
+	y = TokenNamed(`(\w+)@(?P<domain>\w+)\.(\w+)`, "EMAIL")
+	node, _ = y(NewScanner([]byte("user@example.com")))
+	checkchildren(node,
+		[]string{"GROUP1", "domain", "GROUP3"},
+		[]string{"user", "example", "com"},
+		[]int{0, 5, 13})
+
+	// duplicate names across alternation, only matching group is a child.
+	y = TokenNamed(`(?P<num>\d+)|x(?P<num>[a-f]+)`, "NUM")
+	node, _ = y(NewScanner([]byte("xbeef")))
+	checkchildren(node, []string{"num"}, []string{"beef"}, []int{1})
+	node, _ = y(NewScanner([]byte("42")))
+	checkchildren(node, []string{"num"}, []string{"42"}, []int{0})
+
+	// duplicate names, both matching, are in declaration order.
+	y = TokenNamed(`(?P<n>\d)(?P<n>\d)`, "PAIR")
+	node, _ = y(NewScanner([]byte("12")))
+	checkchildren(node, []string{"n", "n"}, []string{"1", "2"}, []int{0, 1})
+
+	// folding scanner, groups are from the input text.
+	y = TokenNamed(`(?P<KEY>[a-z]+)=(?P<VAL>[a-z]+)\b`, "KV")
+	node, _ = y(NewFoldingScanner([]byte("ABC=DEF")))
+	checkchildren(node, []string{"KEY", "VAL"}, []string{"ABC", "DEF"}, []int{0, 4})
+	node, _ = y(NewScannerString(" abc=def"))
+	checkchildren(node, []string{"KEY", "VAL"}, []string{"abc", "def"}, []int{1, 5})
+
+	// empty pattern
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("expected panic")
+			}
+		}()
+		TokenNamed("", "EMPTY")
+	}()
+
+	// no match
+	s = NewScanner([]byte("20-06"))
+	y = TokenNamed(`(?P<year>\d{4})-(?P<month>\d{2})`, "DATE")
+	if node, s := y(s); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+}
+
+func TestLiterals(t *testing.T) {
+	unit := And(nil, Int(), Literals("e", "px", "em", "%"))
+	testcases := []struct {
+		text, name, value string
+	}{
+		{"12em", "EM", "em"},
+		{"12 e", "E", "e"},
+		{"3px", "PX", "px"},
+		{"100 %", "%", "%"},
+	}
+	for _, tcase := range testcases {
+		node, s := unit(NewScanner([]byte(tcase.text)))
+		if node == nil || !s.Endof() {
+			t.Errorf("%q: expected match, got %v", tcase.text, node)
+			continue
+		}
+		term := node.([]ParsecNode)[1].(*Terminal)
+		if term.Name != tcase.name || term.Value != tcase.value {
+			t.Errorf("%q: unexpected %v", tcase.text, term)
+		}
+	}
+	if node, s := unit(NewScanner([]byte("12pt"))); node != nil {
+		t.Errorf("unexpected %v at %v", node, s.GetCursor())
+	}
+
+	// namer hook.
+	y := LiteralsNamed(func(lit string) string { return "OP_" + lit }, "<", "<=")
+	node, s := y(NewScanner([]byte(" <= x")))
+	if term := node.(*Terminal); term.Name != "OP_<=" || term.Position != 1 {
+		t.Errorf("unexpected %v", term)
+	} else if s.GetCursor() != 3 {
+		t.Errorf("expected %v, got %v", 3, s.GetCursor())
+	}
+
+	// folding scanner, value is the matched input, same as Atom.
+	fold := NewFoldingScanner([]byte("PX"))
+	if node, _ := Literals("px")(fold.Clone()); node.(*Terminal).Value != "PX" {
+		t.Errorf("unexpected %v", node)
+	} else if atom, _ := Atom("px", "PX")(fold.Clone()); atom.(*Terminal).Value != "PX" {
+		t.Errorf("unexpected %v", atom)
+	}
+
+	// empty literals are rejected.
+	for _, literals := range [][]string{{}, {"px", ""}} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("%q: expected panic", literals)
+				}
+			}()
+			Literals(literals...)
+		}()
+	}
+}
+
+func TestPrioritizedTokens(t *testing.T) {
+	y := PrioritizedTokens([]PriorityRule{
+		{Pattern: `[a-z]+`, Name: "IDENT", Priority: 1},
+		{Pattern: `(?:if|else)\b`, Name: "KEYWORD", Priority: 2},
+		{Pattern: `[0-9]+`, Name: "INT", Priority: 1},
+		{Pattern: `[0-9]+\.[0-9]+`, Name: "FLOAT", Priority: 1},
+		{Pattern: `[0-9]`, Name: "DIGIT", Priority: 1},
+	})
+	testcases := []struct {
+		text, name, value string
+		cursor            int
+	}{
+		{"if x", "KEYWORD", "if", 2},
+		{" iffy", "IDENT", "iffy", 5},
+		{"else", "KEYWORD", "else", 4},
+		{"12.5", "FLOAT", "12.5", 4},
+		{"12", "INT", "12", 2},
+		{"1", "INT", "1", 1}, // same priority and length, first declared.
+	}
+	for _, tcase := range testcases {
+		node, s := y(NewScanner([]byte(tcase.text)))
+		if node == nil {
+			t.Errorf("%q: expected match", tcase.text)
+			continue
+		}
+		term := node.(*Terminal)
+		if term.Name != tcase.name || term.Value != tcase.value {
+			t.Errorf("%q: unexpected %v", tcase.text, term)
+		} else if s.GetCursor() != tcase.cursor {
+			t.Errorf("%q: expected %v, got %v", tcase.text, tcase.cursor, s.GetCursor())
+		} else if pos := tcase.cursor - len(tcase.value); term.Position != pos {
+			t.Errorf("%q: expected %v, got %v", tcase.text, pos, term.Position)
+		}
+	}
+	if node, s := y(NewScanner([]byte(" +"))); node != nil || s.GetCursor() != 0 {
+		t.Errorf("unexpected %v at %v", node, s.GetCursor())
+	}
+
+	// alternations are anchored as a whole.
+	y = PrioritizedTokens([]PriorityRule{{Pattern: `GET|POST`, Name: "METHOD"}})
+	if node, s := y(NewScanner([]byte("xx POST"))); node != nil || s.GetCursor() != 0 {
+		t.Errorf("unexpected %v at %v", node, s.GetCursor())
+	}
+	if node, _ := y(NewScanner([]byte(" POST"))); node == nil {
+		t.Errorf("expected match")
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+	PrioritizedTokens([]PriorityRule{{Pattern: "", Name: "EMPTY"}})
+}
+
+func TestKeywords(t *testing.T) {
+	y := Keywords("in", "int", "interface")
+	testcases := []struct {
+		text, word string
+		cursor     int
+	}{
+		{" in x", "in", 3},
+		{"int", "int", 3},
+		{"interface{}", "interface", 9},
+		{"int(x)", "int", 3},
+	}
+	for _, tcase := range testcases {
+		node, s := y(NewScanner([]byte(tcase.text)))
+		if node == nil {
+			t.Errorf("%q: expected match", tcase.text)
+			continue
+		}
+		term := node.(*Terminal)
+		if term.Name != "KEYWORD" || term.Value != tcase.word {
+			t.Errorf("%q: unexpected %v", tcase.text, term)
+		} else if s.GetCursor() != tcase.cursor {
+			t.Errorf("%q: expected %v, got %v", tcase.text, tcase.cursor, s.GetCursor())
+		}
+	}
+	for _, text := range []string{"inx", "integer", "interfaces", "i", ""} {
+		if node, s := y(NewScanner([]byte(text))); node != nil {
+			t.Errorf("%q: unexpected %v", text, node)
+		} else if s.GetCursor() != 0 {
+			t.Errorf("%q: expected %v, got %v", text, 0, s.GetCursor())
+		}
+	}
+
+	// json literals
+	y = Keywords("true", "false", "null")
+	node, _ := Many(nil, y, Atom(",", "COMMA"))(NewScanner([]byte("null, true,false")))
+	if nodes := node.([]ParsecNode); len(nodes) != 3 {
+		t.Errorf("unexpected %v", nodes)
+	} else if v := nodes[2].(*Terminal).Value; v != "false" {
+		t.Errorf("expected %v, got %v", "false", v)
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("expected panic")
+			}
+		}()
+		Keywords()
+	}()
+}
+
+func TestScanString(t *testing.T) {
+	texts := []string{
+		`""`, `"hello world"`, `"hello" world`, `"tab\tnew\nline"`,
+		`"\"quoted\" and \\ slash"`, `"unicode \u00e9 and \u4e16"`,
+		`"pair \ud83d\ude00 and more"`, `"lone \ud83d surrogate"`,
+		`"héllo wörld \/"`, "\"ctrl \x01\"", "\"bad \xff utf8\"",
+		"\"esc \\n then bad \xff utf8\"", `"bad \u12g4"`,
+	}
+	for _, text := range texts {
+		reftok, refn := scanStringNaive([]byte(text))
+		tok, n := ScanString([]byte(text))
+		if n != refn || !reflect.DeepEqual(tok, reftok) {
+			t.Errorf("%q expected %q,%v got %q,%v", text, reftok, refn, tok, n)
+		}
+	}
+	// fast path must match the naive path on the whole document.
+	text := makeStringDoc(500)
+	for i := 0; i < len(text); i++ {
+		if text[i] != '"' {
+			continue
+		}
+		reftok, refn := scanStringNaive(text[i:])
+		tok, n := ScanString(text[i:])
+		if n != refn || !reflect.DeepEqual(tok, reftok) {
+			t.Fatalf("at %v expected %q,%v got %q,%v", i, reftok, refn, tok, n)
+		}
+		i += n - 1
+	}
+
+	// unterminated strings don't match.
+	for _, text := range []string{`"hello`, `"hello \"`, `"hello\`} {
+		if tok, n := ScanString([]byte(text)); tok != nil || n != 0 {
+			t.Errorf("%q unexpected %q,%v", text, tok, n)
+		}
+		if node, _ := String()(NewScanner([]byte(text))); node != nil {
+			t.Errorf("%q unexpected %v", text, node)
+		}
+	}
+}
+
+func BenchmarkScanString(b *testing.B) {
+	benchScanString(b, ScanString)
+}
+
+func BenchmarkScanStringNaive(b *testing.B) {
+	benchScanString(b, scanStringNaive)
+}
+
+func benchScanString(b *testing.B, scan func([]byte) ([]byte, int)) {
+	text := makeStringDoc(2000)
+	b.SetBytes(int64(len(text)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < len(text); j++ {
+			if text[j] == '"' {
+				_, n := scan(text[j:])
+				j += n - 1
+			}
+		}
+	}
+}
+
+// makeStringDoc return JSON array of `n` long strings, with an escape
+// sequence once in a while.
+func makeStringDoc(n int) []byte {
+	words := []string{
+		"lorem", "ipsum", "dolor", "sit", "amet", "héllo", "wörld",
+		`\n`, `\"`, `\u00e9`, `\ud83d\ude00`,
+	}
+	rnd := rand.New(rand.NewSource(1))
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteByte('"')
+		for j := 0; j < 40; j++ {
+			word := words[rnd.Intn(len(words)-4)]
+			if rnd.Intn(16) == 0 {
+				word = words[len(words)-4+rnd.Intn(4)]
+			}
+			sb.WriteString(word + " ")
+		}
+		sb.WriteByte('"')
+	}
+	sb.WriteByte(']')
+	return []byte(sb.String())
+}
+
+func TestUUID(t *testing.T) {
+	canonical := "123e4567-e89b-12d3-a456-426614174000"
+	refs := map[string]string{
+		"123e4567-e89b-12d3-a456-426614174000":           "",
+		" 123E4567-E89B-12D3-A456-426614174000":          "",
+		"{123e4567-e89b-12d3-a456-426614174000}":         "",
+		"urn:uuid:123e4567-e89b-12d3-a456-426614174000":  "",
+		"URN:UUID:123E4567-e89b-12d3-a456-426614174000}": "}",
+		"123e4567-e89b-12d3-a456-426614174000, 10":       ", 10",
+	}
+	for text, remain := range refs {
+		node, s := UUID()(NewScanner([]byte(text)))
+		if node == nil {
+			t.Errorf("expected match for %q", text)
+			continue
+		}
+		tok := node.(*Terminal)
+		if x := tok.GetAttribute("canonical"); len(x) != 1 || x[0] != canonical {
+			t.Errorf("%q expected %v, got %v", text, canonical, x)
+		}
+		if x := text[s.GetCursor():]; x != remain {
+			t.Errorf("%q expected %q, got %q", text, remain, x)
+		}
+	}
+
+	// malformed uuids.
+	texts := []string{
+		"123e4567-e89b-12d3-a456-42661417400",   // short last group
+		"123e4567-e89b-12d3-a456-4266141740000", // long last group
+		"123e4567e-89b-12d3-a456-426614174000",  // misplaced hyphen
+		"123e4567-e89b-12d3-a456",               // missing group
+		"123e4567-e89b-12d3-a456-42661417400g",  // not hex
+		"{123e4567-e89b-12d3-a456-426614174000", // unbalanced brace
+		"urn:uuid:{123e4567-e89b-12d3-a456-426614174000}",
+	}
+	for _, text := range texts {
+		if node, s := UUID()(NewScanner([]byte(text))); node != nil {
+			t.Errorf("%q unexpected %v", text, node)
+		} else if s.GetCursor() != 0 {
+			t.Errorf("%q expected cursor 0, got %v", text, s.GetCursor())
+		}
+	}
+}
+
+// scanStringNaive is the char-by-char reference for ScanString.
+func scanStringNaive(txt []byte) ([]byte, int) {
+	if len(txt) < 2 {
+		return nil, 0
+	}
+
+	e := 1
+	for txt[e] != '"' {
+		c := txt[e]
+		if c == '\\' || c == '"' || c < ' ' {
+			break
+		}
+		if c < utf8.RuneSelf {
+			e++
+			continue
+		}
+		r, size := utf8.DecodeRune(txt[e:])
+		if r == utf8.RuneError && size == 1 {
+			return nil, 0
+		}
+		e += size
+	}
+
+	if txt[e] == '"' { // done we have nothing to unquote
+		return txt[:e+1], e + 1
+	}
+
+	out := make([]byte, len(txt)+2*utf8.UTFMax)
+	oute := copy(out, txt[:e]) // copy so far
+
+loop:
+	for e < len(txt) {
+		switch c := txt[e]; {
+		case c == '"':
+			out[oute] = c
+			e++
+			break loop
+
+		case c == '\\':
+			if txt[e+1] == 'u' {
+				r := getu4(txt[e:])
+				if r < 0 { // invalid
+					return nil, 0
+				}
+				e += 6
+				if utf16.IsSurrogate(r) {
+					nextr := getu4(txt[e:])
+					dec := utf16.DecodeRune(r, nextr)
+					if dec != unicode.ReplacementChar { // A valid pair consume
+						oute += utf8.EncodeRune(out[oute:], dec)
+						e += 6
+						continue
+					}
+					// Invalid surrogate; fall back to replacement rune.
+					r = unicode.ReplacementChar
+				}
+				oute += utf8.EncodeRune(out[oute:], r)
+
+			} else { // escaped with " \ / ' b f n r t
+				out[oute] = escapeCode[txt[e+1]]
+				e += 2
+				oute++
+			}
+
+		case c < ' ': // control character is invalid
+			return nil, 0
+
+		case c < utf8.RuneSelf: // ASCII
+			out[oute] = c
+			oute++
+			e++
+
+		default: // coerce to well-formed UTF-8
+			r, size := utf8.DecodeRune(txt[e:])
+			e += size
+			oute += utf8.EncodeRune(out[oute:], r)
+		}
+	}
+
+	if out[oute] == '"' {
+		return out[:oute+1], e
+	}
+	return nil, 0
+}
+
+func TestSkipToNextLine(t *testing.T) {
+	eol := OrdChoice(nil, Token(`\n`, "NL"), End())
+	setting := And(nil, Ident(), Atom("=", "EQUAL"), Token(`[^\n]*`, "VALUE"), eol)
+	y := Many(nil, OrdChoice(nil, setting, SkipToNextLine()))
+
+	text := "a = 1\n!! bad line\r\nb = 2\n\n  c = 3\n???"
+	node, s := y(NewScannerWith([]byte(text), WithWS(`^[ \t]+`)))
+	if node == nil || !s.Endof() {
+		t.Fatalf("expected match, got %v", node)
+	}
+	refs := []string{"a", "!! bad line", "b", "", "c", "???"}
+	nodes := node.([]ParsecNode)
+	if len(nodes) != len(refs) {
+		t.Fatalf("expected %v, got %v", len(refs), len(nodes))
+	}
+	for i, ref := range refs {
+		n := nodes[i].([]ParsecNode)[0]
+		if seq, ok := n.([]ParsecNode); ok {
+			n = seq[0]
+		}
+		if val := n.(*Terminal).Value; val != ref {
+			t.Errorf("expected %q, got %q", ref, val)
+		}
+	}
+	skipped := nodes[1].([]ParsecNode)[0].(*Terminal)
+	if skipped.Name != "SKIPPED_LINE" || skipped.Position != 6 {
+		t.Errorf("unexpected %v %v", skipped.Name, skipped.Position)
+	}
+
+	// skips the rest of the line from cursor, fails at the end of input.
+	node, s = SkipToNextLine()(NewScannerString("last line"))
+	if node.(*Terminal).Value != "last line" || !s.Endof() {
+		t.Errorf("unexpected %v %v", node, s.GetCursor())
+	}
+	if node, _ = SkipToNextLine()(s); node != nil {
+		t.Errorf("unexpected %v", node)
+	}
+}
+
+func TestGap(t *testing.T) {
+	gap := Gap("GAP")
+	y := And(nil, Ident(), gap, Atom("=", "EQUAL"), gap, Int())
+
+	text := "x  // the x\n\t/* block\n comment */ =10"
+	node, s := y(NewScanner([]byte(text)))
+	if node == nil || !s.Endof() {
+		t.Fatalf("expected match, got %v", node)
+	}
+	nodes := node.([]ParsecNode)
+	g1, g2 := nodes[1].(*Terminal), nodes[3].(*Terminal)
+	if ref := "  // the x\n\t/* block\n comment */ "; g1.Value != ref {
+		t.Errorf("expected %q, got %q", ref, g1.Value)
+	} else if g1.Name != "GAP" || g1.Position != 1 {
+		t.Errorf("unexpected %v %v", g1.Name, g1.Position)
+	}
+	if g2.Value != "" || g2.Position != len(text)-2 {
+		t.Errorf("unexpected %q %v", g2.Value, g2.Position)
+	}
+
+	// unterminated block comment is not part of the gap.
+	node, s = gap(NewScannerString(" \n/* x"))
+	if node.(*Terminal).Value != " \n" || s.GetCursor() != 2 {
+		t.Errorf("unexpected %q %v", node.(*Terminal).Value, s.GetCursor())
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "regexp"
+import "strings"
+
+// TokenFuncScanner implements Scanner interface over a stream of tokens
+// produced by an external lexer, like the ones built using text/scanner
+// or bufio.Scanner. Instead of matching input text, Match, SubmatchAll,
+// SkipAny and TryMatch match the next token's name with the pattern and
+// MatchString matches the next token's value with the string. Matched
+// tokens return their value, hence parsers like Token and Atom can be
+// used as is, say Token("IDENT", "IDENT") or Atom("(", "LPAREN").
+//
+// Tokens read from the lexer are buffered and shared by the scanner and
+// all its clones, so that combinators can backtrack. GetCursor returns
+// the position of the next token, as reported by the lexer, or the
+// position after the last token at the end of stream. SkipWS is a no-op,
+// since white space is expected to be handled by the lexer, and Lineno
+// is not supported. BytesRemaining reads the rest of the stream, to
+// return the bytes till the end of the last token.
+type TokenFuncScanner struct {
+	stream       *tokenStream
+	index        int // index of next token in stream
+	patternCache map[string]*regexp.Regexp
+}
+
+// FromTokenFunc create and return a new instance of TokenFuncScanner.
+// `next` shall return the name, value and position of the next token
+// in the stream, and ok as false at the end of the stream.
+func FromTokenFunc(
+	next func() (name, value string, pos int, ok bool)) Scanner {
+
+	return &TokenFuncScanner{
+		stream:       &tokenStream{next: next},
+		index:        0,
+		patternCache: make(map[string]*regexp.Regexp),
+	}
+}
+
+//---- Scanner{} interface.
+
+// SetWSPattern implement Scanner{} interface, white space is handled by
+// the lexer, hence ignored.
+func (s *TokenFuncScanner) SetWSPattern(pattern string) Scanner {
+	return s
+}
+
+// TrackLineno implement Scanner{} interface, not supported.
+func (s *TokenFuncScanner) TrackLineno() Scanner {
+	return s
+}
+
+// Clone implement Scanner{} interface.
+func (s *TokenFuncScanner) Clone() Scanner {
+	return &TokenFuncScanner{
+		stream:       s.stream,
+		index:        s.index,
+		patternCache: s.patternCache,
+	}
+}
+
+// GetCursor implement Scanner{} interface.
+func (s *TokenFuncScanner) GetCursor() int {
+	if tok, ok := s.stream.get(s.index); ok {
+		return tok.pos
+	} else if s.index > 0 {
+		tok, _ := s.stream.get(s.index - 1)
+		return tok.pos + len(tok.value)
+	}
+	return 0
+}
+
+// Match implement Scanner{} interface.
+func (s *TokenFuncScanner) Match(pattern string) ([]byte, Scanner) {
+	if value, ok := s.TryMatch(pattern); ok {
+		s.index++
+		return value, s
+	}
+	return nil, s
+}
+
+// MatchString implement Scanner{} interface.
+func (s *TokenFuncScanner) MatchString(str string) (bool, Scanner) {
+	if tok, ok := s.stream.get(s.index); ok && tok.value == str {
+		s.index++
+		return true, s
+	}
+	return false, s
+}
+
+// SubmatchAll implement Scanner{} interface, return the next token's
+// value keyed by its name.
+func (s *TokenFuncScanner) SubmatchAll(
+	pattern string) (map[string][]byte, Scanner) {
+
+	if tok, ok := s.stream.get(s.index); ok && s.matchname(pattern, tok) {
+		s.index++
+		return map[string][]byte{tok.name: []byte(tok.value)}, s
+	}
+	return nil, s
+}
+
+// SkipWS implement Scanner{} interface, white space is handled by the
+// lexer, hence a no-op.
+func (s *TokenFuncScanner) SkipWS() ([]byte, Scanner) {
+	return nil, s
+}
+
+// SkipAny implement Scanner{} interface.
+func (s *TokenFuncScanner) SkipAny(pattern string) ([]byte, Scanner) {
+	return s.Match(pattern)
+}
+
+// TryMatch implement Scanner{} interface.
+func (s *TokenFuncScanner) TryMatch(pattern string) ([]byte, bool) {
+	if tok, ok := s.stream.get(s.index); ok && s.matchname(pattern, tok) {
+		return []byte(tok.value), true
+	}
+	return nil, false
+}
+
+// SkipN implement Scanner{} interface, skip tokens positioned within
+// the next `n` bytes.
+func (s *TokenFuncScanner) SkipN(n int) Scanner {
+	till := s.GetCursor() + n
+	for tok, ok := s.stream.get(s.index); ok && tok.pos < till; {
+		s.index++
+		tok, ok = s.stream.get(s.index)
+	}
+	return s
+}
+
+// Lineno implement Scanner{} interface, not supported.
+func (s *TokenFuncScanner) Lineno() int {
+	return 0
+}
+
+// Endof implement Scanner{} interface.
+func (s *TokenFuncScanner) Endof() bool {
+	_, ok := s.stream.get(s.index)
+	return !ok
+}
+
+// BytesRemaining implement Scanner{} interface.
+func (s *TokenFuncScanner) BytesRemaining() int {
+	if s.Endof() {
+		return 0
+	}
+	tokens := s.stream.readall()
+	last := tokens[len(tokens)-1]
+	return last.pos + len(last.value) - s.GetCursor()
+}
+
+//---- local methods
+
+// matchname match the whole of token's name with pattern.
+func (s *TokenFuncScanner) matchname(pattern string, tok streamToken) bool {
+	pattern = "^(?:" + strings.TrimPrefix(pattern, "^") + ")$"
+	return getPattern(s.patternCache, pattern).MatchString(tok.name)
+}
+
+type streamToken struct {
+	name, value string
+	pos         int
+}
+
+// tokenStream buffers tokens read from the lexer.
+type tokenStream struct {
+	next   func() (name, value string, pos int, ok bool)
+	tokens []streamToken
+	eof    bool
+}
+
+// get token at `index`, reading from lexer as needed.
+func (ts *tokenStream) get(index int) (streamToken, bool) {
+	for !ts.eof && index >= len(ts.tokens) {
+		name, value, pos, ok := ts.next()
+		if !ok {
+			ts.eof = true
+			break
+		}
+		ts.tokens = append(ts.tokens, streamToken{name, value, pos})
+	}
+	if index < len(ts.tokens) {
+		return ts.tokens[index], true
+	}
+	return streamToken{}, false
+}
+
+// readall tokens from lexer till the end of stream.
+func (ts *tokenStream) readall() []streamToken {
+	for !ts.eof {
+		ts.get(len(ts.tokens))
+	}
+	return ts.tokens
+}
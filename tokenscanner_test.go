@@ -0,0 +1,95 @@
+package parsec
+
+import "testing"
+
+func makeTokenFunc(tokens [][2]string) (func() (string, string, int, bool), *int) {
+	calls, i, pos := new(int), 0, 0
+	next := func() (name, value string, p int, ok bool) {
+		*calls++
+		if i >= len(tokens) {
+			return "", "", 0, false
+		}
+		name, value, p = tokens[i][0], tokens[i][1], pos
+		i, pos = i+1, pos+len(value)+1
+		return name, value, p, true
+	}
+	return next, calls
+}
+
+func TestTokenFuncScanner(t *testing.T) {
+	next, calls := makeTokenFunc([][2]string{
+		{"IDENT", "x"}, {"OP", "="}, {"INT", "10"}, {"OP", "+"}, {"IDENT", "y"},
+	})
+	s := FromTokenFunc(next)
+
+	// backtracking, first alternative fails after consuming two tokens.
+	assign1 := And(nil, Token("IDENT", "ID"), Atom("=", "EQ"), Token("IDENT", "ID"))
+	expr := Many(nil, Token("INT|IDENT", "OPERAND"), Atom("+", "PLUS"))
+	assign2 := And(nil, Token("IDENT", "ID"), Atom("=", "EQ"), expr)
+	node, s := OrdChoice(nil, assign1, assign2)(s)
+	if node == nil {
+		t.Fatalf("expected match")
+	} else if !s.Endof() {
+		t.Fatalf("expected end of stream")
+	}
+	nodes := node.([]ParsecNode)[0].([]ParsecNode)
+	operands := nodes[2].([]ParsecNode)
+	if len(operands) != 2 {
+		t.Fatalf("unexpected %v", operands)
+	} else if term := operands[1].(*Terminal); term.Value != "y" {
+		t.Errorf("expected %v, got %v", "y", term.Value)
+	} else if term.Position != 9 {
+		t.Errorf("expected %v, got %v", 9, term.Position)
+	}
+	// each token is read once, plus once for end of stream.
+	if *calls != 6 {
+		t.Errorf("expected %v, got %v", 6, *calls)
+	}
+
+	// end of stream
+	if s.GetCursor() != 10 {
+		t.Errorf("expected %v, got %v", 10, s.GetCursor())
+	} else if tok, _ := s.Match("IDENT"); tok != nil {
+		t.Errorf("unexpected %q", tok)
+	} else if ok, _ := s.MatchString("y"); ok {
+		t.Errorf("unexpected match")
+	} else if _, ok := s.TryMatch(".*"); ok {
+		t.Errorf("unexpected match")
+	} else if *calls != 6 {
+		t.Errorf("expected %v, got %v", 6, *calls)
+	}
+
+	// token names are matched in whole, SkipN skips by position.
+	next, _ = makeTokenFunc([][2]string{{"IDENTX", "a"}, {"IDENT", "bc"}})
+	s = FromTokenFunc(next)
+	if tok, _ := s.Match("IDENT"); tok != nil {
+		t.Errorf("unexpected %q", tok)
+	} else if tok, ok := s.TryMatch("IDENT.*"); !ok || string(tok) != "a" {
+		t.Errorf("expected %q, got %q", "a", tok)
+	} else if s = s.SkipN(1); s.GetCursor() != 2 {
+		t.Errorf("expected %v, got %v", 2, s.GetCursor())
+	}
+	if captures, _ := s.SubmatchAll("IDENT"); string(captures["IDENT"]) != "bc" {
+		t.Errorf("unexpected %v", captures)
+	} else if !s.Endof() {
+		t.Errorf("expected end of stream")
+	}
+
+	// empty stream
+	next, _ = makeTokenFunc(nil)
+	s = FromTokenFunc(next)
+	if !s.Endof() || s.GetCursor() != 0 {
+		t.Errorf("expected end of stream")
+	} else if n := s.BytesRemaining(); n != 0 {
+		t.Errorf("expected %v, got %v", 0, n)
+	}
+	next, _ = makeTokenFunc([][2]string{{"IDENT", "a"}, {"OP", "+"}})
+	s = FromTokenFunc(next)
+	if n := s.BytesRemaining(); n != 3 {
+		t.Errorf("expected %v, got %v", 3, n)
+	} else if _, s = s.Match("IDENT"); s.BytesRemaining() != 1 {
+		t.Errorf("expected %v, got %v", 1, s.BytesRemaining())
+	} else if s.SkipN(s.BytesRemaining()); !s.Endof() {
+		t.Errorf("expected end of stream")
+	}
+}
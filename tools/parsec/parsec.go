@@ -3,6 +3,8 @@
 
 package main
 
+import encjson "encoding/json"
+import "errors"
 import "flag"
 import "fmt"
 import "io/ioutil"
@@ -13,8 +15,11 @@ import "github.com/prataprc/goparsec/expr"
 import "github.com/prataprc/goparsec/json"
 
 var options struct {
-	expr string
-	json string
+	expr        string
+	json        string
+	progress    bool
+	tolerant    bool
+	errorFormat string
 }
 
 func argParse() {
@@ -22,30 +27,210 @@ func argParse() {
 		"Specify input file or arithmetic expression string")
 	flag.StringVar(&options.json, "json", "",
 		"Specify input file or json string")
+	flag.BoolVar(&options.progress, "progress", false,
+		"Show parsing progress on stderr")
+	flag.BoolVar(&options.tolerant, "tolerant", false,
+		"Parse `;` terminated expressions, reporting all errors")
+	flag.StringVar(&options.errorFormat, "error-format", "text",
+		"Report errors as text on stderr, or as json array on stdout")
 	flag.Parse()
+	if options.errorFormat != "text" && options.errorFormat != "json" {
+		fmsg := "invalid -error-format %q, expected text or json\n"
+		fmt.Fprintf(os.Stderr, fmsg, options.errorFormat)
+		os.Exit(2)
+	}
 }
 
 func main() {
 	argParse()
 	if options.expr != "" {
-		doExpr(getText(options.expr))
+		doExpr(inputName(options.expr), getText(options.expr))
 	} else if options.json != "" {
-		doJSON(getText(options.json))
+		doJSON(inputName(options.json), getText(options.json))
 	}
 }
 
-func doExpr(text string) {
-	s := parsec.NewScanner([]byte(text))
+func doExpr(name, text string) {
+	if options.errorFormat == "json" {
+		diags, ok := exprDiagnostics(name, text, options.tolerant)
+		printDiagnostics(diags)
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	}
+	if !options.progress && !options.tolerant {
+		v, err := parsec.ParseString(expr.Y, text)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(v)
+		return
+	}
+	opts := []parsec.ScannerOption{}
+	if options.progress {
+		opts = append(opts, parsec.WithProgress(progressEvery(text), showProgress))
+		defer fmt.Fprintln(os.Stderr)
+	}
+	s := parsec.NewScannerWith([]byte(text), opts...)
+	if options.tolerant {
+		doExprTolerant(s)
+		return
+	}
 	v, _ := expr.Y(s)
 	fmt.Println(v)
 }
 
-func doJSON(text string) {
-	s := json.NewJSONScanner([]byte(text))
+// doExprTolerant parse a list of `;` terminated expressions, skipping
+// past the next `;` on error, and print all errors.
+func doExprTolerant(s parsec.Scanner) {
+	res := parsec.Run(exprStatements(), s, parsec.WithRecovery(0))
+	if res.Node != nil {
+		for _, v := range res.Node.([]parsec.ParsecNode) {
+			if _, ok := v.(*parsec.ErrorNode); !ok {
+				fmt.Println(v)
+			}
+		}
+	}
+	if errs, ok := res.Err.(parsec.ErrorList); ok {
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	} else if res.Err != nil {
+		fmt.Fprintln(os.Stderr, res.Err)
+	}
+}
+
+// exprStatements return parser for a list of `;` terminated expressions,
+// recovering from errors till the next `;`.
+func exprStatements() parsec.Parser {
+	semicolon := parsec.Atom(";", "SEMICOLON")
+	first := func(ns []parsec.ParsecNode) parsec.ParsecNode { return ns[0] }
+	stmt := parsec.Recover(parsec.And(first, expr.Y, semicolon), ";")
+	return parsec.Kleene(nil, stmt)
+}
+
+func doJSON(name, text string) {
+	if options.errorFormat == "json" {
+		printDiagnostics(jsonDiagnostics(name, text))
+		return
+	}
+	s := parsec.Scanner(json.NewJSONScanner([]byte(text)))
+	if options.progress {
+		js := s.(*json.JSONScanner)
+		s = js.OnProgress(progressEvery(text), showProgress)
+		defer fmt.Fprintln(os.Stderr)
+	}
 	v, _ := json.Y(s)
 	fmt.Println(v)
 }
 
+// diagnostic describes a parse error for -error-format json.
+type diagnostic struct {
+	File    string `json:"file"` // empty if input is given on command line.
+	Offset  int    `json:"offset"`
+	Line    int    `json:"line"`
+	Col     int    `json:"col"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Skipped *span  `json:"skipped,omitempty"` // input skipped on recovery.
+}
+
+// span of input text, [Start, End).
+type span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+func newDiagnostic(name, text string, perr *parsec.ParseError) diagnostic {
+	line, col := parsec.LineCol([]byte(text), perr.Offset)
+	code := "syntax"
+	if errors.Is(perr, parsec.ErrBacktrackLimit) {
+		code = "backtrack-limit"
+	}
+	return diagnostic{
+		File: name, Offset: perr.Offset, Line: line, Col: col,
+		Code: code, Message: perr.Msg,
+	}
+}
+
+// exprDiagnostics parse arithmetic expression, or in tolerant mode a
+// list of expressions, and return the errors. Return false if parsing
+// failed, except in tolerant mode.
+func exprDiagnostics(name, text string, tolerant bool) ([]diagnostic, bool) {
+	diags := []diagnostic{}
+	if !tolerant {
+		if _, err := parsec.ParseString(expr.Y, text); err != nil {
+			perr := err.(*parsec.SourceError).Err
+			return append(diags, newDiagnostic(name, text, perr)), false
+		}
+		return diags, true
+	}
+
+	s := parsec.NewScanner([]byte(text))
+	res := parsec.Run(exprStatements(), s, parsec.WithRecovery(0))
+	if res.Node != nil {
+		for _, v := range res.Node.([]parsec.ParsecNode) {
+			if en, ok := v.(*parsec.ErrorNode); ok && en.Err != nil {
+				diag := newDiagnostic(name, text, en.Err)
+				diag.Skipped = &span{en.Position, en.Position + len(en.Value)}
+				diags = append(diags, diag)
+			}
+		}
+	} else if perr, ok := res.Err.(*parsec.ParseError); ok {
+		diags = append(diags, newDiagnostic(name, text, perr))
+	}
+	return diags, true
+}
+
+// jsonDiagnostics parse json text and return the errors, positioned at
+// the furthest offset where a token was expected.
+func jsonDiagnostics(name, text string) []diagnostic {
+	furthest := 0
+	s := json.NewJSONScanner([]byte(text)).OnProgress(1,
+		func(cursor, total int64) { furthest = int(cursor) })
+	res := parsec.Run(json.Y, s)
+	if res.Err == nil {
+		return []diagnostic{}
+	}
+	perr := res.Err.(*parsec.ParseError)
+	if furthest > perr.Offset {
+		perr.Offset = furthest
+	}
+	return []diagnostic{newDiagnostic(name, text, perr)}
+}
+
+func printDiagnostics(diags []diagnostic) {
+	data, err := encjson.Marshal(diags)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(data))
+}
+
+// progressEvery report progress for every percent of input text.
+func progressEvery(text string) int {
+	return len(text)/100 + 1
+}
+
+func showProgress(consumed, total int64) {
+	if total > 0 {
+		fmt.Fprintf(os.Stderr, "\rparsing %3d%%", consumed*100/total)
+	} else {
+		fmt.Fprintf(os.Stderr, "\rparsing %d bytes", consumed)
+	}
+}
+
+// inputName return the file name for input argument, empty if the input
+// is given on command line.
+func inputName(arg string) string {
+	if _, err := os.Stat(arg); err != nil {
+		return ""
+	}
+	return arg
+}
+
 func getText(filename string) string {
 	if _, err := os.Stat(filename); err != nil {
 		return filename
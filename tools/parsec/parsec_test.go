@@ -0,0 +1,64 @@
+package main
+
+import encjson "encoding/json"
+import "io/ioutil"
+import "reflect"
+import "testing"
+
+func TestJSONDiagnostics(t *testing.T) {
+	name := "testdata/broken.json"
+	text, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := jsonDiagnostics(name, string(text))
+	ref := []diagnostic{{
+		File: name, Offset: 45, Line: 3, Col: 22,
+		Code: "syntax", Message: "parse error",
+	}}
+	if !reflect.DeepEqual(diags, ref) {
+		t.Errorf("expected %v, got %v", ref, diags)
+	}
+	data, _ := encjson.Marshal(diags)
+	refdata := `[{"file":"testdata/broken.json","offset":45,"line":3,"col":22,` +
+		`"code":"syntax","message":"parse error"}]`
+	if string(data) != refdata {
+		t.Errorf("expected %s, got %s", refdata, data)
+	}
+
+	diags = jsonDiagnostics("", `{"tags": ["parser", "go"]}`)
+	if data, _ := encjson.Marshal(diags); string(data) != "[]" {
+		t.Errorf("expected %s, got %s", "[]", data)
+	}
+}
+
+func TestExprDiagnostics(t *testing.T) {
+	diags, ok := exprDiagnostics("", "1 +\n 2 *", false)
+	ref := []diagnostic{
+		{Offset: 8, Line: 2, Col: 5, Code: "syntax", Message: "parse error"},
+	}
+	if ok {
+		t.Errorf("expected failure")
+	} else if !reflect.DeepEqual(diags, ref) {
+		t.Errorf("expected %v, got %v", ref, diags)
+	}
+
+	diags, ok = exprDiagnostics("", "1+2; 3*; 4", true)
+	ref = []diagnostic{
+		{Offset: 5, Line: 1, Col: 6, Code: "syntax", Message: "parse error",
+			Skipped: &span{5, 8}},
+		{Offset: 9, Line: 1, Col: 10, Code: "syntax", Message: "parse error",
+			Skipped: &span{9, 10}},
+	}
+	if !ok {
+		t.Errorf("expected success in tolerant mode")
+	} else if !reflect.DeepEqual(diags, ref) {
+		data, _ := encjson.Marshal(diags)
+		t.Errorf("unexpected %s", data)
+	}
+
+	diags, ok = exprDiagnostics("", "1+2", false)
+	if data, _ := encjson.Marshal(diags); !ok || string(data) != "[]" {
+		t.Errorf("expected %s, got %s", "[]", data)
+	}
+}